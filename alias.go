@@ -0,0 +1,276 @@
+package jubako
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/yacchi/jubako/container"
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/jsonptr"
+	"github.com/yacchi/jubako/layer"
+)
+
+// DeprecationWarning reports a value read from an alias of a field marked
+// with the deprecated directive, e.g. `jubako:"alias=/server/listen_port,deprecated"`.
+type DeprecationWarning struct {
+	// Path is the schema path of the field that receives the value.
+	Path string
+	// Alias is the deprecated path the value was read from.
+	Alias string
+	// Layer provides metadata about the layer containing the value.
+	Layer LayerInfo
+	// Message is a human-readable description of the warning.
+	Message string
+}
+
+func (w DeprecationWarning) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "jubako: WARNING: %s (from layer %s", w.Message, w.Layer.Name())
+	if path := w.Layer.Path(); path != "" {
+		fmt.Fprintf(&sb, " (%s)", path)
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// DeprecationWarningHandler is called when a value is read from a deprecated alias.
+type DeprecationWarningHandler func(warning DeprecationWarning)
+
+// defaultDeprecationWarningHandler logs warnings to stderr using the standard log package.
+var defaultDeprecationWarningHandler DeprecationWarningHandler = func(w DeprecationWarning) {
+	log.Println(w.String())
+}
+
+// deprecationWarningHandler is the current handler for deprecation warnings.
+// Can be set via SetDeprecationWarningHandler.
+var deprecationWarningHandler = defaultDeprecationWarningHandler
+
+// SetDeprecationWarningHandler sets a custom handler for deprecation warnings.
+// Each deprecated alias is reported once per Store while it stays in a layer.
+// Pass nil to disable warnings.
+// This function is not thread-safe and should be called during initialization.
+// The handler is called while the Store is locked, so it must not call methods of the Store.
+//
+// Example:
+//
+//	jubako.SetDeprecationWarningHandler(func(w jubako.DeprecationWarning) {
+//	    myLogger.Warn(w.Message, "layer", w.Layer.Name(), "file", w.Layer.Path())
+//	})
+func SetDeprecationWarningHandler(handler DeprecationWarningHandler) {
+	deprecationWarningHandler = handler
+}
+
+// aliasMove is a value moved from an alias to the path of its field.
+type aliasMove struct {
+	path       string
+	alias      string
+	deprecated bool
+}
+
+// collectAliasMappings returns the mappings that declare aliases.
+func collectAliasMappings(schema *Schema) []*PathMapping {
+	var mappings []*PathMapping
+	for _, m := range schema.Mappings {
+		if len(m.AliasPaths) > 0 && !m.Skipped {
+			mappings = append(mappings, m)
+		}
+	}
+	return mappings
+}
+
+// resolveAliases moves the values at alias paths in data to the paths of their
+// fields, for the fields that have no value in data. The first alias present wins.
+// It returns a modified copy of data and the moves, or data itself if nothing moved.
+func resolveAliases(data map[string]any, mappings []*PathMapping) (map[string]any, []aliasMove) {
+	if data == nil {
+		return nil, nil
+	}
+	result := data
+	var moves []aliasMove
+	for _, m := range mappings {
+		target, err := jsonptr.Parse(m.Path)
+		if err != nil {
+			continue
+		}
+		for _, aliasPath := range m.AliasPaths {
+			segments, err := jsonptr.Parse(aliasPath)
+			if err != nil {
+				continue
+			}
+			matchPaths(result, segments, "", nil, func(alias string, value any, captured []string) {
+				path, ok := fillWildcards(target, captured)
+				if !ok || path == alias {
+					return
+				}
+				if _, exists := jsonptr.GetPath(result, path); exists {
+					return
+				}
+				if len(moves) == 0 {
+					result = container.DeepCopyMap(data)
+				}
+				jsonptr.SetPath(result, path, container.DeepCopyValue(value))
+				jsonptr.DeletePath(result, alias)
+				moves = append(moves, aliasMove{path: path, alias: alias, deprecated: m.Deprecated})
+			})
+		}
+	}
+	return result, moves
+}
+
+// matchPaths calls fn for each value in data at a path matching segments, where
+// "*" matches any map key or slice index. captured holds the matched wildcard segments.
+func matchPaths(data any, segments []string, path string, captured []string, fn func(path string, value any, captured []string)) {
+	if len(segments) == 0 {
+		fn(path, data, captured)
+		return
+	}
+	seg, rest := segments[0], segments[1:]
+	switch v := data.(type) {
+	case map[string]any:
+		if seg != "*" {
+			if child, ok := v[seg]; ok {
+				matchPaths(child, rest, path+"/"+jsonptr.Escape(seg), captured, fn)
+			}
+			return
+		}
+		for _, key := range sortedKeys(v) {
+			matchPaths(v[key], rest, path+"/"+jsonptr.Escape(key), append(captured[:len(captured):len(captured)], key), fn)
+		}
+	case []any:
+		if seg != "*" {
+			return
+		}
+		for i, elem := range v {
+			index := strconv.Itoa(i)
+			matchPaths(elem, rest, path+"/"+index, append(captured[:len(captured):len(captured)], index), fn)
+		}
+	}
+}
+
+// fillWildcards builds a path from segments, replacing each "*" with the next
+// captured segment. It fails unless every captured segment is used.
+func fillWildcards(segments []string, captured []string) (string, bool) {
+	var sb strings.Builder
+	used := 0
+	for _, seg := range segments {
+		if seg == "*" {
+			if used >= len(captured) {
+				return "", false
+			}
+			seg = captured[used]
+			used++
+		}
+		sb.WriteString("/" + jsonptr.Escape(seg))
+	}
+	return sb.String(), used == len(captured)
+}
+
+// resolveAliasesLocked resolves the aliases in every layer's data and reports
+// the deprecated aliases that were not reported before. It returns the aliased
+// data of the layers that read values from aliases.
+// Caller must hold the write lock.
+func (s *Store[T]) resolveAliasesLocked() map[*layerEntry]map[string]any {
+	if len(s.aliasMappings) == 0 {
+		return nil
+	}
+
+	aliased := make(map[*layerEntry]map[string]any)
+	reported := make(map[string]struct{})
+	for _, entry := range s.layers {
+		data, moves := resolveAliases(entry.data, s.aliasMappings)
+		if len(moves) == 0 {
+			continue
+		}
+		aliased[entry] = data
+		for _, move := range moves {
+			if !move.deprecated {
+				continue
+			}
+			key := string(entry.layer.Name()) + "\x00" + move.alias
+			reported[key] = struct{}{}
+			if _, ok := s.reportedAliases[key]; ok || deprecationWarningHandler == nil {
+				continue
+			}
+			deprecationWarningHandler(DeprecationWarning{
+				Path:    move.path,
+				Alias:   move.alias,
+				Layer:   layerInfoAt(entry, move.alias),
+				Message: fmt.Sprintf("%s is deprecated, use %s instead", move.alias, move.path),
+			})
+		}
+	}
+	s.reportedAliases = reported
+	return aliased
+}
+
+// MigrateAliases rewrites the values that a layer provides through aliases
+// (the alias= directive in jubako struct tags) to the current paths of their
+// fields. For each value, a remove patch for the alias and an add patch for the
+// field's path are queued, so that the next Save or SaveLayer updates the
+// layer's document in place, preserving comments for formats that support it.
+//
+// The resolved configuration does not change. MigrateAliases is a no-op if the
+// layer reads no value from an alias.
+//
+// Example:
+//
+//	// Rewrite server.listen_port to server.port in the user's config file
+//	if err := store.MigrateAliases("user"); err != nil {
+//	    log.Fatal(err)
+//	}
+//	if err := store.SaveLayer(ctx, "user"); err != nil {
+//	    log.Fatal(err)
+//	}
+func (s *Store[T]) MigrateAliases(layerName layer.Name) error {
+	current, subscribers, err := s.migrateAliasesLocked(layerName)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subscribers {
+		sub.fn(current)
+	}
+	return nil
+}
+
+// migrateAliasesLocked performs the migration and materialization under lock.
+// Returns the current configuration and subscribers snapshot for notification outside the lock.
+func (s *Store[T]) migrateAliasesLocked(layerName layer.Name) (T, []subscriber[T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var zero T
+	entry := s.findLayerLocked(layerName)
+	if entry == nil {
+		return zero, nil, fmt.Errorf("layer %q not found", layerName)
+	}
+	if !entry.Writable() {
+		if entry.readOnly {
+			return zero, nil, fmt.Errorf("layer %q is marked as read-only", layerName)
+		}
+		return zero, nil, fmt.Errorf("layer %q does not support saving (source is not writable)", layerName)
+	}
+	if entry.data == nil {
+		return zero, nil, fmt.Errorf("layer %q has not been loaded", layerName)
+	}
+
+	data, moves := resolveAliases(entry.data, s.aliasMappings)
+	if len(moves) == 0 {
+		return s.resolved.Get(), nil, nil
+	}
+
+	snapshot := s.snapshotLayersLocked()
+	for _, move := range moves {
+		value, _ := jsonptr.GetPath(data, move.path)
+		entry.changeset = append(entry.changeset,
+			document.NewRemovePatch(move.alias),
+			document.NewAddPatch(move.path, container.DeepCopyValue(value)),
+		)
+	}
+	entry.data = data
+	s.syncLayerDirty(entry)
+
+	return s.commitLocked(context.Background(), snapshot, []layer.Name{layerName})
+}
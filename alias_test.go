@@ -0,0 +1,165 @@
+package jubako
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/layer/mapdata"
+)
+
+type aliasTestBackend struct {
+	Host string `json:"host" jubako:"alias=hostname"`
+}
+
+type aliasTestConfig struct {
+	Server struct {
+		Port int    `json:"port" jubako:"alias=/server/listen_port|/port,deprecated"`
+		Host string `json:"host"`
+	} `json:"server"`
+	Backends map[string]aliasTestBackend `json:"backends"`
+}
+
+// captureDeprecationWarnings replaces the deprecation warning handler for the test.
+func captureDeprecationWarnings(t *testing.T) *[]DeprecationWarning {
+	t.Helper()
+	var warnings []DeprecationWarning
+	SetDeprecationWarningHandler(func(w DeprecationWarning) {
+		warnings = append(warnings, w)
+	})
+	t.Cleanup(func() { SetDeprecationWarningHandler(defaultDeprecationWarningHandler) })
+	return &warnings
+}
+
+func TestStore_Alias(t *testing.T) {
+	ctx := context.Background()
+	warnings := captureDeprecationWarnings(t)
+
+	store := New[aliasTestConfig]()
+	if err := store.Add(mapdata.New("defaults", map[string]any{
+		"server": map[string]any{"port": 80, "host": "localhost"},
+	})); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Add(mapdata.New("user", map[string]any{
+		"server":   map[string]any{"listen_port": 8080},
+		"port":     9090,
+		"backends": map[string]any{"a": map[string]any{"hostname": "a.internal"}, "b": map[string]any{"host": "b.internal"}},
+	})); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	cfg := store.Get()
+	// The user layer's alias overrides the defaults; the first alias present wins
+	if cfg.Server.Port != 8080 {
+		t.Errorf("Server.Port = %d, want 8080", cfg.Server.Port)
+	}
+	if cfg.Backends["a"].Host != "a.internal" || cfg.Backends["b"].Host != "b.internal" {
+		t.Errorf("Backends = %+v", cfg.Backends)
+	}
+
+	rv := store.GetAt("/server/port")
+	if rv.Value != 8080 || rv.Layer.Name() != "user" {
+		t.Errorf("GetAt(/server/port) = %v from %v, want 8080 from user", rv.Value, rv.Layer.Name())
+	}
+	if rv := store.GetAt("/server"); !reflect.DeepEqual(rv.Value, map[string]any{"port": 8080, "host": "localhost"}) {
+		t.Errorf("GetAt(/server) = %v", rv.Value)
+	}
+
+	// Only deprecated aliases are reported, once while they stay in the layer
+	if len(*warnings) != 1 {
+		t.Fatalf("warnings = %v, want 1", *warnings)
+	}
+	w := (*warnings)[0]
+	if w.Path != "/server/port" || w.Alias != "/server/listen_port" || w.Layer.Name() != "user" {
+		t.Errorf("warning = %+v", w)
+	}
+	if got, want := w.String(), "jubako: WARNING: /server/listen_port is deprecated, use /server/port instead (from layer user)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if err := store.SetTo("user", "/server/host", "example.com"); err != nil {
+		t.Fatalf("SetTo() error = %v", err)
+	}
+	if len(*warnings) != 1 {
+		t.Errorf("warnings = %v, want the alias reported once", *warnings)
+	}
+
+	// A value at the field's path takes precedence over its aliases
+	if err := store.SetTo("user", "/server/port", 7070); err != nil {
+		t.Fatalf("SetTo() error = %v", err)
+	}
+	if got := store.Get().Server.Port; got != 7070 {
+		t.Errorf("Server.Port = %d, want 7070", got)
+	}
+
+	// Aliases are known keys
+	if paths := store.schema.Trie.unknownPaths(map[string]any{"server": map[string]any{"listen_port": 1}, "port": 1}); len(paths) != 0 {
+		t.Errorf("unknownPaths() = %v, want aliases to be known", paths)
+	}
+}
+
+func TestStore_MigrateAliases(t *testing.T) {
+	ctx := context.Background()
+	captureDeprecationWarnings(t)
+
+	user := mapdata.New("user", map[string]any{
+		"server":   map[string]any{"listen_port": 8080, "host": "example.com"},
+		"backends": map[string]any{"a": map[string]any{"hostname": "a.internal"}},
+	})
+	store := New[aliasTestConfig]()
+	if err := store.Add(user); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	before := store.Get()
+
+	if err := store.MigrateAliases("user"); err != nil {
+		t.Fatalf("MigrateAliases() error = %v", err)
+	}
+	if !reflect.DeepEqual(store.Get(), before) {
+		t.Errorf("Get() = %+v, want unchanged %+v", store.Get(), before)
+	}
+
+	entry := store.findLayerLocked("user")
+	want := document.JSONPatchSet{
+		document.NewRemovePatch("/server/listen_port"),
+		document.NewAddPatch("/server/port", 8080),
+		document.NewRemovePatch("/backends/a/hostname"),
+		document.NewAddPatch("/backends/a/host", "a.internal"),
+	}
+	if !reflect.DeepEqual(entry.changeset, want) {
+		t.Errorf("changeset = %v, want %v", entry.changeset, want)
+	}
+	if !store.GetLayerInfo("user").Dirty() {
+		t.Error("layer should be dirty after MigrateAliases")
+	}
+
+	if err := store.SaveLayer(ctx, "user"); err != nil {
+		t.Fatalf("SaveLayer() error = %v", err)
+	}
+	wantData := map[string]any{
+		"server":   map[string]any{"port": 8080, "host": "example.com"},
+		"backends": map[string]any{"a": map[string]any{"host": "a.internal"}},
+	}
+	if got := user.Data(); !reflect.DeepEqual(got, wantData) {
+		t.Errorf("saved data = %v, want %v", got, wantData)
+	}
+
+	// Nothing left to migrate
+	if err := store.MigrateAliases("user"); err != nil {
+		t.Fatalf("MigrateAliases() error = %v", err)
+	}
+	if store.GetLayerInfo("user").Dirty() {
+		t.Error("layer should not be dirty when there is nothing to migrate")
+	}
+
+	if err := store.MigrateAliases("missing"); err == nil {
+		t.Error("MigrateAliases() should fail for an unknown layer")
+	}
+}
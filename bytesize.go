@@ -0,0 +1,157 @@
+package jubako
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a size in bytes that is written in configuration files in a
+// human readable form, such as "512MiB" or "1.5GB".
+//
+// Decimal units (kB, MB, GB, TB, PB) are powers of 1000 and binary units
+// (KiB, MiB, GiB, TiB, PiB) are powers of 1024. Units are case-insensitive,
+// the trailing "B" may be omitted ("512Mi", "1G"), and a number without a
+// unit is a number of bytes. Layers may also hold plain numbers.
+//
+// Example:
+//
+//	type Config struct {
+//	    MaxUpload jubako.ByteSize `yaml:"max_upload" jubako:"default=32MiB"`
+//	}
+type ByteSize int64
+
+// Byte size units.
+const (
+	Byte ByteSize = 1
+
+	KB ByteSize = 1000
+	MB ByteSize = 1000 * KB
+	GB ByteSize = 1000 * MB
+	TB ByteSize = 1000 * GB
+	PB ByteSize = 1000 * TB
+
+	KiB ByteSize = 1 << 10
+	MiB ByteSize = 1 << 20
+	GiB ByteSize = 1 << 30
+	TiB ByteSize = 1 << 40
+	PiB ByteSize = 1 << 50
+)
+
+// byteSizeUnits lists the units by suffix, largest first within each base,
+// so that String picks the largest unit dividing a size exactly.
+var byteSizeUnits = []struct {
+	suffix string
+	size   ByteSize
+}{
+	{"PiB", PiB}, {"TiB", TiB}, {"GiB", GiB}, {"MiB", MiB}, {"KiB", KiB},
+	{"PB", PB}, {"TB", TB}, {"GB", GB}, {"MB", MB}, {"kB", KB},
+}
+
+// ParseByteSize parses a size such as "512MiB", "1.5GB" or "1024".
+func ParseByteSize(s string) (ByteSize, error) {
+	text := strings.TrimSpace(s)
+	i := strings.IndexFunc(text, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '+' && r != '-'
+	})
+	number, unit := text, ""
+	if i >= 0 {
+		number, unit = text[:i], strings.TrimSpace(text[i:])
+	}
+	if number == "" {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	multiplier, ok := byteSizeMultiplier(unit)
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size %q: unknown unit %q", s, unit)
+	}
+
+	if n, err := strconv.ParseInt(number, 10, 64); err == nil {
+		if n != 0 && (n > math.MaxInt64/int64(multiplier) || n < math.MinInt64/int64(multiplier)) {
+			return 0, fmt.Errorf("invalid byte size %q: out of range", s)
+		}
+		return ByteSize(n) * multiplier, nil
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	size := f * float64(multiplier)
+	if size >= math.MaxInt64 || size < math.MinInt64 {
+		return 0, fmt.Errorf("invalid byte size %q: out of range", s)
+	}
+	return ByteSize(math.Round(size)), nil
+}
+
+// byteSizeMultiplier returns the size of a unit, ignoring case and an
+// optional trailing "B".
+func byteSizeMultiplier(unit string) (ByteSize, bool) {
+	u := strings.ToLower(unit)
+	if u == "" || u == "b" {
+		return Byte, true
+	}
+	u = strings.TrimSuffix(u, "b")
+	binary := strings.HasSuffix(u, "i")
+	u = strings.TrimSuffix(u, "i")
+	if len(u) != 1 {
+		return 0, false
+	}
+	exponent := strings.Index("kmgtp", u) + 1
+	if exponent == 0 {
+		return 0, false
+	}
+	size, base := Byte, KB
+	if binary {
+		base = KiB
+	}
+	for ; exponent > 0; exponent-- {
+		size *= base
+	}
+	return size, true
+}
+
+// String returns the size with the largest unit that divides it exactly,
+// e.g. "512MiB" or "1500B".
+func (b ByteSize) String() string {
+	if b != 0 {
+		for _, unit := range byteSizeUnits {
+			if b%unit.size == 0 {
+				return strconv.FormatInt(int64(b/unit.size), 10) + unit.suffix
+			}
+		}
+	}
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting a string or a number
+// of bytes.
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	var n int64
+	if err := json.Unmarshal(data, &n); err == nil {
+		*b = ByteSize(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid byte size %s", data)
+	}
+	return b.UnmarshalText([]byte(s))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
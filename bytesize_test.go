@@ -0,0 +1,88 @@
+package jubako
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ByteSize
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"1024", 1024, false},
+		{"512B", 512, false},
+		{"1kB", 1000, false},
+		{"1KB", 1000, false},
+		{"1k", 1000, false},
+		{"1KiB", 1024, false},
+		{"512MiB", 512 * MiB, false},
+		{"512mi", 512 * MiB, false},
+		{" 2 GB ", 2 * GB, false},
+		{"1.5GiB", 3 * GiB / 2, false},
+		{"1TiB", TiB, false},
+		{"1PB", PB, false},
+		{"", 0, true},
+		{"MiB", 0, true},
+		{"12XB", 0, true},
+		{"1.2.3MB", 0, true},
+		{"99999999PiB", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseByteSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseByteSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseByteSize(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByteSize_String(t *testing.T) {
+	tests := []struct {
+		size ByteSize
+		want string
+	}{
+		{0, "0B"},
+		{1500, "1500B"},
+		{2000, "2kB"},
+		{KiB, "1KiB"},
+		{512 * MiB, "512MiB"},
+		{3 * GB, "3GB"},
+		{-2 * GiB, "-2GiB"},
+	}
+	for _, tt := range tests {
+		if got := tt.size.String(); got != tt.want {
+			t.Errorf("ByteSize(%d).String() = %q, want %q", int64(tt.size), got, tt.want)
+		}
+		parsed, err := ParseByteSize(tt.want)
+		if err != nil || parsed != tt.size {
+			t.Errorf("ParseByteSize(%q) = %d, %v, want %d", tt.want, parsed, err, tt.size)
+		}
+	}
+}
+
+func TestByteSize_JSON(t *testing.T) {
+	var v struct {
+		Text   ByteSize `json:"text"`
+		Number ByteSize `json:"number"`
+	}
+	if err := json.Unmarshal([]byte(`{"text":"64KiB","number":100}`), &v); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if v.Text != 64*KiB || v.Number != 100 {
+		t.Errorf("Unmarshal() = %+v", v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(data) != `{"text":"64KiB","number":"100B"}` {
+		t.Errorf("Marshal() = %s", data)
+	}
+}
@@ -0,0 +1,115 @@
+// Package cli implements the operator commands of the jubako CLI: get, set,
+// explain and layers, which rebuild an application's Store from a layering
+// spec file (see Spec) so that configurations can be inspected and fixed
+// without writing Go, and convert and fmt, which rewrite configuration files.
+//
+// The cli package links the JSON format only; the jubako command adds the
+// YAML, TOML and JSONC formats. To work with other formats, build a CLI that
+// imports their packages:
+//
+//	package main
+//
+//	import (
+//	    "fmt"
+//	    "os"
+//
+//	    "github.com/yacchi/jubako/cli"
+//	    _ "github.com/yacchi/jubako/format/toml"
+//	    _ "github.com/yacchi/jubako/format/yaml"
+//	)
+//
+//	func main() {
+//	    if err := cli.Run(os.Args[1:]); err != nil {
+//	        fmt.Fprintf(os.Stderr, "error: %v\n", err)
+//	        os.Exit(1)
+//	    }
+//	}
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	// JSON is always available
+	_ "github.com/yacchi/jubako/format/json"
+)
+
+// Commands lists the commands handled by Run.
+var Commands = []string{"get", "set", "explain", "layers", "convert", "fmt"}
+
+// Run executes the command named by args[0] with the remaining arguments,
+// writing its output to standard output.
+func Run(args []string) error {
+	return run(context.Background(), args, os.Stdout)
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		PrintHelp()
+		return fmt.Errorf("command is required")
+	}
+
+	cmd, cmdArgs := args[0], args[1:]
+	switch cmd {
+	case "get":
+		return runGet(ctx, cmdArgs, stdout)
+	case "set":
+		return runSet(ctx, cmdArgs, stdout)
+	case "explain":
+		return runExplain(ctx, cmdArgs, stdout)
+	case "layers":
+		return runLayers(ctx, cmdArgs, stdout)
+	case "convert":
+		return runConvert(ctx, cmdArgs, stdout)
+	case "fmt":
+		return runFmt(ctx, cmdArgs, stdout)
+	case "-h", "--help", "help":
+		PrintHelp()
+		return nil
+	default:
+		return fmt.Errorf("unknown command: %s", cmd)
+	}
+}
+
+// PrintHelp prints the help message of the operator commands.
+func PrintHelp() {
+	fmt.Fprintf(os.Stderr, `jubako get/set/explain/layers/convert/fmt - Inspect and edit layered configuration
+
+Usage:
+  jubako get [-spec file] [-raw] <path>
+  jubako set [-spec file] -layer <name> [-string] <path> <value>
+  jubako explain [-spec file] <path>
+  jubako layers [-spec file]
+  jubako convert [-from format] [-to format] <input> [output]
+  jubako fmt [-format format] [-w | -l] <file>...
+
+Commands:
+  get        Print the effective value at a JSON Pointer path and the layer it comes from
+  set        Write a value to a layer file, preserving its comments and formatting
+  explain    Print the value of a path in every layer, in priority order
+  layers     Print the layers of the spec
+  convert    Convert a file to another format, carrying comments where both formats support them
+  fmt        Reformat files in their own format, keeping their comments
+
+The layers are read from a spec file, -spec or $JUBAKO_SPEC (default %q):
+
+  {
+    "layers": [
+      {"name": "defaults", "path": "config/defaults.json", "read_only": true},
+      {"name": "user", "path": "~/.config/app/config.json", "optional": true},
+      {"name": "env", "source": "env", "prefix": "APP_"}
+    ]
+  }
+
+Values given to set are parsed as JSON (e.g., 9000, true, ["a","b"]), and
+taken as strings if they are not valid JSON or if -string is given.
+
+convert and fmt take formats from the file extensions unless given, and
+write to standard output without an output file (or with "-"). Keys are
+written in alphabetical order.
+
+Available formats: %s
+`, DefaultSpecFile, availableFormats())
+}
@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeSpec creates a spec with a read-only defaults layer and an optional
+// user layer in a temporary directory and returns the spec path.
+func writeSpec(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"jubako.json": `{
+  "layers": [
+    {"name": "defaults", "path": "defaults.json", "read_only": true},
+    {"name": "user", "path": "user.json", "optional": true}
+  ]
+}`,
+		"defaults.json": `{"server": {"host": "localhost", "port": 8080}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "jubako.json")
+}
+
+func runCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	err := run(context.Background(), args, &buf)
+	return buf.String(), err
+}
+
+func TestRun_GetSetExplain(t *testing.T) {
+	spec := writeSpec(t)
+	dir := filepath.Dir(spec)
+
+	out, err := runCommand(t, "get", "-spec", spec, "/server/port")
+	if err != nil {
+		t.Fatalf("get error = %v", err)
+	}
+	if want := "8080\t# defaults (" + filepath.Join(dir, "defaults.json") + ")\n"; out != want {
+		t.Errorf("get = %q, want %q", out, want)
+	}
+
+	if _, err := runCommand(t, "set", "-spec", spec, "-layer", "user", "/server/port", "9000"); err != nil {
+		t.Fatalf("set error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "user.json"))
+	if err != nil {
+		t.Fatalf("user layer was not written: %v", err)
+	}
+	if !strings.Contains(string(data), `"port": 9000`) {
+		t.Errorf("user.json = %s, want port 9000", data)
+	}
+
+	out, err = runCommand(t, "get", "-spec", spec, "-raw", "/server/port")
+	if err != nil || out != "9000\n" {
+		t.Errorf("get -raw = %q, %v, want 9000", out, err)
+	}
+
+	out, err = runCommand(t, "explain", "-spec", spec, "/server/port")
+	if err != nil {
+		t.Fatalf("explain error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 || lines[0] != "/server/port" {
+		t.Fatalf("explain =\n%s", out)
+	}
+	if fields := strings.Fields(lines[2]); !reflect.DeepEqual(fields[:3], []string{"0", "defaults", "8080"}) {
+		t.Errorf("explain defaults line = %q", lines[2])
+	}
+	if fields := strings.Fields(lines[3]); !reflect.DeepEqual(fields, []string{"10", "user", "9000", filepath.Join(dir, "user.json"), "effective"}) {
+		t.Errorf("explain user line = %q", lines[3])
+	}
+
+	if _, err := runCommand(t, "get", "-spec", spec, "/server/missing"); err == nil {
+		t.Error("get should fail for unset paths")
+	}
+	if _, err := runCommand(t, "set", "-spec", spec, "-layer", "defaults", "/server/port", "1"); err == nil {
+		t.Error("set should fail for read-only layers")
+	}
+}
+
+func TestRun_Layers(t *testing.T) {
+	spec := writeSpec(t)
+
+	out, err := runCommand(t, "layers", "-spec", spec)
+	if err != nil {
+		t.Fatalf("layers error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("layers =\n%s", out)
+	}
+	if fields := strings.Fields(lines[1]); fields[1] != "defaults" || fields[2] != "json" || fields[4] != "read-only" {
+		t.Errorf("layers defaults line = %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); fields[1] != "user" || fields[4] != "writable,optional" {
+		t.Errorf("layers user line = %q", lines[2])
+	}
+}
+
+func TestLoadSpec_Errors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{"unknown field", `{"layers": [{"name": "a", "file": "a.json"}]}`, "unknown field"},
+		{"unknown format", `{"layers": [{"name": "a", "path": "a.yaml", "format": "ini"}]}`, `format "ini" is not linked into this binary`},
+		{"unknown extension", `{"layers": [{"name": "a", "path": "a.conf"}]}`, "cannot infer the format"},
+		{"unknown source", `{"layers": [{"name": "a", "source": "vault"}]}`, `unknown source "vault"`},
+		{"missing name", `{"layers": [{"path": "a.json"}]}`, "name is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "jubako.json")
+			if err := os.WriteFile(path, []byte(tt.spec), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			spec, err := LoadSpec(path)
+			if err == nil {
+				_, err = spec.Store(context.Background())
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		raw      string
+		asString bool
+		want     any
+	}{
+		{"9000", false, int64(9000)},
+		{"1.5", false, 1.5},
+		{"true", false, true},
+		{`["a", 1]`, false, []any{"a", int64(1)}},
+		{`{"port": 1}`, false, map[string]any{"port": int64(1)}},
+		{"localhost", false, "localhost"},
+		{"1 2", false, "1 2"},
+		{"9000", true, "9000"},
+	}
+	for _, tt := range tests {
+		if got := parseValue(tt.raw, tt.asString); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseValue(%q, %v) = %#v, want %#v", tt.raw, tt.asString, got, tt.want)
+		}
+	}
+}
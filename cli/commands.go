@@ -0,0 +1,250 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/yacchi/jubako"
+	"github.com/yacchi/jubako/layer"
+)
+
+// newFlagSet returns the flag set of a command with the -spec flag.
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = PrintHelp
+	defaultSpec := os.Getenv("JUBAKO_SPEC")
+	if defaultSpec == "" {
+		defaultSpec = DefaultSpecFile
+	}
+	spec := fs.String("spec", defaultSpec, "layering spec file")
+	return fs, spec
+}
+
+// openStore loads the spec and its store.
+func openStore(ctx context.Context, specFile string) (*jubako.Store[map[string]any], error) {
+	spec, err := LoadSpec(specFile)
+	if err != nil {
+		return nil, err
+	}
+	return spec.Store(ctx)
+}
+
+func runGet(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, specFile := newFlagSet("get")
+	raw := fs.Bool("raw", false, "print the value only")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("get: exactly one path is required")
+	}
+	path := fs.Arg(0)
+
+	store, err := openStore(ctx, *specFile)
+	if err != nil {
+		return err
+	}
+	rv := store.GetAt(path)
+	if !rv.Exists {
+		return fmt.Errorf("get: %s is not set in any layer", path)
+	}
+
+	if *raw {
+		_, err = fmt.Fprintln(stdout, formatValue(rv.Value))
+		return err
+	}
+	annotation := origin(rv.Layer)
+	if rv.Masked {
+		annotation += ", masked"
+	}
+	_, err = fmt.Fprintf(stdout, "%s\t# %s\n", formatValue(rv.Value), annotation)
+	return err
+}
+
+func runSet(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, specFile := newFlagSet("set")
+	layerName := fs.String("layer", "", "layer to write (required)")
+	asString := fs.Bool("string", false, "set the value as a string")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *layerName == "" {
+		return fmt.Errorf("set: -layer flag is required")
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("set: a path and a value are required")
+	}
+	path, value := fs.Arg(0), parseValue(fs.Arg(1), *asString)
+
+	store, err := openStore(ctx, *specFile)
+	if err != nil {
+		return err
+	}
+	name := layer.Name(*layerName)
+	if err := store.SetTo(name, path, value); err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+	// Layers write through Document.Apply, which keeps comments and formatting
+	if err := store.SaveLayer(ctx, name); err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+
+	_, err = fmt.Fprintf(stdout, "%s = %s\t# %s\n", path, formatValue(value), origin(store.GetLayerInfo(name)))
+	return err
+}
+
+func runExplain(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, specFile := newFlagSet("explain")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("explain: exactly one path is required")
+	}
+	path := fs.Arg(0)
+
+	store, err := openStore(ctx, *specFile)
+	if err != nil {
+		return err
+	}
+	values := store.GetAllAt(path)
+	if len(values) == 0 {
+		return fmt.Errorf("explain: %s is not set in any layer", path)
+	}
+
+	fmt.Fprintln(stdout, path)
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  PRIORITY\tLAYER\tVALUE\tSOURCE\t")
+	for i, rv := range values {
+		value := formatValue(rv.Value)
+		if rv.Deleted {
+			value = "<unset>"
+		}
+		note := ""
+		if i == len(values)-1 && rv.Exists {
+			note = "effective"
+		}
+		fmt.Fprintf(w, "  %d\t%s\t%s\t%s\t%s\n", rv.Layer.Priority(), rv.Layer.Name(), value, source(rv.Layer), note)
+	}
+	return w.Flush()
+}
+
+func runLayers(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, specFile := newFlagSet("layers")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("layers: unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	store, err := openStore(ctx, *specFile)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PRIORITY\tLAYER\tFORMAT\tSOURCE\tFLAGS")
+	for _, info := range store.ListLayers() {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", info.Priority(), info.Name(), info.Format(), source(info), strings.Join(layerFlags(info), ","))
+	}
+	return w.Flush()
+}
+
+// layerFlags describes the state of a layer.
+func layerFlags(info jubako.LayerInfo) []string {
+	var flags []string
+	if !info.Loaded() {
+		flags = append(flags, "not-loaded")
+	}
+	switch {
+	case info.ReadOnly():
+		flags = append(flags, "read-only")
+	case info.Writable():
+		flags = append(flags, "writable")
+	}
+	if info.Optional() {
+		flags = append(flags, "optional")
+	}
+	if info.Sensitive() {
+		flags = append(flags, "sensitive")
+	}
+	if info.Dirty() {
+		flags = append(flags, "dirty")
+	}
+	return flags
+}
+
+// origin describes the layer a value comes from.
+func origin(info jubako.LayerInfo) string {
+	if info == nil {
+		return "no layer"
+	}
+	if path := info.Path(); path != "" {
+		return fmt.Sprintf("%s (%s)", info.Name(), path)
+	}
+	return string(info.Name())
+}
+
+// source returns the file of a layer, or "-" for layers without files.
+func source(info jubako.LayerInfo) string {
+	if path := info.Path(); path != "" {
+		return path
+	}
+	return "-"
+}
+
+// formatValue renders strings as is and other values as JSON.
+func formatValue(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// parseValue parses a value given on the command line as JSON, falling back
+// to the raw string. Integers are kept as integers so that formats like TOML
+// do not write them as floats.
+func parseValue(raw string, asString bool) any {
+	if asString {
+		return raw
+	}
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil || dec.More() {
+		return raw
+	}
+	return convertNumbers(value)
+}
+
+// convertNumbers replaces the json.Number values decoded by parseValue.
+func convertNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i := range v {
+			v[i] = convertNumbers(v[i])
+		}
+	case map[string]any:
+		for key := range v {
+			v[key] = convertNumbers(v[key])
+		}
+	}
+	return value
+}
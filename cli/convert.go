@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/yacchi/jubako/document"
+)
+
+func runConvert(_ context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.Usage = PrintHelp
+	from := fs.String("from", "", "input format (default: from the input file extension)")
+	to := fs.String("to", "", "output format (default: from the output file extension)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return fmt.Errorf("convert: an input file and an optional output file are required")
+	}
+	input, output := fs.Arg(0), fs.Arg(1)
+	if output == "-" {
+		output = ""
+	}
+
+	src, err := lookupDocument(*from, input)
+	if err != nil {
+		return fmt.Errorf("convert: %w", err)
+	}
+	dst, err := lookupDocument(*to, output)
+	if err != nil {
+		return fmt.Errorf("convert: %w", err)
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("convert: %w", err)
+	}
+	out, err := convert(src, dst, data)
+	if err != nil {
+		return fmt.Errorf("convert %s to %s: %w", input, dst.Format(), err)
+	}
+
+	if output == "" {
+		_, err = stdout.Write(out)
+		return err
+	}
+	return os.WriteFile(output, out, 0o644)
+}
+
+func runFmt(_ context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	fs.Usage = PrintHelp
+	format := fs.String("format", "", "file format (default: from the file extension)")
+	write := fs.Bool("w", false, "write the result to the files instead of standard output")
+	list := fs.Bool("l", false, "list the files whose formatting differs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("fmt: at least one file is required")
+	}
+
+	for _, path := range fs.Args() {
+		doc, err := lookupDocument(*format, path)
+		if err != nil {
+			return fmt.Errorf("fmt: %w", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("fmt: %w", err)
+		}
+		out, err := convert(doc, doc, data)
+		if err != nil {
+			return fmt.Errorf("fmt %s: %w", path, err)
+		}
+
+		changed := !bytes.Equal(data, out)
+		if *list && changed {
+			fmt.Fprintln(stdout, path)
+		}
+		if *write && changed {
+			if err := os.WriteFile(path, out, 0o644); err != nil {
+				return fmt.Errorf("fmt: %w", err)
+			}
+		}
+		if !*list && !*write {
+			if _, err := stdout.Write(out); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// convert re-marshals data from the src format in the dst format, carrying
+// the comments if both formats support them. Structures dst cannot hold are
+// reported as document.UnsupportedStructureError with their path.
+func convert(src, dst document.Document, data []byte) ([]byte, error) {
+	values, err := src.Get(data)
+	if err != nil {
+		return nil, err
+	}
+	// JSON has a single number type; whole numbers are written as integers
+	// by formats that tell them apart, like TOML
+	if f := src.Format(); f == document.FormatJSON || f == document.FormatJSONC {
+		values = integralNumbers(values).(map[string]any)
+	}
+	comments, err := document.Comments(src, data)
+	if err != nil {
+		return nil, err
+	}
+	return document.Marshal(dst, values, comments)
+}
+
+// integralNumbers replaces the whole float64 values within value with int64.
+func integralNumbers(value any) any {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v)
+		}
+	case []any:
+		for i := range v {
+			v[i] = integralNumbers(v[i])
+		}
+	case map[string]any:
+		for key := range v {
+			v[key] = integralNumbers(v[key])
+		}
+	}
+	return value
+}
+
+// lookupDocument returns the Document of format, or of the extension of path
+// if format is empty.
+func lookupDocument(format, path string) (document.Document, error) {
+	f := document.DocumentFormat(format)
+	if f == "" {
+		var ok bool
+		if f, ok = document.FormatOf(path); !ok {
+			if path == "" {
+				return nil, fmt.Errorf("the output format is required when writing to standard output")
+			}
+			return nil, fmt.Errorf("cannot infer the format of %s: no format linked into this binary handles %q files (available: %s)",
+				path, filepath.Ext(path), availableFormats())
+		}
+	}
+	doc, ok := document.Lookup(f)
+	if !ok {
+		return nil, fmt.Errorf("format %q is not linked into this binary (available: %s)", f, availableFormats())
+	}
+	return doc, nil
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/format/json"
+	"github.com/yacchi/jubako/jsonptr"
+)
+
+// commentedTestDocument is a JSON document that keeps its comments in a
+// "_comments" member and cannot hold null values.
+type commentedTestDocument struct {
+	*json.Document
+}
+
+func (commentedTestDocument) Format() document.DocumentFormat { return "cli-test" }
+
+func (d commentedTestDocument) Get(data []byte) (map[string]any, error) {
+	values, err := d.Document.Get(data)
+	delete(values, "_comments")
+	return values, err
+}
+
+func (d commentedTestDocument) Comments(data []byte) (map[string]string, error) {
+	values, err := d.Document.Get(data)
+	if err != nil {
+		return nil, err
+	}
+	comments := map[string]string{}
+	raw, _ := values["_comments"].(map[string]any)
+	for path, text := range raw {
+		comments[path], _ = text.(string)
+	}
+	return comments, nil
+}
+
+func (d commentedTestDocument) MarshalWithComments(data map[string]any, comments map[string]string) ([]byte, error) {
+	for path := range comments {
+		if _, ok := jsonptr.GetPath(data, path); !ok {
+			delete(comments, path)
+		}
+	}
+	if path, ok := findNull(data, ""); ok {
+		return nil, document.UnsupportedAt(path, "null values")
+	}
+	out := map[string]any{"_comments": comments}
+	for key, value := range data {
+		out[key] = value
+	}
+	return d.Document.MarshalTestData(out)
+}
+
+// findNull returns the path of the first null value in data.
+func findNull(data map[string]any, path string) (string, bool) {
+	for key, value := range data {
+		childPath := path + "/" + jsonptr.Escape(key)
+		if value == nil {
+			return childPath, true
+		}
+		if m, ok := value.(map[string]any); ok {
+			if p, ok := findNull(m, childPath); ok {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
+
+func init() {
+	document.Register("cli-test", func() document.Document { return commentedTestDocument{json.New()} }, ".clitest")
+}
+
+func TestRun_Convert(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.clitest")
+	if err := os.WriteFile(input, []byte(`{"_comments": {"/port": "the port"}, "port": 8080}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Comments are dropped by formats without comments
+	out, err := runCommand(t, "convert", input, filepath.Join(dir, "out.json"))
+	if err != nil || out != "" {
+		t.Fatalf("convert = %q, %v", out, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "out.json"))
+	if want := "{\n  \"port\": 8080\n}\n"; string(data) != want {
+		t.Errorf("out.json = %q, want %q", data, want)
+	}
+
+	// and carried between formats with comments
+	out, err = runCommand(t, "convert", "-to", "cli-test", input)
+	if err != nil {
+		t.Fatalf("convert error = %v", err)
+	}
+	if !strings.Contains(out, `"/port": "the port"`) {
+		t.Errorf("convert = %s, want the comment carried", out)
+	}
+}
+
+func TestRun_Convert_Errors(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.json")
+	if err := os.WriteFile(input, []byte(`{"server": {"host": null}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runCommand(t, "convert", input, filepath.Join(dir, "out.clitest"))
+	var unsupported *document.UnsupportedStructureError
+	if !errors.As(err, &unsupported) || unsupported.Path != "/server/host" {
+		t.Errorf("convert error = %v, want UnsupportedStructureError at /server/host", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out.clitest")); !os.IsNotExist(err) {
+		t.Error("convert should not write the output on errors")
+	}
+
+	if _, err := runCommand(t, "convert", input); err == nil || !strings.Contains(err.Error(), "output format is required") {
+		t.Errorf("convert to stdout without -to error = %v", err)
+	}
+	if _, err := runCommand(t, "convert", "-to", "ini", input); err == nil || !strings.Contains(err.Error(), `format "ini" is not linked into this binary`) {
+		t.Errorf("convert -to ini error = %v", err)
+	}
+}
+
+func TestRun_Fmt(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"server":{"port":8080}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n  \"server\": {\n    \"port\": 8080\n  }\n}\n"
+
+	out, err := runCommand(t, "fmt", path)
+	if err != nil || out != want {
+		t.Errorf("fmt = %q, %v, want %q", out, err, want)
+	}
+
+	out, err = runCommand(t, "fmt", "-l", path)
+	if err != nil || out != path+"\n" {
+		t.Errorf("fmt -l = %q, %v, want the path", out, err)
+	}
+
+	if _, err := runCommand(t, "fmt", "-w", path); err != nil {
+		t.Fatalf("fmt -w error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != want {
+		t.Errorf("fmt -w wrote %q, want %q", data, want)
+	}
+
+	out, err = runCommand(t, "fmt", "-l", path)
+	if err != nil || out != "" {
+		t.Errorf("fmt -l = %q, %v, want no files after formatting", out, err)
+	}
+}
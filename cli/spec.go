@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yacchi/jubako"
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/layer"
+	"github.com/yacchi/jubako/layer/env"
+	"github.com/yacchi/jubako/source/fs"
+)
+
+// DefaultSpecFile is the layering spec read when neither the -spec flag nor
+// the JUBAKO_SPEC environment variable is set.
+const DefaultSpecFile = "jubako.json"
+
+// Source kinds of a LayerSpec.
+const (
+	// SourceFile reads the layer from the file at Path.
+	SourceFile = "file"
+	// SourceEnv reads the layer from the environment variables with Prefix.
+	SourceEnv = "env"
+)
+
+// Spec describes the layers of an application's configuration, so that the
+// CLI can rebuild the Store the application uses.
+//
+// Example (jubako.json):
+//
+//	{
+//	  "layers": [
+//	    {"name": "defaults", "path": "config/defaults.yaml", "read_only": true},
+//	    {"name": "user", "path": "~/.config/app/config.yaml", "optional": true},
+//	    {"name": "env", "source": "env", "prefix": "APP_"}
+//	  ]
+//	}
+type Spec struct {
+	// Layers lists the layers. Layers without a priority are ordered like
+	// Store.Add orders them, from lowest to highest priority.
+	Layers []LayerSpec `json:"layers"`
+}
+
+// LayerSpec describes a single layer of a Spec.
+type LayerSpec struct {
+	// Name is the layer name.
+	Name string `json:"name"`
+	// Source is SourceFile (the default) or SourceEnv.
+	Source string `json:"source,omitempty"`
+	// Path is the file of a file layer. Relative paths are resolved against
+	// the directory of the spec file, and ~ is expanded.
+	Path string `json:"path,omitempty"`
+	// Format is the document format of a file layer. It defaults to the
+	// format registered for the file extension.
+	Format string `json:"format,omitempty"`
+	// Prefix is the environment variable prefix of an env layer.
+	Prefix string `json:"prefix,omitempty"`
+	// Priority is the layer priority (see jubako.WithPriority).
+	Priority *int `json:"priority,omitempty"`
+	// ReadOnly prevents the set command from writing the layer.
+	ReadOnly bool `json:"read_only,omitempty"`
+	// Optional allows the file of the layer to be missing.
+	Optional bool `json:"optional,omitempty"`
+	// Sensitive marks the layer as holding sensitive data.
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// LoadSpec reads a layering spec. The spec is parsed with the document format
+// registered for its extension, so a YAML spec requires the YAML format to be
+// linked in; files with other extensions are parsed as JSON.
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec: %w", err)
+	}
+
+	var raw any
+	if format, ok := document.FormatOf(path); ok && format != document.FormatJSON {
+		doc, _ := document.Lookup(format)
+		if raw, err = doc.Get(data); err != nil {
+			return nil, fmt.Errorf("failed to parse spec %s: %w", path, err)
+		}
+		// Decode through JSON to reject unknown fields below
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("failed to parse spec %s: %w", path, err)
+		}
+	}
+
+	var spec Spec
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("failed to parse spec %s: %w", path, err)
+	}
+
+	// Resolve file paths against the spec directory
+	dir := filepath.Dir(path)
+	for i := range spec.Layers {
+		l := &spec.Layers[i]
+		if l.Path != "" && !filepath.IsAbs(l.Path) && l.Path != "~" && !strings.HasPrefix(l.Path, "~/") {
+			l.Path = filepath.Join(dir, l.Path)
+		}
+	}
+	return &spec, nil
+}
+
+// Store builds a Store of the spec's layers and loads it. The configuration
+// is untyped, so values are reported as they are written in the layers.
+func (s *Spec) Store(ctx context.Context) (*jubako.Store[map[string]any], error) {
+	store := jubako.New[map[string]any]()
+	for _, ls := range s.Layers {
+		l, err := ls.layer()
+		if err != nil {
+			return nil, err
+		}
+		var opts []jubako.AddOption
+		if ls.Priority != nil {
+			opts = append(opts, jubako.WithPriority(layer.Priority(*ls.Priority)))
+		}
+		if ls.ReadOnly {
+			opts = append(opts, jubako.WithReadOnly())
+		}
+		if ls.Optional {
+			opts = append(opts, jubako.WithOptional())
+		}
+		if ls.Sensitive {
+			opts = append(opts, jubako.WithSensitive())
+		}
+		if err := store.Add(l, opts...); err != nil {
+			return nil, err
+		}
+	}
+	if err := store.Load(ctx); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// layer creates the layer described by ls.
+func (ls LayerSpec) layer() (layer.Layer, error) {
+	if ls.Name == "" {
+		return nil, fmt.Errorf("spec: layer name is required")
+	}
+
+	switch ls.Source {
+	case "", SourceFile:
+		if ls.Path == "" {
+			return nil, fmt.Errorf("spec: layer %q: path is required", ls.Name)
+		}
+		format := document.DocumentFormat(ls.Format)
+		if format == "" {
+			var ok bool
+			if format, ok = document.FormatOf(ls.Path); !ok {
+				return nil, fmt.Errorf("spec: layer %q: cannot infer the format of %s; set format", ls.Name, ls.Path)
+			}
+		}
+		doc, ok := document.Lookup(format)
+		if !ok {
+			return nil, fmt.Errorf("spec: layer %q: format %q is not linked into this binary (available: %s); build a CLI that imports its format package",
+				ls.Name, format, availableFormats())
+		}
+		return layer.New(layer.Name(ls.Name), fs.New(ls.Path), doc), nil
+	case SourceEnv:
+		return env.New(layer.Name(ls.Name), ls.Prefix), nil
+	default:
+		return nil, fmt.Errorf("spec: layer %q: unknown source %q (use %q or %q)", ls.Name, ls.Source, SourceFile, SourceEnv)
+	}
+}
+
+// availableFormats lists the registered document formats.
+func availableFormats() string {
+	formats := document.Formats()
+	names := make([]string, len(formats))
+	for i, format := range formats {
+		names[i] = string(format)
+	}
+	return strings.Join(names, ", ")
+}
@@ -7,6 +7,13 @@
 // Commands:
 //
 //	generate    Code generation commands
+//	get         Print the effective value at a path
+//	set         Write a value to a layer
+//	explain     Print the value of a path in every layer
+//	layers      Print the layers of the layering spec
+//	convert     Convert a configuration file to another format
+//	fmt         Reformat configuration files
+//	lint        Check the jubako struct tags of configuration types
 //	help        Show help for a command
 //	version     Show version information
 package main
@@ -15,7 +22,12 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/yacchi/jubako/cli"
+	_ "github.com/yacchi/jubako/format/jsonc"
+	_ "github.com/yacchi/jubako/format/toml"
+	_ "github.com/yacchi/jubako/format/yaml"
 	"github.com/yacchi/jubako/internal/cmd/generate"
+	"github.com/yacchi/jubako/internal/cmd/lint"
 )
 
 const version = "0.1.0"
@@ -35,6 +47,16 @@ func main() {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
 			os.Exit(1)
 		}
+	case "lint":
+		if err := lint.Run(args); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	case "get", "set", "explain", "layers", "convert", "fmt":
+		if err := cli.Run(os.Args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
 	case "help":
 		if len(args) > 0 {
 			printCommandHelp(args[0])
@@ -62,6 +84,13 @@ Usage:
 
 Commands:
   generate    Code generation commands
+  get         Print the effective value at a path
+  set         Write a value to a layer
+  explain     Print the value of a path in every layer
+  layers      Print the layers of the layering spec
+  convert     Convert a configuration file to another format
+  fmt         Reformat configuration files
+  lint        Check the jubako struct tags of configuration types
   help        Show help for a command
   version     Show version information
 
@@ -72,6 +101,10 @@ func printCommandHelp(cmd string) {
 	switch cmd {
 	case "generate":
 		generate.PrintHelp()
+	case "lint":
+		lint.PrintHelp()
+	case "get", "set", "explain", "layers", "convert", "fmt":
+		cli.PrintHelp()
 	default:
 		fmt.Fprintf(os.Stderr, "unknown command: %s\n", cmd)
 		os.Exit(1)
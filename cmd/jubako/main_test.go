@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yacchi/jubako/cli"
+)
+
+func TestConvert_LinkedFormats(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.json")
+	if err := os.WriteFile(input, []byte(`{"server": {"host": "localhost", "port": 8080}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "out.toml")
+	if err := cli.Run([]string{"convert", input, output}); err != nil {
+		t.Fatalf("convert error = %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[server]\nhost = 'localhost'\nport = 8080\n"; string(data) != want {
+		t.Errorf("out.toml = %q, want %q", data, want)
+	}
+
+	// YAML and JSONC are linked too
+	for _, name := range []string{"out.yaml", "out.jsonc"} {
+		if err := cli.Run([]string{"convert", output, filepath.Join(dir, name)}); err != nil {
+			t.Errorf("convert to %s error = %v", name, err)
+		}
+	}
+}
+
+// captureStdout returns what fn writes to standard output.
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	err = fn()
+	w.Close()
+	out, readErr := io.ReadAll(r)
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	return string(out), err
+}
+
+func TestGetSet_YAML(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"jubako.json": `{"layers": [{"name": "user", "path": "config.yaml"}]}`,
+		"config.yaml": "# Server settings\nserver:\n  host: localhost # local only\n  port: 8080\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	spec := filepath.Join(dir, "jubako.json")
+
+	if err := cli.Run([]string{"set", "-spec", spec, "-layer", "user", "/server/port", "9000"}); err != nil {
+		t.Fatalf("set error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# Server settings\nserver:\n  host: localhost # local only\n  port: 9000\n"; string(data) != want {
+		t.Errorf("config.yaml = %q, want %q", data, want)
+	}
+
+	out, err := captureStdout(t, func() error {
+		return cli.Run([]string{"get", "-spec", spec, "-raw", "/server/port"})
+	})
+	if err != nil || strings.TrimSpace(out) != "9000" {
+		t.Errorf("get -raw = %q, %v, want 9000", out, err)
+	}
+}
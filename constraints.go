@@ -0,0 +1,278 @@
+package jubako
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yacchi/jubako/jsonptr"
+)
+
+// ConstraintError reports a value that violates a constraint declared in a
+// jubako struct tag (required, min=, max=, enum= or pattern=).
+//
+// Constraint violations are detected on the merged configuration before it is
+// decoded, and are returned wrapped in a *ValidationError. Use errors.As to
+// inspect individual violations.
+type ConstraintError struct {
+	// Path is the JSON Pointer path of the value in the merged configuration.
+	Path string
+
+	// Constraint is the violated directive (e.g., "required", "min=1").
+	Constraint string
+
+	// Value is the offending value. It is nil for missing required values
+	// and for sensitive fields.
+	Value any
+
+	// Layer provides metadata about the layer that provided the value.
+	// nil if the value is missing.
+	Layer LayerInfo
+
+	// sensitive hides the value in the error message.
+	sensitive bool
+}
+
+// Error implements the error interface.
+// The message names the path, value, layer and source, for example:
+// "/port=0 violates min=1 (from layer user (~/.config/app.yaml))".
+func (e *ConstraintError) Error() string {
+	var sb strings.Builder
+	switch {
+	case e.Layer == nil:
+		fmt.Fprintf(&sb, "%s is missing (%s)", e.Path, e.Constraint)
+		return sb.String()
+	case e.sensitive:
+		fmt.Fprintf(&sb, "%s violates %s", e.Path, e.Constraint)
+	default:
+		fmt.Fprintf(&sb, "%s=%v violates %s", e.Path, e.Value, e.Constraint)
+	}
+	fmt.Fprintf(&sb, " (from layer %s", e.Layer.Name())
+	if path := e.Layer.Path(); path != "" {
+		fmt.Fprintf(&sb, " (%s)", path)
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// hasConstraints reports whether any mapping in the schema declares constraints.
+func hasConstraints(schema *Schema) bool {
+	for _, m := range schema.Mappings {
+		if !m.Constraints.IsEmpty() {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConstraints evaluates the constraints in table against the merged map,
+// checks that the values of fields with a Converter convert, and that union
+// values name a registered variant, whose constraints they are checked against.
+// Values are looked up the same way applyMappings resolves them, so constraints
+// on remapped fields apply to their source paths. origins is used to report the
+// layer that provided each offending value.
+// All violations are returned joined into a single error.
+func checkConstraints(merged map[string]any, table *MappingTable, origins *origins, converters converterRegistry) error {
+	c := constraintChecker{root: merged, origins: origins, converters: converters}
+	c.checkTable(merged, table, "")
+	return errors.Join(c.errs...)
+}
+
+// constraintChecker walks a MappingTable alongside the merged map.
+type constraintChecker struct {
+	root       map[string]any
+	origins    *origins
+	converters converterRegistry
+	errs       []error
+}
+
+// fieldValue is a field value located in the merged map.
+type fieldValue struct {
+	value  any
+	exists bool
+	path   string
+}
+
+func (c *constraintChecker) checkTable(src map[string]any, table *MappingTable, path string) {
+	if table == nil {
+		return
+	}
+
+	fields := make(map[string]fieldValue, len(table.Mappings))
+	for _, m := range table.Mappings {
+		if m.Skipped {
+			continue
+		}
+		fv := c.locate(src, m, path)
+		fields[m.FieldKey] = fv
+		c.checkMapping(m, fv)
+		c.checkConversion(m, fv)
+	}
+
+	for _, key := range sortedKeys(table.Nested) {
+		sub, _ := src[key].(map[string]any)
+		c.checkTable(sub, table.Nested[key], path+"/"+jsonptr.Escape(key))
+	}
+
+	for _, key := range sortedKeys(table.SliceElement) {
+		fv, ok := fields[key]
+		if !ok {
+			continue
+		}
+		elems, _ := fv.value.([]any)
+		for i, elem := range elems {
+			if elemMap, ok := elem.(map[string]any); ok {
+				c.checkTable(elemMap, table.SliceElement[key], fv.path+"/"+strconv.Itoa(i))
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(table.MapValue) {
+		fv, ok := fields[key]
+		if !ok {
+			continue
+		}
+		values, _ := fv.value.(map[string]any)
+		for _, k := range sortedKeys(values) {
+			if valueMap, ok := values[k].(map[string]any); ok {
+				c.checkTable(valueMap, table.MapValue[key], fv.path+"/"+jsonptr.Escape(k))
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(table.Unions) {
+		if fv, ok := fields[key]; ok {
+			c.checkUnion(table.Unions[key], fv)
+		}
+	}
+}
+
+// locate finds the value for a mapping in the merged map.
+func (c *constraintChecker) locate(src map[string]any, m *PathMapping, path string) fieldValue {
+	switch {
+	case m.SourcePath != "" && m.IsRelative:
+		value, ok := jsonptr.GetPath(src, m.SourcePath)
+		return fieldValue{value: value, exists: ok, path: path + m.SourcePath}
+	case m.SourcePath != "":
+		value, ok := jsonptr.GetPath(c.root, m.SourcePath)
+		return fieldValue{value: value, exists: ok, path: m.SourcePath}
+	default:
+		value, ok := src[m.FieldKey]
+		return fieldValue{value: value, exists: ok, path: path + "/" + jsonptr.Escape(m.FieldKey)}
+	}
+}
+
+// checkMapping checks a single value against the mapping's constraints.
+func (c *constraintChecker) checkMapping(m *PathMapping, fv fieldValue) {
+	cons := m.Constraints
+	if cons.IsEmpty() {
+		return
+	}
+
+	if !fv.exists || fv.value == nil {
+		if cons.Required {
+			c.errs = append(c.errs, &ConstraintError{Path: fv.path, Constraint: "required"})
+		}
+		return
+	}
+
+	if violated := violatedConstraint(m, fv.value); violated != "" {
+		c.errs = append(c.errs, c.newError(m, fv, violated))
+	}
+}
+
+// newError builds a ConstraintError with the origin of the offending value.
+func (c *constraintChecker) newError(m *PathMapping, fv fieldValue, constraint string) *ConstraintError {
+	err := &ConstraintError{
+		Path:       fv.path,
+		Constraint: constraint,
+		sensitive:  m.Sensitive == sensitiveExplicit,
+	}
+	if !err.sensitive {
+		err.Value = fv.value
+	}
+	if c.origins != nil {
+		entry := c.origins.getLeaf(fv.path)
+		if entry == nil {
+			entry = c.origins.getContainer(fv.path)
+		}
+		if entry != nil {
+			err.Layer = layerInfoAt(entry, c.origins.sourcePath(fv.path, entry))
+		}
+	}
+	return err
+}
+
+// violatedConstraint returns the first constraint of m that value violates,
+// in directive form, or "" if value satisfies all of them.
+func violatedConstraint(m *PathMapping, value any) string {
+	cons := m.Constraints
+	if cons.Min != nil || cons.Max != nil {
+		if n, ok := constraintMeasure(value, m.FieldType); ok {
+			if cons.Min != nil && n < *cons.Min {
+				return "min=" + strconv.FormatFloat(*cons.Min, 'g', -1, 64)
+			}
+			if cons.Max != nil && n > *cons.Max {
+				return "max=" + strconv.FormatFloat(*cons.Max, 'g', -1, 64)
+			}
+		}
+	}
+	if len(cons.Enum) > 0 && !slices.Contains(cons.Enum, fmt.Sprint(value)) {
+		return "enum=" + strings.Join(cons.Enum, "|")
+	}
+	if m.pattern != nil && !m.pattern.MatchString(fmt.Sprint(value)) {
+		return "pattern=" + cons.Pattern
+	}
+	return ""
+}
+
+// constraintMeasure returns the number compared against min= and max=.
+// Numbers (and numeric strings for numeric fields, e.g. from environment
+// variables) are measured by value; strings, slices and maps by length.
+func constraintMeasure(value any, fieldType reflect.Type) (float64, bool) {
+	numericField := false
+	if fieldType != nil {
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		switch fieldType.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			numericField = true
+		}
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		if numericField {
+			f, err := strconv.ParseFloat(strings.TrimSpace(rv.String()), 64)
+			return f, err == nil
+		}
+		return float64(utf8.RuneCountInString(rv.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(rv.Len()), true
+	}
+	return 0, false
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
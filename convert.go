@@ -57,6 +57,11 @@ func defaultConvert(path string, value any, targetType reflect.Type) (any, error
 		if err != nil {
 			return nil, err
 		}
+		// Values the element type does not accept, such as maps for
+		// pointers to structs, are left to the decoder
+		if converted == nil || !reflect.TypeOf(converted).AssignableTo(elemType) {
+			return value, nil
+		}
 		// Create a pointer to the converted value
 		ptr := reflect.New(elemType)
 		ptr.Elem().Set(reflect.ValueOf(converted))
@@ -551,6 +551,12 @@ func TestDefaultValueConverter_ToPointer(t *testing.T) {
 			targetType: reflect.TypeOf((*string)(nil)),
 			expected:   stringPtr("hello"),
 		},
+		{
+			name:       "map to *struct is left to the decoder",
+			value:      map[string]any{"a": 1},
+			targetType: reflect.TypeOf((*struct{ A int })(nil)),
+			expected:   map[string]any{"a": 1},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,471 @@
+package jubako
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yacchi/jubako/jsonptr"
+)
+
+// Converter converts layer values to a Go type that configuration formats
+// cannot express directly, such as *url.URL or netip.Prefix.
+// Create one with NewConverter and install it on a Store with WithConverters.
+//
+// Every Store starts with converters for the following types:
+//
+//   - time.Duration: "30s", or a number of nanoseconds
+//   - *url.URL: parsed via url.Parse
+//   - net.IP: parsed via net.ParseIP
+//   - netip.Addr, netip.Prefix: parsed via netip.ParseAddr and netip.ParsePrefix
+//   - *regexp.Regexp: compiled via regexp.Compile
+//   - *time.Location: loaded via time.LoadLocation (e.g., "Asia/Tokyo")
+//   - ByteSize: "512MiB", "1.5GB", or a number of bytes
+//
+// A converter for T also applies to fields of type *T (or of type T when it
+// is registered for a pointer type), and to the elements of slices and maps
+// of these types.
+//
+// Converted values are checked when the configuration is materialized and
+// when a value is set with SetTo. Failures are reported as *ConversionError,
+// naming the path and the layer of the value.
+type Converter struct {
+	typ     reflect.Type
+	convert func(value any) (any, error)
+}
+
+// NewConverter returns a Converter that produces values of type T with fn.
+// fn receives the value held by the layers, such as a string or a number;
+// it is not called for nil values and values that already have type T.
+//
+// Example:
+//
+//	semver := jubako.NewConverter(func(value any) (*semver.Version, error) {
+//	    s, ok := value.(string)
+//	    if !ok {
+//	        return nil, fmt.Errorf("expected a string, got %T", value)
+//	    }
+//	    return semver.NewVersion(s)
+//	})
+//	store := jubako.New[Config](jubako.WithConverters(semver))
+func NewConverter[T any](fn func(value any) (T, error)) Converter {
+	return Converter{
+		typ: reflect.TypeFor[T](),
+		convert: func(value any) (any, error) {
+			return fn(value)
+		},
+	}
+}
+
+// Type returns the type produced by the converter.
+func (c Converter) Type() reflect.Type {
+	return c.typ
+}
+
+// WithConverters adds converters to the Store. They take precedence over the
+// default converters and over the ValueConverter for their types.
+// Converters are scoped to the Store; other Stores are not affected.
+//
+// Example:
+//
+//	store := jubako.New[Config](jubako.WithConverters(
+//	    jubako.NewConverter(parseLogLevel),
+//	))
+func WithConverters(converters ...Converter) StoreOption {
+	return func(o *storeOptions) {
+		o.converters = append(o.converters, converters...)
+	}
+}
+
+// ConversionError reports a value that a Converter failed to convert.
+//
+// When returned by Load or Reload, the error is wrapped in a *ValidationError
+// together with the other invalid values. Use errors.As to inspect it.
+type ConversionError struct {
+	// Path is the JSON Pointer path of the value in the merged configuration.
+	Path string
+
+	// Type is the type the value was converted to.
+	Type reflect.Type
+
+	// Layer provides metadata about the layer that provided the value.
+	Layer LayerInfo
+
+	// Err is the error returned by the converter.
+	Err error
+
+	// sensitive hides the converter's message, which may quote the value.
+	sensitive bool
+}
+
+// Error implements the error interface.
+// The message names the path, type and layer, for example:
+// `cannot convert /timeout to time.Duration: time: invalid duration "soon" (from layer user (~/.config/app.yaml))`.
+func (e *ConversionError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "cannot convert %s to %s", e.Path, e.Type)
+	if !e.sensitive {
+		fmt.Fprintf(&sb, ": %v", e.Err)
+	}
+	if e.Layer != nil {
+		fmt.Fprintf(&sb, " (from layer %s", e.Layer.Name())
+		if path := e.Layer.Path(); path != "" {
+			fmt.Fprintf(&sb, " (%s)", path)
+		}
+		sb.WriteString(")")
+	}
+	return sb.String()
+}
+
+// Unwrap returns the error returned by the converter.
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+// converterRegistry maps types to the functions converting values to them.
+type converterRegistry map[reflect.Type]func(value any) (any, error)
+
+// defaultConverterRegistry holds the default converters shared by all Stores.
+// It is never modified after initialization.
+var defaultConverterRegistry = defaultConverters()
+
+// defaultConverters returns the converters every Store starts with.
+func defaultConverters() converterRegistry {
+	r := converterRegistry{}
+	for _, c := range []Converter{
+		NewConverter(convertDuration),
+		NewConverter(convertByteSize),
+		NewConverter(func(value any) (*url.URL, error) {
+			s, err := converterString(value)
+			if err != nil {
+				return nil, err
+			}
+			return url.Parse(s)
+		}),
+		NewConverter(func(value any) (net.IP, error) {
+			s, err := converterString(value)
+			if err != nil {
+				return nil, err
+			}
+			ip := net.ParseIP(strings.TrimSpace(s))
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", s)
+			}
+			return ip, nil
+		}),
+		NewConverter(func(value any) (netip.Addr, error) {
+			s, err := converterString(value)
+			if err != nil {
+				return netip.Addr{}, err
+			}
+			return netip.ParseAddr(strings.TrimSpace(s))
+		}),
+		NewConverter(func(value any) (netip.Prefix, error) {
+			s, err := converterString(value)
+			if err != nil {
+				return netip.Prefix{}, err
+			}
+			return netip.ParsePrefix(strings.TrimSpace(s))
+		}),
+		NewConverter(func(value any) (*regexp.Regexp, error) {
+			s, err := converterString(value)
+			if err != nil {
+				return nil, err
+			}
+			return regexp.Compile(s)
+		}),
+		NewConverter(func(value any) (*time.Location, error) {
+			s, err := converterString(value)
+			if err != nil {
+				return nil, err
+			}
+			return time.LoadLocation(strings.TrimSpace(s))
+		}),
+	} {
+		r[c.typ] = c.convert
+	}
+	return r
+}
+
+// newConverterRegistry returns a copy of the default converters overridden
+// by converters.
+func newConverterRegistry(converters []Converter) converterRegistry {
+	r := make(converterRegistry, len(defaultConverterRegistry)+len(converters))
+	for t, fn := range defaultConverterRegistry {
+		r[t] = fn
+	}
+	for _, c := range converters {
+		if c.typ != nil && c.convert != nil {
+			r[c.typ] = c.convert
+		}
+	}
+	return r
+}
+
+// handles reports whether values of type t are converted by a converter:
+// t is a registered type, a pointer to one or the element of a registered
+// pointer type, or a slice or string-keyed map of such types.
+func (r converterRegistry) handles(t reflect.Type) bool {
+	if t == nil || len(r) == 0 {
+		return false
+	}
+	if _, ok := r[t]; ok {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return r.handles(t.Elem())
+	case reflect.Slice:
+		return r.handles(t.Elem())
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && r.handles(t.Elem())
+	}
+	_, ok := r[reflect.PointerTo(t)]
+	return ok
+}
+
+// convert converts value to type t, which must be handled by r.
+// Errors are returned as *ConversionError with the path of the value.
+func (r converterRegistry) convert(path string, value any, t reflect.Type) (any, error) {
+	if value == nil || reflect.TypeOf(value) == t {
+		return value, nil
+	}
+	if fn, ok := r[t]; ok {
+		converted, err := fn(value)
+		if err != nil {
+			return nil, &ConversionError{Path: path, Type: t, Err: err}
+		}
+		return converted, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		converted, err := r.convert(path, value, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(reflect.ValueOf(converted))
+		return ptr.Interface(), nil
+
+	case reflect.Slice:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice {
+			return nil, &ConversionError{Path: path, Type: t, Err: fmt.Errorf("expected a list, got %T", value)}
+		}
+		result := reflect.MakeSlice(t, rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			if err := r.convertInto(path+"/"+strconv.Itoa(i), rv.Index(i).Interface(), result.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return result.Interface(), nil
+
+	case reflect.Map:
+		values, ok := value.(map[string]any)
+		if !ok {
+			return nil, &ConversionError{Path: path, Type: t, Err: fmt.Errorf("expected a map, got %T", value)}
+		}
+		result := reflect.MakeMapWithSize(t, len(values))
+		for k, v := range values {
+			elem := reflect.New(t.Elem()).Elem()
+			if err := r.convertInto(path+"/"+jsonptr.Escape(k), v, elem); err != nil {
+				return nil, err
+			}
+			result.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), elem)
+		}
+		return result.Interface(), nil
+	}
+
+	// A converter registered for *T, used for a field of type T
+	fn, ok := r[reflect.PointerTo(t)]
+	if !ok {
+		return value, nil
+	}
+	converted, err := fn(value)
+	if err != nil {
+		return nil, &ConversionError{Path: path, Type: t, Err: err}
+	}
+	if ptr := reflect.ValueOf(converted); !ptr.IsNil() {
+		return ptr.Elem().Interface(), nil
+	}
+	return reflect.Zero(t).Interface(), nil
+}
+
+// convertInto converts value to the type of dst and stores it in dst.
+// nil leaves dst unchanged.
+func (r converterRegistry) convertInto(path string, value any, dst reflect.Value) error {
+	converted, err := r.convert(path, value, dst.Type())
+	if err != nil {
+		return err
+	}
+	if converted != nil {
+		dst.Set(reflect.ValueOf(converted))
+	}
+	return nil
+}
+
+// convertValue converts value to targetType with the Converter handling the
+// type, or with the ValueConverter for other types.
+func (s *Store[T]) convertValue(path string, value any, targetType reflect.Type) (any, error) {
+	if s.converters.handles(targetType) {
+		return s.converters.convert(path, value, targetType)
+	}
+	return s.valueConverter(path, value, targetType)
+}
+
+// hasConverters reports whether any field of the schema has a type handled
+// by converters.
+func hasConverters(schema *Schema, converters converterRegistry) bool {
+	for _, m := range schema.Mappings {
+		if converters.handles(m.FieldType) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConversion converts a field value whose type has a Converter,
+// recording a failure with the layer that provided the value.
+func (c *constraintChecker) checkConversion(m *PathMapping, fv fieldValue) {
+	if !fv.exists || fv.value == nil || !c.converters.handles(m.FieldType) {
+		return
+	}
+	_, err := c.converters.convert(fv.path, fv.value, m.FieldType)
+	var convErr *ConversionError
+	if !errors.As(err, &convErr) {
+		return
+	}
+	convErr.sensitive = m.Sensitive == sensitiveExplicit
+	if c.origins != nil {
+		entry := c.origins.getLeaf(convErr.Path)
+		if entry == nil {
+			entry = c.origins.getContainer(convErr.Path)
+		}
+		if entry != nil {
+			convErr.Layer = layerInfoAt(entry, c.origins.sourcePath(convErr.Path, entry))
+		}
+	}
+	c.errs = append(c.errs, convErr)
+}
+
+// assignConverted sets the fields of dst whose types have a Converter to the
+// converted values in src, the map decoded into dst. Decoders that re-encode
+// values, such as decoder.JSON, do not reproduce all of them; for example a
+// *time.Location has no exported fields.
+func assignConverted(dst reflect.Value, src map[string]any, table *MappingTable, converters converterRegistry) {
+	_ = walkDecoded(dst, src, table, "", func(_ *MappingTable, m *PathMapping, field reflect.Value, value any, _ string) (bool, error) {
+		if !converters.handles(m.FieldType) {
+			return true, nil
+		}
+		if rv := reflect.ValueOf(value); rv.Type() == m.FieldType && field.CanSet() {
+			field.Set(rv)
+		}
+		return false, nil
+	})
+}
+
+// converterDocumentValue returns value in the form written to layer
+// documents by SetTo. Values of converted types are written as text, e.g. a
+// *url.URL as its string, so that documents stay readable.
+func converterDocumentValue(value any) any {
+	switch v := value.(type) {
+	case nil, string, bool, map[string]any, []any:
+		return value
+	case encoding.TextMarshaler:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil
+		}
+		if text, err := v.MarshalText(); err == nil {
+			return string(text)
+		}
+		return value
+	case fmt.Stringer:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil
+		}
+		return v.String()
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return converterDocumentValue(rv.Elem().Interface())
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return value
+		}
+		elems := make([]any, rv.Len())
+		for i := range elems {
+			elems[i] = converterDocumentValue(rv.Index(i).Interface())
+		}
+		return elems
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return value
+		}
+		values := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = converterDocumentValue(iter.Value().Interface())
+		}
+		return values
+	}
+	return value
+}
+
+// converterString returns value as a string for the default converters.
+func converterString(value any) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string, got %T", value)
+	}
+	return s, nil
+}
+
+// durationType is the reflect.Type of time.Duration.
+var durationType = reflect.TypeFor[time.Duration]()
+
+// convertDuration converts a duration string such as "30s", or a number of
+// nanoseconds, to time.Duration.
+func convertDuration(value any) (time.Duration, error) {
+	if s, ok := value.(string); ok {
+		s = strings.TrimSpace(s)
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Duration(n), nil
+		}
+		return time.ParseDuration(s)
+	}
+	converted, err := convertToInt(value, durationType)
+	if err != nil {
+		return 0, err
+	}
+	return converted.(time.Duration), nil
+}
+
+// byteSizeType is the reflect.Type of ByteSize.
+var byteSizeType = reflect.TypeFor[ByteSize]()
+
+// convertByteSize converts a size such as "512MiB", or a number of bytes,
+// to ByteSize.
+func convertByteSize(value any) (ByteSize, error) {
+	if s, ok := value.(string); ok {
+		return ParseByteSize(s)
+	}
+	converted, err := convertToInt(value, byteSizeType)
+	if err != nil {
+		return 0, err
+	}
+	return converted.(ByteSize), nil
+}
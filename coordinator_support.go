@@ -11,7 +11,8 @@ import (
 )
 
 func (s *Store[T]) syncLayerDirty(entry *layerEntry) {
-	entry.dirty = !entry.changeset.IsEmpty() || len(entry.projectionDirty) > 0
+	entry.dirty = !entry.changeset.IsEmpty() || len(entry.projectionDirty) > 0 ||
+		(entry.original != nil && entry.persistMigration)
 }
 
 func normalizeProjectionDirty(paths []string) []string {
@@ -64,15 +65,8 @@ func patchSetTouchesPath(patches document.JSONPatchSet, root string) bool {
 }
 
 func (s *Store[T]) mergeLayerDataLocked(selectData func(*layerEntry) map[string]any) map[string]any {
-	merged := make(map[string]any)
-	for _, entry := range s.layers {
-		data := selectData(entry)
-		if data == nil {
-			continue
-		}
-		deepMerge(merged, data)
-	}
-	return merged
+	merger := layerMerger{trie: s.schema.Trie, sentinel: s.unsetSentinel}
+	return s.mergeEntriesLocked(merger, selectData)
 }
 
 type layerSaveContext[T any] struct {
@@ -3,6 +3,7 @@ package jubako
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/yacchi/jubako/container"
@@ -29,6 +30,28 @@ const (
 	sensitiveExplicit = tag.SensitiveExplicit
 )
 
+// MergeStrategy controls how slice values from multiple layers are combined.
+// It is configured per path with the merge: directive in jubako struct tags.
+type MergeStrategy = tag.MergeStrategy
+
+// Merge strategy constants.
+const (
+	// MergeReplace replaces a lower layer's slice with the higher layer's slice (default).
+	MergeReplace = tag.MergeReplace
+	// MergeAppend concatenates slices from all layers in priority order (merge:append).
+	MergeAppend = tag.MergeAppend
+	// MergeUnion concatenates slices from all layers in priority order,
+	// skipping elements that are already present (merge:union).
+	MergeUnion = tag.MergeUnion
+	// MergeByKey merges map elements sharing the same key value and appends the rest
+	// (merge:key=NAME).
+	MergeByKey = tag.MergeByKey
+)
+
+// Constraints holds the declarative validation rules declared with the required,
+// min=, max=, enum= and pattern= directives in jubako struct tags.
+type Constraints = tag.Constraints
+
 // MapDecoder is a function that decodes a map[string]any into a target struct.
 // See decoder.Func for the function signature.
 // The default implementation is decoder.JSON.
@@ -58,13 +81,45 @@ type PathMapping struct {
 	FieldType reflect.Type
 	// StructField is the original reflected struct field metadata.
 	StructField reflect.StructField
+	// Merge is the strategy used to combine slice values from multiple layers.
+	// Only meaningful for slice fields; MergeReplace (the default) keeps the
+	// highest priority layer's slice.
+	Merge MergeStrategy
+	// MergeKey is the element key used to match elements when Merge is MergeByKey.
+	MergeKey string
+	// Constraints are the validation rules checked against the merged configuration
+	// before it is decoded.
+	Constraints Constraints
+	// Default is the raw default value from the default= directive.
+	// It is applied by the defaults layer (layer/defaults).
+	Default string
+	// HasDefault is true if the field declares a default= directive.
+	HasDefault bool
+	// Aliases are the former paths from the alias= directive, as written in the tag.
+	Aliases []string
+	// AliasPaths are the schema paths of Aliases, resolved like Path.
+	// When a layer has no value at Path, the value at the first alias present is used.
+	AliasPaths []string
+	// Deprecated is true if values read from an alias are reported as deprecated
+	// (see SetDeprecationWarningHandler).
+	Deprecated bool
+	// Union is the discriminator key from the union= directive.
+	// The variants of the field are in MappingTable.Unions.
+	Union string
+	// Embedded is true for an anonymous struct field whose fields are
+	// promoted to the embedding struct, as in encoding/json.
+	Embedded bool
+
+	// pattern is the compiled Constraints.Pattern (nil if unset).
+	pattern *regexp.Regexp
 }
 
 // HasDirective returns true if this mapping has any jubako tag directives.
 // This is used to distinguish between fields that only have type info
 // (for SetTo conversion) vs fields with actual jubako tag mappings.
 func (m *PathMapping) HasDirective() bool {
-	return m.SourcePath != "" || m.Skipped || m.Sensitive == sensitiveExplicit
+	return m.SourcePath != "" || m.Skipped || m.Sensitive == sensitiveExplicit || m.Merge != MergeReplace ||
+		!m.Constraints.IsEmpty() || m.HasDefault || len(m.Aliases) > 0 || m.Union != ""
 }
 
 // MappingTable holds all path mappings for a struct type.
@@ -82,6 +137,8 @@ type MappingTable struct {
 	SliceElement map[string]*MappingTable
 	// MapValue contains mapping table for map value type (if map with struct values).
 	MapValue map[string]*MappingTable
+	// Unions contains the variants of union fields (union= directive).
+	Unions map[string]*UnionTable
 }
 
 // buildMappingTable creates a mapping table for the given struct type.
@@ -89,11 +146,20 @@ type MappingTable struct {
 // The delimiter is used to separate path and directives in jubako struct tags.
 // The fieldTagName specifies which struct tag to use for field name resolution (e.g., "json", "yaml").
 func buildMappingTable(t reflect.Type, delimiter string, fieldTagName string) *MappingTable {
-	return buildMappingTableRecursive(t, delimiter, fieldTagName, "")
+	return buildMappingTableRecursive(t, delimiter, fieldTagName, "", nil, nil)
+}
+
+// buildMappingTableWithConverters is like buildMappingTable, but treats
+// fields of types handled by converters as leaves: their values are
+// converted as a whole, so their structure is not mapped.
+func buildMappingTableWithConverters(t reflect.Type, delimiter string, fieldTagName string, converters converterRegistry) *MappingTable {
+	return buildMappingTableRecursive(t, delimiter, fieldTagName, "", converters, nil)
 }
 
 // buildMappingTableRecursive creates a mapping table with type path tracking for warnings.
-func buildMappingTableRecursive(t reflect.Type, delimiter string, fieldTagName string, typePath string) *MappingTable {
+// variantTables holds the variant tables of the unions being built, by interface
+// type, so that recursive variants share their tables (see buildUnionTable).
+func buildMappingTableRecursive(t reflect.Type, delimiter string, fieldTagName string, typePath string, converters converterRegistry, variantTables map[reflect.Type]map[string]*MappingTable) *MappingTable {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
@@ -112,6 +178,7 @@ func buildMappingTableRecursive(t reflect.Type, delimiter string, fieldTagName s
 		Nested:       make(map[string]*MappingTable),
 		SliceElement: make(map[string]*MappingTable),
 		MapValue:     make(map[string]*MappingTable),
+		Unions:       make(map[string]*UnionTable),
 	}
 
 	for i := 0; i < t.NumField(); i++ {
@@ -124,6 +191,9 @@ func buildMappingTableRecursive(t reflect.Type, delimiter string, fieldTagName s
 
 		// Parse all struct tags at once
 		tagInfo := tag.Parse(field, fieldTagName, delimiter)
+		if tagInfo.Err != nil {
+			panic(fmt.Sprintf("jubako: invalid jubako tag on field %s.%s: %v", currentTypePath, field.Name, tagInfo.Err))
+		}
 
 		// Skip if field key is "-" (json:"-")
 		if tagInfo.FieldKey == "-" {
@@ -131,7 +201,10 @@ func buildMappingTableRecursive(t reflect.Type, delimiter string, fieldTagName s
 		}
 
 		// Check for sensitive tag on non-leaf types and emit warning
-		checkSensitiveOnNonLeaf(currentTypePath, field, tagInfo)
+		converted := converters.handles(field.Type)
+		if !converted {
+			checkSensitiveOnNonLeaf(currentTypePath, field, tagInfo)
+		}
 
 		// Create PathMapping for all fields to support type conversion in SetTo.
 		// Even fields without jubako tags need type info for proper conversion.
@@ -143,8 +216,23 @@ func buildMappingTableRecursive(t reflect.Type, delimiter string, fieldTagName s
 			Skipped:     tagInfo.Skipped,
 			FieldType:   field.Type,
 			StructField: field,
+			Merge:       tagInfo.Merge,
+			MergeKey:    tagInfo.MergeKey,
+			Constraints: tagInfo.Constraints,
+			Default:     tagInfo.Default,
+			HasDefault:  tagInfo.HasDefault,
+			Aliases:     tagInfo.Aliases,
+			Deprecated:  tagInfo.Deprecated,
+			Union:       tagInfo.Union,
+			Embedded:    tagInfo.Embedded,
+		}
+		if m.Constraints.Pattern != "" {
+			m.pattern = regexp.MustCompile(m.Constraints.Pattern)
 		}
 		table.Mappings = append(table.Mappings, m)
+		if converted {
+			continue
+		}
 
 		// Check for nested types (struct, slice, map) that may have jubako tags
 		fieldType := field.Type
@@ -155,11 +243,19 @@ func buildMappingTableRecursive(t reflect.Type, delimiter string, fieldTagName s
 		// Build nested type path for warnings
 		nestedTypePath := currentTypePath + "." + field.Name
 
+		// Union fields are mapped by the variant their discriminator selects
+		if tagInfo.Union != "" {
+			if union := buildUnionTable(field.Type, tagInfo.Union, delimiter, fieldTagName, nestedTypePath, converters, variantTables); union != nil {
+				table.Unions[tagInfo.FieldKey] = union
+			}
+			continue
+		}
+
 		switch fieldType.Kind() {
 		case reflect.Struct:
 			// Recursively build mapping table for nested struct
 			// Use HasMappings() to include tables needed for type conversion (not just jubako directives)
-			if nested := buildMappingTableRecursive(fieldType, delimiter, fieldTagName, nestedTypePath); nested != nil && nested.HasMappings() {
+			if nested := buildMappingTableRecursive(fieldType, delimiter, fieldTagName, nestedTypePath, converters, variantTables); nested != nil && nested.HasMappings() {
 				table.Nested[tagInfo.FieldKey] = nested
 			}
 
@@ -171,7 +267,7 @@ func buildMappingTableRecursive(t reflect.Type, delimiter string, fieldTagName s
 			}
 			if elemType.Kind() == reflect.Struct {
 				elemTypePath := nestedTypePath + "[]"
-				if elemTable := buildMappingTableRecursive(elemType, delimiter, fieldTagName, elemTypePath); elemTable != nil && elemTable.HasMappings() {
+				if elemTable := buildMappingTableRecursive(elemType, delimiter, fieldTagName, elemTypePath, converters, variantTables); elemTable != nil && elemTable.HasMappings() {
 					table.SliceElement[tagInfo.FieldKey] = elemTable
 				}
 			}
@@ -184,7 +280,7 @@ func buildMappingTableRecursive(t reflect.Type, delimiter string, fieldTagName s
 			}
 			if valueType.Kind() == reflect.Struct {
 				mapTypePath := nestedTypePath + "[key]"
-				if valueTable := buildMappingTableRecursive(valueType, delimiter, fieldTagName, mapTypePath); valueTable != nil && valueTable.HasMappings() {
+				if valueTable := buildMappingTableRecursive(valueType, delimiter, fieldTagName, mapTypePath, converters, variantTables); valueTable != nil && valueTable.HasMappings() {
 					table.MapValue[tagInfo.FieldKey] = valueTable
 				}
 			}
@@ -200,11 +296,13 @@ func (t *MappingTable) String() string {
 		return "(no mappings)"
 	}
 	var sb strings.Builder
-	t.writeString(&sb, "")
+	t.writeString(&sb, "", make(map[*MappingTable]bool))
 	return sb.String()
 }
 
-func (t *MappingTable) writeString(sb *strings.Builder, indent string) {
+// writeString writes the table; visiting holds the variant tables being
+// written, so that recursive variants are written once.
+func (t *MappingTable) writeString(sb *strings.Builder, indent string, visiting map[*MappingTable]bool) {
 	for _, m := range t.Mappings {
 		if m.Skipped {
 			fmt.Fprintf(sb, "%s%s: (skipped)\n", indent, m.FieldKey)
@@ -216,15 +314,28 @@ func (t *MappingTable) writeString(sb *strings.Builder, indent string) {
 	}
 	for key, nested := range t.Nested {
 		fmt.Fprintf(sb, "%s%s:\n", indent, key)
-		nested.writeString(sb, indent+"  ")
+		nested.writeString(sb, indent+"  ", visiting)
 	}
 	for key, elemTable := range t.SliceElement {
 		fmt.Fprintf(sb, "%s%s[]: (slice element)\n", indent, key)
-		elemTable.writeString(sb, indent+"  ")
+		elemTable.writeString(sb, indent+"  ", visiting)
 	}
 	for key, valueTable := range t.MapValue {
 		fmt.Fprintf(sb, "%s%s{}: (map value)\n", indent, key)
-		valueTable.writeString(sb, indent+"  ")
+		valueTable.writeString(sb, indent+"  ", visiting)
+	}
+	for key, union := range t.Unions {
+		for _, name := range sortedKeys(union.Tables) {
+			variantTable := union.Tables[name]
+			if visiting[variantTable] {
+				fmt.Fprintf(sb, "%s%s{%s=%s}: (recursive union variant)\n", indent, key, union.Discriminator, name)
+				continue
+			}
+			fmt.Fprintf(sb, "%s%s{%s=%s}: (union variant)\n", indent, key, union.Discriminator, name)
+			visiting[variantTable] = true
+			variantTable.writeString(sb, indent+"  ", visiting)
+			delete(visiting, variantTable)
+		}
 	}
 }
 
@@ -242,7 +353,7 @@ func (t *MappingTable) IsEmpty() bool {
 		}
 	}
 	// Check nested tables
-	if len(t.Nested) > 0 || len(t.SliceElement) > 0 || len(t.MapValue) > 0 {
+	if len(t.Nested) > 0 || len(t.SliceElement) > 0 || len(t.MapValue) > 0 || len(t.Unions) > 0 {
 		return false
 	}
 	return true
@@ -254,7 +365,18 @@ func (t *MappingTable) HasMappings() bool {
 	if t == nil {
 		return false
 	}
-	return len(t.Mappings) > 0 || len(t.Nested) > 0 || len(t.SliceElement) > 0 || len(t.MapValue) > 0
+	return len(t.Mappings) > 0 || len(t.Nested) > 0 || len(t.SliceElement) > 0 || len(t.MapValue) > 0 || len(t.Unions) > 0
+}
+
+// hasFieldKey reports whether a field of the table, other than an embedded
+// struct, is encoded at key.
+func (t *MappingTable) hasFieldKey(key string) bool {
+	for _, m := range t.Mappings {
+		if m.FieldKey == key && !m.Embedded && !m.Skipped {
+			return true
+		}
+	}
+	return false
 }
 
 // applyMappings applies the mapping table to transform the source map.
@@ -304,7 +426,8 @@ func applyMappingsWithRoot(root, src map[string]any, table *MappingTable, pathPr
 	// Apply type conversion for all fields with known types
 	if converter != nil {
 		for _, m := range table.Mappings {
-			if m.FieldType == nil || m.Skipped {
+			// Union values are converted by the mappings of their variants
+			if m.FieldType == nil || m.Skipped || m.Union != "" {
 				continue
 			}
 			value, exists := dst[m.FieldKey]
@@ -369,5 +492,18 @@ func applyMappingsWithRoot(root, src map[string]any, table *MappingTable, pathPr
 		}
 	}
 
+	// Apply the mappings of the variants selected by union discriminators
+	for fieldKey, union := range table.Unions {
+		if value, ok := dst[fieldKey]; ok {
+			unionPath := pathPrefix + "/" + jsonptr.Escape(fieldKey)
+			dst[fieldKey] = union.rebuild(value, unionPath, func(elem map[string]any, elemPath string) any {
+				if name, ok := union.variantName(elem); ok {
+					return applyMappingsWithRoot(root, elem, union.Tables[name], elemPath, converter)
+				}
+				return elem
+			})
+		}
+	}
+
 	return dst
 }
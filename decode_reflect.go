@@ -0,0 +1,693 @@
+package jubako
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yacchi/jubako/internal/variant"
+)
+
+// WithReflectDecoder decodes the merged map into T by assigning the values
+// directly with reflection, skipping the JSON round-trip of the default
+// decoder.JSON.
+//
+// Struct fields are resolved through the Store's MappingTable, so field keys,
+// skipped fields and embedded structs follow the same rules as every other
+// Store operation, including WithTagName and WithTagDelimiter. Keys differing
+// only in case are matched as encoding/json does. Values are assigned into
+// pointers, maps, slices and arrays, strings are decoded by
+// encoding.TextUnmarshaler implementations, and other json.Unmarshaler
+// implementations receive the value as JSON.
+//
+// Unlike decoder.JSON, integers keep their precision: an int64 held in the map
+// is assigned as is instead of passing through float64. Fields of type any
+// receive copies of the values without converting numbers to float64.
+//
+// Example:
+//
+//	store := jubako.New[Config](jubako.WithReflectDecoder())
+func WithReflectDecoder() StoreOption {
+	return func(o *storeOptions) {
+		o.decoder = nil
+		o.reflectDecoder = true
+	}
+}
+
+// reflectDecoder assigns map values to struct fields resolved by mapping tables.
+type reflectDecoder struct {
+	tagName    string
+	delimiter  string
+	converters converterRegistry
+
+	// tables holds the *MappingTable of each struct type, seeded with the
+	// tables of the Store's schema. Types the schema does not reach, such as
+	// the elements of [][]T, are added when they are first decoded.
+	tables sync.Map
+	// fields caches the []reflectField of each *MappingTable.
+	fields sync.Map
+}
+
+// newReflectDecoder creates a decoder for values of type t, whose mapping
+// table is table.
+func newReflectDecoder(t reflect.Type, table *MappingTable, tagName, delimiter string, converters converterRegistry) *reflectDecoder {
+	d := &reflectDecoder{tagName: tagName, delimiter: delimiter, converters: converters}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if table != nil && t != nil {
+		d.tables.Store(t, table)
+		d.seedVariants(table, make(map[*MappingTable]bool))
+	}
+	return d
+}
+
+// seedVariants records the tables of the union variants reachable from table,
+// which unionDecoder decodes as separate targets.
+func (d *reflectDecoder) seedVariants(table *MappingTable, visited map[*MappingTable]bool) {
+	if table == nil || visited[table] {
+		return
+	}
+	visited[table] = true
+	for _, union := range table.Unions {
+		for name, typ := range union.Variants {
+			d.tables.LoadOrStore(variant.Struct(typ), union.Tables[name])
+			d.seedVariants(union.Tables[name], visited)
+		}
+	}
+	for _, nested := range table.Nested {
+		d.seedVariants(nested, visited)
+	}
+	for _, nested := range table.SliceElement {
+		d.seedVariants(nested, visited)
+	}
+	for _, nested := range table.MapValue {
+		d.seedVariants(nested, visited)
+	}
+}
+
+// tableFor returns the mapping table of struct type t.
+func (d *reflectDecoder) tableFor(t reflect.Type) *MappingTable {
+	if table, ok := d.tables.Load(t); ok {
+		return table.(*MappingTable)
+	}
+	table, _ := d.tables.LoadOrStore(t, buildMappingTableWithConverters(t, d.delimiter, d.tagName, d.converters))
+	return table.(*MappingTable)
+}
+
+// reflectField is a struct field decoded by key, including the promoted
+// fields of embedded structs.
+type reflectField struct {
+	key   string
+	index []int
+	// table is the mapping table of the struct declaring the field, which
+	// holds the tables of its nested values.
+	table   *MappingTable
+	mapping *PathMapping
+	tagged  bool
+	// quoted is set by the ",string" option: numbers and booleans are
+	// written as strings.
+	quoted bool
+}
+
+// childTable returns the mapping table passed down to the value of f: the
+// table of the struct, or of the elements of the slice or map.
+func (f *reflectField) childTable() *MappingTable {
+	fieldType := f.mapping.FieldType
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	switch fieldType.Kind() {
+	case reflect.Slice, reflect.Array:
+		return f.table.SliceElement[f.key]
+	case reflect.Map:
+		return f.table.MapValue[f.key]
+	}
+	return f.table.Nested[f.key]
+}
+
+// decode implements MapDecoder.
+func (d *reflectDecoder) decode(m map[string]any, target any) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("failed to decode into %T: target must be a non-nil pointer", target)
+	}
+	if m == nil {
+		return nil
+	}
+	if err := d.decodeValue(m, rv.Elem(), nil); err != nil {
+		return fmt.Errorf("failed to decode to target type: %w", err)
+	}
+	return nil
+}
+
+// decodeValue assigns src to dst. table is the mapping table of dst if it is
+// a struct, or of its elements if it is a slice, an array or a map; if nil,
+// the table of the struct type is looked up.
+func (d *reflectDecoder) decodeValue(src any, dst reflect.Value, table *MappingTable) error {
+	if src == nil {
+		// Like encoding/json, null clears pointers, maps, slices and
+		// interfaces, and leaves other values unchanged.
+		switch dst.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+			dst.Set(reflect.Zero(dst.Type()))
+		}
+		return nil
+	}
+
+	// Values already of the field's type, e.g. converted by the Store's
+	// ValueConverter or Converters, are assigned as is. Slices of scalars
+	// are copied; other containers are copied below.
+	if sv := reflect.ValueOf(src); dst.Kind() != reflect.Interface && sv.Type().AssignableTo(dst.Type()) {
+		switch sv.Kind() {
+		case reflect.Map, reflect.Interface:
+		case reflect.Slice:
+			switch sv.Type().Elem().Kind() {
+			case reflect.Map, reflect.Slice, reflect.Ptr, reflect.Interface:
+			default:
+				dst.Set(reflect.AppendSlice(reflect.MakeSlice(dst.Type(), 0, sv.Len()), sv))
+				return nil
+			}
+		default:
+			dst.Set(sv)
+			return nil
+		}
+	}
+
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return d.decodeValue(src, dst.Elem(), table)
+	}
+
+	src = genericValue(src)
+	if dst.CanAddr() {
+		if handled, err := d.decodeUnmarshaler(src, dst.Addr()); handled {
+			return err
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.Interface:
+		if dst.NumMethod() != 0 {
+			return typeError(src, dst.Type())
+		}
+		dst.Set(reflect.ValueOf(copyValue(src)))
+		return nil
+
+	case reflect.Struct:
+		obj, ok := src.(map[string]any)
+		if !ok {
+			return typeError(src, dst.Type())
+		}
+		if table == nil {
+			table = d.tableFor(dst.Type())
+		}
+		return d.decodeStruct(obj, dst, table)
+
+	case reflect.Map:
+		obj, ok := src.(map[string]any)
+		if !ok {
+			return typeError(src, dst.Type())
+		}
+		return d.decodeMap(obj, dst, table)
+
+	case reflect.Slice:
+		if s, ok := src.(string); ok && dst.Type().Elem().Kind() == reflect.Uint8 {
+			// []byte is written as a base64 string, as with encoding/json
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return &decodeError{err: err}
+			}
+			dst.SetBytes(b)
+			return nil
+		}
+		if b, ok := src.([]byte); ok && dst.Type().Elem().Kind() == reflect.Uint8 {
+			dst.SetBytes(append([]byte(nil), b...))
+			return nil
+		}
+		arr, ok := src.([]any)
+		if !ok {
+			return typeError(src, dst.Type())
+		}
+		slice := reflect.MakeSlice(dst.Type(), len(arr), len(arr))
+		for i, elem := range arr {
+			if err := d.decodeValue(elem, slice.Index(i), table); err != nil {
+				return atKey(err, strconv.Itoa(i))
+			}
+		}
+		dst.Set(slice)
+		return nil
+
+	case reflect.Array:
+		arr, ok := src.([]any)
+		if !ok {
+			return typeError(src, dst.Type())
+		}
+		for i := 0; i < dst.Len(); i++ {
+			if i >= len(arr) {
+				dst.Index(i).Set(reflect.Zero(dst.Type().Elem()))
+				continue
+			}
+			if err := d.decodeValue(arr[i], dst.Index(i), table); err != nil {
+				return atKey(err, strconv.Itoa(i))
+			}
+		}
+		return nil
+	}
+
+	return assignScalar(src, dst)
+}
+
+// decodeUnmarshaler decodes src with the encoding.TextUnmarshaler or
+// json.Unmarshaler implementation of ptr, reporting whether it has one.
+func (d *reflectDecoder) decodeUnmarshaler(src any, ptr reflect.Value) (bool, error) {
+	if s, ok := src.(string); ok && ptr.Type().Implements(textUnmarshalerType) {
+		if err := ptr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return true, &decodeError{err: err}
+		}
+		return true, nil
+	}
+	if ptr.Type().Implements(jsonUnmarshalerType) {
+		data, err := json.Marshal(src)
+		if err != nil {
+			return true, &decodeError{err: err}
+		}
+		if err := ptr.Interface().(json.Unmarshaler).UnmarshalJSON(data); err != nil {
+			return true, &decodeError{err: err}
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (d *reflectDecoder) decodeStruct(obj map[string]any, dst reflect.Value, table *MappingTable) error {
+	fields := d.fieldsOf(table)
+	matched := 0
+	for i := range fields {
+		f := &fields[i]
+		value, ok := obj[f.key]
+		if !ok {
+			continue
+		}
+		matched++
+		if err := d.decodeField(value, dst, f); err != nil {
+			return atKey(err, f.key)
+		}
+	}
+
+	// Keys differing only in case are matched as encoding/json does; the
+	// map is scanned only when it has keys left over.
+	if matched == len(obj) {
+		return nil
+	}
+	for i := range fields {
+		f := &fields[i]
+		if _, ok := obj[f.key]; ok {
+			continue
+		}
+		for key, value := range obj {
+			if isFieldKey(fields, key) || !strings.EqualFold(key, f.key) {
+				continue
+			}
+			if err := d.decodeField(value, dst, f); err != nil {
+				return atKey(err, key)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// isFieldKey reports whether key matches a field exactly.
+func isFieldKey(fields []reflectField, key string) bool {
+	for i := range fields {
+		if fields[i].key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *reflectDecoder) decodeField(value any, dst reflect.Value, f *reflectField) error {
+	fv, ok := fieldByIndex(dst, f.index, value != nil)
+	if !ok {
+		return nil
+	}
+	if f.quoted {
+		if s, isString := value.(string); isString {
+			switch fv.Kind() {
+			case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+				reflect.Float32, reflect.Float64:
+				return assignScalar(json.Number(s), fv)
+			}
+		}
+	}
+	return d.decodeValue(value, fv, f.childTable())
+}
+
+func (d *reflectDecoder) decodeMap(obj map[string]any, dst reflect.Value, table *MappingTable) error {
+	t := dst.Type()
+	keyType := t.Key()
+	m := reflect.MakeMapWithSize(t, len(obj))
+	for key, value := range obj {
+		kv := reflect.New(keyType).Elem()
+		switch {
+		case reflect.PointerTo(keyType).Implements(textUnmarshalerType):
+			if err := kv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+				return atKey(&decodeError{err: err}, key)
+			}
+		case keyType.Kind() == reflect.String:
+			kv.SetString(key)
+		default:
+			if err := assignScalar(json.Number(key), kv); err != nil {
+				return atKey(err, key)
+			}
+		}
+		ev := reflect.New(t.Elem()).Elem()
+		if err := d.decodeValue(value, ev, table); err != nil {
+			return atKey(err, key)
+		}
+		m.SetMapIndex(kv, ev)
+	}
+	dst.Set(m)
+	return nil
+}
+
+// fieldsOf returns the fields decoded with table.
+func (d *reflectDecoder) fieldsOf(table *MappingTable) []reflectField {
+	if fields, ok := d.fields.Load(table); ok {
+		return fields.([]reflectField)
+	}
+	fields, _ := d.fields.LoadOrStore(table, d.tableFields(table))
+	return fields.([]reflectField)
+}
+
+// tableFields returns the fields of the mappings of table, following the
+// rules of encoding/json: the fields of embedded structs are promoted, and of
+// fields with the same key the shallowest wins, then the tagged one. Keys
+// still ambiguous are dropped. Skipped fields are not decoded.
+func (d *reflectDecoder) tableFields(table *MappingTable) []reflectField {
+	type queued struct {
+		table *MappingTable
+		index []int
+	}
+	var current []queued
+	next := []queued{{table: table}}
+	visited := map[*MappingTable]bool{}
+
+	var fields []reflectField
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		var depth []reflectField
+		count := map[string]int{}
+
+		for _, q := range current {
+			if q.table == nil || visited[q.table] {
+				continue
+			}
+			visited[q.table] = true
+
+			for _, m := range q.table.Mappings {
+				if m.Skipped {
+					continue
+				}
+				index := append(slices.Clone(q.index), m.StructField.Index...)
+				if m.Embedded {
+					// Promote the fields of the embedded struct
+					next = append(next, queued{table: q.table.Nested[m.FieldKey], index: index})
+					continue
+				}
+				name, opts, _ := strings.Cut(m.StructField.Tag.Get(d.tagName), ",")
+				depth = append(depth, reflectField{
+					key:     m.FieldKey,
+					index:   index,
+					table:   q.table,
+					mapping: m,
+					tagged:  name != "",
+					quoted:  slices.Contains(strings.Split(opts, ","), "string"),
+				})
+				count[m.FieldKey]++
+			}
+		}
+
+		known := map[string]bool{}
+		for _, f := range fields {
+			known[f.key] = true
+		}
+		for _, f := range depth {
+			if known[f.key] {
+				// Shadowed by a shallower field
+				continue
+			}
+			if count[f.key] > 1 {
+				if f, ok := dominantField(depth, f.key); ok {
+					fields = append(fields, f)
+				}
+				known[f.key] = true
+				continue
+			}
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// dominantField returns the only tagged field with key among fields of the
+// same depth.
+func dominantField(fields []reflectField, key string) (reflectField, bool) {
+	var dominant reflectField
+	tagged := 0
+	for _, f := range fields {
+		if f.key == key && f.tagged {
+			dominant = f
+			tagged++
+		}
+	}
+	return dominant, tagged == 1
+}
+
+// fieldByIndex returns the field of v at index, allocating nil embedded
+// struct pointers on the way if alloc is set.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// assignScalar assigns a boolean, number or string to dst.
+func assignScalar(src any, dst reflect.Value) error {
+	sv := reflect.ValueOf(src)
+	_, isNumber := src.(json.Number)
+	switch dst.Kind() {
+	case reflect.Bool:
+		if isNumber {
+			// Quoted with the ",string" option
+			if b, err := strconv.ParseBool(string(src.(json.Number))); err == nil {
+				dst.SetBool(b)
+				return nil
+			}
+		} else if sv.Kind() == reflect.Bool {
+			dst.SetBool(sv.Bool())
+			return nil
+		}
+
+	case reflect.String:
+		if sv.Kind() == reflect.String && !isNumber {
+			dst.SetString(sv.String())
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := toInt64(sv); ok && !dst.OverflowInt(n) {
+			dst.SetInt(n)
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, ok := toUint64(sv); ok && !dst.OverflowUint(n) {
+			dst.SetUint(n)
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if f, ok := toFloat64(sv); ok && !dst.OverflowFloat(f) {
+			dst.SetFloat(f)
+			return nil
+		}
+	}
+	return typeError(src, dst.Type())
+}
+
+func toInt64(sv reflect.Value) (int64, bool) {
+	if n, ok := sv.Interface().(json.Number); ok {
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	switch sv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return sv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := sv.Uint(); u <= math.MaxInt64 {
+			return int64(u), true
+		}
+	case reflect.Float32, reflect.Float64:
+		if f := sv.Float(); f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return int64(f), true
+		}
+	}
+	return 0, false
+}
+
+func toUint64(sv reflect.Value) (uint64, bool) {
+	if n, ok := sv.Interface().(json.Number); ok {
+		u, err := strconv.ParseUint(string(n), 10, 64)
+		return u, err == nil
+	}
+	switch sv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := sv.Int(); i >= 0 {
+			return uint64(i), true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return sv.Uint(), true
+	case reflect.Float32, reflect.Float64:
+		if f := sv.Float(); f >= 0 && f == math.Trunc(f) && f < math.MaxUint64 {
+			return uint64(f), true
+		}
+	}
+	return 0, false
+}
+
+func toFloat64(sv reflect.Value) (float64, bool) {
+	if n, ok := sv.Interface().(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	switch sv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(sv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(sv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return sv.Float(), true
+	}
+	return 0, false
+}
+
+// genericValue returns typed maps with string keys and typed slices in v,
+// e.g. a map[string]string set by a layer, as map[string]any and []any.
+func genericValue(v any) any {
+	switch v.(type) {
+	case map[string]any, []any:
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String || rv.IsNil() {
+			return v
+		}
+		m := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		return m
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 || rv.IsNil() {
+			return v
+		}
+		s := make([]any, rv.Len())
+		for i := range s {
+			s[i] = rv.Index(i).Interface()
+		}
+		return s
+	}
+	return v
+}
+
+// copyValue returns a deep copy of the maps and slices in v, so that the
+// decoded value does not share them with the Store.
+func copyValue(v any) any {
+	switch v := genericValue(v).(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[key] = copyValue(value)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, value := range v {
+			s[i] = copyValue(value)
+		}
+		return s
+	}
+	return v
+}
+
+// decodeError reports a value that failed to decode. The path is collected
+// while the error is returned, so that decoding builds no paths.
+type decodeError struct {
+	// keys holds the path segments of the value, innermost first.
+	keys []string
+	src  any
+	typ  reflect.Type
+	err  error
+}
+
+// typeError reports a value that cannot be assigned to t.
+func typeError(src any, t reflect.Type) error {
+	return &decodeError{src: src, typ: t}
+}
+
+// atKey adds the map key or slice index holding the failed value to err.
+func atKey(err error, key string) error {
+	if de, ok := err.(*decodeError); ok {
+		de.keys = append(de.keys, key)
+	}
+	return err
+}
+
+func (e *decodeError) path() string {
+	if len(e.keys) == 0 {
+		return "/"
+	}
+	var b strings.Builder
+	for i := len(e.keys) - 1; i >= 0; i-- {
+		b.WriteByte('/')
+		b.WriteString(e.keys[i])
+	}
+	return b.String()
+}
+
+func (e *decodeError) Error() string {
+	if e.err != nil {
+		return e.path() + ": " + e.err.Error()
+	}
+	return fmt.Sprintf("cannot decode %T into %s at %s", e.src, e.typ, e.path())
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,439 @@
+package jubako
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yacchi/jubako/decoder"
+	"github.com/yacchi/jubako/layer/mapdata"
+)
+
+// reflectDecode decodes m into target with the decoder of WithReflectDecoder.
+func reflectDecode(m map[string]any, target any) error {
+	d := newReflectDecoder(reflect.TypeOf(target), nil, DefaultFieldTagName, DefaultTagDelimiter, nil)
+	return d.decode(m, target)
+}
+
+type reflectLevel int
+
+func (l *reflectLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+type reflectRaw struct {
+	JSON string
+}
+
+func (r *reflectRaw) UnmarshalJSON(data []byte) error {
+	r.JSON = string(data)
+	return nil
+}
+
+type ReflectBase struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type ReflectMeta struct {
+	Owner string `json:"owner"`
+}
+
+type reflectServer struct {
+	Host    string            `json:"host"`
+	Port    int               `json:"port"`
+	Weight  float64           `json:"weight"`
+	Enabled *bool             `json:"enabled"`
+	Tags    []string          `json:"tags"`
+	Labels  map[string]string `json:"labels"`
+}
+
+type reflectTarget struct {
+	ReflectBase
+	*ReflectMeta
+	Name      string                    `json:"name"`
+	Untagged  string                    // matched by the field name, ignoring case
+	Count     uint8                     `json:"count"`
+	Big       int64                     `json:"big"`
+	Server    reflectServer             `json:"server"`
+	Backup    *reflectServer            `json:"backup"`
+	Servers   []reflectServer           `json:"servers"`
+	ByName    map[string]*reflectServer `json:"by_name"`
+	ByPort    map[int]string            `json:"by_port"`
+	Fixed     [3]int                    `json:"fixed"`
+	Level     reflectLevel              `json:"level"`
+	Addr      netip.Addr                `json:"addr"`
+	Created   time.Time                 `json:"created"`
+	Timeout   time.Duration             `json:"timeout"`
+	Raw       reflectRaw                `json:"raw"`
+	Extra     any                       `json:"extra"`
+	Data      []byte                    `json:"data"`
+	Quoted    int                       `json:"quoted,string"`
+	Skipped   string                    `json:"-"`
+	unexposed string
+}
+
+func reflectInput() map[string]any {
+	return map[string]any{
+		"id":       "svc-1",
+		"name":     "outer",
+		"owner":    "team-a",
+		"untagged": "by name",
+		"count":    float64(7),
+		"big":      float64(1 << 40),
+		"server": map[string]any{
+			"host":    "localhost",
+			"port":    float64(8080),
+			"weight":  0.5,
+			"enabled": true,
+			"tags":    []any{"a", "b"},
+			"labels":  map[string]any{"env": "prod"},
+		},
+		"backup": map[string]any{"host": "backup", "port": float64(9090)},
+		"servers": []any{
+			map[string]any{"host": "s1"},
+			map[string]any{"host": "s2", "port": float64(2)},
+		},
+		"by_name": map[string]any{
+			"x": map[string]any{"host": "x"},
+			"y": nil,
+		},
+		"by_port": map[string]any{"80": "http", "443": "https"},
+		"fixed":   []any{float64(1), float64(2)},
+		"level":   "info",
+		"addr":    "192.0.2.1",
+		"created": "2024-01-02T03:04:05Z",
+		"timeout": float64(time.Second),
+		"raw":     map[string]any{"k": []any{float64(1), "v"}},
+		"extra":   map[string]any{"list": []any{float64(1), "two"}, "flag": true},
+		"data":    "aGVsbG8=",
+		"quoted":  "42",
+		"Skipped": "ignored",
+		"unknown": "ignored",
+	}
+}
+
+func TestReflect_MatchesJSON(t *testing.T) {
+	var want, got reflectTarget
+	if err := decoder.JSON(reflectInput(), &want); err != nil {
+		t.Fatalf("decoder.JSON() error = %v", err)
+	}
+	if err := reflectDecode(reflectInput(), &got); err != nil {
+		t.Fatalf("Reflect() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decode() = %+v\ndecoder.JSON() = %+v", got, want)
+	}
+	if got.Name != "outer" || got.ID != "svc-1" || got.ReflectMeta == nil || got.Owner != "team-a" {
+		t.Errorf("embedded fields = %q %q %+v", got.Name, got.ID, got.ReflectMeta)
+	}
+	if got.Untagged != "by name" || got.Quoted != 42 || got.Level != 1 {
+		t.Errorf("Untagged = %q, Quoted = %d, Level = %d", got.Untagged, got.Quoted, got.Level)
+	}
+}
+
+func TestReflect_MapTarget(t *testing.T) {
+	input := map[string]any{"a": map[string]any{"b": []any{1, "x"}}}
+	var got map[string]any
+	if err := reflectDecode(input, &got); err != nil {
+		t.Fatalf("Reflect() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, input) {
+		t.Errorf("decode() = %v, want %v", got, input)
+	}
+
+	// The decoded value does not share containers with the input
+	got["a"].(map[string]any)["b"].([]any)[0] = 2
+	if input["a"].(map[string]any)["b"].([]any)[0] != 1 {
+		t.Error("decode() shares containers with the input map")
+	}
+}
+
+func TestReflect_TypedContainers(t *testing.T) {
+	var got struct {
+		Labels map[string]string `json:"labels"`
+		Ports  []int             `json:"ports"`
+		Data   []byte            `json:"data"`
+		Extra  any               `json:"extra"`
+	}
+	labels := map[string]string{"env": "prod"}
+	input := map[string]any{
+		"labels": labels,
+		"ports":  []int{80, 443},
+		"data":   []byte("raw"),
+		"extra":  map[string][]string{"a": {"b"}},
+	}
+	if err := reflectDecode(input, &got); err != nil {
+		t.Fatalf("Reflect() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.Labels, labels) || !reflect.DeepEqual(got.Ports, []int{80, 443}) || string(got.Data) != "raw" {
+		t.Errorf("decode() = %+v", got)
+	}
+	if want := map[string]any{"a": []any{"b"}}; !reflect.DeepEqual(got.Extra, want) {
+		t.Errorf("Extra = %#v, want %#v", got.Extra, want)
+	}
+
+	got.Labels["env"] = "dev"
+	if labels["env"] != "prod" {
+		t.Error("decode() shares the map with the input")
+	}
+}
+
+func TestReflect_Precision(t *testing.T) {
+	var got struct {
+		ID    int64  `json:"id"`
+		Count uint64 `json:"count"`
+		Extra any    `json:"extra"`
+	}
+	input := map[string]any{
+		"id":    int64(math.MaxInt64),
+		"count": uint64(math.MaxUint64),
+		"extra": int64(1<<53 + 1),
+	}
+	if err := reflectDecode(input, &got); err != nil {
+		t.Fatalf("Reflect() error = %v", err)
+	}
+	if got.ID != math.MaxInt64 || got.Count != math.MaxUint64 || got.Extra != int64(1<<53+1) {
+		t.Errorf("decode() = %+v, want values kept exactly", got)
+	}
+}
+
+func TestReflect_ConvertedValues(t *testing.T) {
+	// Values converted by the Store's ValueConverter are assigned as is
+	type named string
+	var got struct {
+		Timeout time.Duration `json:"timeout"`
+		Kind    named         `json:"kind"`
+		Small   int8          `json:"small"`
+		Ratio   float32       `json:"ratio"`
+		URL     *url.URL      `json:"url"`
+		IP      net.IP        `json:"ip"`
+	}
+	endpoint := &url.URL{Scheme: "https", User: url.UserPassword("u", "p"), Host: "example.com"}
+	input := map[string]any{
+		"timeout": 30 * time.Second,
+		"kind":    named("x"),
+		"small":   int(-3),
+		"ratio":   float32(0.25),
+		"url":     endpoint,
+		"ip":      net.ParseIP("192.0.2.1"),
+	}
+	if err := reflectDecode(input, &got); err != nil {
+		t.Fatalf("Reflect() error = %v", err)
+	}
+	if got.Timeout != 30*time.Second || got.Kind != "x" || got.Small != -3 || got.Ratio != 0.25 {
+		t.Errorf("decode() = %+v", got)
+	}
+	if got.URL != endpoint || got.IP.String() != "192.0.2.1" {
+		t.Errorf("URL = %v, IP = %v", got.URL, got.IP)
+	}
+}
+
+func TestReflect_NullClearsReferences(t *testing.T) {
+	port := 1
+	got := struct {
+		Port *int           `json:"port"`
+		Tags []string       `json:"tags"`
+		Name string         `json:"name"`
+		Meta map[string]int `json:"meta"`
+	}{Port: &port, Tags: []string{"a"}, Name: "kept", Meta: map[string]int{"a": 1}}
+	if err := reflectDecode(map[string]any{"port": nil, "tags": nil, "name": nil, "meta": nil}, &got); err != nil {
+		t.Fatalf("Reflect() error = %v", err)
+	}
+	if got.Port != nil || got.Tags != nil || got.Meta != nil || got.Name != "kept" {
+		t.Errorf("decode() = %+v", got)
+	}
+}
+
+func TestReflect_Errors(t *testing.T) {
+	type inner struct {
+		Port int `json:"port"`
+	}
+	tests := []struct {
+		name    string
+		input   map[string]any
+		target  any
+		wantErr string
+	}{
+		{
+			name:  "type mismatch",
+			input: map[string]any{"server": map[string]any{"port": "http"}},
+			target: &struct {
+				Server inner `json:"server"`
+			}{},
+			wantErr: "cannot decode string into int at /server/port",
+		},
+		{
+			name:    "fraction into int",
+			input:   map[string]any{"port": 1.5},
+			target:  &inner{},
+			wantErr: "cannot decode float64 into int at /port",
+		},
+		{
+			name:  "overflow",
+			input: map[string]any{"port": float64(300)},
+			target: &struct {
+				Port uint8 `json:"port"`
+			}{},
+			wantErr: "cannot decode float64 into uint8 at /port",
+		},
+		{
+			name:  "slice element",
+			input: map[string]any{"ports": []any{float64(1), true}},
+			target: &struct {
+				Ports []int `json:"ports"`
+			}{},
+			wantErr: "at /ports/1",
+		},
+		{
+			name:  "text unmarshaler",
+			input: map[string]any{"level": "trace"},
+			target: &struct {
+				Level reflectLevel `json:"level"`
+			}{},
+			wantErr: `/level: unknown level "trace"`,
+		},
+		{
+			name:    "non-pointer target",
+			input:   map[string]any{},
+			target:  inner{},
+			wantErr: "target must be a non-nil pointer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := reflectDecode(tt.input, tt.target)
+			if err == nil {
+				t.Fatal("decode() expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want to contain %q", err.Error(), tt.wantErr)
+			}
+			// decoder.JSON rejects the same input
+			if tt.name != "non-pointer target" {
+				if err := decoder.JSON(tt.input, tt.target); err == nil {
+					t.Error("decoder.JSON() accepted the input")
+				}
+			}
+		})
+	}
+}
+
+func TestReflect_Dominance(t *testing.T) {
+	type A struct {
+		Name string `json:"name"`
+		Dup  string
+	}
+	type B struct {
+		Name string
+		Dup  string
+	}
+	type target struct {
+		A
+		B
+	}
+	var got target
+	if err := reflectDecode(map[string]any{"name": "tagged", "Dup": "ambiguous"}, &got); err != nil {
+		t.Fatalf("Reflect() error = %v", err)
+	}
+	var want target
+	if err := json.Unmarshal([]byte(`{"name":"tagged","Dup":"ambiguous"}`), &want); err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("decode() = %+v, want %+v", got, want)
+	}
+}
+
+func TestStore_WithReflectDecoder_MappingTable(t *testing.T) {
+	type Common struct {
+		Region string `yaml:"region"`
+	}
+	type config struct {
+		Common `yaml:",inline"`
+		Port   int    `yaml:"port" jubako:"/listen/port;sensitive"`
+		Token  string `yaml:"token" jubako:"-"`
+		Name   string `yaml:"app_name" json:"name"`
+	}
+	data := map[string]any{
+		"region":   "eu",
+		"listen":   map[string]any{"port": 8080},
+		"token":    "secret",
+		"app_name": "app",
+		"name":     "ignored",
+	}
+
+	// Keys follow the yaml tags, the path and skip directives use the
+	// custom delimiter, and the inline struct is promoted
+	store := New[config](WithTagName("yaml"), WithTagDelimiter(";"), WithReflectDecoder())
+	if err := store.Add(mapdata.New("user", data)); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	got := store.Get()
+	want := config{Common: Common{Region: "eu"}, Port: 8080, Name: "app"}
+	if got != want {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+}
+
+func benchmarkDecoder(b *testing.B, decode MapDecoder) {
+	servers := make([]any, 32)
+	for i := range servers {
+		servers[i] = map[string]any{
+			"host":    fmt.Sprintf("host-%d", i),
+			"port":    8000 + i,
+			"weight":  float64(i) / 10,
+			"enabled": i%2 == 0,
+			"tags":    []any{"a", "b", "c"},
+			"labels":  map[string]any{"zone": "z1", "rack": fmt.Sprintf("r%d", i)},
+		}
+	}
+	input := map[string]any{
+		"id":      "svc-1",
+		"name":    "bench",
+		"server":  servers[0],
+		"servers": servers,
+		"by_port": map[string]any{"80": "http", "443": "https"},
+		"level":   "info",
+		"timeout": float64(time.Second),
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var target reflectTarget
+		if err := decode(input, &target); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecoder_JSON(b *testing.B) {
+	benchmarkDecoder(b, decoder.JSON)
+}
+
+func BenchmarkDecoder_Reflect(b *testing.B) {
+	d := newReflectDecoder(reflect.TypeFor[reflectTarget](), nil, DefaultFieldTagName, DefaultTagDelimiter, nil)
+	benchmarkDecoder(b, d.decode)
+}
@@ -0,0 +1,47 @@
+package document
+
+// Commenter is implemented by Documents of formats with comments. It lets
+// tools carry comments across formats, such as the jubako convert command.
+//
+// Comments are keyed by the JSON Pointer of the value they are written
+// above, and the empty path holds the comment at the head of the document.
+// A value without a comment above it takes its trailing comment on the same
+// line. The comment text has no comment markers; multi-line comments are
+// separated by newlines.
+type Commenter interface {
+	// Comments returns the comments of data.
+	Comments(data []byte) (map[string]string, error)
+
+	// MarshalWithComments marshals data, writing each comment above the
+	// value at its path. Comments of paths missing from data are dropped.
+	//
+	// Returns UnsupportedStructureError if data contains structures that
+	// cannot be represented in this document format.
+	MarshalWithComments(data map[string]any, comments map[string]string) ([]byte, error)
+}
+
+// Comments returns the comments of data if doc implements Commenter, and nil otherwise.
+func Comments(doc Document, data []byte) (map[string]string, error) {
+	c, ok := doc.(Commenter)
+	if !ok {
+		return nil, nil
+	}
+	return c.Comments(data)
+}
+
+// Marshal marshals data in the format of doc. The comments are written if
+// doc implements Commenter, and dropped otherwise.
+//
+// Example (converting JSONC to YAML):
+//
+//	data, err := src.Get(input)
+//	comments, err := document.Comments(src, input)
+//	output, err := document.Marshal(dst, data, comments)
+func Marshal(doc Document, data map[string]any, comments map[string]string) ([]byte, error) {
+	if c, ok := doc.(Commenter); ok {
+		return c.MarshalWithComments(data, comments)
+	}
+	// Documents without comments marshal plainly, which is what
+	// MarshalTestData does
+	return doc.MarshalTestData(data)
+}
@@ -0,0 +1,43 @@
+package document
+
+import (
+	"reflect"
+	"testing"
+)
+
+type commentTestDocument struct {
+	Document
+}
+
+func (commentTestDocument) Comments(data []byte) (map[string]string, error) {
+	return map[string]string{"/port": string(data)}, nil
+}
+
+func (commentTestDocument) MarshalWithComments(data map[string]any, comments map[string]string) ([]byte, error) {
+	return []byte(comments["/port"]), nil
+}
+
+type plainTestDocument struct {
+	Document
+}
+
+func (plainTestDocument) MarshalTestData(data map[string]any) ([]byte, error) {
+	return []byte("plain"), nil
+}
+
+func TestCommentsAndMarshal(t *testing.T) {
+	comments, err := Comments(commentTestDocument{}, []byte("the port"))
+	if err != nil || !reflect.DeepEqual(comments, map[string]string{"/port": "the port"}) {
+		t.Errorf("Comments() = %v, %v", comments, err)
+	}
+	if out, err := Marshal(commentTestDocument{}, nil, comments); err != nil || string(out) != "the port" {
+		t.Errorf("Marshal() = %q, %v, want comments written", out, err)
+	}
+
+	if comments, err := Comments(plainTestDocument{}, []byte("x")); comments != nil || err != nil {
+		t.Errorf("Comments() = %v, %v, want nil for documents without comments", comments, err)
+	}
+	if out, err := Marshal(plainTestDocument{}, nil, comments); err != nil || string(out) != "plain" {
+		t.Errorf("Marshal() = %q, %v, want MarshalTestData output", out, err)
+	}
+}
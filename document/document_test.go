@@ -90,3 +90,45 @@ func TestJSONPatchSet_BasicsAndApplyTo(t *testing.T) {
 		}
 	})
 }
+
+// TestDiff tests that Diff produces the patches turning one map into another.
+func TestDiff(t *testing.T) {
+	from := map[string]any{
+		"keep":    1,
+		"removed": true,
+		"changed": "a",
+		"nested":  map[string]any{"x": 1, "y": []any{1, 2}},
+		"a/b":     "escaped",
+		"scalar":  map[string]any{"k": "v"},
+	}
+	to := map[string]any{
+		"keep":    1,
+		"changed": "b",
+		"nested":  map[string]any{"x": 2, "y": []any{1, 2}, "z": "new"},
+		"added":   map[string]any{"k": "v"},
+		"a/b":     "changed",
+		"scalar":  "v",
+	}
+
+	got := Diff(from, to)
+	want := JSONPatchSet{
+		NewReplacePatch("/a~1b", "changed"),
+		NewAddPatch("/added", map[string]any{"k": "v"}),
+		NewReplacePatch("/changed", "b"),
+		NewReplacePatch("/nested/x", 2),
+		NewAddPatch("/nested/z", "new"),
+		NewRemovePatch("/removed"),
+		NewReplacePatch("/scalar", "v"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff() = %v, want %v", got, want)
+	}
+
+	got.ApplyTo(from)
+	if !reflect.DeepEqual(from, to) {
+		t.Errorf("ApplyTo(Diff()) = %v, want %v", from, to)
+	}
+	if patches := Diff(to, to); !patches.IsEmpty() {
+		t.Errorf("Diff() of equal maps = %v, want empty", patches)
+	}
+}
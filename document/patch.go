@@ -1,7 +1,12 @@
 // Package document provides the Document interface and related types.
 package document
 
-import "github.com/yacchi/jubako/jsonptr"
+import (
+	"reflect"
+	"sort"
+
+	"github.com/yacchi/jubako/jsonptr"
+)
 
 // PatchOp represents a JSON Patch operation type (RFC 6902).
 type PatchOp string
@@ -81,3 +86,46 @@ func (ps JSONPatchSet) ApplyTo(data map[string]any) {
 		}
 	}
 }
+
+// Diff returns the patches that turn from into to.
+// Maps are compared key by key, in sorted key order; other values, including
+// slices, are replaced as a whole when they differ. Patch values share memory
+// with to.
+func Diff(from, to map[string]any) JSONPatchSet {
+	var patches JSONPatchSet
+	diffMaps(&patches, "", from, to)
+	return patches
+}
+
+func diffMaps(patches *JSONPatchSet, prefix string, from, to map[string]any) {
+	keys := make([]string, 0, len(from)+len(to))
+	for key := range from {
+		keys = append(keys, key)
+	}
+	for key := range to {
+		if _, ok := from[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := prefix + "/" + jsonptr.Escape(key)
+		fromValue, inFrom := from[key]
+		toValue, inTo := to[key]
+		switch {
+		case !inTo:
+			patches.Remove(path)
+		case !inFrom:
+			patches.Add(path, toValue)
+		default:
+			fromMap, fromIsMap := fromValue.(map[string]any)
+			toMap, toIsMap := toValue.(map[string]any)
+			if fromIsMap && toIsMap {
+				diffMaps(patches, path, fromMap, toMap)
+			} else if !reflect.DeepEqual(fromValue, toValue) {
+				patches.Replace(path, toValue)
+			}
+		}
+	}
+}
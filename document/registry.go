@@ -0,0 +1,88 @@
+package document
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory creates a Document for a registered format.
+type Factory func() Document
+
+var registry = struct {
+	mu         sync.RWMutex
+	factories  map[DocumentFormat]Factory
+	extensions map[string]DocumentFormat
+}{
+	factories:  make(map[DocumentFormat]Factory),
+	extensions: make(map[string]DocumentFormat),
+}
+
+// Register makes a document format available by name to tools that select
+// formats at runtime, such as the jubako CLI. extensions lists the file
+// extensions of the format, including the leading dot (e.g., ".yaml").
+//
+// Format packages register themselves when they are imported, so a program
+// enables a format with a blank import:
+//
+//	import _ "github.com/yacchi/jubako/format/yaml"
+//
+// Register panics if factory is nil or if format is registered twice.
+func Register(format DocumentFormat, factory Factory, extensions ...string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if factory == nil {
+		panic("document: Register factory is nil")
+	}
+	if _, dup := registry.factories[format]; dup {
+		panic(fmt.Sprintf("document: Register called twice for format %q", format))
+	}
+	registry.factories[format] = factory
+	for _, ext := range extensions {
+		registry.extensions[strings.ToLower(ext)] = format
+	}
+}
+
+// Lookup returns a new Document of a registered format.
+//
+// Example:
+//
+//	doc, ok := document.Lookup(document.FormatYAML)
+//	if !ok {
+//	  return fmt.Errorf("yaml support is not linked in")
+//	}
+func Lookup(format DocumentFormat) (Document, bool) {
+	registry.mu.RLock()
+	factory, ok := registry.factories[format]
+	registry.mu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+	return factory(), true
+}
+
+// FormatOf returns the registered format of a file path, based on its extension.
+func FormatOf(path string) (DocumentFormat, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	format, ok := registry.extensions[strings.ToLower(filepath.Ext(path))]
+	return format, ok
+}
+
+// Formats returns the registered formats in alphabetical order.
+func Formats() []DocumentFormat {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	formats := make([]DocumentFormat, 0, len(registry.factories))
+	for format := range registry.factories {
+		formats = append(formats, format)
+	}
+	sort.Slice(formats, func(i, j int) bool { return formats[i] < formats[j] })
+	return formats
+}
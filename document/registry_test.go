@@ -0,0 +1,52 @@
+package document
+
+import "testing"
+
+type registryTestDocument struct{ Document }
+
+func (registryTestDocument) Format() DocumentFormat { return "registry-test" }
+
+func TestRegister(t *testing.T) {
+	Register("registry-test", func() Document { return registryTestDocument{} }, ".rt", ".RTX")
+
+	doc, ok := Lookup("registry-test")
+	if !ok || doc.Format() != "registry-test" {
+		t.Fatalf("Lookup() = %v, %v", doc, ok)
+	}
+	if _, ok := Lookup("registry-missing"); ok {
+		t.Error("Lookup() should fail for unregistered formats")
+	}
+
+	for _, path := range []string{"config.rt", "/etc/app/CONFIG.RT", "config.rtx"} {
+		if format, ok := FormatOf(path); !ok || format != "registry-test" {
+			t.Errorf("FormatOf(%q) = %q, %v", path, format, ok)
+		}
+	}
+	if _, ok := FormatOf("config.unknown"); ok {
+		t.Error("FormatOf() should fail for unknown extensions")
+	}
+
+	found := false
+	for _, format := range Formats() {
+		found = found || format == "registry-test"
+	}
+	if !found {
+		t.Errorf("Formats() = %v, want registry-test", Formats())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Register() should panic for duplicate formats")
+		}
+	}()
+	Register("registry-test", func() Document { return registryTestDocument{} })
+}
+
+func TestRegister_NilFactory(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Register() should panic for a nil factory")
+		}
+	}()
+	Register("registry-nil", nil)
+}
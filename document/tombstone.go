@@ -0,0 +1,52 @@
+package document
+
+import "encoding/json"
+
+const (
+	// UnsetTag is the YAML tag used to mark a value as a tombstone (e.g., `key: !unset`).
+	UnsetTag = "!unset"
+
+	// DefaultUnsetSentinel is the string written by formats without tag support
+	// (JSON, JSONC, TOML) to represent a tombstone without a Sentinel.
+	// Stores read it back as a tombstone only when it is their configured
+	// sentinel (see jubako.WithUnsetSentinel).
+	DefaultUnsetSentinel = "!unset"
+)
+
+// Tombstone marks a path as deleted. When a layer provides a Tombstone at a path,
+// the path is removed from the merged configuration instead of being overridden,
+// so values from lower priority layers no longer show through.
+//
+// Documents with tag support (YAML) read and write tombstones as UnsetTag.
+// Other formats persist them as the Sentinel string, which the store recognizes
+// as a tombstone when it matches its configured sentinel.
+type Tombstone struct {
+	// Sentinel is the string written by formats without tag support.
+	// DefaultUnsetSentinel is used when empty.
+	Sentinel string
+}
+
+// IsTombstone reports whether v is a Tombstone.
+func IsTombstone(v any) bool {
+	_, ok := v.(Tombstone)
+	return ok
+}
+
+// String returns the sentinel string for the tombstone.
+func (t Tombstone) String() string {
+	if t.Sentinel == "" {
+		return DefaultUnsetSentinel
+	}
+	return t.Sentinel
+}
+
+// MarshalJSON encodes the tombstone as its sentinel string.
+func (t Tombstone) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// MarshalText encodes the tombstone as its sentinel string.
+// This is used by encoders that support encoding.TextMarshaler (e.g., TOML).
+func (t Tombstone) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
@@ -0,0 +1,42 @@
+package document
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTombstone(t *testing.T) {
+	if !IsTombstone(Tombstone{}) {
+		t.Error("IsTombstone(Tombstone{}) = false, want true")
+	}
+	if IsTombstone(DefaultUnsetSentinel) {
+		t.Error("IsTombstone(sentinel string) = true, want false")
+	}
+
+	tests := []struct {
+		name string
+		in   Tombstone
+		want string
+	}{
+		{"default sentinel", Tombstone{}, DefaultUnsetSentinel},
+		{"custom sentinel", Tombstone{Sentinel: "__unset__"}, "__unset__"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(map[string]any{"x": tt.in})
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if want := `{"x":"` + tt.want + `"}`; string(b) != want {
+				t.Errorf("json.Marshal() = %s, want %s", b, want)
+			}
+			text, err := tt.in.MarshalText()
+			if err != nil {
+				t.Fatalf("MarshalText() error = %v", err)
+			}
+			if string(text) != tt.want {
+				t.Errorf("MarshalText() = %q, want %q", text, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,463 @@
+package jubako
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/jsonptr"
+)
+
+// DefaultExamplePlaceholder is the value RenderExample writes for sensitive
+// fields unless WithExamplePlaceholder is given.
+const DefaultExamplePlaceholder = "<sensitive>"
+
+// exampleMapKey is the key of the sample entry written for maps of structs.
+const exampleMapKey = "example"
+
+// ExampleOption is a functional option for configuring RenderExample.
+type ExampleOption func(*exampleOptions)
+
+// exampleOptions holds the options for RenderExample.
+type exampleOptions struct {
+	comments    map[string]string
+	placeholder string
+}
+
+// WithExampleComments sets the comments written above fields, keyed by the
+// schema path of the field (e.g., "/server/port", with "*" for map keys and
+// slice indices). Multi-line comments are written as several comment lines.
+// The "generate example" command fills them from Go doc comments.
+func WithExampleComments(comments map[string]string) ExampleOption {
+	return func(o *exampleOptions) {
+		o.comments = comments
+	}
+}
+
+// WithExamplePlaceholder sets the value written for sensitive fields instead
+// of DefaultExamplePlaceholder.
+func WithExamplePlaceholder(placeholder string) ExampleOption {
+	return func(o *exampleOptions) {
+		o.placeholder = placeholder
+	}
+}
+
+// RenderExample renders an example configuration document listing every
+// field of the configuration type, for onboarding and documentation.
+//
+// Like JSONSchema, the document follows the paths the Store reads values from.
+// Each field holds the value of its default= directive, or the zero value of
+// its type. Sensitive fields hold a placeholder (see WithExamplePlaceholder)
+// so that no secret is ever written, and maps and slices of structs hold a
+// single sample element. Aliases are omitted.
+//
+// format is one of document.FormatYAML, document.FormatTOML,
+// document.FormatJSONC and document.FormatJSON; pass doc.Format() to render
+// for a layer's document. Comments set with WithExampleComments are written
+// in all formats but JSON.
+//
+// Example:
+//
+//	data, err := store.RenderExample(document.FormatYAML)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	os.WriteFile("config.example.yaml", data, 0o644)
+func (s *Store[T]) RenderExample(format document.DocumentFormat, opts ...ExampleOption) ([]byte, error) {
+	options := exampleOptions{placeholder: DefaultExamplePlaceholder}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	b := exampleBuilder{
+		gen: jsonSchemaGenerator{
+			tagName:        s.tagName,
+			tagDelimiter:   s.tagDelimiter,
+			valueConverter: s.convertValue,
+		},
+		options: options,
+	}
+	root := newJSONSchemaNode()
+	for _, m := range s.schema.Mappings {
+		if m.Skipped || m.Path == "" {
+			continue
+		}
+		root.insert(m.Path, m, false)
+	}
+	members := b.members(root, "")
+
+	var buf bytes.Buffer
+	switch format {
+	case document.FormatYAML:
+		renderExampleYAML(&buf, members, "")
+	case document.FormatTOML:
+		renderExampleTOML(&buf, members, nil)
+	case document.FormatJSONC:
+		renderExampleJSON(&buf, members, "", true)
+	case document.FormatJSON:
+		renderExampleJSON(&buf, members, "", false)
+	default:
+		return nil, fmt.Errorf("unsupported example format %q", format)
+	}
+	return buf.Bytes(), nil
+}
+
+// exampleNode is a field of the example document.
+type exampleNode struct {
+	key     string
+	comment string
+	// value is the value of a leaf field.
+	value any
+	// object is true if the field is an object of children.
+	object bool
+	// list is true if the field is a list with a single object of children.
+	list     bool
+	children []*exampleNode
+}
+
+// exampleBuilder converts the document tree of the schema to example nodes.
+type exampleBuilder struct {
+	gen     jsonSchemaGenerator
+	options exampleOptions
+}
+
+// members returns the example nodes of the children of node, in declaration order.
+func (b exampleBuilder) members(node *jsonSchemaNode, path string) []*exampleNode {
+	var members []*exampleNode
+	for _, key := range node.keys {
+		if member := b.build(key, path+"/"+jsonptr.Escape(key), node.children[key]); member != nil {
+			members = append(members, member)
+		}
+	}
+	return members
+}
+
+// build returns the example node of the field at path.
+func (b exampleBuilder) build(key, path string, node *jsonSchemaNode) *exampleNode {
+	if node.alias {
+		return nil
+	}
+	n := &exampleNode{key: key, comment: b.options.comments[path]}
+	m := node.mapping
+	if m == nil {
+		n.object, n.children = true, b.members(node, path)
+		return n
+	}
+
+	t := m.FieldType
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if m.Sensitive == sensitiveExplicit {
+		n.value = b.options.placeholder
+		return n
+	}
+	if m.HasDefault && t != nil {
+		if value, ok := b.gen.defaultValue(m.Path, m.Default, t); ok {
+			n.value = exampleValue(value)
+			return n
+		}
+	}
+
+	// The fields of union values depend on their variant
+	if m.Union != "" {
+		switch exampleKind(t) {
+		case reflect.Map:
+			n.object = true
+		case reflect.Slice:
+			n.value = []any{}
+		}
+		return n
+	}
+
+	switch exampleKind(t) {
+	case reflect.Struct:
+		n.object, n.children = true, b.members(node, path)
+	case reflect.Map:
+		n.object = true
+		if node.wildcard != nil {
+			if sample := b.build(exampleMapKey, path+"/*", node.wildcard); sample != nil {
+				n.children = []*exampleNode{sample}
+			}
+		}
+	case reflect.Slice:
+		n.value = []any{}
+		if node.wildcard != nil {
+			elem := b.build("", path+"/*", node.wildcard)
+			switch {
+			case elem == nil:
+			case elem.object:
+				n.value, n.list, n.children = nil, true, elem.children
+			default:
+				n.value = []any{elem.value}
+			}
+		}
+	default:
+		n.value = exampleZero(t)
+	}
+	return n
+}
+
+// exampleKind returns the kind of t as far as the example document is
+// concerned: types decoded from strings or custom JSON are leaves.
+func exampleKind(t reflect.Type) reflect.Kind {
+	switch {
+	case t == nil, t == timeType,
+		reflect.PointerTo(t).Implements(jsonUnmarshalerType),
+		reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return reflect.Invalid
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		// encoding/json decodes []byte from a base64 string
+		return reflect.String
+	case t.Kind() == reflect.Array:
+		return reflect.Slice
+	}
+	return t.Kind()
+}
+
+// exampleZero returns the value written for a field of type t without default.
+func exampleZero(t reflect.Type) any {
+	if t == nil {
+		return nil
+	}
+	switch {
+	case t == timeType:
+		return time.Time{}.Format(time.RFC3339)
+	case t == durationType:
+		return time.Duration(0).String()
+	case reflect.PointerTo(t).Implements(jsonUnmarshalerType):
+		return nil
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return ""
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return false
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return 0
+	case reflect.String:
+		return ""
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return ""
+		}
+		return []any{}
+	case reflect.Array:
+		return []any{}
+	case reflect.Map, reflect.Struct:
+		return map[string]any{}
+	default:
+		return nil
+	}
+}
+
+// exampleValue converts a default value to the form written in documents.
+func exampleValue(value any) any {
+	switch v := value.(type) {
+	case time.Duration:
+		return v.String()
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return value
+}
+
+// renderExampleYAML writes nodes as a YAML block mapping.
+func renderExampleYAML(buf *bytes.Buffer, nodes []*exampleNode, indent string) {
+	for _, n := range nodes {
+		writeExampleComment(buf, indent, "#", n.comment)
+		key := exampleKey(n.key)
+		switch {
+		case n.list && len(n.children) > 0:
+			buf.WriteString(indent + key + ":\n")
+			// The first key of the element starts the sequence entry
+			var elem bytes.Buffer
+			renderExampleYAML(&elem, n.children, indent+"    ")
+			lines := strings.SplitAfter(elem.String(), "\n")
+			for i, line := range lines {
+				if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+					lines[i] = indent + "  - " + strings.TrimPrefix(line, indent+"    ")
+					break
+				}
+			}
+			buf.WriteString(strings.Join(lines, ""))
+		case n.list:
+			buf.WriteString(indent + key + ": []\n")
+		case n.object && len(n.children) > 0:
+			buf.WriteString(indent + key + ":\n")
+			renderExampleYAML(buf, n.children, indent+"  ")
+		case n.object:
+			buf.WriteString(indent + key + ": {}\n")
+		default:
+			buf.WriteString(indent + key + ": " + exampleJSON(n.value) + "\n")
+		}
+	}
+}
+
+// renderExampleTOML writes nodes as the key/value pairs of the table at
+// table, followed by their sub-tables and arrays of tables.
+func renderExampleTOML(buf *bytes.Buffer, nodes []*exampleNode, table []string) {
+	var tables []*exampleNode
+	for _, n := range nodes {
+		if len(n.children) > 0 {
+			tables = append(tables, n)
+			continue
+		}
+		writeExampleComment(buf, "", "#", n.comment)
+		key := exampleKey(n.key)
+		switch {
+		case n.list:
+			buf.WriteString(key + " = []\n")
+		case n.object:
+			buf.WriteString(key + " = {}\n")
+		case n.value == nil:
+			// TOML has no null; leave the key for the user to fill in
+			buf.WriteString("# " + key + " =\n")
+		default:
+			buf.WriteString(key + " = " + exampleTOMLValue(n.value) + "\n")
+		}
+	}
+	for _, n := range tables {
+		path := append(append([]string{}, table...), exampleKey(n.key))
+		// Tables holding only sub-tables are implicit, unless commented
+		if n.list || n.comment != "" || hasExampleLeaf(n.children) {
+			if buf.Len() > 0 {
+				buf.WriteString("\n")
+			}
+			writeExampleComment(buf, "", "#", n.comment)
+			if n.list {
+				buf.WriteString("[[" + strings.Join(path, ".") + "]]\n")
+			} else {
+				buf.WriteString("[" + strings.Join(path, ".") + "]\n")
+			}
+		}
+		renderExampleTOML(buf, n.children, path)
+	}
+}
+
+// hasExampleLeaf reports whether nodes include a key/value pair of a TOML table.
+func hasExampleLeaf(nodes []*exampleNode) bool {
+	for _, n := range nodes {
+		if len(n.children) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// exampleTOMLValue returns a TOML inline value.
+func exampleTOMLValue(value any) string {
+	switch v := value.(type) {
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if item != nil {
+				items = append(items, exampleTOMLValue(item))
+			}
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]any:
+		if len(v) == 0 {
+			return "{}"
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if v[key] != nil {
+				items = append(items, exampleKey(key)+" = "+exampleTOMLValue(v[key]))
+			}
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	}
+	return exampleJSON(value)
+}
+
+// renderExampleJSON writes nodes as a JSON object, with // comments if comments is true.
+func renderExampleJSON(buf *bytes.Buffer, nodes []*exampleNode, indent string, comments bool) {
+	if len(nodes) == 0 {
+		buf.WriteString("{}")
+		if indent == "" {
+			buf.WriteString("\n")
+		}
+		return
+	}
+	buf.WriteString("{\n")
+	inner := indent + "  "
+	for i, n := range nodes {
+		if comments {
+			writeExampleComment(buf, inner, "//", n.comment)
+		}
+		buf.WriteString(inner + exampleJSON(n.key) + ": ")
+		switch {
+		case n.list && len(n.children) > 0:
+			buf.WriteString("[\n" + inner + "  ")
+			renderExampleJSON(buf, n.children, inner+"  ", comments)
+			buf.WriteString("\n" + inner + "]")
+		case n.list:
+			buf.WriteString("[]")
+		case n.object:
+			renderExampleJSON(buf, n.children, inner, comments)
+		default:
+			buf.WriteString(exampleJSON(n.value))
+		}
+		if i < len(nodes)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString(indent + "}")
+	if indent == "" {
+		buf.WriteString("\n")
+	}
+}
+
+// writeExampleComment writes comment as lines starting with marker.
+func writeExampleComment(buf *bytes.Buffer, indent, marker, comment string) {
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		if line = strings.TrimRight(line, " \t"); line == "" {
+			buf.WriteString(indent + marker + "\n")
+		} else {
+			buf.WriteString(indent + marker + " " + line + "\n")
+		}
+	}
+}
+
+// exampleKey returns key as a YAML or TOML key, quoted unless it is bare.
+func exampleKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return exampleJSON(key)
+		}
+	}
+	return key
+}
+
+// exampleJSON encodes value as compact JSON, which is also valid YAML flow
+// syntax and, for scalars, TOML.
+func exampleJSON(value any) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return "null"
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
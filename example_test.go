@@ -0,0 +1,176 @@
+package jubako
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/format/json"
+	"github.com/yacchi/jubako/layer"
+	"github.com/yacchi/jubako/source/bytes"
+)
+
+type exampleBackend struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port" jubako:"default=80"`
+}
+
+type exampleConfig struct {
+	Server struct {
+		Port    int           `yaml:"port" jubako:"default=8080,alias=/server/listen_port"`
+		Timeout time.Duration `yaml:"timeout" jubako:"default=30s"`
+	} `yaml:"server"`
+	Password string                    `yaml:"password" jubako:"/auth/password,sensitive"`
+	Backends map[string]exampleBackend `yaml:"backends"`
+	Replicas []exampleBackend          `yaml:"replicas"`
+	Tags     []string                  `yaml:"tags"`
+	Debug    bool                      `yaml:"debug"`
+	Internal string                    `yaml:"internal" jubako:"-"`
+}
+
+var exampleComments = map[string]string{
+	"/server":          "Server settings.",
+	"/server/port":     "Port to listen on.\nMust be free.",
+	"/backends/*/host": "Backend host.",
+}
+
+func TestStore_RenderExample(t *testing.T) {
+	store := New[exampleConfig](WithTagName("yaml"))
+
+	tests := []struct {
+		format document.DocumentFormat
+		want   string
+	}{
+		{
+			format: document.FormatYAML,
+			want: `# Server settings.
+server:
+  # Port to listen on.
+  # Must be free.
+  port: 8080
+  timeout: "30s"
+auth:
+  password: "<sensitive>"
+backends:
+  example:
+    # Backend host.
+    host: ""
+    port: 80
+replicas:
+  - host: ""
+    port: 80
+tags: []
+debug: false
+`,
+		},
+		{
+			format: document.FormatTOML,
+			want: `tags = []
+debug = false
+
+# Server settings.
+[server]
+# Port to listen on.
+# Must be free.
+port = 8080
+timeout = "30s"
+
+[auth]
+password = "<sensitive>"
+
+[backends.example]
+# Backend host.
+host = ""
+port = 80
+
+[[replicas]]
+host = ""
+port = 80
+`,
+		},
+		{
+			format: document.FormatJSONC,
+			want: `{
+  // Server settings.
+  "server": {
+    // Port to listen on.
+    // Must be free.
+    "port": 8080,
+    "timeout": "30s"
+  },
+  "auth": {
+    "password": "<sensitive>"
+  },
+  "backends": {
+    "example": {
+      // Backend host.
+      "host": "",
+      "port": 80
+    }
+  },
+  "replicas": [
+    {
+      "host": "",
+      "port": 80
+    }
+  ],
+  "tags": [],
+  "debug": false
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			data, err := store.RenderExample(tt.format, WithExampleComments(exampleComments))
+			if err != nil {
+				t.Fatalf("RenderExample() error = %v", err)
+			}
+			if got := string(data); got != tt.want {
+				t.Errorf("RenderExample() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStore_RenderExample_JSONLoads(t *testing.T) {
+	store := New[exampleConfig](WithTagName("yaml"))
+	data, err := store.RenderExample(document.FormatJSON, WithExampleComments(exampleComments), WithExamplePlaceholder("changeme"))
+	if err != nil {
+		t.Fatalf("RenderExample() error = %v", err)
+	}
+	if strings.Contains(string(data), "//") {
+		t.Errorf("JSON example should not contain comments:\n%s", data)
+	}
+
+	// The example is a valid configuration for the Store
+	if err := store.Add(layer.New("example", bytes.New(data), json.New())); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	cfg := store.Get()
+	if cfg.Server.Port != 8080 || cfg.Server.Timeout != 30*time.Second {
+		t.Errorf("Server = %+v, want port 8080 and timeout 30s", cfg.Server)
+	}
+	if cfg.Password != "changeme" {
+		t.Errorf("Password = %q, want placeholder", cfg.Password)
+	}
+	if got := cfg.Backends["example"]; got.Port != 80 {
+		t.Errorf("Backends[example] = %+v, want port 80", got)
+	}
+	if len(cfg.Replicas) != 1 {
+		t.Errorf("Replicas = %+v, want one sample element", cfg.Replicas)
+	}
+}
+
+func TestStore_RenderExample_UnsupportedFormat(t *testing.T) {
+	store := New[exampleConfig](WithTagName("yaml"))
+	if _, err := store.RenderExample("ini"); err == nil {
+		t.Error("RenderExample() should fail for unsupported formats")
+	}
+}
@@ -20,6 +20,10 @@ type Document struct{}
 // Ensure Document implements document.Document interface.
 var _ document.Document = (*Document)(nil)
 
+func init() {
+	document.Register(document.FormatJSON, func() document.Document { return New() }, ".json")
+}
+
 // New returns a JSON Document.
 //
 // Example:
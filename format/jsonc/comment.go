@@ -0,0 +1,186 @@
+package jsonc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tailscale/hujson"
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/jsonptr"
+)
+
+// Ensure Document implements document.Commenter interface.
+var _ document.Commenter = (*Document)(nil)
+
+// Comments returns the comments of data keyed by JSON Pointer path.
+func (d *Document) Comments(data []byte) (map[string]string, error) {
+	comments := make(map[string]string)
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return comments, nil
+	}
+
+	v, err := hujson.Parse(trimmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSONC: %w", err)
+	}
+	if text := commentText(v.BeforeExtra); text != "" {
+		comments[""] = text
+	}
+	collectComments(&v, "", comments)
+	return comments, nil
+}
+
+// collectComments adds the comments of the members or elements of v to comments.
+//
+// The extra before a member holds the trailing comment of the previous line
+// (e.g., after the comma) followed by the comments above the member.
+func collectComments(v *hujson.Value, path string, comments map[string]string) {
+	var children []*hujson.Value
+	var paths []string
+	var afterExtra hujson.Extra
+	switch val := v.Value.(type) {
+	case *hujson.Object:
+		for i := range val.Members {
+			m := &val.Members[i]
+			name, _ := m.Name.Value.(hujson.Literal)
+			children = append(children, &m.Name)
+			paths = append(paths, path+"/"+jsonptr.Escape(name.String()))
+		}
+		afterExtra = val.AfterExtra
+	case *hujson.Array:
+		for i := range val.Elements {
+			children = append(children, &val.Elements[i])
+			paths = append(paths, path+"/"+strconv.Itoa(i))
+		}
+		afterExtra = val.AfterExtra
+	default:
+		return
+	}
+
+	// The previous line of the first child is the line of the opening
+	// brace, whose trailing comment belongs to the container itself
+	previous := path
+	for i, child := range children {
+		trailing, head := splitExtra(child.BeforeExtra)
+		if _, ok := comments[previous]; !ok && trailing != "" && previous != "" {
+			comments[previous] = trailing
+		}
+		if head != "" {
+			comments[paths[i]] = head
+		}
+		previous = paths[i]
+	}
+	if trailing, _ := splitExtra(afterExtra); trailing != "" && len(children) > 0 {
+		if _, ok := comments[previous]; !ok {
+			comments[previous] = trailing
+		}
+	}
+
+	// Recurse into the values
+	switch val := v.Value.(type) {
+	case *hujson.Object:
+		for i := range val.Members {
+			collectComments(&val.Members[i].Value, paths[i], comments)
+		}
+	case *hujson.Array:
+		for i := range val.Elements {
+			collectComments(&val.Elements[i], paths[i], comments)
+		}
+	}
+}
+
+// splitExtra splits extra into the comment text before its first newline
+// and the comment text after it.
+func splitExtra(extra hujson.Extra) (trailing, head string) {
+	i := bytes.IndexByte(extra, '\n')
+	if i < 0 {
+		return commentText(extra), ""
+	}
+	return commentText(extra[:i]), commentText(extra[i+1:])
+}
+
+// commentText returns the text of the line and block comments in extra.
+func commentText(extra hujson.Extra) string {
+	var lines []string
+	rest := string(extra)
+	for {
+		rest = strings.TrimLeft(rest, " \t\r\n")
+		switch {
+		case strings.HasPrefix(rest, "//"):
+			line, next, _ := strings.Cut(rest[2:], "\n")
+			lines = append(lines, strings.TrimPrefix(strings.TrimRight(line, " \t\r"), " "))
+			rest = next
+		case strings.HasPrefix(rest, "/*"):
+			block, next, _ := strings.Cut(rest[2:], "*/")
+			for _, line := range strings.Split(strings.TrimSpace(block), "\n") {
+				line = strings.TrimPrefix(strings.TrimSpace(line), "*")
+				lines = append(lines, strings.TrimPrefix(line, " "))
+			}
+			rest = next
+		default:
+			return strings.TrimSpace(strings.Join(lines, "\n"))
+		}
+	}
+}
+
+// MarshalWithComments marshals data as JSONC, writing each comment above
+// the value at its path.
+func (d *Document) MarshalWithComments(data map[string]any, comments map[string]string) ([]byte, error) {
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSONC: %w", err)
+	}
+	v, err := hujson.Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSONC: %w", err)
+	}
+
+	if text := comments[""]; text != "" {
+		v.BeforeExtra = hujson.Extra(commentLines(text, "\n") + "\n")
+	}
+	applyComments(&v, "", comments)
+	return append(v.Pack(), '\n'), nil
+}
+
+// applyComments writes the comments above the members or elements of v.
+func applyComments(v *hujson.Value, path string, comments map[string]string) {
+	switch val := v.Value.(type) {
+	case *hujson.Object:
+		for i := range val.Members {
+			m := &val.Members[i]
+			name, _ := m.Name.Value.(hujson.Literal)
+			childPath := path + "/" + jsonptr.Escape(name.String())
+			insertComment(&m.Name, comments[childPath])
+			applyComments(&m.Value, childPath, comments)
+		}
+	case *hujson.Array:
+		for i := range val.Elements {
+			childPath := path + "/" + strconv.Itoa(i)
+			insertComment(&val.Elements[i], comments[childPath])
+			applyComments(&val.Elements[i], childPath, comments)
+		}
+	}
+}
+
+// insertComment writes text above v, which starts on its own indented line.
+func insertComment(v *hujson.Value, text string) {
+	if text == "" {
+		return
+	}
+	// The extra of an indented value is a newline followed by the indent
+	indent := string(v.BeforeExtra)
+	v.BeforeExtra = hujson.Extra(indent + commentLines(text, indent) + indent)
+}
+
+// commentLines formats text as line comments separated by sep.
+func commentLines(text, sep string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("// "+line, " ")
+	}
+	return strings.Join(lines, sep)
+}
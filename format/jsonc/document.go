@@ -22,6 +22,10 @@ type Document struct{}
 // Ensure Document implements document.Document interface.
 var _ document.Document = (*Document)(nil)
 
+func init() {
+	document.Register(document.FormatJSONC, func() document.Document { return New() }, ".jsonc")
+}
+
 // New returns a JSONC Document.
 //
 // Example:
@@ -69,6 +73,7 @@ func (d *Document) Get(data []byte) (map[string]any, error) {
 // If changeset is provided: parses data, applies changeset operations
 // using hujson's Patch API to preserve comments, then returns the result.
 // If changeset is empty: marshals parsed data directly.
+// document.Tombstone values are written as their sentinel string since JSONC has no tags.
 func (d *Document) Apply(data []byte, changeset document.JSONPatchSet) ([]byte, error) {
 	// If no changeset, parse and re-marshal
 	if changeset.IsEmpty() {
@@ -245,3 +245,21 @@ func TestEnsureIntermediateObjects(t *testing.T) {
 		}
 	})
 }
+
+func TestDocument_Apply_Tombstone(t *testing.T) {
+	input := []byte("{\n  // heading\n  \"x\": true, // inline\n  \"y\": 1\n}\n")
+	doc := New()
+
+	out, err := doc.Apply(input, document.JSONPatchSet{
+		document.NewReplacePatch("/x", document.Tombstone{}),
+	})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	output := string(out)
+	for _, want := range []string{"// heading", "// inline", `"x": "!unset"`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Apply() output missing %q:\n%s", want, output)
+		}
+	}
+}
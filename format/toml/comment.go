@@ -0,0 +1,185 @@
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+	"github.com/yacchi/jubako/document"
+)
+
+// Ensure Document implements document.Commenter interface.
+var _ document.Commenter = (*Document)(nil)
+
+// tomlEntry is a top-level expression of a TOML document.
+type tomlEntry struct {
+	kind unstable.Kind
+	// path is the JSON Pointer of a key-value or table. Array tables are
+	// addressed by element (e.g., "/servers/1").
+	path      string
+	lineStart int
+	lineEnd   int
+	// comment is the text of a comment, or the trailing comment of a key-value.
+	comment string
+}
+
+// entries lists the top-level expressions of src.
+func entries(src []byte) ([]tomlEntry, error) {
+	p := unstable.Parser{KeepComments: true}
+	p.Reset(src)
+
+	var result []tomlEntry
+	var table []string
+	// arrays counts the elements of each array table by resolved path
+	arrays := make(map[string]int)
+	for p.NextExpression() {
+		n := p.Expression()
+		switch n.Kind {
+		case unstable.Comment:
+			off := int(n.Raw.Offset)
+			result = append(result, tomlEntry{
+				kind:      n.Kind,
+				lineStart: findLineStart(src, off),
+				lineEnd:   findLineEnd(src, off),
+				comment:   commentText(n.Data),
+			})
+		case unstable.Table, unstable.ArrayTable:
+			keys, lineStart := tablePathAndLineStart(&p, n)
+			// Tables below an array table belong to its last element
+			table = nil
+			for i, key := range keys {
+				table = append(table, key)
+				if count := arrays[strings.Join(table, "\x00")]; count > 0 && i < len(keys)-1 {
+					table = append(table, strconv.Itoa(count-1))
+				}
+			}
+			if n.Kind == unstable.ArrayTable {
+				key := strings.Join(table, "\x00")
+				table = append(table, strconv.Itoa(arrays[key]))
+				arrays[key]++
+			}
+			result = append(result, tomlEntry{
+				kind:      n.Kind,
+				path:      buildPointer(table),
+				lineStart: lineStart,
+				lineEnd:   findLineEnd(src, lineStart),
+			})
+		case unstable.KeyValue:
+			kv, fullPath := keyValueInfo(&p, n, table, src)
+			entry := tomlEntry{
+				kind:      n.Kind,
+				path:      buildPointer(fullPath),
+				lineStart: kv.lineStart,
+				lineEnd:   kv.lineEnd,
+			}
+			if next := n.Next(); next != nil && next.Kind == unstable.Comment {
+				entry.comment = commentText(next.Data)
+			}
+			result = append(result, entry)
+		}
+	}
+	if err := p.Error(); err != nil {
+		return nil, fmt.Errorf("failed to parse TOML: %w", err)
+	}
+	return result, nil
+}
+
+// Comments returns the comments of data keyed by JSON Pointer path.
+// Comments above the first array table element are keyed by the element
+// (e.g., "/servers/0").
+func (d *Document) Comments(data []byte) (map[string]string, error) {
+	comments := make(map[string]string)
+	list, err := entries(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	pendingEnd := 0
+	first := true
+	for _, e := range list {
+		// A leading comment followed by a blank line is the document header
+		if first && len(pending) > 0 && bytes.Contains(data[pendingEnd:e.lineStart], []byte("\n")) {
+			if _, ok := comments[""]; !ok {
+				comments[""] = strings.TrimSpace(strings.Join(pending, "\n"))
+				pending = nil
+			}
+		}
+		if e.kind == unstable.Comment {
+			pending = append(pending, e.comment)
+			pendingEnd = e.lineEnd
+			continue
+		}
+		if len(pending) > 0 {
+			comments[e.path] = strings.TrimSpace(strings.Join(pending, "\n"))
+			pending = nil
+		}
+		if _, ok := comments[e.path]; !ok && e.comment != "" {
+			comments[e.path] = e.comment
+		}
+		first = false
+	}
+	return comments, nil
+}
+
+// commentText strips the comment marker of a TOML comment.
+func commentText(comment []byte) string {
+	text := strings.TrimPrefix(string(comment), "#")
+	return strings.TrimRight(strings.TrimPrefix(text, " "), " \t\r")
+}
+
+// MarshalWithComments marshals data as TOML, writing each comment above the
+// key-value or table at its path. Comments of array table elements are
+// written above their [[header]], and the comment of the array above the
+// first element if that has none. Comments of values inside inline tables
+// and arrays are dropped.
+//
+// Returns UnsupportedStructureError for null values, which TOML cannot represent.
+func (d *Document) MarshalWithComments(data map[string]any, comments map[string]string) ([]byte, error) {
+	if err := checkNilMap("", data); err != nil {
+		return nil, err
+	}
+	src, err := tomlMarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal TOML: %w", err)
+	}
+	list, err := entries(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if head := comments[""]; head != "" {
+		buf.WriteString(tomlComment(head, ""))
+		buf.WriteString("\n")
+	}
+	last := 0
+	for _, e := range list {
+		text := comments[e.path]
+		if text == "" && e.kind == unstable.ArrayTable && strings.HasSuffix(e.path, "/0") {
+			text = comments[strings.TrimSuffix(e.path, "/0")]
+		}
+		if text == "" {
+			continue
+		}
+		line := src[e.lineStart:e.lineEnd]
+		indent := line[:len(line)-len(bytes.TrimLeft(line, " \t"))]
+		buf.Write(src[last:e.lineStart])
+		buf.WriteString(tomlComment(text, string(indent)))
+		last = e.lineStart
+	}
+	buf.Write(src[last:])
+	return buf.Bytes(), nil
+}
+
+// tomlComment formats text as comment lines with the given indent.
+func tomlComment(text, indent string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString(strings.TrimRight(indent+"# "+line, " "))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package toml
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/yacchi/jubako/document"
+)
+
+func TestDocument_Comments(t *testing.T) {
+	src := []byte(`# Application config
+
+# Enable debug logging.
+debug = false # ignored: head comment wins
+name = "app" # application name
+
+# Server settings
+[server]
+# Port to listen on.
+# Must be free.
+port = 8080
+
+[[backends]]
+host = "a"
+
+# second backend
+[[backends]]
+host = "b"
+
+[backends.tls]
+# verify certificates
+verify = true
+`)
+	got, err := New().Comments(src)
+	if err != nil {
+		t.Fatalf("Comments() error = %v", err)
+	}
+	want := map[string]string{
+		"":                       "Application config",
+		"/debug":                 "Enable debug logging.",
+		"/name":                  "application name",
+		"/server":                "Server settings",
+		"/server/port":           "Port to listen on.\nMust be free.",
+		"/backends/1":            "second backend",
+		"/backends/1/tls/verify": "verify certificates",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Comments() = %q, want %q", got, want)
+	}
+}
+
+func TestDocument_MarshalWithComments(t *testing.T) {
+	d := New()
+	data := map[string]any{
+		"debug":    false,
+		"server":   map[string]any{"port": 8080},
+		"backends": []any{map[string]any{"host": "a"}, map[string]any{"host": "b"}},
+	}
+	comments := map[string]string{
+		"":             "Application config",
+		"/debug":       "Enable debug logging.",
+		"/server/port": "Port to listen on.\nMust be free.",
+		"/backends":    "Backends to proxy to.",
+		"/backends/1":  "second backend",
+		"/missing":     "dropped",
+	}
+	got, err := d.MarshalWithComments(data, comments)
+	if err != nil {
+		t.Fatalf("MarshalWithComments() error = %v", err)
+	}
+	want := `# Application config
+
+# Enable debug logging.
+debug = false
+
+# Backends to proxy to.
+[[backends]]
+host = 'a'
+
+# second backend
+[[backends]]
+host = 'b'
+
+[server]
+# Port to listen on.
+# Must be free.
+port = 8080
+`
+	if string(got) != want {
+		t.Errorf("MarshalWithComments() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDocument_MarshalWithComments_Null(t *testing.T) {
+	_, err := New().MarshalWithComments(map[string]any{"server": map[string]any{"host": nil}}, nil)
+	var unsupported *document.UnsupportedStructureError
+	if !errors.As(err, &unsupported) || unsupported.Path != "/server/host" {
+		t.Errorf("MarshalWithComments() error = %v, want UnsupportedStructureError at /server/host", err)
+	}
+}
@@ -23,6 +23,10 @@ type Document struct{}
 // Ensure Document implements document.Document interface.
 var _ document.Document = (*Document)(nil)
 
+func init() {
+	document.Register(document.FormatTOML, func() document.Document { return New() }, ".toml")
+}
+
 var tomlMarshal = toml.Marshal
 var tomlUnmarshal = toml.Unmarshal
 
@@ -64,6 +68,7 @@ func (d *Document) Get(data []byte) (map[string]any, error) {
 // If changeset is provided: parses data, applies changeset operations
 // using minimal text edits to preserve comments, then returns the result.
 // If changeset is empty: marshals parsed data directly.
+// document.Tombstone values are written as their sentinel string since TOML has no tags.
 func (d *Document) Apply(data []byte, changeset document.JSONPatchSet) ([]byte, error) {
 	// Parse data to check for nil values
 	var current map[string]any
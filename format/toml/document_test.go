@@ -710,3 +710,31 @@ func TestBuildIndex_SectionEndComputation(t *testing.T) {
 		t.Fatalf("section end = %d, want %d", idx.sections[0].lineEnd, idx.sections[1].lineStart)
 	}
 }
+
+func TestDocument_Apply_Tombstone(t *testing.T) {
+	input := []byte("# heading\n[feature]\nx = true # inline\ny = 1\n")
+	doc := New()
+
+	out, err := doc.Apply(input, document.JSONPatchSet{
+		document.NewReplacePatch("/feature/x", document.Tombstone{}),
+		document.NewAddPatch("/feature/z", document.Tombstone{Sentinel: "~unset~"}),
+	})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	output := string(out)
+	for _, want := range []string{"# heading", "# inline", document.DefaultUnsetSentinel, "~unset~"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Apply() output missing %q:\n%s", want, output)
+		}
+	}
+
+	got, err := doc.Get(out)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	feature := got["feature"].(map[string]any)
+	if feature["x"] != document.DefaultUnsetSentinel || feature["z"] != "~unset~" {
+		t.Errorf("Get() after Apply() = %#v, want sentinel strings", feature)
+	}
+}
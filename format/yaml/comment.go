@@ -0,0 +1,120 @@
+package yaml
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/jsonptr"
+	"gopkg.in/yaml.v3"
+)
+
+// Ensure Document implements document.Commenter interface.
+var _ document.Commenter = (*Document)(nil)
+
+// Comments returns the comments of data keyed by JSON Pointer path.
+func (d *Document) Comments(data []byte) (map[string]string, error) {
+	comments := make(map[string]string)
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return comments, nil
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	collectComments(&node, "", comments)
+	return comments, nil
+}
+
+// collectComments adds the comments of node and its descendants to comments.
+func collectComments(node *yaml.Node, path string, comments map[string]string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		addComment(comments, "", node.HeadComment)
+		for _, child := range node.Content {
+			collectComments(child, path, comments)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := path + "/" + jsonptr.Escape(key.Value)
+			addComment(comments, childPath, key.HeadComment, key.LineComment, value.LineComment)
+			collectComments(value, childPath, comments)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			childPath := path + "/" + strconv.Itoa(i)
+			addComment(comments, childPath, child.HeadComment, child.LineComment)
+			collectComments(child, childPath, comments)
+		}
+	}
+}
+
+// addComment records the first non-empty comment for path.
+func addComment(comments map[string]string, path string, candidates ...string) {
+	for _, c := range candidates {
+		if text := commentText(c); text != "" {
+			comments[path] = text
+			return
+		}
+	}
+}
+
+// commentText strips the comment markers of a yaml.v3 comment.
+func commentText(comment string) string {
+	if comment == "" {
+		return ""
+	}
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "#")
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// MarshalWithComments marshals data as YAML, writing each comment above the
+// value at its path.
+func (d *Document) MarshalWithComments(data map[string]any, comments map[string]string) ([]byte, error) {
+	var node yaml.Node
+	if err := node.Encode(encodeTombstones(data)); err != nil {
+		return nil, err
+	}
+	applyComments(&node, "", comments)
+
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&node}}
+	doc.HeadComment = yamlComment(comments[""])
+	return d.marshal(doc)
+}
+
+// applyComments sets the head comments of node and its descendants.
+func applyComments(node *yaml.Node, path string, comments map[string]string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := path + "/" + jsonptr.Escape(key.Value)
+			key.HeadComment = yamlComment(comments[childPath])
+			applyComments(value, childPath, comments)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			childPath := path + "/" + strconv.Itoa(i)
+			child.HeadComment = yamlComment(comments[childPath])
+			applyComments(child, childPath, comments)
+		}
+	}
+}
+
+// yamlComment formats comment text as YAML comment lines.
+func yamlComment(text string) string {
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("# "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
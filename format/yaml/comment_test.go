@@ -0,0 +1,78 @@
+package yaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDocument_Comments(t *testing.T) {
+	src := []byte(`# Application config
+
+# Server settings
+server:
+  # Port to listen on.
+  # Must be free.
+  port: 8080 # ignored: head comment wins
+  host: localhost # bind address
+items:
+  # first item
+  - a
+  - b
+`)
+	got, err := New().Comments(src)
+	if err != nil {
+		t.Fatalf("Comments() error = %v", err)
+	}
+	want := map[string]string{
+		"":             "Application config",
+		"/server":      "Server settings",
+		"/server/port": "Port to listen on.\nMust be free.",
+		"/server/host": "bind address",
+		"/items/0":     "first item",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Comments() = %q, want %q", got, want)
+	}
+}
+
+func TestDocument_MarshalWithComments(t *testing.T) {
+	d := New()
+	data := map[string]any{
+		"server": map[string]any{"port": 8080},
+		"items":  []any{"a", "b"},
+	}
+	comments := map[string]string{
+		"":             "Application config",
+		"/server/port": "Port to listen on.\nMust be free.",
+		"/items/1":     "second item",
+		"/missing":     "dropped",
+	}
+	got, err := d.MarshalWithComments(data, comments)
+	if err != nil {
+		t.Fatalf("MarshalWithComments() error = %v", err)
+	}
+	want := `# Application config
+
+items:
+  - a
+  # second item
+  - b
+server:
+  # Port to listen on.
+  # Must be free.
+  port: 8080
+`
+	if string(got) != want {
+		t.Errorf("MarshalWithComments() =\n%s\nwant\n%s", got, want)
+	}
+
+	// The comments survive a round trip
+	roundTrip, err := d.Comments(got)
+	if err != nil {
+		t.Fatalf("Comments() error = %v", err)
+	}
+	delete(comments, "/missing")
+	if !reflect.DeepEqual(roundTrip, comments) {
+		t.Errorf("Comments() = %q, want %q", roundTrip, comments)
+	}
+}
@@ -19,6 +19,10 @@ type Document struct{}
 // Ensure Document implements document.Document interface.
 var _ document.Document = (*Document)(nil)
 
+func init() {
+	document.Register(document.FormatYAML, func() document.Document { return New() }, ".yaml", ".yml")
+}
+
 // New returns a YAML Document.
 //
 // Example:
@@ -41,8 +45,16 @@ func (d *Document) Get(data []byte) (map[string]any, error) {
 		return map[string]any{}, nil
 	}
 
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	// Tombstones (!unset) are decoded as null and restored afterwards
+	tombstones := collectTombstones(&node, "", nil)
+
 	var result map[string]any
-	if err := yaml.Unmarshal(data, &result); err != nil {
+	if err := node.Decode(&result); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
@@ -50,9 +62,40 @@ func (d *Document) Get(data []byte) (map[string]any, error) {
 		return map[string]any{}, nil
 	}
 
+	for _, path := range tombstones {
+		jsonptr.SetPath(result, path, document.Tombstone{})
+	}
+
 	return result, nil
 }
 
+// collectTombstones returns the JSON Pointer paths of all scalar nodes tagged
+// with document.UnsetTag, retagging them as null so they decode cleanly.
+func collectTombstones(node *yaml.Node, path string, paths []string) []string {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			paths = collectTombstones(child, path, paths)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childPath := path + "/" + jsonptr.Escape(node.Content[i].Value)
+			paths = collectTombstones(node.Content[i+1], childPath, paths)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			paths = collectTombstones(child, path+"/"+strconv.Itoa(i), paths)
+		}
+	case yaml.ScalarNode:
+		if node.Tag == document.UnsetTag {
+			node.Tag = "!!null"
+			node.Value = ""
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
 // Apply applies changeset to data bytes and returns new bytes.
 // If changeset is provided: parses data, applies changeset operations
 // to preserve comments, then marshals the result.
@@ -60,16 +103,11 @@ func (d *Document) Get(data []byte) (map[string]any, error) {
 func (d *Document) Apply(data []byte, changeset document.JSONPatchSet) ([]byte, error) {
 	// If no changeset, parse and re-marshal (for format consistency)
 	if changeset.IsEmpty() {
-		var m map[string]any
-		if len(bytes.TrimSpace(data)) > 0 {
-			if err := yaml.Unmarshal(data, &m); err != nil {
-				return nil, fmt.Errorf("failed to parse YAML: %w", err)
-			}
-		}
-		if m == nil {
-			m = map[string]any{}
+		m, err := d.Get(data)
+		if err != nil {
+			return nil, err
 		}
-		return d.marshal(m)
+		return d.marshal(encodeTombstones(m))
 	}
 
 	// Parse existing data to preserve comments
@@ -126,7 +164,54 @@ func (d *Document) Apply(data []byte, changeset document.JSONPatchSet) ([]byte,
 // YAML supports all common data structures, so this never returns
 // UnsupportedStructureError.
 func (d *Document) MarshalTestData(data map[string]any) ([]byte, error) {
-	return d.marshal(data)
+	return d.marshal(encodeTombstones(data))
+}
+
+// encodeTombstones returns v with every document.Tombstone replaced by a node
+// tagged with document.UnsetTag. Containers are copied only when they hold a tombstone.
+func encodeTombstones(v any) any {
+	if !containsTombstone(v) {
+		return v
+	}
+	switch val := v.(type) {
+	case document.Tombstone:
+		return valueToNode(val)
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, elem := range val {
+			out[k] = encodeTombstones(elem)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, elem := range val {
+			out[i] = encodeTombstones(elem)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// containsTombstone reports whether v is or contains a document.Tombstone.
+func containsTombstone(v any) bool {
+	switch val := v.(type) {
+	case document.Tombstone:
+		return true
+	case map[string]any:
+		for _, elem := range val {
+			if containsTombstone(elem) {
+				return true
+			}
+		}
+	case []any:
+		for _, elem := range val {
+			if containsTombstone(elem) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // marshal encodes data to YAML with standard indentation (2 spaces).
@@ -381,6 +466,12 @@ func updateNodeValue(node *yaml.Node, value any) {
 		node.Value = ""
 		node.Content = nil
 
+	case document.Tombstone:
+		node.Kind = yaml.ScalarNode
+		node.Tag = document.UnsetTag
+		node.Value = ""
+		node.Content = nil
+
 	case []any:
 		node.Kind = yaml.SequenceNode
 		node.Tag = ""
@@ -650,3 +650,67 @@ func TestApply_NoChangeset_InvalidYAML(t *testing.T) {
 		t.Fatal("Apply(invalid,nil) expected error, got nil")
 	}
 }
+
+func TestDocument_Tombstone(t *testing.T) {
+	doc := New()
+
+	t.Run("get decodes unset tag", func(t *testing.T) {
+		got, err := doc.Get([]byte("feature:\n  x: !unset\n  y: 1\nlist:\n  - a\n  - !unset\n"))
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		want := map[string]any{
+			"feature": map[string]any{"x": document.Tombstone{}, "y": 1},
+			"list":    []any{"a", document.Tombstone{}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Get() = %#v, want %#v", got, want)
+		}
+	})
+
+	t.Run("apply writes unset tag and preserves comments", func(t *testing.T) {
+		input := []byte("# Feature flags\nfeature:\n  x: true # enabled\n  y: 1\n")
+		out, err := doc.Apply(input, document.JSONPatchSet{
+			document.NewReplacePatch("/feature/x", document.Tombstone{}),
+			document.NewAddPatch("/feature/z", document.Tombstone{}),
+		})
+		if err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		output := string(out)
+		for _, want := range []string{"# Feature flags", "x: !unset # enabled", "z: !unset"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("Apply() output missing %q:\n%s", want, output)
+			}
+		}
+
+		got, err := doc.Get(out)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		feature := got["feature"].(map[string]any)
+		if !document.IsTombstone(feature["x"]) || !document.IsTombstone(feature["z"]) {
+			t.Errorf("Get() after Apply() = %#v, want tombstones at x and z", feature)
+		}
+	})
+
+	t.Run("apply without changeset keeps tombstones", func(t *testing.T) {
+		out, err := doc.Apply([]byte("x: !unset\n"), nil)
+		if err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if strings.TrimSpace(string(out)) != "x: !unset" {
+			t.Errorf("Apply() = %q, want %q", out, "x: !unset\n")
+		}
+	})
+
+	t.Run("marshal test data", func(t *testing.T) {
+		out, err := doc.MarshalTestData(map[string]any{"x": document.Tombstone{}})
+		if err != nil {
+			t.Fatalf("MarshalTestData() error = %v", err)
+		}
+		if strings.TrimSpace(string(out)) != "x: !unset" {
+			t.Errorf("MarshalTestData() = %q, want %q", out, "x: !unset\n")
+		}
+	})
+}
@@ -8,11 +8,24 @@ tool github.com/yacchi/jubako/cmd/jubako
 
 require (
 	github.com/fsnotify/fsnotify v1.9.0
+	github.com/yacchi/jubako/format/jsonc v0.6.0
+	github.com/yacchi/jubako/format/toml v0.6.0
+	github.com/yacchi/jubako/format/yaml v0.6.0
 	golang.org/x/tools v0.28.0
 )
 
 require (
+	github.com/pelletier/go-toml/v2 v2.2.4 // indirect
+	github.com/tailscale/hujson v0.0.0-20250605163823-992244df8c5a // indirect
 	golang.org/x/mod v0.22.0 // indirect
 	golang.org/x/sync v0.10.0 // indirect
 	golang.org/x/sys v0.39.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The jubako command links the format modules of this repository.
+replace (
+	github.com/yacchi/jubako/format/jsonc => ./format/jsonc
+	github.com/yacchi/jubako/format/toml => ./format/toml
+	github.com/yacchi/jubako/format/yaml => ./format/yaml
 )
@@ -0,0 +1,128 @@
+// Package envdocs provides the "generate env-docs" subcommand.
+package envdocs
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/yacchi/jubako/internal/cmd/generate/storeprog"
+)
+
+// Options holds the command-line options for the env-docs generator.
+type Options struct {
+	TypeName string
+	Prefix   string
+	Format   string
+	Output   string
+}
+
+// Run executes the env-docs generation command.
+func Run(args []string) error {
+	fs := flag.NewFlagSet("generate env-docs", flag.ExitOnError)
+
+	var opts Options
+	fs.StringVar(&opts.TypeName, "type", "", "target struct type name (required)")
+	fs.StringVar(&opts.Prefix, "prefix", "", "environment variable prefix of the env layer (e.g., APP_)")
+	fs.StringVar(&opts.Format, "format", "markdown", "output format: markdown or json")
+	fs.StringVar(&opts.Output, "output", "", "output file path (default: stdout)")
+
+	fs.Usage = func() {
+		printHelp()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if opts.TypeName == "" {
+		printHelp()
+		return fmt.Errorf("-type flag is required")
+	}
+
+	remaining := fs.Args()
+	if len(remaining) != 1 {
+		printHelp()
+		return fmt.Errorf("exactly one source file is required")
+	}
+
+	return runGenerate(remaining[0], opts)
+}
+
+// programConfig returns the program that renders the reference in the
+// requested format.
+func programConfig(sourceFile string, opts Options) (storeprog.Config, error) {
+	cfg := storeprog.Config{
+		SourceFile: sourceFile,
+		TypeName:   opts.TypeName,
+		Imports:    []string{"github.com/yacchi/jubako/layer/env"},
+	}
+	ref := fmt.Sprintf("env.BuildSchemaMapping[Target]().Reference(%s)", strconv.Quote(opts.Prefix))
+	switch opts.Format {
+	case "markdown":
+		cfg.Expr = ref + ".MarshalMarkdown()"
+	case "json":
+		cfg.Expr = fmt.Sprintf(`json.MarshalIndent(%s, "", "  ")`, ref)
+		cfg.Imports = append(cfg.Imports, "encoding/json")
+	default:
+		return cfg, fmt.Errorf("unsupported format %q: use markdown or json", opts.Format)
+	}
+	return cfg, nil
+}
+
+func runGenerate(sourceFile string, opts Options) error {
+	cfg, err := programConfig(sourceFile, opts)
+	if err != nil {
+		return err
+	}
+
+	// The reference is built by env.BuildSchemaMapping in a program importing
+	// the package, so that it matches the mappings used by the env layer.
+	data, err := storeprog.Run(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to generate env docs: %w", err)
+	}
+	if opts.Format == "json" {
+		data = append(data, '\n')
+	}
+
+	if opts.Output == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(opts.Output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "generated %s\n", opts.Output)
+
+	return nil
+}
+
+func printHelp() {
+	fmt.Fprintln(os.Stderr, `jubako generate env-docs - Generate environment variable reference docs
+
+Usage:
+  go tool jubako generate env-docs [options] <source-file>
+
+The reference lists every env: directive of the type, as mapped by
+env.NewWithAutoSchema and env.WithSchemaMapping: the variable name with the
+prefix, its JSON path, Go type, sensitivity, and the meaning of {key} and
+{index} placeholders. The type must be declared in a package of the current
+module. For a main package, the init functions of the package run during
+generation.
+
+Options:
+  -type string      Target struct type name (required)
+  -prefix string    Environment variable prefix of the env layer (e.g., APP_)
+  -format string    Output format: markdown or json (default "markdown")
+  -output string    Output file path (default: stdout)
+
+Examples:
+  go tool jubako generate env-docs -type AppConfig -prefix APP_ config.go
+  go tool jubako generate env-docs -type AppConfig -prefix APP_ -format json -output env.json config.go
+
+For use with go:generate:
+  //go:generate go tool jubako generate env-docs -type AppConfig -prefix APP_ -output ENVIRONMENT.md config.go
+  //go:generate go tool jubako generate env-docs -type AppConfig -prefix APP_ -format json -output environment.json config.go`)
+}
@@ -0,0 +1,34 @@
+package envdocs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProgramConfig(t *testing.T) {
+	cfg, err := programConfig("config.go", Options{TypeName: "AppConfig", Prefix: "APP_", Format: "markdown"})
+	if err != nil {
+		t.Fatalf("programConfig() error = %v", err)
+	}
+	if want := `env.BuildSchemaMapping[Target]().Reference("APP_").MarshalMarkdown()`; cfg.Expr != want {
+		t.Errorf("Expr = %s, want %s", cfg.Expr, want)
+	}
+	if want := []string{"github.com/yacchi/jubako/layer/env"}; !reflect.DeepEqual(cfg.Imports, want) {
+		t.Errorf("Imports = %v, want %v", cfg.Imports, want)
+	}
+
+	cfg, err = programConfig("config.go", Options{TypeName: "AppConfig", Format: "json"})
+	if err != nil {
+		t.Fatalf("programConfig() error = %v", err)
+	}
+	if want := `json.MarshalIndent(env.BuildSchemaMapping[Target]().Reference(""), "", "  ")`; cfg.Expr != want {
+		t.Errorf("Expr = %s, want %s", cfg.Expr, want)
+	}
+	if want := []string{"github.com/yacchi/jubako/layer/env", "encoding/json"}; !reflect.DeepEqual(cfg.Imports, want) {
+		t.Errorf("Imports = %v, want %v", cfg.Imports, want)
+	}
+
+	if _, err := programConfig("config.go", Options{TypeName: "AppConfig", Format: "html"}); err == nil {
+		t.Error("programConfig() should reject unknown formats")
+	}
+}
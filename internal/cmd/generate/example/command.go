@@ -0,0 +1,175 @@
+// Package example provides the "generate example" subcommand.
+package example
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yacchi/jubako/internal/cmd/generate/paths"
+	"github.com/yacchi/jubako/internal/cmd/generate/storeprog"
+)
+
+// Options holds the command-line options for the example generator.
+type Options struct {
+	TypeName string
+	TagName  string
+	Format   string
+	Output   string
+}
+
+// formats lists the supported output formats.
+var formats = []string{"yaml", "toml", "jsonc", "json"}
+
+// Run executes the example generation command.
+func Run(args []string) error {
+	fs := flag.NewFlagSet("generate example", flag.ExitOnError)
+
+	var opts Options
+	fs.StringVar(&opts.TypeName, "type", "", "target struct type name (required)")
+	fs.StringVar(&opts.TagName, "tag", "json", "tag name for field resolution")
+	fs.StringVar(&opts.Format, "format", "yaml", "output format: yaml, toml, jsonc or json")
+	fs.StringVar(&opts.Output, "output", "", "output file path (default: stdout)")
+
+	fs.Usage = func() {
+		printHelp()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if opts.TypeName == "" {
+		printHelp()
+		return fmt.Errorf("-type flag is required")
+	}
+
+	remaining := fs.Args()
+	if len(remaining) != 1 {
+		printHelp()
+		return fmt.Errorf("exactly one source file is required")
+	}
+
+	return runGenerate(remaining[0], opts)
+}
+
+func runGenerate(sourceFile string, opts Options) error {
+	// Doc comments are only available from the source, while defaults,
+	// sensitivity, converters and unions follow the Store at runtime. The
+	// fields come from the analysis of "generate paths", and the program
+	// checks them against the Store's schema so that the two cannot drift.
+	fields, docs, err := paths.Fields(sourceFile, opts.TypeName, opts.TagName)
+	if err != nil {
+		return fmt.Errorf("failed to analyze fields: %w", err)
+	}
+	cfg, err := programConfig(sourceFile, opts, fields, docs)
+	if err != nil {
+		return err
+	}
+
+	data, err := storeprog.Run(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to generate example: %w", err)
+	}
+
+	if opts.Output == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(opts.Output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "generated %s\n", opts.Output)
+
+	return nil
+}
+
+// exampleExpr renders the example with the comments, after checking that
+// every field found by the source analysis is in the Store's schema.
+const exampleExpr = `func() ([]byte, error) {
+	schema := make(map[string]bool)
+	for _, d := range store.SchemaView().Descriptors() {
+		schema[d.Path()] = true
+	}
+	for _, path := range %s {
+		if !schema[path] {
+			return nil, fmt.Errorf("field %%s found by the source analysis is not in the schema of the Store", path)
+		}
+	}
+	return store.RenderExample(%s, jubako.WithExampleComments(%s))
+}()`
+
+// programConfig returns the program that renders the example with the doc
+// comments of the fields.
+func programConfig(sourceFile string, opts Options, fields []string, docs map[string]string) (storeprog.Config, error) {
+	supported := false
+	for _, format := range formats {
+		supported = supported || format == opts.Format
+	}
+	if !supported {
+		return storeprog.Config{}, fmt.Errorf("unsupported format %q: use %s", opts.Format, strings.Join(formats, ", "))
+	}
+
+	var paths strings.Builder
+	paths.WriteString("[]string{")
+	for i, field := range fields {
+		if i > 0 {
+			paths.WriteString(", ")
+		}
+		paths.WriteString(strconv.Quote(field))
+	}
+	paths.WriteString("}")
+
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var comments strings.Builder
+	comments.WriteString("map[string]string{")
+	for _, key := range keys {
+		fmt.Fprintf(&comments, "\n%s: %s,", strconv.Quote(key), strconv.Quote(docs[key]))
+	}
+	if len(keys) > 0 {
+		comments.WriteString("\n")
+	}
+	comments.WriteString("}")
+
+	return storeprog.Config{
+		SourceFile: sourceFile,
+		TypeName:   opts.TypeName,
+		TagName:    opts.TagName,
+		Expr:       fmt.Sprintf(exampleExpr, paths.String(), strconv.Quote(opts.Format), comments.String()),
+	}, nil
+}
+
+func printHelp() {
+	fmt.Fprintln(os.Stderr, `jubako generate example - Generate a commented example configuration file
+
+Usage:
+  go tool jubako generate example [options] <source-file>
+
+The example lists every field of the type at the path the Store reads it
+from, with the value of its default= directive or its zero value. Go doc
+comments of the fields are written as comments (except in JSON), sensitive
+fields hold a placeholder, and maps and slices of structs hold one sample
+element. The type must be declared in a package of the current module. For
+a main package, the init functions of the package run during generation.
+
+Options:
+  -type string      Target struct type name (required)
+  -tag string       Tag name for field resolution (default "json")
+  -format string    Output format: yaml, toml, jsonc or json (default "yaml")
+  -output string    Output file path (default: stdout)
+
+Examples:
+  go tool jubako generate example -type AppConfig -tag yaml config.go
+  go tool jubako generate example -type AppConfig -format toml -output config.example.toml config.go
+
+For use with go:generate:
+  //go:generate go tool jubako generate example -type AppConfig -tag yaml -output config.example.yaml config.go`)
+}
@@ -0,0 +1,42 @@
+package example
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProgramConfig(t *testing.T) {
+	cfg, err := programConfig("config.go", Options{TypeName: "AppConfig", TagName: "yaml", Format: "toml"}, []string{"/server", "/server/port"}, map[string]string{
+		"/server/port": "Port is the TCP port.\nIt must be free.",
+		"/server":      "Server settings.",
+	})
+	if err != nil {
+		t.Fatalf("programConfig() error = %v", err)
+	}
+	for _, want := range []string{
+		`for _, path := range []string{"/server", "/server/port"} {`,
+		`store.RenderExample("toml", jubako.WithExampleComments(map[string]string{
+"/server": "Server settings.",
+"/server/port": "Port is the TCP port.\nIt must be free.",
+}))`,
+	} {
+		if !strings.Contains(cfg.Expr, want) {
+			t.Errorf("Expr =\n%s\nwant to contain\n%s", cfg.Expr, want)
+		}
+	}
+	if cfg.TypeName != "AppConfig" || cfg.TagName != "yaml" {
+		t.Errorf("programConfig() = %+v", cfg)
+	}
+
+	cfg, err = programConfig("config.go", Options{TypeName: "AppConfig", Format: "yaml"}, nil, nil)
+	if err != nil {
+		t.Fatalf("programConfig() error = %v", err)
+	}
+	if want := `store.RenderExample("yaml", jubako.WithExampleComments(map[string]string{}))`; !strings.Contains(cfg.Expr, want) {
+		t.Errorf("Expr = %s, want to contain %s", cfg.Expr, want)
+	}
+
+	if _, err := programConfig("config.go", Options{TypeName: "AppConfig", Format: "ini"}, nil, nil); err == nil {
+		t.Error("programConfig() should reject unknown formats")
+	}
+}
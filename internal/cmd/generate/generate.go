@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/yacchi/jubako/internal/cmd/generate/envdocs"
+	"github.com/yacchi/jubako/internal/cmd/generate/example"
 	"github.com/yacchi/jubako/internal/cmd/generate/paths"
+	"github.com/yacchi/jubako/internal/cmd/generate/schema"
 )
 
 // Run executes the generate subcommand.
@@ -21,6 +24,12 @@ func Run(args []string) error {
 	switch subcmd {
 	case "paths":
 		return paths.Run(subargs)
+	case "schema":
+		return schema.Run(subargs)
+	case "env-docs":
+		return envdocs.Run(subargs)
+	case "example":
+		return example.Run(subargs)
 	case "help", "-h", "--help":
 		PrintHelp()
 		return nil
@@ -39,6 +48,9 @@ Usage:
 
 Subcommands:
   paths       Generate JSONPointer path constants and functions from struct types
+  schema      Generate a JSON Schema for configuration files from struct types
+  env-docs    Generate environment variable reference docs from env: directives
+  example     Generate a commented example configuration file from struct types
 
 Use "go tool jubako generate <subcommand> -h" for more information.`)
 }
@@ -2,6 +2,7 @@ package paths
 
 import (
 	"fmt"
+	"go/token"
 	"go/types"
 	"reflect"
 	"regexp"
@@ -25,6 +26,21 @@ type PathInfo struct {
 	FieldName string
 	// Comment is a description for documentation
 	Comment string
+	// Type is the Go type of the field
+	Type types.Type
+	// Access is the Go expression reading the field from the config struct
+	Access []accessStep
+}
+
+// accessStep is one step from the config struct to a field: a field
+// selector, or a map key or slice index given by a dynamic parameter.
+type accessStep struct {
+	Field string // field name for selectors
+	Param string // parameter name for map keys and slice indexes
+	Index bool   // Param indexes a slice or array
+	// Pointer is true if the step yields a pointer, which must be checked
+	// for nil before the next step.
+	Pointer bool
 }
 
 // ParamInfo describes a dynamic parameter.
@@ -36,22 +52,28 @@ type ParamInfo struct {
 // AnalysisResult holds all discovered paths.
 type AnalysisResult struct {
 	Paths []PathInfo
+	// Docs maps the path of each documented field (including struct fields)
+	// to its doc comment, e.g., "/hosts/{key}/url".
+	Docs map[string]string
 }
 
 // analysisContext tracks state during recursive analysis.
 type analysisContext struct {
-	currentPath    string
-	dynamicParams  []ParamInfo
-	tagName        string
-	mapKeyCount    int
-	sliceIdxCount  int
+	currentPath   string
+	dynamicParams []ParamInfo
+	tagName       string
+	mapKeyCount   int
+	sliceIdxCount int
+	docs          map[token.Pos]string
+	access        []accessStep
 }
 
-func newAnalysisContext(tagName string) *analysisContext {
+func newAnalysisContext(tagName string, docs map[token.Pos]string) *analysisContext {
 	return &analysisContext{
 		currentPath:   "",
 		dynamicParams: nil,
 		tagName:       tagName,
+		docs:          docs,
 	}
 }
 
@@ -62,6 +84,8 @@ func (ctx *analysisContext) withPath(segment string) *analysisContext {
 		tagName:       ctx.tagName,
 		mapKeyCount:   ctx.mapKeyCount,
 		sliceIdxCount: ctx.sliceIdxCount,
+		docs:          ctx.docs,
+		access:        append([]accessStep{}, ctx.access...),
 	}
 	return newCtx
 }
@@ -77,6 +101,8 @@ func (ctx *analysisContext) withMapKey() *analysisContext {
 		tagName:       ctx.tagName,
 		mapKeyCount:   ctx.mapKeyCount + 1,
 		sliceIdxCount: ctx.sliceIdxCount,
+		docs:          ctx.docs,
+		access:        append(append([]accessStep{}, ctx.access...), accessStep{Param: paramName}),
 	}
 	return newCtx
 }
@@ -92,6 +118,8 @@ func (ctx *analysisContext) withSliceIndex() *analysisContext {
 		tagName:       ctx.tagName,
 		mapKeyCount:   ctx.mapKeyCount,
 		sliceIdxCount: ctx.sliceIdxCount + 1,
+		docs:          ctx.docs,
+		access:        append(append([]accessStep{}, ctx.access...), accessStep{Param: paramName, Index: true}),
 	}
 	return newCtx
 }
@@ -101,12 +129,14 @@ func (ctx *analysisContext) isDynamic() bool {
 }
 
 // analyzeStruct analyzes a struct type and returns all path information.
-func analyzeStruct(structType *types.Struct, tagName string) (*AnalysisResult, error) {
+// docs holds the field doc comments by position (see ParsedPackage.Docs), or is nil.
+func analyzeStruct(structType *types.Struct, tagName string, docs map[token.Pos]string) (*AnalysisResult, error) {
 	result := &AnalysisResult{
 		Paths: make([]PathInfo, 0),
+		Docs:  make(map[string]string),
 	}
 
-	ctx := newAnalysisContext(tagName)
+	ctx := newAnalysisContext(tagName, docs)
 	analyzeStructRecursive(structType, ctx, result)
 
 	return result, nil
@@ -141,6 +171,8 @@ func analyzeStructRecursive(structType *types.Struct, ctx *analysisContext, resu
 				currentPath:   jubakoPath,
 				dynamicParams: nil, // Absolute paths reset dynamic params
 				tagName:       ctx.tagName,
+				docs:          ctx.docs,
+				access:        append([]accessStep{}, ctx.access...),
 			}
 		} else if jubakoPath != "" {
 			// Relative jubako path
@@ -153,6 +185,13 @@ func analyzeStructRecursive(structType *types.Struct, ctx *analysisContext, resu
 			effectiveCtx = ctx.withPath(fieldKey)
 		}
 
+		if doc := ctx.docs[field.Pos()]; doc != "" {
+			result.Docs[effectivePath] = doc
+		}
+
+		_, isPointer := field.Type().(*types.Pointer)
+		effectiveCtx.access = append(effectiveCtx.access, accessStep{Field: field.Name(), Pointer: isPointer})
+
 		// Analyze field type
 		fieldType := field.Type()
 		analyzeFieldType(fieldType, field.Name(), effectivePath, effectiveCtx, result)
@@ -160,6 +199,8 @@ func analyzeStructRecursive(structType *types.Struct, ctx *analysisContext, resu
 }
 
 func analyzeFieldType(fieldType types.Type, fieldName, path string, ctx *analysisContext, result *AnalysisResult) {
+	goType := fieldType
+
 	// Handle pointer types
 	if ptr, ok := fieldType.(*types.Pointer); ok {
 		fieldType = ptr.Elem()
@@ -169,7 +210,7 @@ func analyzeFieldType(fieldType types.Type, fieldName, path string, ctx *analysi
 	if named, ok := fieldType.(*types.Named); ok {
 		// External package types (like time.Time) should be treated as leaf values
 		if isExternalType(named) {
-			addPathInfo(path, fieldName, ctx, result)
+			addPathInfo(path, fieldName, goType, ctx, result)
 			return
 		}
 		fieldType = named.Underlying()
@@ -182,7 +223,7 @@ func analyzeFieldType(fieldType types.Type, fieldName, path string, ctx *analysi
 
 	case *types.Slice, *types.Array:
 		// Always add path to the container itself
-		addPathInfo(path, fieldName, ctx, result)
+		addPathInfo(path, fieldName, goType, ctx, result)
 
 		var elemType types.Type
 		if slice, ok := t.(*types.Slice); ok {
@@ -192,7 +233,8 @@ func analyzeFieldType(fieldType types.Type, fieldName, path string, ctx *analysi
 		}
 
 		// Handle pointer element
-		if ptr, ok := elemType.(*types.Pointer); ok {
+		ptr, elemIsPointer := elemType.(*types.Pointer)
+		if elemIsPointer {
 			elemType = ptr.Elem()
 		}
 
@@ -208,17 +250,19 @@ func analyzeFieldType(fieldType types.Type, fieldName, path string, ctx *analysi
 		if structElem, ok := elemType.(*types.Struct); ok {
 			// Slice of structs - add dynamic index and recurse
 			sliceCtx := ctx.withSliceIndex()
+			sliceCtx.access[len(sliceCtx.access)-1].Pointer = elemIsPointer
 			analyzeStructRecursive(structElem, sliceCtx, result)
 		}
 
 	case *types.Map:
 		// Always add path to the container itself
-		addPathInfo(path, fieldName, ctx, result)
+		addPathInfo(path, fieldName, goType, ctx, result)
 
 		valueType := t.Elem()
 
 		// Handle pointer value
-		if ptr, ok := valueType.(*types.Pointer); ok {
+		ptr, valueIsPointer := valueType.(*types.Pointer)
+		if valueIsPointer {
 			valueType = ptr.Elem()
 		}
 
@@ -234,20 +278,23 @@ func analyzeFieldType(fieldType types.Type, fieldName, path string, ctx *analysi
 		if structValue, ok := valueType.(*types.Struct); ok {
 			// Map with struct values - add dynamic key and recurse
 			mapCtx := ctx.withMapKey()
+			mapCtx.access[len(mapCtx.access)-1].Pointer = valueIsPointer
 			analyzeStructRecursive(structValue, mapCtx, result)
 		}
 
 	default:
 		// Leaf value (primitives, strings, etc.)
-		addPathInfo(path, fieldName, ctx, result)
+		addPathInfo(path, fieldName, goType, ctx, result)
 	}
 }
 
-func addPathInfo(path, fieldName string, ctx *analysisContext, result *AnalysisResult) {
+func addPathInfo(path, fieldName string, goType types.Type, ctx *analysisContext, result *AnalysisResult) {
 	info := PathInfo{
 		JSONPointer:   path,
 		FieldName:     fieldName,
 		DynamicParams: append([]ParamInfo{}, ctx.dynamicParams...),
+		Type:          goType,
+		Access:        append([]accessStep{}, ctx.access...),
 	}
 
 	if ctx.isDynamic() {
@@ -335,6 +382,14 @@ func parseJubakoTagPath(tag string) (path string, isAbsolute bool) {
 	case "sensitive", "!sensitive", "-":
 		return "", false
 	}
+	if pathPart == "required" {
+		return "", false
+	}
+	for _, prefix := range []string{"env:", "merge:", "min=", "max=", "enum=", "pattern=", "default="} {
+		if strings.HasPrefix(pathPart, prefix) {
+			return "", false
+		}
+	}
 
 	// Check for explicit relative prefix
 	if strings.HasPrefix(pathPart, "./") {
@@ -407,4 +462,3 @@ func toCamelCase(s string) string {
 
 	return result.String()
 }
-
@@ -15,6 +15,7 @@ type Options struct {
 	TagName     string
 	Output      string
 	PackageName string
+	Accessors   bool
 }
 
 // Run executes the paths generation command.
@@ -26,6 +27,7 @@ func Run(args []string) error {
 	fs.StringVar(&opts.TagName, "tag", "json", "tag name for field resolution")
 	fs.StringVar(&opts.Output, "output", "", "output file path (default: stdout)")
 	fs.StringVar(&opts.PackageName, "package", "", "output package name (default: same as input)")
+	fs.BoolVar(&opts.Accessors, "accessors", false, "also generate typed Get and Set functions for each path")
 
 	fs.Usage = func() {
 		printHelp()
@@ -63,6 +65,9 @@ func runGenerate(sourceFile string, opts Options) error {
 	if pkgName == "" {
 		pkgName = pkg.Name
 	}
+	if opts.Accessors && pkgName != pkg.Name {
+		return fmt.Errorf("-accessors requires the package of %s (%s)", opts.TypeName, pkg.Name)
+	}
 
 	// Determine output file name
 	outputFile := opts.Output
@@ -71,7 +76,7 @@ func runGenerate(sourceFile string, opts Options) error {
 	}
 
 	// Analyze the struct
-	analysis, err := analyzeStruct(structType, opts.TagName)
+	analysis, err := analyzeStruct(structType, opts.TagName, pkg.Docs)
 	if err != nil {
 		return fmt.Errorf("failed to analyze struct: %w", err)
 	}
@@ -83,6 +88,8 @@ func runGenerate(sourceFile string, opts Options) error {
 		SourceFile:  sourceFile,
 		TagName:     opts.TagName,
 		Output:      outputFile,
+		Accessors:   opts.Accessors,
+		PkgPath:     pkg.Path,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to generate code: %w", err)
@@ -118,10 +125,19 @@ Options:
   -tag string       Tag name for field resolution (default "json")
   -output string    Output file path (default: <source>_paths.go)
   -package string   Output package name (default: same as input)
+  -accessors        Also generate typed Get and Set functions for each path
 
 Examples:
   go tool jubako generate paths -type AppConfig config.go
   go tool jubako generate paths -type AppConfig -tag yaml config.go
+  go tool jubako generate paths -type AppConfig -accessors config.go
+
+With -accessors, each path also gets functions checked by the compiler for
+both the path and the value type:
+
+  func GetServerPort(s *jubako.Store[AppConfig]) (int, jubako.ResolvedValue)
+  func SetServerPort(s *jubako.Store[AppConfig], layerName layer.Name, value int) error
+  func GetHostsPort(s *jubako.Store[AppConfig], key string) (int, jubako.ResolvedValue)
 
 For use with go:generate:
   //go:generate go tool jubako generate paths -type AppConfig`)
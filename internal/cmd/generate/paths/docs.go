@@ -0,0 +1,67 @@
+package paths
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Fields returns the schema paths of the fields of typeName, declared in the
+// package of sourceFile, as found by the analysis behind "generate paths",
+// and the doc comments of the fields. Paths are in the form of
+// jubako.PathMapping.Path, with "*" for map keys and slice indices, e.g.,
+// "/hosts/*/url". The paths include the documented struct fields; the doc
+// comments are keyed by the same paths.
+func Fields(sourceFile, typeName, tagName string) ([]string, map[string]string, error) {
+	pkg, structType, err := parseSourceFile(sourceFile, typeName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse source file: %w", err)
+	}
+	analysis, err := analyzeStruct(structType, tagName, pkg.Docs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to analyze struct: %w", err)
+	}
+	paths, docs := analysis.schemaFields()
+	return paths, docs, nil
+}
+
+// schemaFields returns the paths of the analysis and of its doc comments,
+// sorted, and the doc comments, keyed by schema path.
+func (r *AnalysisResult) schemaFields() ([]string, map[string]string) {
+	docs := make(map[string]string, len(r.Docs))
+	for path, doc := range r.Docs {
+		docs[schemaPath(path)] = doc
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	for _, p := range r.Paths {
+		add(schemaPath(p.JSONPointer))
+	}
+	for path := range docs {
+		add(path)
+	}
+	sort.Strings(paths)
+	return paths, docs
+}
+
+// schemaPath replaces the dynamic segments of path, such as {key} or
+// {index2}, with the "*" wildcard.
+func schemaPath(path string) string {
+	segments := splitPath(path)
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			segments[i] = "*"
+		}
+	}
+	if len(segments) == 0 {
+		return path
+	}
+	return "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,95 @@
+package paths
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+const docsTestSource = `package config
+
+type Config struct {
+	// Server holds the listener settings.
+	Server struct {
+		// Port is the TCP port.
+		// It must be free.
+		Port int ` + "`json:\"port\"`" + `
+		Host string ` + "`json:\"host\"`" + ` // Host is the bind address.
+	} ` + "`json:\"server\"`" + `
+
+	// Hosts are the upstream hosts.
+	Hosts map[string]struct {
+		// URL is the upstream URL.
+		URL string ` + "`json:\"url\"`" + `
+	} ` + "`json:\"hosts\"`" + `
+
+	// Password is remapped.
+	Password string ` + "`json:\"password\" jubako:\"/auth/password\"`" + `
+
+	Undocumented string ` + "`json:\"undocumented\"`" + `
+}
+`
+
+func TestAnalyzeStruct_Docs(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "config.go", docsTestSource, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	pkg, err := new(types.Config).Check("config", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	structType := pkg.Scope().Lookup("Config").Type().Underlying().(*types.Struct)
+
+	analysis, err := analyzeStruct(structType, "json", fieldDocs([]*ast.File{file}))
+	if err != nil {
+		t.Fatalf("analyzeStruct() error = %v", err)
+	}
+
+	want := map[string]string{
+		"/server":          "Server holds the listener settings.",
+		"/server/port":     "Port is the TCP port.\nIt must be free.",
+		"/server/host":     "Host is the bind address.",
+		"/hosts":           "Hosts are the upstream hosts.",
+		"/hosts/{key}/url": "URL is the upstream URL.",
+		"/auth/password":   "Password is remapped.",
+	}
+	if !reflect.DeepEqual(analysis.Docs, want) {
+		t.Errorf("Docs = %#v, want %#v", analysis.Docs, want)
+	}
+
+	paths, docs := analysis.schemaFields()
+	wantPaths := []string{
+		"/auth/password",
+		"/hosts",
+		"/hosts/*/url",
+		"/server",
+		"/server/host",
+		"/server/port",
+		"/undocumented",
+	}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("paths = %v, want %v", paths, wantPaths)
+	}
+	if got := docs["/hosts/*/url"]; got != "URL is the upstream URL." {
+		t.Errorf("docs[/hosts/*/url] = %q", got)
+	}
+}
+
+func TestSchemaPath(t *testing.T) {
+	tests := map[string]string{
+		"":                                    "",
+		"/server/port":                        "/server/port",
+		"/hosts/{key}/url":                    "/hosts/*/url",
+		"/groups/{key}/members/{index2}/name": "/groups/*/members/*/name",
+	}
+	for path, want := range tests {
+		if got := schemaPath(path); got != want {
+			t.Errorf("schemaPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
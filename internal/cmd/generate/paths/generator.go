@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"go/format"
+	"go/types"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -15,6 +17,11 @@ type GeneratorConfig struct {
 	SourceFile  string
 	TagName     string
 	Output      string
+	// Accessors enables the typed Get and Set functions of each path.
+	Accessors bool
+	// PkgPath is the import path of the package declaring the type,
+	// used to qualify the types of other packages in accessors.
+	PkgPath string
 }
 
 // generateCode generates Go source code from the analysis result.
@@ -43,8 +50,27 @@ func generateCode(analysis *AnalysisResult, config GeneratorConfig) ([]byte, err
 		}
 	}
 
+	var imports []string
+	if needsStrconv {
+		imports = append(imports, "strconv")
+	}
+	if needsJsonptr {
+		imports = append(imports, "github.com/yacchi/jubako/jsonptr")
+	}
+
+	// Accessors are written first to collect the packages of field types
+	var accessors bytes.Buffer
+	if config.Accessors && len(analysis.Paths) > 0 {
+		qualifier := newImportQualifier(config.PkgPath)
+		for _, p := range analysis.Paths {
+			writeAccessors(&accessors, p, config.TypeName, qualifier.qualify)
+		}
+		imports = append(imports, "github.com/yacchi/jubako", "github.com/yacchi/jubako/layer")
+		imports = append(imports, qualifier.imports...)
+	}
+
 	// Write imports
-	writeImports(&buf, needsStrconv, needsJsonptr)
+	writeImports(&buf, imports)
 
 	// Write constants for static paths
 	if len(staticPaths) > 0 {
@@ -56,6 +82,8 @@ func generateCode(analysis *AnalysisResult, config GeneratorConfig) ([]byte, err
 		writeFunction(&buf, p)
 	}
 
+	buf.Write(accessors.Bytes())
+
 	// Format the code
 	formatted, err := format.Source(buf.Bytes())
 	if err != nil {
@@ -77,6 +105,9 @@ func writeHeader(buf *bytes.Buffer, config GeneratorConfig) {
 	if config.TagName != "json" {
 		cmd += fmt.Sprintf(" -tag %s", config.TagName)
 	}
+	if config.Accessors {
+		cmd += " -accessors"
+	}
 	cmd += fmt.Sprintf(" %s", filepath.Base(config.SourceFile))
 	buf.WriteString(cmd + "\n")
 
@@ -84,21 +115,34 @@ func writeHeader(buf *bytes.Buffer, config GeneratorConfig) {
 	buf.WriteString(fmt.Sprintf("package %s\n", config.PackageName))
 }
 
-func writeImports(buf *bytes.Buffer, needsStrconv, needsJsonptr bool) {
-	if !needsStrconv && !needsJsonptr {
+// writeImports writes the import declaration, with the standard library
+// packages first.
+func writeImports(buf *bytes.Buffer, imports []string) {
+	if len(imports) == 0 {
 		buf.WriteString("\n")
 		return
 	}
 
+	var std, other []string
+	for _, path := range imports {
+		if isLocalPackage(path) {
+			other = append(other, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+
 	buf.WriteString("\nimport (\n")
-	if needsStrconv {
-		buf.WriteString("\t\"strconv\"\n")
+	for _, path := range std {
+		buf.WriteString(fmt.Sprintf("\t%q\n", path))
 	}
-	if needsJsonptr {
-		if needsStrconv {
-			buf.WriteString("\n")
-		}
-		buf.WriteString("\t\"github.com/yacchi/jubako/jsonptr\"\n")
+	if len(std) > 0 && len(other) > 0 {
+		buf.WriteString("\n")
+	}
+	for _, path := range other {
+		buf.WriteString(fmt.Sprintf("\t%q\n", path))
 	}
 	buf.WriteString(")\n")
 }
@@ -210,3 +254,149 @@ func escapeGoString(s string) string {
 	// fmt.Sprintf with %q handles escaping
 	return s
 }
+
+// writeAccessors writes the typed Get and Set functions of a path. The
+// getter reads the field from the materialized configuration, so that
+// defaults, converters and the decoder apply as for Store.Get, and returns
+// the origin reported by Store.GetAt.
+func writeAccessors(buf *bytes.Buffer, p PathInfo, typeName string, qualify types.Qualifier) {
+	if p.Type == nil || !accessParamsMatch(p) {
+		// Absolute remaps inside maps and slices lose the parameters
+		// needed to reach the field
+		return
+	}
+
+	name := strings.TrimPrefix(p.ConstName, "Path")
+	pathExpr := p.ConstName
+	if len(p.DynamicParams) > 0 {
+		name = strings.TrimPrefix(p.FuncName, "Path")
+		var args []string
+		for _, param := range p.DynamicParams {
+			args = append(args, param.Name)
+		}
+		pathExpr = fmt.Sprintf("%s(%s)", p.FuncName, strings.Join(args, ", "))
+	}
+	valueType := types.TypeString(p.Type, qualify)
+	params := buildParamList(p.DynamicParams)
+	if params != "" {
+		params = ", " + params
+	}
+	store := fmt.Sprintf("s *jubako.Store[%s]", typeName)
+
+	expr, conds := accessExpr("c", p.Access)
+	buf.WriteString("\n")
+	buf.WriteString(fmt.Sprintf("// Get%s returns the value at %s and its origin.\n", name, pathExpr))
+	buf.WriteString(fmt.Sprintf("func Get%s(%s%s) (%s, jubako.ResolvedValue) {\n", name, store, params, valueType))
+	buf.WriteString("\tc := s.Get()\n")
+	if len(conds) == 0 {
+		buf.WriteString(fmt.Sprintf("\treturn %s, s.GetAt(%s)\n", expr, pathExpr))
+	} else {
+		buf.WriteString(fmt.Sprintf("\tvar v %s\n", valueType))
+		buf.WriteString(fmt.Sprintf("\tif %s {\n\t\tv = %s\n\t}\n", strings.Join(conds, " && "), expr))
+		buf.WriteString(fmt.Sprintf("\treturn v, s.GetAt(%s)\n", pathExpr))
+	}
+	buf.WriteString("}\n")
+
+	if hasStructElements(p.Type) {
+		// Set the fields of the elements instead
+		return
+	}
+	buf.WriteString("\n")
+	buf.WriteString(fmt.Sprintf("// Set%s sets the value at %s in the layer.\n", name, pathExpr))
+	buf.WriteString(fmt.Sprintf("func Set%s(%s, layerName layer.Name%s, value %s) error {\n", name, store, params, valueType))
+	buf.WriteString(fmt.Sprintf("\treturn s.SetTo(layerName, %s, value)\n", pathExpr))
+	buf.WriteString("}\n")
+}
+
+// accessParamsMatch reports whether the map keys and slice indexes on the
+// way to the field are the dynamic parameters of its path.
+func accessParamsMatch(p PathInfo) bool {
+	var names []string
+	for _, step := range p.Access {
+		if step.Field == "" {
+			names = append(names, step.Param)
+		}
+	}
+	if len(names) != len(p.DynamicParams) {
+		return false
+	}
+	for i, param := range p.DynamicParams {
+		if names[i] != param.Name {
+			return false
+		}
+	}
+	return true
+}
+
+// accessExpr returns the Go expression reading a field from root, and the
+// conditions guarding it against nil pointers and indexes out of range.
+func accessExpr(root string, steps []accessStep) (string, []string) {
+	expr := root
+	var conds []string
+	for i, step := range steps {
+		switch {
+		case step.Field != "":
+			expr += "." + step.Field
+		case step.Index:
+			conds = append(conds, fmt.Sprintf("%s >= 0 && %s < len(%s)", step.Param, step.Param, expr))
+			expr += "[" + step.Param + "]"
+		default:
+			expr += "[" + step.Param + "]"
+		}
+		if step.Pointer && i < len(steps)-1 {
+			conds = append(conds, expr+" != nil")
+		}
+	}
+	return expr, conds
+}
+
+// hasStructElements reports whether t is a slice, array or map of structs,
+// whose paths have accessors for each field.
+func hasStructElements(t types.Type) bool {
+	if ptr, ok := t.Underlying().(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	var elem types.Type
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		elem = u.Elem()
+	case *types.Array:
+		elem = u.Elem()
+	case *types.Map:
+		elem = u.Elem()
+	default:
+		return false
+	}
+	if ptr, ok := elem.(*types.Pointer); ok {
+		elem = ptr.Elem()
+	}
+	if named, ok := elem.(*types.Named); ok && isExternalType(named) {
+		return false
+	}
+	_, ok := elem.Underlying().(*types.Struct)
+	return ok
+}
+
+// importQualifier qualifies the types of other packages by their name and
+// records their import paths.
+type importQualifier struct {
+	pkgPath string
+	imports []string
+}
+
+func newImportQualifier(pkgPath string) *importQualifier {
+	return &importQualifier{pkgPath: pkgPath}
+}
+
+func (q *importQualifier) qualify(pkg *types.Package) string {
+	if pkg.Path() == q.pkgPath {
+		return ""
+	}
+	for _, path := range q.imports {
+		if path == pkg.Path() {
+			return pkg.Name()
+		}
+	}
+	q.imports = append(q.imports, pkg.Path())
+	return pkg.Name()
+}
@@ -132,6 +132,11 @@ func TestParseJubakoTagPath(t *testing.T) {
 		{`jubako:"sensitive"`, "", false},
 		{`jubako:"-"`, "", false},
 		{`jubako:"/path,sensitive"`, "/path", true},
+		{`jubako:"merge:append"`, "", false},
+		{`jubako:"/plugins,merge:union"`, "/plugins", true},
+		{`jubako:"required,min=1"`, "", false},
+		{`jubako:"enum=debug|info"`, "", false},
+		{`jubako:"/log/level,enum=debug|info"`, "/log/level", true},
 		{`json:"field"`, "", false},
 	}
 
@@ -241,3 +246,93 @@ func TestGenerateCode(t *testing.T) {
 		t.Error("generated code should import jsonptr")
 	}
 }
+
+func TestGenerateCode_Accessors(t *testing.T) {
+	pkg, structType, err := parseSourceFile("testdata/config.go", "AppConfig")
+	if err != nil {
+		t.Fatalf("parseSourceFile() error = %v", err)
+	}
+	analysis, err := analyzeStruct(structType, "json", pkg.Docs)
+	if err != nil {
+		t.Fatalf("analyzeStruct() error = %v", err)
+	}
+
+	code, err := generateCode(analysis, GeneratorConfig{
+		PackageName: pkg.Name,
+		TypeName:    "AppConfig",
+		SourceFile:  "config.go",
+		TagName:     "json",
+		Accessors:   true,
+		PkgPath:     pkg.Path,
+	})
+	if err != nil {
+		t.Fatalf("generateCode() error = %v", err)
+	}
+	codeStr := string(code)
+
+	for _, want := range []string{
+		"//go:generate go tool jubako generate paths -type AppConfig -accessors config.go",
+		`"github.com/yacchi/jubako/layer"`,
+		"func GetServerPort(s *jubako.Store[AppConfig]) (int, jubako.ResolvedValue) {\n\tc := s.Get()\n\treturn c.Server.Port, s.GetAt(PathServerPort)\n}",
+		"func SetServerPort(s *jubako.Store[AppConfig], layerName layer.Name, value int) error {\n\treturn s.SetTo(layerName, PathServerPort, value)\n}",
+		"func GetHostsUrl(s *jubako.Store[AppConfig], key string) (string, jubako.ResolvedValue) {",
+		"return c.Hosts[key].URL, s.GetAt(PathHostsUrl(key))",
+		"func SetPluginsEnabled(s *jubako.Store[AppConfig], layerName layer.Name, index int, value bool) error {",
+		"if index >= 0 && index < len(c.Plugins) {\n\t\tv = c.Plugins[index].Enabled\n\t}",
+		"func GetPlugins(s *jubako.Store[AppConfig]) ([]PluginConfig, jubako.ResolvedValue) {",
+	} {
+		if !strings.Contains(codeStr, want) {
+			t.Errorf("generated code does not contain %q\n%s", want, codeStr)
+		}
+	}
+
+	// Containers of structs are set through the fields of their elements
+	if strings.Contains(codeStr, "func SetPlugins(") || strings.Contains(codeStr, "func SetHosts(") {
+		t.Error("generated code should not contain setters for containers of structs")
+	}
+}
+
+func TestAccessExpr(t *testing.T) {
+	tests := []struct {
+		name      string
+		steps     []accessStep
+		wantExpr  string
+		wantConds []string
+	}{
+		{
+			name:     "fields",
+			steps:    []accessStep{{Field: "Server"}, {Field: "Port"}},
+			wantExpr: "c.Server.Port",
+		},
+		{
+			name:      "pointer field",
+			steps:     []accessStep{{Field: "Server", Pointer: true}, {Field: "TLS", Pointer: true}},
+			wantExpr:  "c.Server.TLS",
+			wantConds: []string{"c.Server != nil"},
+		},
+		{
+			name:      "map of pointers",
+			steps:     []accessStep{{Field: "Hosts"}, {Param: "key", Pointer: true}, {Field: "URL"}},
+			wantExpr:  "c.Hosts[key].URL",
+			wantConds: []string{"c.Hosts[key] != nil"},
+		},
+		{
+			name:      "nested slice",
+			steps:     []accessStep{{Field: "Servers"}, {Param: "key"}, {Field: "Ports"}, {Param: "index", Index: true}, {Field: "Address"}},
+			wantExpr:  "c.Servers[key].Ports[index].Address",
+			wantConds: []string{"index >= 0 && index < len(c.Servers[key].Ports)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, conds := accessExpr("c", tt.steps)
+			if expr != tt.wantExpr {
+				t.Errorf("accessExpr() expr = %q, want %q", expr, tt.wantExpr)
+			}
+			if strings.Join(conds, " && ") != strings.Join(tt.wantConds, " && ") {
+				t.Errorf("accessExpr() conds = %q, want %q", conds, tt.wantConds)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"go/token"
 	"go/types"
 	"path/filepath"
+	"strings"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -15,6 +16,8 @@ import (
 type ParsedPackage struct {
 	Name string
 	Path string
+	// Docs maps the position of each documented struct field name to its doc comment.
+	Docs map[token.Pos]string
 }
 
 // parseSourceFile parses the source file and returns the package info and struct type.
@@ -26,10 +29,12 @@ func parseSourceFile(sourceFile string, typeName string) (*ParsedPackage, *types
 
 	dir := filepath.Dir(absPath)
 
-	// Load the package
+	// Load the package. Dependencies are type-checked from source so that
+	// packages importing the standard library load with any toolchain.
 	cfg := &packages.Config{
-		Mode: packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
-		Dir:  dir,
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo |
+			packages.NeedImports | packages.NeedDeps,
+		Dir: dir,
 	}
 
 	pkgs, err := packages.Load(cfg, ".")
@@ -60,9 +65,41 @@ func parseSourceFile(sourceFile string, typeName string) (*ParsedPackage, *types
 	return &ParsedPackage{
 		Name: pkg.Name,
 		Path: pkg.PkgPath,
+		Docs: fieldDocs(pkg.Syntax),
 	}, typeObj, nil
 }
 
+// fieldDocs collects the doc comments of struct fields, falling back to the
+// line comment. Field objects of go/types are positioned at their name, so
+// the comments are keyed by the position of each name.
+func fieldDocs(files []*ast.File) map[token.Pos]string {
+	docs := make(map[token.Pos]string)
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			field, ok := n.(*ast.Field)
+			if !ok {
+				return true
+			}
+			doc := strings.TrimSpace(field.Doc.Text())
+			if doc == "" {
+				doc = strings.TrimSpace(field.Comment.Text())
+			}
+			if doc == "" {
+				return true
+			}
+			if len(field.Names) == 0 {
+				// Embedded fields are positioned at their type
+				docs[field.Type.Pos()] = doc
+			}
+			for _, name := range field.Names {
+				docs[name.Pos()] = doc
+			}
+			return true
+		})
+	}
+	return docs
+}
+
 // parseSourceFileAST parses the source file using AST only (for simpler cases).
 // This is an alternative implementation that doesn't require full type checking.
 func parseSourceFileAST(sourceFile string, typeName string) (*ParsedPackage, *ast.TypeSpec, error) {
@@ -0,0 +1,98 @@
+// Package schema provides the "generate schema" subcommand.
+package schema
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yacchi/jubako/internal/cmd/generate/storeprog"
+)
+
+// Options holds the command-line options for the schema generator.
+type Options struct {
+	TypeName string
+	TagName  string
+	Output   string
+}
+
+// Run executes the schema generation command.
+func Run(args []string) error {
+	fs := flag.NewFlagSet("generate schema", flag.ExitOnError)
+
+	var opts Options
+	fs.StringVar(&opts.TypeName, "type", "", "target struct type name (required)")
+	fs.StringVar(&opts.TagName, "tag", "json", "tag name for field resolution")
+	fs.StringVar(&opts.Output, "output", "", "output file path (default: stdout)")
+
+	fs.Usage = func() {
+		printHelp()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if opts.TypeName == "" {
+		printHelp()
+		return fmt.Errorf("-type flag is required")
+	}
+
+	remaining := fs.Args()
+	if len(remaining) != 1 {
+		printHelp()
+		return fmt.Errorf("exactly one source file is required")
+	}
+
+	return runGenerate(remaining[0], opts)
+}
+
+func runGenerate(sourceFile string, opts Options) error {
+	// The schema is generated by Store.JSONSchema in a program importing the
+	// package, so that it follows the same rules as the Store at runtime.
+	data, err := storeprog.Run(storeprog.Config{
+		SourceFile: sourceFile,
+		TypeName:   opts.TypeName,
+		TagName:    opts.TagName,
+		Expr:       "store.JSONSchema()",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate schema: %w", err)
+	}
+	data = append(data, '\n')
+
+	if opts.Output == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(opts.Output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "generated %s\n", opts.Output)
+
+	return nil
+}
+
+func printHelp() {
+	fmt.Fprintln(os.Stderr, `jubako generate schema - Generate a JSON Schema for configuration files
+
+Usage:
+  go tool jubako generate schema [options] <source-file>
+
+The schema (draft 2020-12) describes the documents read by a Store of the
+type, following jubako struct tags. The type must be declared in a package
+of the current module. For a main package, the init functions of the
+package run during generation.
+
+Options:
+  -type string      Target struct type name (required)
+  -tag string       Tag name for field resolution (default "json")
+  -output string    Output file path (default: stdout)
+
+Examples:
+  go tool jubako generate schema -type AppConfig config.go
+  go tool jubako generate schema -type AppConfig -output config.schema.json config.go
+
+For use with go:generate:
+  //go:generate go tool jubako generate schema -type AppConfig -output config.schema.json config.go`)
+}
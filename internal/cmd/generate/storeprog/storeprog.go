@@ -0,0 +1,236 @@
+// Package storeprog runs a temporary program that creates a jubako.Store for
+// a configuration type declared in the user's package.
+//
+// Generators that need the runtime schema of a type (JSON Schema, example
+// configurations, ...) use it instead of re-implementing the struct tag
+// rules on top of static analysis. The program is built inside the user's
+// module through a go build overlay, so the target package and jubako
+// resolve to the versions the user depends on, and nothing is written to
+// the user's source tree.
+package storeprog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"text/template"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Config describes the program to run.
+type Config struct {
+	// SourceFile is a Go file of the package declaring the type.
+	SourceFile string
+	// TypeName is the name of the configuration struct type.
+	TypeName string
+	// TagName is the struct tag used for field name resolution (WithTagName).
+	TagName string
+	// Expr is a Go expression of type ([]byte, error) evaluated with the
+	// Store in the variable store, e.g. "store.JSONSchema()". The
+	// configuration type is available as Target.
+	Expr string
+	// Imports lists additional import paths used by Expr.
+	Imports []string
+}
+
+// Package identifies the package declaring the configuration type.
+type Package struct {
+	Name string
+	Path string
+	Dir  string
+}
+
+// IsMain reports whether the package is a main package, which the program
+// cannot import.
+func (p *Package) IsMain() bool {
+	return p.Name == "main"
+}
+
+// Run builds and runs the program, and returns what Expr produced.
+func Run(cfg Config) ([]byte, error) {
+	pkg, err := LoadPackage(cfg.SourceFile, cfg.TypeName)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := Source(pkg, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "jubako-generate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	programFile := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(programFile, src, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write program: %w", err)
+	}
+
+	// The overlay places the program in the user's package directory without
+	// writing there. A main package cannot be imported, so the program is
+	// added to the package itself; otherwise it is a package of its own in a
+	// directory that exists only in the overlay.
+	name := filepath.Base(dir)
+	overlayPath := filepath.Join(pkg.Dir, name, "main.go")
+	target := "./" + name
+	if pkg.IsMain() {
+		overlayPath = filepath.Join(pkg.Dir, "zzz_"+name+".go")
+		target = "."
+	}
+	overlay, err := json.Marshal(map[string]any{
+		"Replace": map[string]string{overlayPath: programFile},
+	})
+	if err != nil {
+		return nil, err
+	}
+	overlayFile := filepath.Join(dir, "overlay.json")
+	if err := os.WriteFile(overlayFile, overlay, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write overlay: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("go", "run", "-overlay", overlayFile, target)
+	cmd.Dir = pkg.Dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run program: %w\n%s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// LoadPackage loads the package of sourceFile and checks that it declares
+// typeName. The type must be exported unless the package is a main package.
+func LoadPackage(sourceFile string, typeName string) (*Package, error) {
+	absPath, err := filepath.Abs(sourceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	dir := filepath.Dir(absPath)
+
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax,
+		Dir:  dir,
+	}, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load package: %w", err)
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no packages found")
+	}
+	p := pkgs[0]
+	if len(p.Errors) > 0 {
+		return nil, fmt.Errorf("package errors: %v", p.Errors)
+	}
+	if p.Name != "main" && !ast.IsExported(typeName) {
+		return nil, fmt.Errorf("type %s is not exported", typeName)
+	}
+	if !declaresType(p.Syntax, typeName) {
+		return nil, fmt.Errorf("type %s not found in package %s", typeName, p.Name)
+	}
+
+	return &Package{Name: p.Name, Path: p.PkgPath, Dir: dir}, nil
+}
+
+// declaresType reports whether files declare a type named typeName.
+func declaresType(files []*ast.File, typeName string) bool {
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				if typeSpec, ok := spec.(*ast.TypeSpec); ok && typeSpec.Name.Name == typeName {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// programTemplate is the source of the program. For a main package, the
+// program is part of the package, and runs in an init function that exits
+// before the package's main function; the init functions of the package's
+// other files run before it.
+var programTemplate = template.Must(template.New("main").Parse(`// Code generated by jubako generate. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/yacchi/jubako"
+{{- if not .Main}}
+	target {{.ImportPath}}
+{{- end}}
+{{- range .Imports}}
+	{{.}}
+{{- end}}
+)
+
+func {{if .Main}}init{{else}}main{{end}}() {
+{{- if ne .TypeRef "Target"}}
+	type Target = {{.TypeRef}}
+{{- end}}
+	data, err := func(store *jubako.Store[Target]) ([]byte, error) {
+		return {{.Expr}}
+	}(jubako.New[Target](jubako.WithTagName({{.TagName}})))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(data)
+{{- if .Main}}
+	os.Exit(0)
+{{- end}}
+}
+`))
+
+// Source returns the source code of the program.
+func Source(pkg *Package, cfg Config) ([]byte, error) {
+	tagName := cfg.TagName
+	if tagName == "" {
+		tagName = "json"
+	}
+
+	imports := make([]string, len(cfg.Imports))
+	for i, path := range cfg.Imports {
+		imports[i] = strconv.Quote(path)
+	}
+
+	typeRef := "target." + cfg.TypeName
+	if pkg.IsMain() {
+		typeRef = cfg.TypeName
+	}
+
+	var buf bytes.Buffer
+	if err := programTemplate.Execute(&buf, map[string]any{
+		"Main":       pkg.IsMain(),
+		"ImportPath": strconv.Quote(pkg.Path),
+		"TypeRef":    typeRef,
+		"TagName":    strconv.Quote(tagName),
+		"Expr":       cfg.Expr,
+		"Imports":    imports,
+	}); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format program: %w\n%s", err, buf.String())
+	}
+	return src, nil
+}
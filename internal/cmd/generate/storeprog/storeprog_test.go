@@ -0,0 +1,111 @@
+package storeprog
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	pkg := &Package{Name: "config", Path: "example.com/app/config"}
+	src, err := Source(pkg, Config{TypeName: "AppConfig", TagName: "yaml", Expr: "store.JSONSchema()"})
+	if err != nil {
+		t.Fatalf("Source() error = %v", err)
+	}
+
+	code := string(src)
+	for _, want := range []string{
+		`target "example.com/app/config"`,
+		`type Target = target.AppConfig`,
+		`jubako.New[Target](jubako.WithTagName("yaml"))`,
+		`return store.JSONSchema()`,
+		`func main() {`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Source() does not contain %q:\n%s", want, code)
+		}
+	}
+
+	// The tag name defaults to "json"
+	src, err = Source(pkg, Config{TypeName: "AppConfig", Expr: "store.JSONSchema()"})
+	if err != nil {
+		t.Fatalf("Source() error = %v", err)
+	}
+	if !strings.Contains(string(src), `jubako.WithTagName("json")`) {
+		t.Errorf("Source() should default to the json tag:\n%s", src)
+	}
+
+	// Additional imports are available to the expression
+	src, err = Source(pkg, Config{
+		TypeName: "AppConfig",
+		Expr:     `env.BuildSchemaMapping[Target]().Reference("APP_").MarshalMarkdown()`,
+		Imports:  []string{"github.com/yacchi/jubako/layer/env"},
+	})
+	if err != nil {
+		t.Fatalf("Source() error = %v", err)
+	}
+	if !strings.Contains(string(src), `"github.com/yacchi/jubako/layer/env"`) {
+		t.Errorf("Source() does not import the env package:\n%s", src)
+	}
+}
+
+func TestSource_MainPackage(t *testing.T) {
+	// A main package cannot be imported; the program becomes part of it
+	pkg := &Package{Name: "main", Path: "example.com/app/cmd/app"}
+	src, err := Source(pkg, Config{TypeName: "appConfig", Expr: "store.JSONSchema()"})
+	if err != nil {
+		t.Fatalf("Source() error = %v", err)
+	}
+
+	code := string(src)
+	for _, want := range []string{
+		`type Target = appConfig`,
+		`func init() {`,
+		`os.Exit(0)`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Source() does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "example.com/app/cmd/app") {
+		t.Errorf("Source() should not import the main package:\n%s", code)
+	}
+
+	// A type named Target needs no alias
+	src, err = Source(pkg, Config{TypeName: "Target", Expr: "store.JSONSchema()"})
+	if err != nil {
+		t.Fatalf("Source() error = %v", err)
+	}
+	if strings.Contains(string(src), "type Target =") {
+		t.Errorf("Source() should not alias Target to itself:\n%s", src)
+	}
+}
+
+func TestDeclaresType(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "config.go", `package config
+
+type (
+	AppConfig struct{}
+	other     int
+)
+
+func AppConfigFunc() {}
+`, 0)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	tests := map[string]bool{
+		"AppConfig":     true,
+		"other":         true,
+		"AppConfigFunc": false,
+		"Missing":       false,
+	}
+	for name, want := range tests {
+		if got := declaresType([]*ast.File{file}, name); got != want {
+			t.Errorf("declaresType(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
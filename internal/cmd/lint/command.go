@@ -0,0 +1,120 @@
+// Package lint provides the "lint" command, which checks the jubako struct
+// tags of configuration types in Go packages.
+package lint
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	jubakolint "github.com/yacchi/jubako/lint"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+)
+
+// Run executes the lint command.
+func Run(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	tests := fs.Bool("test", true, "also check test files")
+	// Analyzer flags (-tag, -delimiter) are shared with go vet tools
+	jubakolint.Analyzer.Flags.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+	})
+
+	fs.Usage = func() {
+		PrintHelp()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		PrintHelp()
+		return fmt.Errorf("at least one package pattern is required")
+	}
+
+	n, err := run(patterns, *tests, os.Stderr)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("found %d problem(s)", n)
+	}
+	return nil
+}
+
+// run analyzes the packages matching patterns, prints the diagnostics in
+// the file:line:column format of the compiler to w and returns their count.
+func run(patterns []string, tests bool, w io.Writer) (int, error) {
+	cfg := &packages.Config{
+		Mode:  packages.LoadAllSyntax,
+		Tests: tests,
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load packages: %w", err)
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return 0, fmt.Errorf("failed to load packages")
+	}
+
+	graph, err := checker.Analyze([]*analysis.Analyzer{jubakolint.Analyzer}, pkgs, nil)
+	if err != nil {
+		return 0, err
+	}
+	if err := graph.PrintText(w, -1); err != nil {
+		return 0, err
+	}
+
+	// Packages with tests are loaded twice; count their diagnostics once
+	seen := make(map[string]bool)
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			return 0, fmt.Errorf("%s: %w", act.Package.PkgPath, act.Err)
+		}
+		for _, diag := range act.Diagnostics {
+			seen[fmt.Sprintf("%s: %s", act.Package.Fset.Position(diag.Pos), diag.Message)] = true
+		}
+	}
+	return len(seen), nil
+}
+
+// PrintHelp prints the help message for the lint command.
+func PrintHelp() {
+	fmt.Println(`Check the jubako struct tags of configuration types
+
+Usage:
+  go tool jubako lint [flags] <packages>
+
+Flags:
+  -tag string
+        struct tag name used for field keys, as set by jubako.WithTagName (default "json")
+  -delimiter string
+        delimiter of jubako tag directives, as set by jubako.WithTagDelimiter (default ",")
+  -test
+        also check test files (default true)
+
+Reported problems:
+  - the sensitive directive on a struct, map, slice or array field
+  - malformed env: patterns, and {key} or {index} placeholders not matching
+    the maps and slices the field is nested in
+  - fields remapped, or aliased, onto the path of another field
+  - environment variables mapped to more than one field
+
+Problems are printed as file:line:column: message, and the command exits
+with status 1 when any is found.
+
+Example:
+  go tool jubako lint ./...
+
+The same checks run from go vet with a vet tool built from the
+github.com/yacchi/jubako/lint package:
+
+  func main() { unitchecker.Main(lint.Analyzer) }
+
+  go vet -vettool=$(pwd)/jubako-vet ./...`)
+}
@@ -0,0 +1,35 @@
+package lint
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+	n, err := run([]string{"./testdata/config"}, true, &out)
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("run() = %d problems, want 2\n%s", n, out.String())
+	}
+
+	want := []string{
+		"config.go:5:2: remapped path /server/host of field Config.Host collides with field Config.Server.Host\n",
+		"config.go:10:2: environment variable HOST of field Config.Server.Name is also mapped to field Config.Server.Host\n",
+	}
+	for _, w := range want {
+		if !strings.Contains(out.String(), w) {
+			t.Errorf("output does not contain %q\n%s", w, out.String())
+		}
+	}
+}
+
+func TestRun_LoadError(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := run([]string{"./testdata/missing"}, false, &out); err == nil {
+		t.Error("run() succeeded for a missing package")
+	}
+}
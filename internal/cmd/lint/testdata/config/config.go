@@ -0,0 +1,11 @@
+package config
+
+type Config struct {
+	Server Server `json:"server"`
+	Host   string `json:"host" jubako:"/server/host"`
+}
+
+type Server struct {
+	Host string `json:"host" jubako:"env:HOST"`
+	Name string `json:"name" jubako:"env:HOST"`
+}
@@ -2,7 +2,12 @@
 package tag
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -29,6 +34,62 @@ const (
 	SensitiveExplicit
 )
 
+// MergeStrategy controls how slice values from multiple layers are combined.
+type MergeStrategy int
+
+const (
+	// MergeReplace replaces a lower layer's slice with the higher layer's slice (default).
+	MergeReplace MergeStrategy = iota
+	// MergeAppend concatenates slices in layer priority order.
+	MergeAppend
+	// MergeUnion concatenates slices in layer priority order, skipping elements
+	// that are already present.
+	MergeUnion
+	// MergeByKey merges map elements that share the same value for MergeKey,
+	// appending elements whose key is not yet present.
+	MergeByKey
+)
+
+// String returns the directive form of the merge strategy.
+func (m MergeStrategy) String() string {
+	switch m {
+	case MergeAppend:
+		return "append"
+	case MergeUnion:
+		return "union"
+	case MergeByKey:
+		return "key"
+	default:
+		return "replace"
+	}
+}
+
+// Constraints holds the declarative validation rules from jubako tag directives.
+// The zero value has no constraints.
+type Constraints struct {
+	// Required rejects missing and null values (required directive).
+	Required bool
+
+	// Min is the lower bound from the min= directive, or nil if not set.
+	// Numbers are compared by value; strings, slices and maps by length.
+	Min *float64
+
+	// Max is the upper bound from the max= directive, or nil if not set.
+	// Numbers are compared by value; strings, slices and maps by length.
+	Max *float64
+
+	// Enum lists the allowed values from the enum= directive (separated by "|").
+	Enum []string
+
+	// Pattern is the regular expression from the pattern= directive.
+	Pattern string
+}
+
+// IsEmpty returns true if no constraint is set.
+func (c Constraints) IsEmpty() bool {
+	return !c.Required && c.Min == nil && c.Max == nil && len(c.Enum) == 0 && c.Pattern == ""
+}
+
 // FieldInfo contains all parsed tag information for a struct field.
 // This struct consolidates json/yaml tag and jubako tag parsing results.
 // It is designed to be extensible for future directives.
@@ -56,6 +117,45 @@ type FieldInfo struct {
 	// EnvVar is the environment variable name (without prefix) from env: directive.
 	// Empty if no env: directive is present.
 	EnvVar string
+
+	// Merge is the slice merge strategy from the merge: directive.
+	Merge MergeStrategy
+
+	// MergeKey is the element key used when Merge is MergeByKey.
+	MergeKey string
+
+	// Constraints are the validation rules from required, min=, max=, enum= and pattern= directives.
+	Constraints Constraints
+
+	// Default is the raw default value from the default= directive.
+	// Slices, maps and structs use JSON syntax (e.g., `default=["a","b"]`).
+	Default string
+
+	// HasDefault is true if a default= directive is present (Default may be empty).
+	HasDefault bool
+
+	// Aliases are the former paths of the field from the alias= directive.
+	// Absolute aliases start with "/"; relative aliases (e.g., "old_name") are
+	// resolved from the current context, like relative paths.
+	Aliases []string
+
+	// Deprecated is true if the deprecated directive is present.
+	// Values read from an alias of a deprecated field are reported as deprecated.
+	Deprecated bool
+
+	// Union is the discriminator key from the union= directive. The value of
+	// the discriminator selects the variant type of an interface field.
+	Union string
+
+	// Embedded is true for an anonymous struct field without a key in its
+	// fieldTagName tag. As in encoding/json, its fields are promoted to the
+	// embedding struct.
+	Embedded bool
+
+	// Err reports directives with invalid values, such as a min= bound that
+	// is not a number, a pattern= that does not compile or an unknown merge:
+	// strategy. The Store rejects fields with such tags when it is created.
+	Err error
 }
 
 // Parse parses all relevant struct tags for a field and returns FieldInfo.
@@ -76,6 +176,8 @@ func Parse(field reflect.StructField, fieldTagName string, jubakoDelimiter strin
 
 	// Parse field key from fieldTagName tag (e.g., json, yaml)
 	info.FieldKey = ParseFieldKey(field, fieldTagName)
+	info.Embedded = field.Anonymous && info.FieldType.Kind() == reflect.Struct &&
+		parseJSONTagKey(field.Tag.Get(fieldTagName)) == ""
 
 	// If field key is "-", the field should be skipped
 	if info.FieldKey == "-" {
@@ -134,6 +236,16 @@ func ParseFieldKey(field reflect.StructField, tagName string) string {
 // Directives (delimiter-separated, default ","):
 //   - "sensitive" or "sensitive=true" - marks field as containing sensitive data
 //   - "env:VAR_NAME" - maps environment variable VAR_NAME to this field
+//   - "merge:append", "merge:union", "merge:key=NAME", "merge:replace" - slice merge strategy
+//   - "required" - the value must be present and not null
+//   - "min=N", "max=N" - bounds for numbers, or for the length of strings, slices and maps
+//   - "enum=a|b|c" - the value must be one of the listed values
+//   - "pattern=REGEXP" - string values must match the regular expression
+//     (use WithTagDelimiter if the expression contains the delimiter)
+//   - "default=VALUE" - default value; JSON arrays and objects may contain the delimiter
+//   - "alias=/old/path|old_name" - former paths read when the field's path is absent
+//   - "deprecated" - report values read from an alias as deprecated
+//   - "union=KEY" - decode an interface field into the variant named by the KEY value
 //
 // Examples (with default delimiter ","):
 //   - `jubako:"sensitive"` - sensitive field, no path remap
@@ -141,9 +253,14 @@ func ParseFieldKey(field reflect.StructField, tagName string) string {
 //   - `jubako:"/path,sensitive"` - sensitive field with absolute path remap
 //   - `jubako:"env:SERVER_PORT"` - map env var SERVER_PORT to this field
 //   - `jubako:"/path,env:PORT,sensitive"` - path remap + env mapping + sensitive
+//   - `jubako:"merge:key=name"` - merge slice elements by their "name" key
+//   - `jubako:"required,min=1,max=65535"` - required value between 1 and 65535
+//   - `jubako:"default=[\"a\",\"b\"]"` - default slice in JSON syntax
+//   - `jubako:"alias=/server/listen_port,deprecated"` - renamed from /server/listen_port
+//   - `jubako:"union=type"` - the "type" key selects the variant of an interface field
 func ParseJubakoDirectives(tag string, delimiter string, info *FieldInfo) {
 	// Split by delimiter to get path and directives
-	parts := strings.Split(tag, delimiter)
+	parts := joinJSONDefault(strings.Split(tag, delimiter), delimiter)
 	pathPart := ""
 	if len(parts) > 0 {
 		pathPart = strings.TrimSpace(parts[0])
@@ -151,23 +268,11 @@ func ParseJubakoDirectives(tag string, delimiter string, info *FieldInfo) {
 
 	// Parse directives
 	for i := 1; i < len(parts); i++ {
-		directive := strings.TrimSpace(parts[i])
-		switch {
-		case directive == "sensitive", directive == "sensitive=true":
-			info.Sensitive = SensitiveExplicit
-		case strings.HasPrefix(directive, "env:"):
-			info.EnvVar = strings.TrimPrefix(directive, "env:")
-		}
+		parseDirective(strings.TrimSpace(parts[i]), info)
 	}
 
 	// Check if first part is just a directive (no path)
-	switch {
-	case pathPart == "sensitive", pathPart == "sensitive=true":
-		info.Sensitive = SensitiveExplicit
-		return
-	case strings.HasPrefix(pathPart, "env:"):
-		// First part is env directive (no path)
-		info.EnvVar = strings.TrimPrefix(pathPart, "env:")
+	if parseDirective(pathPart, info) {
 		return
 	}
 
@@ -191,6 +296,110 @@ func ParseJubakoDirectives(tag string, delimiter string, info *FieldInfo) {
 	}
 }
 
+// parseDirective applies a single directive to info.
+// Returns false if the string is not a recognized directive.
+func parseDirective(directive string, info *FieldInfo) bool {
+	switch {
+	case directive == "sensitive", directive == "sensitive=true":
+		info.Sensitive = SensitiveExplicit
+	case strings.HasPrefix(directive, "env:"):
+		info.EnvVar = strings.TrimPrefix(directive, "env:")
+	case strings.HasPrefix(directive, "merge:"):
+		parseMergeDirective(strings.TrimPrefix(directive, "merge:"), info)
+	case directive == "required":
+		info.Constraints.Required = true
+	case strings.HasPrefix(directive, "min="):
+		info.Constraints.Min = parseBound("min", strings.TrimPrefix(directive, "min="), info)
+	case strings.HasPrefix(directive, "max="):
+		info.Constraints.Max = parseBound("max", strings.TrimPrefix(directive, "max="), info)
+	case strings.HasPrefix(directive, "enum="):
+		info.Constraints.Enum = strings.Split(strings.TrimPrefix(directive, "enum="), "|")
+	case strings.HasPrefix(directive, "pattern="):
+		info.Constraints.Pattern = strings.TrimPrefix(directive, "pattern=")
+		if _, err := regexp.Compile(info.Constraints.Pattern); err != nil {
+			info.addError(fmt.Errorf("invalid pattern= directive: %w", err))
+		}
+	case strings.HasPrefix(directive, "default="):
+		info.Default = strings.TrimPrefix(directive, "default=")
+		info.HasDefault = true
+	case strings.HasPrefix(directive, "alias="):
+		for _, alias := range strings.Split(strings.TrimPrefix(directive, "alias="), "|") {
+			if alias = strings.TrimPrefix(strings.TrimSpace(alias), "./"); alias != "" {
+				info.Aliases = append(info.Aliases, alias)
+			}
+		}
+	case directive == "deprecated":
+		info.Deprecated = true
+	case strings.HasPrefix(directive, "union="):
+		info.Union = strings.TrimSpace(strings.TrimPrefix(directive, "union="))
+	default:
+		return false
+	}
+	return true
+}
+
+// parseMergeDirective parses the value of a merge: directive.
+// Unknown strategies and key merges without a key name are recorded in
+// info.Err and leave MergeReplace.
+func parseMergeDirective(value string, info *FieldInfo) {
+	info.Merge = MergeReplace
+	info.MergeKey = ""
+	switch {
+	case value == "replace":
+	case value == "append":
+		info.Merge = MergeAppend
+	case value == "union":
+		info.Merge = MergeUnion
+	case strings.HasPrefix(value, "key="):
+		key := strings.TrimPrefix(value, "key=")
+		if key == "" {
+			info.addError(errors.New("invalid merge: directive: key= needs a key name"))
+			return
+		}
+		info.Merge = MergeByKey
+		info.MergeKey = key
+	default:
+		info.addError(fmt.Errorf("invalid merge: directive: unknown strategy %q", value))
+	}
+}
+
+// joinJSONDefault rejoins a default= directive whose JSON array or object value
+// was split at the delimiter, e.g. `default=["a","b"]` with the "," delimiter.
+// Parts are joined until the value is valid JSON; if it never becomes valid,
+// parts are left as split.
+func joinJSONDefault(parts []string, delimiter string) []string {
+	for i := 0; i < len(parts); i++ {
+		value, ok := strings.CutPrefix(strings.TrimSpace(parts[i]), "default=")
+		if !ok || (!strings.HasPrefix(value, "[") && !strings.HasPrefix(value, "{")) || json.Valid([]byte(value)) {
+			continue
+		}
+		for j := i + 1; j < len(parts); j++ {
+			joined := strings.Join(parts[i:j+1], delimiter)
+			if json.Valid([]byte(strings.TrimPrefix(strings.TrimSpace(joined), "default="))) {
+				parts = append(parts[:i:i], append([]string{joined}, parts[j+1:]...)...)
+				break
+			}
+		}
+	}
+	return parts
+}
+
+// parseBound parses the value of a min= or max= directive.
+// Values that are not numbers are recorded in info.Err.
+func parseBound(name, value string, info *FieldInfo) *float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		info.addError(fmt.Errorf("invalid %s= directive: %q is not a number", name, value))
+		return nil
+	}
+	return &f
+}
+
+// addError records an invalid directive in info.Err.
+func (info *FieldInfo) addError(err error) {
+	info.Err = errors.Join(info.Err, err)
+}
+
 // ParseJubakoTag is a convenience function for parsing just a jubako tag string.
 // Used for testing and cases where only the jubako tag needs to be parsed.
 func ParseJubakoTag(tag string, delimiter string) FieldInfo {
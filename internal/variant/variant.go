@@ -0,0 +1,90 @@
+// Package variant holds the registry of union variants shared by jubako and
+// its layers. Variants are registered with jubako.RegisterVariant.
+package variant
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// registry maps interface types to their variants by discriminator value.
+var registry = struct {
+	sync.RWMutex
+	m map[reflect.Type]map[string]reflect.Type
+}{m: make(map[reflect.Type]map[string]reflect.Type)}
+
+// Register registers typ as the variant of the interface type iface selected
+// by name. typ must be a struct or a pointer to a struct implementing iface.
+// It panics if the registration is invalid or name is already registered for iface.
+func Register(iface reflect.Type, name string, typ reflect.Type) {
+	switch {
+	case iface == nil || iface.Kind() != reflect.Interface:
+		panic(fmt.Sprintf("jubako: RegisterVariant: %v is not an interface type", iface))
+	case name == "":
+		panic(fmt.Sprintf("jubako: RegisterVariant: empty variant name for %v", iface))
+	case typ == nil || Struct(typ) == nil:
+		panic(fmt.Sprintf("jubako: RegisterVariant: variant %q of %v must be a struct or a pointer to a struct, got %v", name, iface, typ))
+	case !typ.Implements(iface):
+		panic(fmt.Sprintf("jubako: RegisterVariant: variant %q type %v does not implement %v", name, typ, iface))
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	variants := registry.m[iface]
+	if variants == nil {
+		variants = make(map[string]reflect.Type)
+		registry.m[iface] = variants
+	}
+	if _, dup := variants[name]; dup {
+		panic(fmt.Sprintf("jubako: RegisterVariant: variant %q of %v registered twice", name, iface))
+	}
+	variants[name] = typ
+}
+
+// Lookup returns a copy of the variants registered for iface, keyed by name.
+// It returns nil if no variant is registered.
+func Lookup(iface reflect.Type) map[string]reflect.Type {
+	registry.RLock()
+	defer registry.RUnlock()
+	variants := registry.m[iface]
+	if len(variants) == 0 {
+		return nil
+	}
+	result := make(map[string]reflect.Type, len(variants))
+	for name, typ := range variants {
+		result[name] = typ
+	}
+	return result
+}
+
+// Interface returns the interface type of a field declared with the union=
+// directive: the field's type itself, or the element type of a slice or of a
+// map with string keys. It returns nil for other types.
+func Interface(t reflect.Type) reflect.Type {
+	switch t.Kind() {
+	case reflect.Interface:
+		return t
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Interface {
+			return t.Elem()
+		}
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.Interface {
+			return t.Elem()
+		}
+	}
+	return nil
+}
+
+// Struct returns the struct type of a variant type, dereferencing a pointer.
+// It returns nil if t is neither a struct nor a pointer to a struct.
+func Struct(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,272 @@
+package jubako
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/yacchi/jubako/container"
+	"github.com/yacchi/jubako/jsonptr"
+)
+
+// WithInterpolation enables references in string values, expanded after all
+// layers are merged and before the configuration is decoded:
+//
+//   - ${/server/host}: the resolved value at a JSON Pointer path
+//   - ${env:NAME}: the environment variable NAME
+//   - ${home}: the home directory of the current user
+//   - $${: a literal "${"
+//
+// A string that consists of a single path reference takes the type of the
+// referenced value (e.g., "${/server/port}" becomes the number 8080); references
+// embedded in a longer string are formatted as text. Referenced values are
+// expanded themselves, and reference cycles are reported as errors.
+//
+// Interpolation only affects the resolved configuration. Layers keep the
+// templates, so Save writes them unchanged. GetAt reports the template in
+// ResolvedValue.Raw when a value was expanded.
+//
+// Example:
+//
+//	// server.host: example.com
+//	// api.url: "https://${/server/host}/api"
+//	// cache.dir: "${home}/.cache/app"
+//	store := jubako.New[Config](jubako.WithInterpolation())
+func WithInterpolation() StoreOption {
+	return func(o *storeOptions) {
+		o.interpolation = true
+	}
+}
+
+// InterpolationError reports a reference in a string value that cannot be expanded.
+type InterpolationError struct {
+	// Path is the JSON Pointer path of the value containing the reference.
+	Path string
+
+	// Reference is the reference that failed, including the ${} delimiters.
+	// It is empty for syntax errors.
+	Reference string
+
+	// Layer provides metadata about the layer that provided the value.
+	Layer LayerInfo
+
+	// Err describes the failure.
+	Err error
+}
+
+// Error implements the error interface.
+// The message names the path, reference, layer and source, for example:
+// "interpolate /api/url: ${/server/hostname}: path not found (from layer user (~/.config/app.yaml))".
+func (e *InterpolationError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "interpolate %s: ", e.Path)
+	if e.Reference != "" {
+		fmt.Fprintf(&sb, "%s: ", e.Reference)
+	}
+	sb.WriteString(e.Err.Error())
+	if e.Layer != nil {
+		fmt.Fprintf(&sb, " (from layer %s", e.Layer.Name())
+		if path := e.Layer.Path(); path != "" {
+			fmt.Fprintf(&sb, " (%s)", path)
+		}
+		sb.WriteString(")")
+	}
+	return sb.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *InterpolationError) Unwrap() error {
+	return e.Err
+}
+
+// interpolate expands the references in the string values of merged.
+// It returns a new map (merged is not modified) and the expanded values of the
+// leaf paths that changed, keyed by path.
+func interpolate(merged map[string]any, origins *origins) (map[string]any, map[string]any, error) {
+	it := &interpolator{
+		root:      merged,
+		origins:   origins,
+		expanded:  make(map[string]any),
+		changed:   make(map[string]any),
+		resolving: make(map[string]bool),
+	}
+	result, err := it.expandValue("", merged)
+	if err != nil {
+		return nil, nil, err
+	}
+	expanded, _ := result.(map[string]any)
+	return expanded, it.changed, nil
+}
+
+// interpolator expands references against the merged configuration.
+type interpolator struct {
+	root    map[string]any
+	origins *origins
+
+	// expanded caches the expanded string values by path.
+	expanded map[string]any
+
+	// changed records the string values that differ from their templates.
+	changed map[string]any
+
+	// resolving and stack track the paths being expanded, to detect cycles.
+	resolving map[string]bool
+	stack     []string
+}
+
+// expandValue returns value with the references in its strings expanded.
+// Maps and slices are copied.
+func (it *interpolator) expandValue(path string, value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return it.expandPath(path, v)
+	case map[string]any:
+		// Keys are visited in order so that errors are reported deterministically
+		result := make(map[string]any, len(v))
+		for _, key := range sortedKeys(v) {
+			expanded, err := it.expandValue(path+"/"+jsonptr.Escape(key), v[key])
+			if err != nil {
+				return nil, err
+			}
+			result[key] = expanded
+		}
+		return result, nil
+	case []any:
+		result := make([]any, len(v))
+		for i, child := range v {
+			expanded, err := it.expandValue(path+"/"+strconv.Itoa(i), child)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = expanded
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
+
+// expandPath expands the string value at path, caching the result.
+func (it *interpolator) expandPath(path, raw string) (any, error) {
+	if value, ok := it.expanded[path]; ok {
+		// A reference to a map or slice must not share it with the referenced path
+		return container.DeepCopyValue(value), nil
+	}
+	if !strings.Contains(raw, "${") {
+		return raw, nil
+	}
+	if it.resolving[path] {
+		cycle := append(append([]string(nil), it.stack[indexOf(it.stack, path):]...), path)
+		return nil, it.newError(path, "", fmt.Errorf("reference cycle: %s", strings.Join(cycle, " -> ")))
+	}
+
+	it.resolving[path] = true
+	it.stack = append(it.stack, path)
+	value, err := it.expandString(path, raw)
+	it.stack = it.stack[:len(it.stack)-1]
+	delete(it.resolving, path)
+	if err != nil {
+		return nil, err
+	}
+
+	it.expanded[path] = value
+	if s, ok := value.(string); !ok || s != raw {
+		it.changed[path] = value
+	}
+	return value, nil
+}
+
+// expandString parses raw and substitutes its references.
+func (it *interpolator) expandString(path, raw string) (any, error) {
+	var sb strings.Builder
+	rest := raw
+	for {
+		i := strings.Index(rest, "${")
+		if i < 0 {
+			sb.WriteString(rest)
+			break
+		}
+		// "$${" escapes a literal "${"
+		if i > 0 && rest[i-1] == '$' {
+			sb.WriteString(rest[:i-1])
+			sb.WriteString("${")
+			rest = rest[i+2:]
+			continue
+		}
+		sb.WriteString(rest[:i])
+
+		end := strings.IndexByte(rest[i:], '}')
+		if end < 0 {
+			return nil, it.newError(path, "", fmt.Errorf("unterminated reference in %q", raw))
+		}
+		ref := rest[i+2 : i+end]
+		value, err := it.lookup(path, ref)
+		if err != nil {
+			return nil, err
+		}
+
+		// A string that is a single reference keeps the referenced type
+		if i == 0 && end == len(rest)-1 && rest == raw {
+			return value, nil
+		}
+		switch v := value.(type) {
+		case nil:
+		case map[string]any, []any:
+			return nil, it.newError(path, "${"+ref+"}", fmt.Errorf("cannot embed %T in a string", v))
+		default:
+			fmt.Fprint(&sb, v)
+		}
+		rest = rest[i+end+1:]
+	}
+	return sb.String(), nil
+}
+
+// lookup resolves a single reference found in the value at path.
+func (it *interpolator) lookup(path, ref string) (any, error) {
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		name := strings.TrimPrefix(ref, "env:")
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, it.newError(path, "${"+ref+"}", fmt.Errorf("environment variable %s is not set", name))
+		}
+		return value, nil
+	case ref == "home":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, it.newError(path, "${"+ref+"}", err)
+		}
+		return home, nil
+	case strings.HasPrefix(ref, "/"):
+		raw, ok := jsonptr.GetPath(it.root, ref)
+		if !ok {
+			return nil, it.newError(path, "${"+ref+"}", errors.New("path not found"))
+		}
+		return it.expandValue(ref, raw)
+	default:
+		return nil, it.newError(path, "${"+ref+"}", errors.New("unknown reference"))
+	}
+}
+
+// newError builds an InterpolationError with the origin of the value at path.
+func (it *interpolator) newError(path, ref string, err error) *InterpolationError {
+	ierr := &InterpolationError{Path: path, Reference: ref, Err: err}
+	if it.origins != nil {
+		if entry := it.origins.getLeaf(path); entry != nil {
+			ierr.Layer = layerInfoAt(entry, it.origins.sourcePath(path, entry))
+		}
+	}
+	return ierr
+}
+
+// indexOf returns the index of s in list, or 0 if it is not present.
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,340 @@
+package jubako
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/yacchi/jubako/internal/tag"
+	"github.com/yacchi/jubako/jsonptr"
+)
+
+// Keywords of the generated JSON Schema.
+const (
+	// jsonSchemaDraft is the dialect of the schemas generated by JSONSchema.
+	jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+	// jsonSchemaSensitive marks fields declared with the sensitive directive.
+	jsonSchemaSensitive = "x-jubako-sensitive"
+	// jsonSchemaEnv names the environment variable from the env: directive.
+	jsonSchemaEnv = "x-jubako-env"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// JSONSchema returns a JSON Schema (draft 2020-12) describing the documents
+// of the Store's layers, so that editors can validate and complete
+// configuration files written in YAML, TOML or JSON.
+//
+// The schema follows the paths the Store reads values from: field names come
+// from the tag configured with WithTagName, and fields remapped with jubako
+// struct tags appear at their remapped paths. Aliases appear as additional
+// properties, marked deprecated with the deprecated directive. Constraints
+// and defaults from jubako struct tags are translated to the corresponding
+// keywords. Sensitive fields are marked writeOnly and x-jubako-sensitive, and
+// the x-jubako-env keyword names the environment variable of the env: directive.
+//
+// Since each layer usually holds a partial configuration, required
+// constraints are not part of the schema. Unknown keys are rejected with
+// additionalProperties, like WithUnknownKeys does.
+//
+// Example:
+//
+//	data, err := store.JSONSchema()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	os.WriteFile("config.schema.json", data, 0o644)
+func (s *Store[T]) JSONSchema() ([]byte, error) {
+	var zero T
+	t := reflect.TypeOf(zero)
+
+	gen := jsonSchemaGenerator{
+		tagName:        s.tagName,
+		tagDelimiter:   s.tagDelimiter,
+		valueConverter: s.convertValue,
+		primary:        make(map[*PathMapping]*jsonSchemaNode),
+	}
+	root := newJSONSchemaNode()
+	for _, m := range s.schema.Mappings {
+		if m.Skipped || m.Path == "" {
+			continue
+		}
+		if node := root.insert(m.Path, m, false); node != nil {
+			gen.primary[m] = node
+		}
+		for _, aliasPath := range m.AliasPaths {
+			root.insert(aliasPath, m, true)
+		}
+	}
+
+	schema := gen.typeSchema(t, root)
+	result := map[string]any{"$schema": jsonSchemaDraft}
+	if t != nil && t.Name() != "" {
+		result["title"] = t.Name()
+	}
+	for key, value := range schema {
+		result[key] = value
+	}
+	return json.MarshalIndent(result, "", "  ")
+}
+
+// jsonSchemaNode is a node of the document tree built from the schema paths.
+type jsonSchemaNode struct {
+	children map[string]*jsonSchemaNode
+	// keys lists the children in insertion (declaration) order.
+	keys     []string
+	wildcard *jsonSchemaNode
+	mapping  *PathMapping
+	// alias is true if the node is an alias of mapping.
+	alias bool
+}
+
+func newJSONSchemaNode() *jsonSchemaNode {
+	return &jsonSchemaNode{children: make(map[string]*jsonSchemaNode)}
+}
+
+// insert adds the mapping at path. The first mapping inserted at a path wins.
+// It returns the node of m, or nil if another mapping was inserted at path.
+func (n *jsonSchemaNode) insert(path string, m *PathMapping, alias bool) *jsonSchemaNode {
+	segments, err := jsonptr.Parse(path)
+	if err != nil || len(segments) == 0 {
+		return nil
+	}
+	node := n
+	for _, seg := range segments {
+		if seg == "*" {
+			if node.wildcard == nil {
+				node.wildcard = newJSONSchemaNode()
+			}
+			node = node.wildcard
+			continue
+		}
+		child, ok := node.children[seg]
+		if !ok {
+			child = newJSONSchemaNode()
+			node.children[seg] = child
+			node.keys = append(node.keys, seg)
+		}
+		node = child
+	}
+	if node.mapping != nil {
+		return nil
+	}
+	node.mapping = m
+	node.alias = alias
+	return node
+}
+
+// jsonSchemaGenerator converts Go types and their mappings to JSON Schema.
+type jsonSchemaGenerator struct {
+	tagName        string
+	tagDelimiter   string
+	valueConverter ValueConverter
+	// primary maps each mapping to the node at its path, for aliases.
+	primary map[*PathMapping]*jsonSchemaNode
+}
+
+// nodeSchema returns the schema of a node: the schema of its field type with
+// the keywords of its jubako struct tag, or an object of its children if no
+// field maps to it.
+func (g jsonSchemaGenerator) nodeSchema(node *jsonSchemaNode) map[string]any {
+	if node.mapping == nil {
+		return g.objectSchema(node)
+	}
+	m := node.mapping
+	if node.alias {
+		// Aliases accept the same values as the field's path
+		schema := map[string]any{}
+		if primary := g.primary[m]; primary != nil {
+			schema = g.nodeSchema(primary)
+			delete(schema, jsonSchemaEnv)
+			delete(schema, "default")
+		}
+		schema["description"] = "Alias of " + m.Path + "."
+		if m.Deprecated {
+			schema["deprecated"] = true
+		}
+		return schema
+	}
+	schema := g.typeSchema(m.FieldType, node)
+	g.annotate(schema, m)
+	return schema
+}
+
+// objectSchema returns a closed object schema with the children of node as
+// properties, or an open object schema if node is nil.
+func (g jsonSchemaGenerator) objectSchema(node *jsonSchemaNode) map[string]any {
+	schema := map[string]any{"type": "object"}
+	if node == nil {
+		return schema
+	}
+	if len(node.children) > 0 {
+		properties := make(map[string]any, len(node.children))
+		for key, child := range node.children {
+			properties[key] = g.nodeSchema(child)
+		}
+		schema["properties"] = properties
+	}
+	if node.wildcard != nil {
+		schema["additionalProperties"] = g.nodeSchema(node.wildcard)
+	} else {
+		schema["additionalProperties"] = false
+	}
+	return schema
+}
+
+// typeSchema returns the schema of values of type t. node holds the mappings
+// below the value, or is nil for types without mappings (e.g., map values).
+func (g jsonSchemaGenerator) typeSchema(t reflect.Type, node *jsonSchemaNode) map[string]any {
+	if t == nil {
+		return map[string]any{}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case reflect.PointerTo(t).Implements(jsonUnmarshalerType):
+		// Custom JSON decoding accepts any shape
+		return map[string]any{}
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			// encoding/json encodes []byte as a base64 string
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": g.elemSchema(t.Elem(), node)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.elemSchema(t.Elem(), node)}
+	case reflect.Struct:
+		// Structs without mappings (e.g., in slices of maps) are left open
+		return g.objectSchema(node)
+	default:
+		// Interfaces accept any value
+		return map[string]any{}
+	}
+}
+
+// elemSchema returns the schema of the elements of a slice or map.
+func (g jsonSchemaGenerator) elemSchema(elem reflect.Type, node *jsonSchemaNode) map[string]any {
+	if node != nil {
+		return g.typeSchema(elem, node.wildcard)
+	}
+	return g.typeSchema(elem, nil)
+}
+
+// annotate adds the keywords of the jubako struct tag of m to schema.
+func (g jsonSchemaGenerator) annotate(schema map[string]any, m *PathMapping) {
+	if m.Sensitive == sensitiveExplicit {
+		schema["writeOnly"] = true
+		schema[jsonSchemaSensitive] = true
+	}
+	if info := tag.Parse(m.StructField, g.tagName, g.tagDelimiter); info.EnvVar != "" {
+		schema[jsonSchemaEnv] = info.EnvVar
+	}
+
+	fieldType := m.FieldType
+	for fieldType != nil && fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	c := m.Constraints
+	if c.Min != nil || c.Max != nil {
+		var minKey, maxKey string
+		switch schema["type"] {
+		case "integer", "number":
+			minKey, maxKey = "minimum", "maximum"
+		case "string":
+			minKey, maxKey = "minLength", "maxLength"
+		case "array":
+			minKey, maxKey = "minItems", "maxItems"
+		case "object":
+			minKey, maxKey = "minProperties", "maxProperties"
+		}
+		if minKey != "" && c.Min != nil {
+			schema[minKey] = jsonSchemaBound(*c.Min, minKey)
+		}
+		if maxKey != "" && c.Max != nil {
+			schema[maxKey] = jsonSchemaBound(*c.Max, maxKey)
+		}
+	}
+	if len(c.Enum) > 0 {
+		values := make([]any, 0, len(c.Enum))
+		for _, raw := range c.Enum {
+			values = append(values, jsonSchemaEnumValue(raw, schema["type"]))
+		}
+		schema["enum"] = values
+	}
+	if c.Pattern != "" && schema["type"] == "string" {
+		schema["pattern"] = c.Pattern
+	}
+	if m.HasDefault && fieldType != nil {
+		if value, ok := g.defaultValue(m.Path, m.Default, fieldType); ok {
+			schema["default"] = value
+		}
+	}
+}
+
+// jsonSchemaBound returns a min= or max= bound for key. Length bounds are integers.
+func jsonSchemaBound(bound float64, key string) any {
+	if key == "minimum" || key == "maximum" {
+		return bound
+	}
+	return int(bound)
+}
+
+// jsonSchemaEnumValue converts an enum= value to the JSON type of the field.
+func jsonSchemaEnumValue(raw string, typ any) any {
+	switch typ {
+	case "integer", "number":
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
+
+// defaultValue converts a default= value like the defaults layer does.
+func (g jsonSchemaGenerator) defaultValue(path, raw string, fieldType reflect.Type) (any, bool) {
+	switch fieldType.Kind() {
+	case reflect.String:
+		return raw, true
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct, reflect.Interface:
+		var value any
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return raw, fieldType.Kind() == reflect.Interface || fieldType.Kind() == reflect.Struct
+		}
+		return value, true
+	default:
+		if g.valueConverter == nil {
+			return nil, false
+		}
+		value, err := g.valueConverter(path, raw, fieldType)
+		return value, err == nil
+	}
+}
@@ -0,0 +1,455 @@
+// Package jsonschema validates decoded configuration data against a JSON Schema.
+//
+// It implements the validation vocabulary of JSON Schema draft 2020-12 for
+// self-contained schemas: type, enum, const, numeric, string, array and
+// object keywords, the allOf/anyOf/oneOf/not and if/then/else applicators,
+// and $ref to locations within the same document (such as "#/$defs/name").
+// The array form of items and additionalItems from draft-07 are accepted as
+// well. Annotations such as format, title and description are ignored.
+//
+// Values are expected in the form produced by Jubako's formats: maps as
+// map[string]any, arrays as []any, and numbers of any Go numeric type.
+//
+// Reference: https://json-schema.org/draft/2020-12/json-schema-validation
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yacchi/jubako/jsonptr"
+)
+
+// Schema is a compiled JSON Schema.
+// A Schema is immutable and safe for concurrent use.
+type Schema struct {
+	// boolean is set for the boolean schemas true and false.
+	boolean *bool
+
+	types    []string
+	enum     []any
+	constant any
+	hasConst bool
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	prefixItems []*Schema
+	items       *Schema
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+	contains    *Schema
+	minContains *int
+	maxContains *int
+
+	properties           map[string]*Schema
+	patternProperties    []patternSchema
+	additionalProperties *Schema
+	propertyNames        *Schema
+	required             []string
+	minProperties        *int
+	maxProperties        *int
+	dependentRequired    map[string][]string
+	dependentSchemas     map[string]*Schema
+
+	allOf     []*Schema
+	anyOf     []*Schema
+	oneOf     []*Schema
+	not       *Schema
+	ifSchema  *Schema
+	thenElse  [2]*Schema
+	ref       string
+	refSchema *Schema
+}
+
+// patternSchema is a schema from patternProperties.
+type patternSchema struct {
+	pattern *regexp.Regexp
+	schema  *Schema
+}
+
+// Compile parses a JSON Schema document.
+// It returns an error if the document is not valid JSON, if a keyword has a
+// value of the wrong type, or if a $ref cannot be resolved within the document.
+func Compile(data []byte) (*Schema, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	c := &compiler{root: doc, schemas: make(map[string]*Schema)}
+	schema, err := c.compile(doc, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	// References are resolved once every schema is compiled, so that
+	// recursive references point to the same Schema.
+	for i := 0; i < len(c.refs); i++ {
+		s := c.refs[i]
+		target, err := c.resolve(s.ref)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+		}
+		s.refSchema = target
+	}
+	return schema, nil
+}
+
+// MustCompile is like Compile but panics if the schema cannot be compiled.
+func MustCompile(data []byte) *Schema {
+	s, err := Compile(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// compiler holds the state of a Compile call.
+type compiler struct {
+	root any
+	// schemas maps JSON Pointers within the document to compiled schemas.
+	schemas map[string]*Schema
+	// refs are the schemas with a $ref to resolve.
+	refs []*Schema
+}
+
+// resolve returns the schema referenced by ref.
+func (c *compiler) resolve(ref string) (*Schema, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %q: only references within the document are supported", ref)
+	}
+	ptr := strings.TrimPrefix(ref, "#")
+	if s, ok := c.schemas[ptr]; ok {
+		return s, nil
+	}
+	segments, err := jsonptr.Parse(ptr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid $ref %q: %w", ref, err)
+	}
+	value := c.root
+	for _, seg := range segments {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[seg]
+			if !ok {
+				return nil, fmt.Errorf("$ref %q not found", ref)
+			}
+			value = next
+		case []any:
+			index, err := strconv.Atoi(seg)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, fmt.Errorf("$ref %q not found", ref)
+			}
+			value = v[index]
+		default:
+			return nil, fmt.Errorf("$ref %q not found", ref)
+		}
+	}
+	return c.compile(value, ptr)
+}
+
+// compile compiles the schema value found at ptr.
+func (c *compiler) compile(value any, ptr string) (*Schema, error) {
+	if s, ok := c.schemas[ptr]; ok {
+		return s, nil
+	}
+	s := &Schema{}
+	c.schemas[ptr] = s
+
+	switch v := value.(type) {
+	case bool:
+		s.boolean = &v
+		return s, nil
+	case map[string]any:
+		if err := c.compileKeywords(s, v, ptr); err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("%s: schema must be an object or a boolean", displayPath(ptr))
+	}
+}
+
+// compileKeywords compiles the keywords of an object schema into s.
+func (c *compiler) compileKeywords(s *Schema, obj map[string]any, ptr string) error {
+	var err error
+	sub := func(keyword string) (*Schema, error) {
+		value, ok := obj[keyword]
+		if !ok {
+			return nil, nil
+		}
+		return c.compile(value, ptr+"/"+jsonptr.Escape(keyword))
+	}
+	subList := func(keyword string) ([]*Schema, error) {
+		value, ok := obj[keyword]
+		if !ok {
+			return nil, nil
+		}
+		list, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s/%s: must be an array", displayPath(ptr), keyword)
+		}
+		schemas := make([]*Schema, len(list))
+		for i, item := range list {
+			if schemas[i], err = c.compile(item, fmt.Sprintf("%s/%s/%d", ptr, keyword, i)); err != nil {
+				return nil, err
+			}
+		}
+		return schemas, nil
+	}
+	subMap := func(keyword string) (map[string]*Schema, error) {
+		value, ok := obj[keyword]
+		if !ok {
+			return nil, nil
+		}
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s/%s: must be an object", displayPath(ptr), keyword)
+		}
+		schemas := make(map[string]*Schema, len(m))
+		for key, item := range m {
+			if schemas[key], err = c.compile(item, ptr+"/"+keyword+"/"+jsonptr.Escape(key)); err != nil {
+				return nil, err
+			}
+		}
+		return schemas, nil
+	}
+	number := func(keyword string) (*float64, error) {
+		value, ok := obj[keyword]
+		if !ok {
+			return nil, nil
+		}
+		f, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s/%s: must be a number", displayPath(ptr), keyword)
+		}
+		return &f, nil
+	}
+	count := func(keyword string) (*int, error) {
+		f, err := number(keyword)
+		if err != nil || f == nil {
+			return nil, err
+		}
+		if *f < 0 || *f != float64(int(*f)) {
+			return nil, fmt.Errorf("%s/%s: must be a non-negative integer", displayPath(ptr), keyword)
+		}
+		n := int(*f)
+		return &n, nil
+	}
+	regex := func(keyword, expr string) (*regexp.Regexp, error) {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", displayPath(ptr), keyword, err)
+		}
+		return re, nil
+	}
+
+	// $defs and definitions are compiled when referenced.
+	if ref, ok := obj["$ref"]; ok {
+		str, ok := ref.(string)
+		if !ok {
+			return fmt.Errorf("%s/$ref: must be a string", displayPath(ptr))
+		}
+		s.ref = str
+		c.refs = append(c.refs, s)
+	}
+
+	switch t := obj["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []any:
+		for _, item := range t {
+			str, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("%s/type: must be a string or an array of strings", displayPath(ptr))
+			}
+			s.types = append(s.types, str)
+		}
+	default:
+		return fmt.Errorf("%s/type: must be a string or an array of strings", displayPath(ptr))
+	}
+	if enum, ok := obj["enum"]; ok {
+		list, ok := enum.([]any)
+		if !ok {
+			return fmt.Errorf("%s/enum: must be an array", displayPath(ptr))
+		}
+		s.enum = list
+	}
+	s.constant, s.hasConst = obj["const"]
+
+	if s.minimum, err = number("minimum"); err != nil {
+		return err
+	}
+	if s.maximum, err = number("maximum"); err != nil {
+		return err
+	}
+	if s.exclusiveMinimum, err = number("exclusiveMinimum"); err != nil {
+		return err
+	}
+	if s.exclusiveMaximum, err = number("exclusiveMaximum"); err != nil {
+		return err
+	}
+	if s.multipleOf, err = number("multipleOf"); err != nil {
+		return err
+	}
+	if s.multipleOf != nil && *s.multipleOf <= 0 {
+		return fmt.Errorf("%s/multipleOf: must be greater than 0", displayPath(ptr))
+	}
+
+	if s.minLength, err = count("minLength"); err != nil {
+		return err
+	}
+	if s.maxLength, err = count("maxLength"); err != nil {
+		return err
+	}
+	if pattern, ok := obj["pattern"]; ok {
+		str, ok := pattern.(string)
+		if !ok {
+			return fmt.Errorf("%s/pattern: must be a string", displayPath(ptr))
+		}
+		if s.pattern, err = regex("pattern", str); err != nil {
+			return err
+		}
+	}
+
+	if s.prefixItems, err = subList("prefixItems"); err != nil {
+		return err
+	}
+	if _, ok := obj["items"].([]any); ok {
+		// Draft-07 tuple validation: items is an array and additionalItems
+		// applies to the remaining elements
+		if s.prefixItems, err = subList("items"); err != nil {
+			return err
+		}
+		if s.items, err = sub("additionalItems"); err != nil {
+			return err
+		}
+	} else if s.items, err = sub("items"); err != nil {
+		return err
+	}
+	if s.minItems, err = count("minItems"); err != nil {
+		return err
+	}
+	if s.maxItems, err = count("maxItems"); err != nil {
+		return err
+	}
+	if unique, ok := obj["uniqueItems"].(bool); ok {
+		s.uniqueItems = unique
+	}
+	if s.contains, err = sub("contains"); err != nil {
+		return err
+	}
+	if s.minContains, err = count("minContains"); err != nil {
+		return err
+	}
+	if s.maxContains, err = count("maxContains"); err != nil {
+		return err
+	}
+
+	if s.properties, err = subMap("properties"); err != nil {
+		return err
+	}
+	if patterns, ok := obj["patternProperties"].(map[string]any); ok {
+		keys := make([]string, 0, len(patterns))
+		for key := range patterns {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			re, err := regex("patternProperties", key)
+			if err != nil {
+				return err
+			}
+			schema, err := c.compile(patterns[key], ptr+"/patternProperties/"+jsonptr.Escape(key))
+			if err != nil {
+				return err
+			}
+			s.patternProperties = append(s.patternProperties, patternSchema{pattern: re, schema: schema})
+		}
+	}
+	if s.additionalProperties, err = sub("additionalProperties"); err != nil {
+		return err
+	}
+	if s.propertyNames, err = sub("propertyNames"); err != nil {
+		return err
+	}
+	if required, ok := obj["required"]; ok {
+		if s.required, err = stringList(required); err != nil {
+			return fmt.Errorf("%s/required: %w", displayPath(ptr), err)
+		}
+	}
+	if s.minProperties, err = count("minProperties"); err != nil {
+		return err
+	}
+	if s.maxProperties, err = count("maxProperties"); err != nil {
+		return err
+	}
+	if deps, ok := obj["dependentRequired"].(map[string]any); ok {
+		s.dependentRequired = make(map[string][]string, len(deps))
+		for key, value := range deps {
+			if s.dependentRequired[key], err = stringList(value); err != nil {
+				return fmt.Errorf("%s/dependentRequired/%s: %w", displayPath(ptr), key, err)
+			}
+		}
+	}
+	if s.dependentSchemas, err = subMap("dependentSchemas"); err != nil {
+		return err
+	}
+
+	if s.allOf, err = subList("allOf"); err != nil {
+		return err
+	}
+	if s.anyOf, err = subList("anyOf"); err != nil {
+		return err
+	}
+	if s.oneOf, err = subList("oneOf"); err != nil {
+		return err
+	}
+	if s.not, err = sub("not"); err != nil {
+		return err
+	}
+	if s.ifSchema, err = sub("if"); err != nil {
+		return err
+	}
+	if s.thenElse[0], err = sub("then"); err != nil {
+		return err
+	}
+	if s.thenElse[1], err = sub("else"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// stringList converts a JSON array of strings.
+func stringList(value any) ([]string, error) {
+	list, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("must be an array of strings")
+	}
+	result := make([]string, len(list))
+	for i, item := range list {
+		str, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be an array of strings")
+		}
+		result[i] = str
+	}
+	return result, nil
+}
+
+// displayPath returns ptr for error messages, using "#" for the root.
+func displayPath(ptr string) string {
+	return "#" + ptr
+}
@@ -0,0 +1,170 @@
+package jsonschema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{"invalid JSON", `{`, "invalid JSON Schema"},
+		{"not a schema", `1`, "#: schema must be an object or a boolean"},
+		{"bad type", `{"type": 1}`, "#/type: must be a string or an array of strings"},
+		{"bad minimum", `{"minimum": "1"}`, "#/minimum: must be a number"},
+		{"bad maxLength", `{"maxLength": -1}`, "#/maxLength: must be a non-negative integer"},
+		{"bad pattern", `{"pattern": "("}`, "#/pattern: error parsing regexp"},
+		{"bad multipleOf", `{"multipleOf": 0}`, "#/multipleOf: must be greater than 0"},
+		{"bad nested schema", `{"properties": {"a": 1}}`, "#/properties/a: schema must be an object or a boolean"},
+		{"bad required", `{"required": [1]}`, "#/required: must be an array of strings"},
+		{"remote ref", `{"$ref": "https://example.com/schema.json"}`, "only references within the document are supported"},
+		{"missing ref", `{"$ref": "#/$defs/missing"}`, `$ref "#/$defs/missing" not found`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile([]byte(tt.schema))
+			if err == nil {
+				t.Fatal("Compile() should fail")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Compile() error = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMustCompile_Panics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustCompile() should panic")
+		}
+	}()
+	MustCompile([]byte(`{"type": 1}`))
+}
+
+func TestSchema_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  any
+		want   []string
+	}{
+		{"true", `true`, "anything", nil},
+		{"false", `false`, "anything", []string{"/: no value is allowed"}},
+		{"type", `{"type": "integer"}`, "8080", []string{"/: expected integer, got string"}},
+		{"type integer from Go int", `{"type": "integer"}`, 8080, nil},
+		{"type integer from float", `{"type": "integer"}`, 1.5, []string{"/: expected integer, got number"}},
+		{"type number accepts integer", `{"type": "number"}`, int64(3), nil},
+		{"type list", `{"type": ["string", "null"]}`, nil, nil},
+		{"time is a string", `{"type": "string"}`, time.Unix(0, 0).UTC(), nil},
+		{"enum", `{"enum": ["dev", "prod"]}`, "test", []string{`/: must be one of ["dev", "prod"]`}},
+		{"enum number", `{"enum": [1, 2]}`, uint8(2), nil},
+		{"const", `{"const": {"a": [1]}}`, map[string]any{"a": []any{1}}, nil},
+		{"minimum", `{"minimum": 1}`, 0, []string{"/: must be >= 1"}},
+		{"maximum", `{"maximum": 65535}`, 70000, []string{"/: must be <= 65535"}},
+		{"exclusive", `{"exclusiveMinimum": 0, "exclusiveMaximum": 1}`, 1, []string{"/: must be < 1"}},
+		{"multipleOf", `{"multipleOf": 0.5}`, 1.25, []string{"/: must be a multiple of 0.5"}},
+		{"minLength runes", `{"minLength": 3}`, "日本", []string{"/: length must be >= 3"}},
+		{"maxLength", `{"maxLength": 2}`, "日本", nil},
+		{"pattern", `{"pattern": "^[a-z]+$"}`, "Dev", []string{`/: must match pattern "^[a-z]+$"`}},
+		{"items", `{"items": {"type": "string"}}`, []any{"a", 1}, []string{"/1: expected string, got integer"}},
+		{"typed slice", `{"items": {"type": "string"}}`, []string{"a", "b"}, nil},
+		{"prefixItems", `{"prefixItems": [{"type": "string"}], "items": false}`, []any{"a", "b"},
+			[]string{"/1: no value is allowed"}},
+		{"draft-07 items array", `{"items": [{"type": "string"}], "additionalItems": {"type": "integer"}}`,
+			[]any{"a", 1, "c"}, []string{"/2: expected integer, got string"}},
+		{"minItems maxItems", `{"minItems": 2, "maxItems": 3}`, []any{1}, []string{"/: must have at least 2 items"}},
+		{"uniqueItems", `{"uniqueItems": true}`, []any{1, 2, 1.0}, []string{"/: items 0 and 2 must be unique"}},
+		{"contains", `{"contains": {"type": "string"}, "maxContains": 1}`, []any{"a", "b"},
+			[]string{"/: must contain at most 1 matching items (found 2)"}},
+		{"required", `{"required": ["host", "port"]}`, map[string]any{"port": 80},
+			[]string{`/: missing required property "host"`}},
+		{"properties", `{"properties": {"port": {"type": "integer"}}}`, map[string]any{"port": "80"},
+			[]string{"/port: expected integer, got string"}},
+		{"additionalProperties false", `{"properties": {"a": true}, "additionalProperties": false}`,
+			map[string]any{"a": 1, "b": 2}, []string{"/b: property is not allowed"}},
+		{"additionalProperties schema", `{"additionalProperties": {"type": "string"}}`,
+			map[string]string{"a": "x"}, nil},
+		{"patternProperties", `{"patternProperties": {"^x-": {"type": "string"}}, "additionalProperties": false}`,
+			map[string]any{"x-a": 1}, []string{"/x-a: expected string, got integer"}},
+		{"propertyNames", `{"propertyNames": {"pattern": "^[a-z]+$"}}`, map[string]any{"A": 1},
+			[]string{"/A: property name is not allowed"}},
+		{"escaped path", `{"additionalProperties": false}`, map[string]any{"a/b": 1},
+			[]string{"/a~1b: property is not allowed"}},
+		{"min max properties", `{"minProperties": 1}`, map[string]any{}, []string{"/: must have at least 1 properties"}},
+		{"dependentRequired", `{"dependentRequired": {"tls": ["cert"]}}`, map[string]any{"tls": true},
+			[]string{`/: property "cert" is required when "tls" is present`}},
+		{"dependentSchemas", `{"dependentSchemas": {"tls": {"required": ["cert"]}}}`, map[string]any{"tls": true},
+			[]string{`/: missing required property "cert"`}},
+		{"allOf", `{"allOf": [{"minimum": 1}, {"maximum": 2}]}`, 3, []string{"/: must be <= 2"}},
+		{"anyOf", `{"anyOf": [{"type": "string"}, {"type": "boolean"}]}`, 1,
+			[]string{"/: must match at least one schema in anyOf"}},
+		{"oneOf", `{"oneOf": [{"type": "integer"}, {"type": "number"}]}`, 1,
+			[]string{"/: must match exactly one schema in oneOf (matched 2)"}},
+		{"not", `{"not": {"type": "null"}}`, nil, []string{"/: must not match the schema in not"}},
+		{"if then", `{"if": {"required": ["tls"]}, "then": {"required": ["cert"]}, "else": {"required": ["port"]}}`,
+			map[string]any{"tls": true}, []string{`/: missing required property "cert"`}},
+		{"if else", `{"if": {"required": ["tls"]}, "then": {"required": ["cert"]}, "else": {"required": ["port"]}}`,
+			map[string]any{}, []string{`/: missing required property "port"`}},
+		{"nested", `{"properties": {"server": {"properties": {"port": {"maximum": 65535}}}}}`,
+			map[string]any{"server": map[string]any{"port": 70000}}, []string{"/server/port: must be <= 65535"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := MustCompile([]byte(tt.schema))
+			var got []string
+			for _, v := range s.Validate(tt.value) {
+				got = append(got, v.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchema_Validate_Ref(t *testing.T) {
+	s := MustCompile([]byte(`{
+		"$defs": {
+			"node": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"},
+					"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}
+				},
+				"required": ["name"]
+			}
+		},
+		"$ref": "#/$defs/node"
+	}`))
+
+	valid := map[string]any{
+		"name": "root",
+		"children": []any{
+			map[string]any{"name": "a"},
+			map[string]any{"name": "b", "children": []any{}},
+		},
+	}
+	if violations := s.Validate(valid); violations != nil {
+		t.Errorf("Validate() = %v, want no violations", violations)
+	}
+
+	invalid := map[string]any{
+		"name": "root",
+		"children": []any{
+			map[string]any{"children": []any{map[string]any{"name": 1}}},
+		},
+	}
+	want := []Violation{
+		{Path: "/children/0", Keyword: "required", Message: `missing required property "name"`},
+		{Path: "/children/0/children/0/name", Keyword: "type", Message: "expected string, got integer"},
+	}
+	if got := s.Validate(invalid); !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() = %v, want %v", got, want)
+	}
+}
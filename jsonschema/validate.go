@@ -0,0 +1,415 @@
+package jsonschema
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yacchi/jubako/jsonptr"
+)
+
+// Violation describes a value that does not satisfy a schema keyword.
+type Violation struct {
+	// Path is the JSON Pointer of the value within the validated data.
+	Path string
+	// Keyword is the schema keyword that failed, e.g. "type" or "required".
+	Keyword string
+	// Message is a human-readable description of the violation.
+	Message string
+}
+
+// String returns the violation as "path: message", using "/" for the root.
+func (v Violation) String() string {
+	path := v.Path
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + v.Message
+}
+
+// Validate checks value against the schema and returns the violations, in
+// the order they are found. It returns nil if value is valid.
+func (s *Schema) Validate(value any) []Violation {
+	var violations []Violation
+	s.validate(value, "", &violations)
+	return violations
+}
+
+// valid reports whether value satisfies the schema.
+func (s *Schema) valid(value any) bool {
+	var violations []Violation
+	s.validate(value, "", &violations)
+	return len(violations) == 0
+}
+
+func (s *Schema) validate(value any, path string, out *[]Violation) {
+	report := func(keyword, format string, args ...any) {
+		*out = append(*out, Violation{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.boolean != nil {
+		if !*s.boolean {
+			report("false", "no value is allowed")
+		}
+		return
+	}
+
+	if s.refSchema != nil {
+		s.refSchema.validate(value, path, out)
+	}
+
+	value = normalize(value)
+	typ := typeOf(value)
+
+	if len(s.types) > 0 && !matchesType(value, typ, s.types) {
+		report("type", "expected %s, got %s", strings.Join(s.types, " or "), typ)
+		// Further keywords would only repeat the type mismatch
+		return
+	}
+	if s.enum != nil && !containsValue(s.enum, value) {
+		report("enum", "must be one of %s", formatValues(s.enum))
+	}
+	if s.hasConst && !equal(s.constant, value) {
+		report("const", "must be %s", formatValue(s.constant))
+	}
+
+	switch v := value.(type) {
+	case float64:
+		s.validateNumber(v, report)
+	case string:
+		s.validateString(v, report)
+	case []any:
+		s.validateArray(v, path, out, report)
+	case map[string]any:
+		s.validateObject(v, path, out, report)
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(value, path, out)
+	}
+	if len(s.anyOf) > 0 {
+		matched := false
+		for _, sub := range s.anyOf {
+			if sub.valid(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			report("anyOf", "must match at least one schema in anyOf")
+		}
+	}
+	if len(s.oneOf) > 0 {
+		matched := 0
+		for _, sub := range s.oneOf {
+			if sub.valid(value) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			report("oneOf", "must match exactly one schema in oneOf (matched %d)", matched)
+		}
+	}
+	if s.not != nil && s.not.valid(value) {
+		report("not", "must not match the schema in not")
+	}
+	if s.ifSchema != nil {
+		branch := s.thenElse[1]
+		if s.ifSchema.valid(value) {
+			branch = s.thenElse[0]
+		}
+		if branch != nil {
+			branch.validate(value, path, out)
+		}
+	}
+}
+
+func (s *Schema) validateNumber(v float64, report func(keyword, format string, args ...any)) {
+	if s.minimum != nil && v < *s.minimum {
+		report("minimum", "must be >= %s", formatNumber(*s.minimum))
+	}
+	if s.maximum != nil && v > *s.maximum {
+		report("maximum", "must be <= %s", formatNumber(*s.maximum))
+	}
+	if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+		report("exclusiveMinimum", "must be > %s", formatNumber(*s.exclusiveMinimum))
+	}
+	if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+		report("exclusiveMaximum", "must be < %s", formatNumber(*s.exclusiveMaximum))
+	}
+	if s.multipleOf != nil {
+		if q := v / *s.multipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+			report("multipleOf", "must be a multiple of %s", formatNumber(*s.multipleOf))
+		}
+	}
+}
+
+func (s *Schema) validateString(v string, report func(keyword, format string, args ...any)) {
+	length := utf8.RuneCountInString(v)
+	if s.minLength != nil && length < *s.minLength {
+		report("minLength", "length must be >= %d", *s.minLength)
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		report("maxLength", "length must be <= %d", *s.maxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(v) {
+		report("pattern", "must match pattern %q", s.pattern.String())
+	}
+}
+
+func (s *Schema) validateArray(v []any, path string, out *[]Violation, report func(keyword, format string, args ...any)) {
+	if s.minItems != nil && len(v) < *s.minItems {
+		report("minItems", "must have at least %d items", *s.minItems)
+	}
+	if s.maxItems != nil && len(v) > *s.maxItems {
+		report("maxItems", "must have at most %d items", *s.maxItems)
+	}
+	if s.uniqueItems {
+	unique:
+		for i := range v {
+			for j := 0; j < i; j++ {
+				if equal(v[i], v[j]) {
+					report("uniqueItems", "items %d and %d must be unique", j, i)
+					break unique
+				}
+			}
+		}
+	}
+	for i, elem := range v {
+		elemPath := path + "/" + strconv.Itoa(i)
+		switch {
+		case i < len(s.prefixItems):
+			s.prefixItems[i].validate(elem, elemPath, out)
+		case s.items != nil:
+			s.items.validate(elem, elemPath, out)
+		}
+	}
+	if s.contains != nil {
+		matched := 0
+		for _, elem := range v {
+			if s.contains.valid(elem) {
+				matched++
+			}
+		}
+		minContains := 1
+		if s.minContains != nil {
+			minContains = *s.minContains
+		}
+		if matched < minContains {
+			report("contains", "must contain at least %d matching items (found %d)", minContains, matched)
+		}
+		if s.maxContains != nil && matched > *s.maxContains {
+			report("maxContains", "must contain at most %d matching items (found %d)", *s.maxContains, matched)
+		}
+	}
+}
+
+func (s *Schema) validateObject(v map[string]any, path string, out *[]Violation, report func(keyword, format string, args ...any)) {
+	if s.minProperties != nil && len(v) < *s.minProperties {
+		report("minProperties", "must have at least %d properties", *s.minProperties)
+	}
+	if s.maxProperties != nil && len(v) > *s.maxProperties {
+		report("maxProperties", "must have at most %d properties", *s.maxProperties)
+	}
+	for _, name := range s.required {
+		if _, ok := v[name]; !ok {
+			report("required", "missing required property %q", name)
+		}
+	}
+	for _, key := range sortedKeys(v) {
+		if deps, ok := s.dependentRequired[key]; ok {
+			for _, name := range deps {
+				if _, ok := v[name]; !ok {
+					report("dependentRequired", "property %q is required when %q is present", name, key)
+				}
+			}
+		}
+		if dep, ok := s.dependentSchemas[key]; ok {
+			dep.validate(v, path, out)
+		}
+	}
+
+	for _, key := range sortedKeys(v) {
+		child := v[key]
+		childPath := path + "/" + jsonptr.Escape(key)
+		if s.propertyNames != nil && !s.propertyNames.valid(key) {
+			*out = append(*out, Violation{Path: childPath, Keyword: "propertyNames", Message: "property name is not allowed"})
+		}
+
+		evaluated := false
+		if sub, ok := s.properties[key]; ok {
+			sub.validate(child, childPath, out)
+			evaluated = true
+		}
+		for _, ps := range s.patternProperties {
+			if ps.pattern.MatchString(key) {
+				ps.schema.validate(child, childPath, out)
+				evaluated = true
+			}
+		}
+		if evaluated || s.additionalProperties == nil {
+			continue
+		}
+		if b := s.additionalProperties.boolean; b != nil && !*b {
+			*out = append(*out, Violation{Path: childPath, Keyword: "additionalProperties", Message: "property is not allowed"})
+			continue
+		}
+		s.additionalProperties.validate(child, childPath, out)
+	}
+}
+
+// normalize converts Go numbers to float64 and time values to strings, so
+// that data decoded by any format compares like JSON.
+func normalize(value any) any {
+	switch v := value.(type) {
+	case nil, bool, string, float64, map[string]any, []any:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Slice, reflect.Array:
+		list := make([]any, rv.Len())
+		for i := range list {
+			list[i] = rv.Index(i).Interface()
+		}
+		return list
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return value
+		}
+		m := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		return m
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return normalize(rv.Elem().Interface())
+	}
+	return value
+}
+
+// typeOf returns the JSON type of a normalized value.
+func typeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// matchesType reports whether a value of JSON type typ matches one of types.
+func matchesType(value any, typ string, types []string) bool {
+	for _, t := range types {
+		if t == typ || (t == "number" && typ == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares two values as JSON values.
+func equal(a, b any) bool {
+	a, b = normalize(a), normalize(b)
+	switch av := a.(type) {
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, value := range av {
+			other, ok := bv[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func containsValue(list []any, value any) bool {
+	for _, item := range list {
+		if equal(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return formatNumber(v)
+	case nil:
+		return "null"
+	}
+	return fmt.Sprint(value)
+}
+
+func formatValues(values []any) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = formatValue(value)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
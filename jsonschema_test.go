@@ -0,0 +1,119 @@
+package jubako
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type jsonSchemaBackend struct {
+	Host string `yaml:"host" jubako:"alias=hostname"`
+}
+
+type jsonSchemaConfig struct {
+	Server struct {
+		Port    int           `yaml:"port" jubako:"env:PORT,min=1,max=65535,default=8080,alias=/server/listen_port,deprecated"`
+		Mode    string        `yaml:"mode" jubako:"enum=dev|prod,pattern=^[a-z]+$"`
+		Timeout time.Duration `yaml:"timeout"`
+	} `yaml:"server"`
+	Password string                       `yaml:"password" jubako:"/auth/password,sensitive"`
+	Backends map[string]jsonSchemaBackend `yaml:"backends"`
+	Labels   map[string]string            `yaml:"labels"`
+	Tags     []string                     `yaml:"tags" jubako:"max=3"`
+	Ratio    *float64                     `yaml:"ratio"`
+	Retries  uint                         `yaml:"retries"`
+	Created  time.Time                    `yaml:"created"`
+	Extra    any                          `yaml:"extra"`
+	Internal string                       `yaml:"internal" jubako:"-"`
+}
+
+func TestStore_JSONSchema(t *testing.T) {
+	store := New[jsonSchemaConfig](WithTagName("yaml"))
+	data, err := store.JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema() error = %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("JSONSchema() returned invalid JSON: %v", err)
+	}
+
+	want := map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "jsonSchemaConfig",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"server": map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties": map[string]any{
+					"port": map[string]any{
+						"type":         "integer",
+						"minimum":      float64(1),
+						"maximum":      float64(65535),
+						"default":      float64(8080),
+						"x-jubako-env": "PORT",
+					},
+					"listen_port": map[string]any{
+						"type":        "integer",
+						"minimum":     float64(1),
+						"maximum":     float64(65535),
+						"description": "Alias of /server/port.",
+						"deprecated":  true,
+					},
+					"mode": map[string]any{
+						"type":    "string",
+						"enum":    []any{"dev", "prod"},
+						"pattern": "^[a-z]+$",
+					},
+					"timeout": map[string]any{"type": "integer"},
+				},
+			},
+			"auth": map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties": map[string]any{
+					"password": map[string]any{
+						"type":               "string",
+						"writeOnly":          true,
+						"x-jubako-sensitive": true,
+					},
+				},
+			},
+			"backends": map[string]any{
+				"type": "object",
+				"additionalProperties": map[string]any{
+					"type":                 "object",
+					"additionalProperties": false,
+					"properties": map[string]any{
+						"host": map[string]any{"type": "string"},
+						"hostname": map[string]any{
+							"type":        "string",
+							"description": "Alias of /backends/*/host.",
+						},
+					},
+				},
+			},
+			"labels": map[string]any{
+				"type":                 "object",
+				"additionalProperties": map[string]any{"type": "string"},
+			},
+			"tags": map[string]any{
+				"type":     "array",
+				"items":    map[string]any{"type": "string"},
+				"maxItems": float64(3),
+			},
+			"ratio":   map[string]any{"type": "number"},
+			"retries": map[string]any{"type": "integer", "minimum": float64(0)},
+			"created": map[string]any{"type": "string", "format": "date-time"},
+			"extra":   map[string]any{},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.MarshalIndent(got, "", "  ")
+		t.Errorf("JSONSchema() =\n%s", gotJSON)
+	}
+}
@@ -55,6 +55,19 @@ type SnapshotAwareLayer interface {
 	Stabilize(ctx context.Context, c StabilizeContext) (*StabilizeResult, error)
 }
 
+// FillLayer is an optional extension for layers that only supply values missing
+// from all other layers, such as defaults derived from struct tags.
+//
+// When Fill returns true, the Store merges the layer after all non-fill layers,
+// regardless of priority: its values never override another layer's value or
+// tombstone, and its slices fill the elements of the merged slice at the same
+// index instead of replacing it. Snapshot-aware fill layers receive a snapshot
+// that excludes fill layers.
+type FillLayer interface {
+	Layer
+	Fill() bool
+}
+
 // SaveContext provides logical before/after views for context-aware save implementations.
 type SaveContext interface {
 	Logical() map[string]any
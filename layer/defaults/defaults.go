@@ -0,0 +1,383 @@
+// Package defaults provides a layer that supplies the default values declared
+// with the default= directive in jubako struct tags.
+//
+// The layer is built from the Store's schema, so the struct definition is the
+// single source of truth for defaults:
+//
+//	type Config struct {
+//	    Port    int           `json:"port" jubako:"default=8080"`
+//	    Timeout time.Duration `json:"timeout" jubako:"default=30s"`
+//	    Tags    []string      `json:"tags" jubako:"default=[\"a\",\"b\"]"`
+//	    Servers []Server      `json:"servers"`
+//	}
+//
+//	type Server struct {
+//	    Host string `json:"host"`
+//	    Port int    `json:"port" jubako:"default=80"`
+//	}
+//
+//	store := jubako.New[Config]()
+//	store.Add(defaults.New())
+//
+// Defaults inside slice elements and map values apply to every element present
+// in the other layers. The layer is a fill layer (layer.FillLayer): it only
+// supplies values that no other layer sets, regardless of its priority.
+package defaults
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/yacchi/jubako/container"
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/internal/tag"
+	"github.com/yacchi/jubako/jsonptr"
+	"github.com/yacchi/jubako/layer"
+	"github.com/yacchi/jubako/source"
+	"github.com/yacchi/jubako/types"
+	"github.com/yacchi/jubako/watcher"
+)
+
+// DefaultName is the default layer name, reported as the origin of default values.
+const DefaultName layer.Name = "defaults(tag)"
+
+// TypeDefaults is the source type identifier for the defaults layer.
+const TypeDefaults types.SourceType = "defaults"
+
+// wildcard is the schema path segment that matches any map key or slice index.
+const wildcard = "*"
+
+// Option configures the defaults layer.
+type Option func(*Layer)
+
+// WithName sets the layer name (default: DefaultName).
+func WithName(name layer.Name) Option {
+	return func(l *Layer) {
+		l.name = name
+	}
+}
+
+// Layer supplies default values from jubako struct tags.
+// Use New to create it through Store.Add.
+type Layer struct {
+	name     layer.Name
+	defaults []fieldDefault
+	err      error
+}
+
+// fieldDefault is a parsed default= directive.
+type fieldDefault struct {
+	path     string
+	segments []string
+	value    any
+	wildcard bool
+}
+
+// Ensure Layer implements the layer interfaces.
+var (
+	_ layer.Layer              = (*Layer)(nil)
+	_ layer.SnapshotAwareLayer = (*Layer)(nil)
+	_ layer.FillLayer          = (*Layer)(nil)
+)
+
+// New returns a Store-aware layer that builds its data from the default=
+// directives in the Store's schema. Values are converted to the field types
+// with the Store's ValueConverter; slices, maps and structs use JSON syntax.
+//
+// Example:
+//
+//	store.Add(defaults.New())
+//	store.GetAt("/port").Layer.Name() // "defaults(tag)" unless set by another layer
+func New(opts ...Option) layer.Layer {
+	return layer.StoreAwareLayerFunc(func(provider layer.StoreProvider) layer.Layer {
+		l := &Layer{name: DefaultName}
+		for _, opt := range opts {
+			opt(l)
+		}
+		l.defaults, l.err = buildDefaults(provider)
+		return l
+	})
+}
+
+// buildDefaults collects the default= directives from the schema descriptors.
+func buildDefaults(provider layer.StoreProvider) ([]fieldDefault, error) {
+	var defaults []fieldDefault
+	for _, d := range provider.SchemaView().Descriptors() {
+		raw, ok := d.Tag(tag.JubakoTagName)
+		if !ok {
+			continue
+		}
+		info := tag.ParseJubakoTag(raw, provider.TagDelimiter())
+		if !info.HasDefault {
+			continue
+		}
+
+		segments, err := jsonptr.Parse(d.Path())
+		if err != nil {
+			return nil, fmt.Errorf("invalid default path %q: %w", d.Path(), err)
+		}
+		value, err := convertDefault(provider, d.Path(), info.Default, d.StructField().Type)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default %q for %s: %w", info.Default, d.Path(), err)
+		}
+
+		fd := fieldDefault{path: d.Path(), segments: segments, value: value}
+		for _, segment := range segments {
+			if segment == wildcard {
+				fd.wildcard = true
+				break
+			}
+		}
+		defaults = append(defaults, fd)
+	}
+
+	// More specific defaults are placed first so that they win over
+	// defaults of enclosing maps and structs.
+	sort.Slice(defaults, func(i, j int) bool {
+		if len(defaults[i].segments) != len(defaults[j].segments) {
+			return len(defaults[i].segments) > len(defaults[j].segments)
+		}
+		return defaults[i].path < defaults[j].path
+	})
+	return defaults, nil
+}
+
+// convertDefault converts the raw default string to a value for fieldType.
+// Slices, arrays, maps and structs are parsed as JSON; other types go through
+// the Store's ValueConverter (see layer.ValueConverterProvider), or are parsed
+// as JSON literals when the provider does not convert values.
+func convertDefault(provider layer.StoreProvider, path, raw string, fieldType reflect.Type) (any, error) {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	switch fieldType.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct, reflect.Interface:
+		var value any
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			if fieldType.Kind() == reflect.Interface || fieldType.Kind() == reflect.Struct {
+				// Not JSON: keep the string (e.g., time.Time in RFC 3339)
+				return raw, nil
+			}
+			return nil, fmt.Errorf("expected JSON: %w", err)
+		}
+		return value, nil
+	default:
+		if converter, ok := provider.(layer.ValueConverterProvider); ok {
+			return converter.ConvertValue(path, raw, fieldType)
+		}
+		var value any
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return raw, nil
+		}
+		return value, nil
+	}
+}
+
+// Name returns the layer name.
+func (l *Layer) Name() layer.Name {
+	return l.name
+}
+
+// Load returns the defaults for paths outside slices and maps.
+// Defaults for slice elements and map values are added during stabilization,
+// once the elements provided by other layers are known.
+func (l *Layer) Load(ctx context.Context) (map[string]any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if l.err != nil {
+		return nil, l.err
+	}
+	return l.static(), nil
+}
+
+// static builds the data for defaults without wildcard segments.
+func (l *Layer) static() map[string]any {
+	data := make(map[string]any)
+	for _, d := range l.defaults {
+		if !d.wildcard {
+			place(data, nil, d.segments, d.value)
+		}
+	}
+	return data
+}
+
+// Stabilize expands defaults for slice elements and map values over the
+// elements present in the snapshot of the other layers.
+func (l *Layer) Stabilize(ctx context.Context, c layer.StabilizeContext) (*layer.StabilizeResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	data := l.static()
+	view := c.Snapshot()
+	if view == nil {
+		view = make(map[string]any)
+	}
+	dropShadowedSlices(data, view)
+	fill(view, data)
+
+	for _, d := range l.defaults {
+		if !d.wildcard {
+			continue
+		}
+		for _, segments := range expand(view, d.segments, nil) {
+			place(data, view, segments, d.value)
+		}
+	}
+	return &layer.StabilizeResult{Data: data}, nil
+}
+
+// expand resolves the wildcard segments of a schema path against view and
+// returns the concrete paths for every existing map key and slice index.
+func expand(view any, segments []string, prefix []string) [][]string {
+	if len(segments) == 0 {
+		return [][]string{append([]string(nil), prefix...)}
+	}
+	segment := segments[0]
+	if segment != wildcard {
+		var child any
+		if m, ok := view.(map[string]any); ok {
+			child = m[segment]
+		}
+		return expand(child, segments[1:], append(prefix, segment))
+	}
+
+	var paths [][]string
+	switch v := view.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			paths = append(paths, expand(v[key], segments[1:], append(prefix, key))...)
+		}
+	case []any:
+		for i, elem := range v {
+			paths = append(paths, expand(elem, segments[1:], append(prefix, strconv.Itoa(i)))...)
+		}
+	}
+	return paths
+}
+
+// place sets value at the concrete path below node, creating maps and slices
+// shaped like view along the way, and returns the updated node.
+// Existing values are kept; existing maps are filled with the keys they lack.
+func place(node any, view any, segments []string, value any) any {
+	if len(segments) == 0 {
+		if node == nil {
+			return container.DeepCopyValue(value)
+		}
+		if dst, ok := node.(map[string]any); ok {
+			if src, ok := value.(map[string]any); ok {
+				fill(dst, src)
+			}
+		}
+		return node
+	}
+
+	segment := segments[0]
+	if v, ok := view.([]any); ok {
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index >= len(v) {
+			return node
+		}
+		s, ok := node.([]any)
+		if !ok {
+			if node != nil {
+				return node
+			}
+			s = make([]any, len(v))
+		}
+		for len(s) < len(v) {
+			s = append(s, nil)
+		}
+		s[index] = place(s[index], v[index], segments[1:], value)
+		return s
+	}
+
+	m, ok := node.(map[string]any)
+	if !ok {
+		if node != nil {
+			return node
+		}
+		m = make(map[string]any)
+	}
+	var childView any
+	if v, ok := view.(map[string]any); ok {
+		childView = v[segment]
+	}
+	m[segment] = place(m[segment], childView, segments[1:], value)
+	return m
+}
+
+// dropShadowedSlices empties the default slices that other layers provide.
+// The Store fills such slices element by element, so the elements of a default
+// slice must not leak into the elements of the slice that replaces it.
+func dropShadowedSlices(data, snapshot map[string]any) {
+	for key, value := range data {
+		switch v := value.(type) {
+		case []any:
+			if other, ok := snapshot[key].([]any); ok {
+				data[key] = make([]any, len(other))
+			}
+		case map[string]any:
+			if other, ok := snapshot[key].(map[string]any); ok {
+				dropShadowedSlices(v, other)
+			}
+		}
+	}
+}
+
+// fill copies the keys of src missing from dst, recursing into nested maps.
+func fill(dst, src map[string]any) {
+	for key, srcValue := range src {
+		dstValue, exists := dst[key]
+		if !exists {
+			dst[key] = container.DeepCopyValue(srcValue)
+			continue
+		}
+		dstMap, ok := dstValue.(map[string]any)
+		srcMap, ok2 := srcValue.(map[string]any)
+		if ok && ok2 {
+			fill(dstMap, srcMap)
+		}
+	}
+}
+
+// Fill returns true: defaults only supply values that no other layer sets.
+func (l *Layer) Fill() bool {
+	return true
+}
+
+// Save returns source.ErrSaveNotSupported because defaults come from struct tags.
+func (l *Layer) Save(ctx context.Context, changeset document.JSONPatchSet) error {
+	return source.ErrSaveNotSupported
+}
+
+// CanSave returns false because defaults come from struct tags.
+func (l *Layer) CanSave() bool {
+	return false
+}
+
+// FillDetails populates the Details struct with metadata from this layer.
+func (l *Layer) FillDetails(d *types.Details) {
+	d.Source = TypeDefaults
+	d.Watcher = watcher.TypeNoop
+}
+
+// Watch returns a noop LayerWatcher since struct tags do not change at runtime.
+func (l *Layer) Watch(opts ...layer.WatchOption) (layer.LayerWatcher, error) {
+	return layer.NewNoopLayerWatcher(), nil
+}
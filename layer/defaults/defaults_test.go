@@ -0,0 +1,182 @@
+package defaults_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yacchi/jubako"
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/layer"
+	"github.com/yacchi/jubako/layer/defaults"
+	"github.com/yacchi/jubako/layer/mapdata"
+)
+
+type server struct {
+	Host string `json:"host"`
+	Port int    `json:"port" jubako:"default=80"`
+}
+
+type limits struct {
+	Max int  `json:"max" jubako:"default=10"`
+	Hard bool `json:"hard"`
+}
+
+type testConfig struct {
+	Port    int               `json:"port" jubako:"default=8080"`
+	Host    string            `json:"host" jubako:"default=localhost"`
+	Timeout time.Duration     `json:"timeout" jubako:"default=30s"`
+	Tags    []string          `json:"tags" jubako:"default=[\"a\",\"b\"]"`
+	Servers []server          `json:"servers"`
+	Limits  map[string]limits `json:"limits"`
+}
+
+func newStore(t *testing.T, data map[string]any) *jubako.Store[testConfig] {
+	t.Helper()
+	store := jubako.New[testConfig]()
+	if err := store.Add(defaults.New(), jubako.WithPriority(jubako.PriorityDefaults)); err != nil {
+		t.Fatalf("Add(defaults) error = %v", err)
+	}
+	if err := store.Add(mapdata.New("user", data), jubako.WithPriority(jubako.PriorityUser)); err != nil {
+		t.Fatalf("Add(user) error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return store
+}
+
+func TestNew_Scalars(t *testing.T) {
+	store := newStore(t, map[string]any{"host": "example.com"})
+	got := store.Get()
+
+	if got.Port != 8080 {
+		t.Errorf("Port = %d, want 8080", got.Port)
+	}
+	if got.Host != "example.com" {
+		t.Errorf("Host = %q, want %q", got.Host, "example.com")
+	}
+	if got.Timeout != 30*time.Second {
+		t.Errorf("Timeout = %v, want 30s", got.Timeout)
+	}
+	if !reflect.DeepEqual(got.Tags, []string{"a", "b"}) {
+		t.Errorf("Tags = %v, want [a b]", got.Tags)
+	}
+
+	rv := store.GetAt("/port")
+	if !rv.Exists || rv.Layer == nil {
+		t.Fatal("GetAt(/port) should exist")
+	}
+	if rv.Layer.Name() != defaults.DefaultName {
+		t.Errorf("GetAt(/port).Layer = %q, want %q", rv.Layer.Name(), defaults.DefaultName)
+	}
+	if rv := store.GetAt("/host"); rv.Layer == nil || rv.Layer.Name() != "user" {
+		t.Errorf("GetAt(/host) should come from user layer, got %v", rv.Layer)
+	}
+}
+
+func TestNew_FillsRegardlessOfPriority(t *testing.T) {
+	store := jubako.New[testConfig]()
+	if err := store.Add(mapdata.New("base", map[string]any{"port": 9000}), jubako.WithPriority(jubako.PriorityDefaults)); err != nil {
+		t.Fatalf("Add(base) error = %v", err)
+	}
+	if err := store.Add(defaults.New(), jubako.WithPriority(jubako.PriorityFlags)); err != nil {
+		t.Fatalf("Add(defaults) error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := store.Get().Port; got != 9000 {
+		t.Errorf("Port = %d, want 9000", got)
+	}
+	if got := store.Get().Host; got != "localhost" {
+		t.Errorf("Host = %q, want localhost", got)
+	}
+}
+
+func TestNew_SliceElements(t *testing.T) {
+	store := newStore(t, map[string]any{
+		"servers": []any{
+			map[string]any{"host": "a"},
+			map[string]any{"host": "b", "port": 8443},
+		},
+	})
+
+	want := []server{{Host: "a", Port: 80}, {Host: "b", Port: 8443}}
+	if got := store.Get().Servers; !reflect.DeepEqual(got, want) {
+		t.Errorf("Servers = %+v, want %+v", got, want)
+	}
+
+	if rv := store.GetAt("/servers/0/port"); rv.Layer == nil || rv.Layer.Name() != defaults.DefaultName {
+		t.Errorf("GetAt(/servers/0/port).Layer = %v, want %q", rv.Layer, defaults.DefaultName)
+	}
+	if rv := store.GetAt("/servers/1/port"); rv.Layer == nil || rv.Layer.Name() != "user" {
+		t.Errorf("GetAt(/servers/1/port).Layer = %v, want user", rv.Layer)
+	}
+}
+
+func TestNew_MapValues(t *testing.T) {
+	store := newStore(t, map[string]any{
+		"limits": map[string]any{
+			"cpu": map[string]any{"hard": true},
+			"mem": map[string]any{"max": 64},
+		},
+	})
+
+	want := map[string]limits{"cpu": {Max: 10, Hard: true}, "mem": {Max: 64}}
+	if got := store.Get().Limits; !reflect.DeepEqual(got, want) {
+		t.Errorf("Limits = %+v, want %+v", got, want)
+	}
+}
+
+func TestNew_DefaultSliceReplaced(t *testing.T) {
+	store := newStore(t, map[string]any{"tags": []any{"x"}})
+
+	if got := store.Get().Tags; !reflect.DeepEqual(got, []string{"x"}) {
+		t.Errorf("Tags = %v, want [x]", got)
+	}
+}
+
+func TestNew_TombstoneNotFilled(t *testing.T) {
+	store := jubako.New[testConfig]()
+	if err := store.Add(defaults.New()); err != nil {
+		t.Fatalf("Add(defaults) error = %v", err)
+	}
+	if err := store.Add(mapdata.New("user", map[string]any{"port": document.Tombstone{}})); err != nil {
+		t.Fatalf("Add(user) error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if rv := store.GetAt("/port"); rv.Exists {
+		t.Errorf("GetAt(/port) should not exist after tombstone, got %v", rv.Value)
+	}
+}
+
+func TestNew_InvalidDefault(t *testing.T) {
+	type config struct {
+		Port int `json:"port" jubako:"default=abc"`
+	}
+	store := jubako.New[config]()
+	if err := store.Add(defaults.New()); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(context.Background()); err == nil {
+		t.Error("Load() should fail for an invalid default")
+	}
+}
+
+func TestWithName(t *testing.T) {
+	store := jubako.New[testConfig]()
+	if err := store.Add(defaults.New(defaults.WithName("builtin"))); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if rv := store.GetAt("/port"); rv.Layer == nil || rv.Layer.Name() != layer.Name("builtin") {
+		t.Errorf("GetAt(/port).Layer = %v, want builtin", rv.Layer)
+	}
+}
@@ -0,0 +1,128 @@
+package env
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Reference documents the environment variables read through a SchemaMapping,
+// for generating operator documentation.
+type Reference struct {
+	// Prefix is the env var prefix of the layer (e.g., "APP_").
+	Prefix string `json:"prefix"`
+	// Variables lists the environment variables sorted by name.
+	Variables []Variable `json:"variables"`
+}
+
+// Variable describes a single env: directive.
+type Variable struct {
+	// Name is the environment variable name including the prefix.
+	// Pattern mappings keep their placeholders (e.g., "APP_USERS_{key}_NAME").
+	Name string `json:"name"`
+	// Path is the target JSON Pointer path, with placeholders for patterns.
+	Path string `json:"path"`
+	// Type is the Go type of the target field (e.g., "int", "time.Duration").
+	Type string `json:"type"`
+	// Sensitive is true if the field is marked with the sensitive directive.
+	Sensitive bool `json:"sensitive"`
+	// Placeholders describes the placeholders of a pattern mapping, in order.
+	Placeholders []Placeholder `json:"placeholders,omitempty"`
+}
+
+// Placeholder describes a {key} or {index} placeholder of a pattern mapping.
+type Placeholder struct {
+	// Name is "key" or "index".
+	Name string `json:"name"`
+	// Match is the regular expression matched in the variable name.
+	Match string `json:"match"`
+	// Filter is the template filter applied before the value is used in the
+	// path (e.g., "lower"), or empty.
+	Filter string `json:"filter,omitempty"`
+	// Description explains how the matched text is used.
+	Description string `json:"description"`
+}
+
+// Reference returns the documentation of the mapped environment variables
+// for a layer with the given prefix.
+//
+// Example:
+//
+//	ref := env.BuildSchemaMapping[Config]().Reference("APP_")
+//	md, _ := ref.MarshalMarkdown()
+func (s *SchemaMapping) Reference(prefix string) *Reference {
+	ref := &Reference{Prefix: prefix, Variables: []Variable{}}
+	for _, m := range s.Mappings {
+		ref.Variables = append(ref.Variables, Variable{
+			Name:      prefix + m.EnvVar,
+			Path:      m.JSONPath,
+			Type:      m.FieldType.String(),
+			Sensitive: m.Sensitive,
+		})
+	}
+	for _, p := range s.Patterns {
+		ref.Variables = append(ref.Variables, Variable{
+			Name:         prefix + p.Pattern,
+			Path:         p.PathPattern,
+			Type:         p.FieldType.String(),
+			Sensitive:    p.Sensitive,
+			Placeholders: parsePlaceholders(p.Pattern),
+		})
+	}
+	sort.Slice(ref.Variables, func(i, j int) bool {
+		return ref.Variables[i].Name < ref.Variables[j].Name
+	})
+	return ref
+}
+
+// parsePlaceholders describes the placeholders of an env var pattern.
+func parsePlaceholders(pattern string) []Placeholder {
+	var placeholders []Placeholder
+	for _, m := range placeholderRegex.FindAllStringSubmatch(pattern, -1) {
+		p := Placeholder{Name: m[1], Filter: strings.TrimSpace(m[2])}
+		if p.Name == "index" {
+			p.Match = `\d+`
+			p.Description = "slice index; matches decimal digits"
+		} else {
+			p.Match = ".+"
+			p.Description = "map key; matches any non-empty text"
+		}
+		if p.Filter != "" {
+			p.Description += ", converted with " + p.Filter
+		}
+		placeholders = append(placeholders, p)
+	}
+	return placeholders
+}
+
+// MarshalMarkdown renders the reference as a Markdown table.
+func (r *Reference) MarshalMarkdown() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("# Environment Variables\n\n")
+	if len(r.Variables) == 0 {
+		buf.WriteString("No environment variables are mapped.\n")
+		return buf.Bytes(), nil
+	}
+
+	buf.WriteString("| Variable | Path | Type | Sensitive | Placeholders |\n")
+	buf.WriteString("|----------|------|------|-----------|--------------|\n")
+	for _, v := range r.Variables {
+		sensitive := ""
+		if v.Sensitive {
+			sensitive = "yes"
+		}
+		placeholders := make([]string, len(v.Placeholders))
+		for i, p := range v.Placeholders {
+			placeholders[i] = fmt.Sprintf("`{%s}`: %s", p.Name, p.Description)
+		}
+		fmt.Fprintf(&buf, "| `%s` | `%s` | `%s` | %s | %s |\n",
+			escapeTableCell(v.Name), v.Path, v.Type, sensitive, escapeTableCell(strings.Join(placeholders, "<br>")))
+	}
+	return buf.Bytes(), nil
+}
+
+// escapeTableCell escapes the pipe characters of filters such as {key|lower}.
+func escapeTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
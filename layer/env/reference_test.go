@@ -0,0 +1,81 @@
+package env
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchemaMapping_Reference(t *testing.T) {
+	type User struct {
+		Name  string `json:"name" jubako:"env:USERS_{key|lower}_NAME"`
+		Token string `json:"token" jubako:"env:USERS_{key|lower}_TOKEN,sensitive"`
+	}
+	type Config struct {
+		Port    int             `json:"port" jubako:"env:SERVER_PORT"`
+		Timeout time.Duration   `json:"timeout" jubako:"env:TIMEOUT"`
+		Users   map[string]User `json:"users"`
+		Ports   []struct {
+			Num int `json:"num" jubako:"env:PORTS_{index}_NUM"`
+		} `json:"ports"`
+		Ignored string `json:"ignored"`
+	}
+
+	ref := BuildSchemaMapping[Config]().Reference("APP_")
+
+	want := &Reference{
+		Prefix: "APP_",
+		Variables: []Variable{
+			{
+				Name: "APP_PORTS_{index}_NUM", Path: "/ports/{index}/num", Type: "int",
+				Placeholders: []Placeholder{
+					{Name: "index", Match: `\d+`, Description: "slice index; matches decimal digits"},
+				},
+			},
+			{Name: "APP_SERVER_PORT", Path: "/port", Type: "int"},
+			{Name: "APP_TIMEOUT", Path: "/timeout", Type: "time.Duration"},
+			{
+				Name: "APP_USERS_{key|lower}_NAME", Path: "/users/{key}/name", Type: "string",
+				Placeholders: []Placeholder{
+					{Name: "key", Match: ".+", Filter: "lower", Description: "map key; matches any non-empty text, converted with lower"},
+				},
+			},
+			{
+				Name: "APP_USERS_{key|lower}_TOKEN", Path: "/users/{key}/token", Type: "string", Sensitive: true,
+				Placeholders: []Placeholder{
+					{Name: "key", Match: ".+", Filter: "lower", Description: "map key; matches any non-empty text, converted with lower"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(ref, want) {
+		t.Errorf("Reference() = %+v, want %+v", ref, want)
+	}
+
+	md, err := ref.MarshalMarkdown()
+	if err != nil {
+		t.Fatalf("MarshalMarkdown() error = %v", err)
+	}
+	for _, line := range []string{
+		"| `APP_SERVER_PORT` | `/port` | `int` |  |  |",
+		"| `APP_USERS_{key\\|lower}_TOKEN` | `/users/{key}/token` | `string` | yes | `{key}`: map key; matches any non-empty text, converted with lower |",
+	} {
+		if !strings.Contains(string(md), line+"\n") {
+			t.Errorf("MarshalMarkdown() does not contain %q:\n%s", line, md)
+		}
+	}
+}
+
+func TestReference_MarshalMarkdown_Empty(t *testing.T) {
+	type Config struct {
+		Port int `json:"port"`
+	}
+	md, err := BuildSchemaMapping[Config]().Reference("APP_").MarshalMarkdown()
+	if err != nil {
+		t.Fatalf("MarshalMarkdown() error = %v", err)
+	}
+	if want := "# Environment Variables\n\nNo environment variables are mapped.\n"; string(md) != want {
+		t.Errorf("MarshalMarkdown() = %q, want %q", md, want)
+	}
+}
@@ -3,14 +3,18 @@ package env
 
 import (
 	"bytes"
+	"encoding"
+	"fmt"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
 	"github.com/yacchi/jubako/internal/tag"
+	"github.com/yacchi/jubako/internal/variant"
 	"github.com/yacchi/jubako/jsonptr"
 )
 
@@ -22,16 +26,24 @@ type EnvMapping struct {
 	JSONPath string
 	// FieldType is the target field type for automatic conversion.
 	FieldType reflect.Type
+	// Sensitive is true if the field is marked with the sensitive directive.
+	Sensitive bool
 }
 
 // PatternMapping represents a dynamic environment variable mapping using placeholders.
 type PatternMapping struct {
+	// Pattern is the env var pattern from the tag WITHOUT prefix (e.g., "USERS_{key}_NAME").
+	Pattern string
+	// PathPattern is the JSON Pointer path with placeholders (e.g., "/users/{key}/name").
+	PathPattern string
 	// EnvPattern is the compiled regex for matching environment variables.
 	EnvPattern *regexp.Regexp
 	// PathTemplate is the template for generating JSON Pointer paths.
 	PathTemplate *template.Template
 	// FieldType is the target field type.
 	FieldType reflect.Type
+	// Sensitive is true if the field is marked with the sensitive directive.
+	Sensitive bool
 }
 
 // SchemaMapping holds all env var mappings derived from struct tags.
@@ -99,6 +111,8 @@ func buildSchemaMappingFromType(t reflect.Type, basePath string) *SchemaMapping
 		// Build JSON path for this field
 		fieldPath := basePath + "/" + jsonptr.Escape(tagInfo.FieldKey)
 
+		sensitive := tagInfo.Sensitive == tag.SensitiveExplicit
+
 		// Check for env: directive
 		var currentPattern *PatternMapping
 		if tagInfo.EnvVar != "" {
@@ -119,9 +133,12 @@ func buildSchemaMappingFromType(t reflect.Type, basePath string) *SchemaMapping
 				regex, tmpl, err := compileEnvPattern(tagInfo.EnvVar, jsonPath)
 				if err == nil {
 					currentPattern = &PatternMapping{
+						Pattern:      tagInfo.EnvVar,
+						PathPattern:  jsonPath,
 						EnvPattern:   regex,
 						PathTemplate: tmpl,
 						FieldType:    unwrapPointer(field.Type),
+						Sensitive:    sensitive,
 					}
 				}
 			} else {
@@ -130,6 +147,7 @@ func buildSchemaMappingFromType(t reflect.Type, basePath string) *SchemaMapping
 					EnvVar:    tagInfo.EnvVar,
 					JSONPath:  jsonPath,
 					FieldType: unwrapPointer(field.Type),
+					Sensitive: sensitive,
 				}
 			}
 		}
@@ -156,15 +174,29 @@ func buildSchemaMappingFromType(t reflect.Type, basePath string) *SchemaMapping
 		}
 
 		if shouldRecurse && !isSpecialType(nextType) {
-			nested := buildSchemaMappingFromType(nextType, nextPath)
-			// Merge nested mappings
-			for k, v := range nested.Mappings {
-				if _, exists := schema.Mappings[k]; !exists {
-					schema.Mappings[k] = v
+			schema.merge(buildSchemaMappingFromType(nextType, nextPath))
+		}
+
+		// Union fields map the env vars of all their variants (see jubako.RegisterVariant)
+		if tagInfo.Union != "" {
+			if iface := variant.Interface(field.Type); iface != nil {
+				unionPath := fieldPath
+				switch field.Type.Kind() {
+				case reflect.Slice:
+					unionPath += "/{index}"
+				case reflect.Map:
+					unionPath += "/{key}"
+				}
+				variants := variant.Lookup(iface)
+				names := make([]string, 0, len(variants))
+				for name := range variants {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					schema.merge(buildSchemaMappingFromType(variants[name], unionPath))
 				}
 			}
-			// Merge nested patterns
-			schema.Patterns = append(schema.Patterns, nested.Patterns...)
 		}
 
 		// Add current pattern after nested patterns (so specific nested patterns are checked first)
@@ -176,6 +208,17 @@ func buildSchemaMappingFromType(t reflect.Type, basePath string) *SchemaMapping
 	return schema
 }
 
+// merge adds the mappings and patterns of nested to s.
+// Mappings already present in s take precedence.
+func (s *SchemaMapping) merge(nested *SchemaMapping) {
+	for k, v := range nested.Mappings {
+		if _, exists := s.Mappings[k]; !exists {
+			s.Mappings[k] = v
+		}
+	}
+	s.Patterns = append(s.Patterns, nested.Patterns...)
+}
+
 func isStructOrPtrStruct(t reflect.Type) bool {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
@@ -198,6 +241,24 @@ var defaultFuncMap = template.FuncMap{
 	"escape": jsonptr.Escape,
 }
 
+// ValidatePattern reports whether the pattern of an env: directive is well
+// formed. Placeholders must be {key} or {index}, optionally with a filter
+// (e.g., {key|lower}). Patterns failing these checks are ignored when the
+// schema mapping is built, so tools such as jubako lint report them ahead.
+func ValidatePattern(pattern string) error {
+	if rest := placeholderRegex.ReplaceAllString(pattern, ""); strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("malformed placeholder in %q: use {key} or {index}", pattern)
+	}
+	var path strings.Builder
+	for _, m := range placeholderRegex.FindAllStringSubmatch(pattern, -1) {
+		path.WriteString("/{" + m[1] + "}")
+	}
+	if _, _, err := compileEnvPattern(pattern, path.String()); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
 // compileEnvPattern converts an env var pattern to a regexp and a path template.
 // e.g. envPattern: "USERS_{key}_NAME", jsonPathPattern: "/users/{key}/name"
 // -> regex: "^USERS_(?P<key>.+)_NAME$"
@@ -349,13 +410,21 @@ func (s *SchemaMapping) CreateTransformFunc() TransformFunc {
 	}
 }
 
+// textUnmarshalerType is the reflect.Type of encoding.TextUnmarshaler.
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
 // convertStringToType converts a string value to the target type.
 // Supported types: string, int*, uint*, float*, bool, []string, time.Duration.
+// Values of types implementing encoding.TextUnmarshaler (e.g., jubako.ByteSize)
+// are kept as strings for the Store's converters and decoder.
 func convertStringToType(value string, targetType reflect.Type) (any, error) {
 	// Handle pointer types
 	if targetType.Kind() == reflect.Ptr {
 		targetType = targetType.Elem()
 	}
+	if reflect.PointerTo(targetType).Implements(textUnmarshalerType) {
+		return value, nil
+	}
 
 	switch targetType.Kind() {
 	case reflect.String:
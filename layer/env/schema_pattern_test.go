@@ -233,3 +233,27 @@ func TestCompileEnvPattern(t *testing.T) {
 		}
 	}
 }
+
+func TestValidatePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{pattern: "SERVER_PORT"},
+		{pattern: "USERS_{key}_NAME"},
+		{pattern: "USERS_{key|upper}_NAME"},
+		{pattern: "ITEMS_{index}_{key|lower}"},
+		{pattern: "USERS_{name}_NAME", wantErr: true},
+		{pattern: "USERS_{key_NAME", wantErr: true},
+		{pattern: "USERS_key}_NAME", wantErr: true},
+		{pattern: "USERS_{key|trim}_NAME", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			err := ValidatePattern(tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePattern(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/yacchi/jubako/internal/variant"
 )
 
 func TestBuildSchemaMapping_Simple(t *testing.T) {
@@ -44,6 +46,55 @@ func TestBuildSchemaMapping_Simple(t *testing.T) {
 	}
 }
 
+type unionStorage interface{ isStorage() }
+
+type unionS3 struct {
+	Bucket string `json:"bucket" jubako:"env:STORAGE_BUCKET"`
+	Key    string `json:"key" jubako:"env:STORAGE_KEY,sensitive"`
+}
+
+func (unionS3) isStorage() {}
+
+type unionLocal struct {
+	Path string `json:"path" jubako:"env:BACKUP_{index}_PATH"`
+}
+
+func (*unionLocal) isStorage() {}
+
+func init() {
+	variant.Register(reflect.TypeFor[unionStorage](), "s3", reflect.TypeFor[unionS3]())
+	variant.Register(reflect.TypeFor[unionStorage](), "local", reflect.TypeFor[*unionLocal]())
+}
+
+func TestBuildSchemaMapping_Union(t *testing.T) {
+	type Config struct {
+		Storage unionStorage `json:"storage" jubako:"union=type"`
+	}
+
+	schema := BuildSchemaMapping[Config]()
+
+	bucket, ok := schema.Mappings["STORAGE_BUCKET"]
+	if !ok {
+		t.Fatal("STORAGE_BUCKET mapping not found")
+	}
+	if bucket.JSONPath != "/storage/bucket" {
+		t.Errorf("STORAGE_BUCKET path = %q, want /storage/bucket", bucket.JSONPath)
+	}
+	if key := schema.Mappings["STORAGE_KEY"]; key == nil || !key.Sensitive {
+		t.Errorf("STORAGE_KEY mapping = %+v, want sensitive", key)
+	}
+
+	type SliceConfig struct {
+		Backups []unionStorage `json:"backups" jubako:"union=type"`
+	}
+
+	transform := BuildSchemaMapping[SliceConfig]().CreateTransformFunc()
+	path, value := transform("BACKUP_1_PATH", "/backup")
+	if path != "/backups/1/path" || value != "/backup" {
+		t.Errorf("transform(BACKUP_1_PATH) = %q, %v, want /backups/1/path, /backup", path, value)
+	}
+}
+
 func TestBuildSchemaMapping_Nested(t *testing.T) {
 	type Database struct {
 		Host string `json:"host" jubako:"env:DB_HOST"`
@@ -163,6 +214,13 @@ func TestBuildSchemaMapping_VariousTypes(t *testing.T) {
 	}
 }
 
+// textSize is an integer type decoded from text, like jubako.ByteSize.
+type textSize int64
+
+func (s *textSize) UnmarshalText(text []byte) error {
+	return nil
+}
+
 func TestConvertStringToType(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -189,6 +247,8 @@ func TestConvertStringToType(t *testing.T) {
 		{"string slice empty", "", reflect.TypeOf([]string{}), []string{}, false},
 		{"duration", "1h30m", reflect.TypeOf(time.Duration(0)), time.Hour + 30*time.Minute, false},
 		{"duration invalid", "invalid", reflect.TypeOf(time.Duration(0)), time.Duration(0), true},
+		{"text unmarshaler", "2MiB", reflect.TypeOf(textSize(0)), "2MiB", false},
+		{"text unmarshaler pointer", "2MiB", reflect.TypeOf(new(textSize)), "2MiB", false},
 	}
 
 	for _, tt := range tests {
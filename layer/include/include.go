@@ -0,0 +1,387 @@
+// Package include provides a layer that resolves structural references inside
+// configuration documents, so that a document can reuse whole subtrees:
+//
+//	# config.yaml
+//	templates:
+//	  db:
+//	    host: db.internal
+//	    port: 5432
+//	databases:
+//	  primary:
+//	    $ref: "#/templates/db"      # subtree of the same document
+//	  replica:
+//	    $include: ./replica.yaml    # sibling document
+//
+// A reference is a map with a single key. $ref takes "[document]#<JSON Pointer>"
+// and $include takes a document path; "document#pointer" selects a subtree of
+// another document for both. Documents are opened through the
+// source.RelativeSource implemented by the layer's source (e.g., fs.Source),
+// parsed with the layer's Document, and may contain references themselves.
+//
+// References are resolved at Load. The Store reports where each value comes
+// from in ResolvedValue.Reference, Save writes changes to the document that
+// owns the value, and Watch also watches the included documents.
+package include
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/jsonptr"
+	"github.com/yacchi/jubako/layer"
+	"github.com/yacchi/jubako/source"
+	"github.com/yacchi/jubako/types"
+)
+
+// Reference keys recognized in documents.
+const (
+	// RefKey references a subtree: {"$ref": "#/templates/db"}.
+	RefKey = "$ref"
+
+	// IncludeKey references another document: {"$include": "./db.yaml"}.
+	IncludeKey = "$include"
+)
+
+// Layer resolves $ref and $include references in the document of a source.
+type Layer struct {
+	name layer.Name
+	doc  document.Document
+	root *file
+
+	// mu protects files and mounts, which are replaced on every resolution.
+	mu     sync.Mutex
+	files  map[string]*file
+	mounts []mount
+}
+
+// file is a document taking part in the layer.
+type file struct {
+	// location identifies the document in references, e.g. "/etc/app/db.yaml".
+	// It is empty for the layer's own document.
+	location string
+	src      source.Source
+	layer    layer.Layer
+	data     map[string]any
+}
+
+// mount records that the subtree at path in the layer's data is provided by
+// the subtree at target in file.
+type mount struct {
+	path   string
+	file   *file
+	target string
+}
+
+// Ensure Layer implements the layer interfaces.
+var (
+	_ layer.Layer            = (*Layer)(nil)
+	_ layer.ReferenceTracker = (*Layer)(nil)
+)
+
+// New creates a layer reading src with doc and resolving the references in it.
+// It is a drop-in replacement for layer.New.
+//
+// Example:
+//
+//	store.Add(include.New("user", fs.New("~/.config/app/config.yaml"), yaml.New()))
+func New(name layer.Name, src source.Source, doc document.Document) *Layer {
+	root := &file{src: src, layer: layer.New(name, src, doc)}
+	return &Layer{
+		name:  name,
+		doc:   doc,
+		root:  root,
+		files: map[string]*file{"": root},
+	}
+}
+
+// Name returns the layer name.
+func (l *Layer) Name() layer.Name {
+	return l.name
+}
+
+// Load reads the layer's document and every document it includes,
+// and returns the data with all references resolved.
+func (l *Layer) Load(ctx context.Context) (map[string]any, error) {
+	r := newResolver(ctx, l, nil)
+	return l.resolve(r)
+}
+
+// resolve resolves the root document with r and publishes the result.
+func (l *Layer) resolve(r *resolver) (map[string]any, error) {
+	// Each resolution uses its own file values, so that published files are never modified.
+	data, err := r.resolveFile(&file{src: l.root.src, layer: l.root.layer})
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.files = r.files
+	l.mounts = r.mounts
+	return data, nil
+}
+
+// ReferenceOf returns the location of the value at path, or "" if the value
+// is read from the layer's own document without a reference.
+func (l *Layer) ReferenceOf(path string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m, rest, ok := l.mountFor(path, true)
+	if !ok {
+		return ""
+	}
+	return m.file.location + "#" + m.target + rest
+}
+
+// mountFor returns the innermost mount containing path and the remainder of
+// path below it. The mount point itself is included only if inclusive is true.
+// Caller must hold mu.
+func (l *Layer) mountFor(path string, inclusive bool) (mount, string, bool) {
+	// mounts are sorted by descending path length, so the first match is the innermost.
+	for _, m := range l.mounts {
+		if path == m.path {
+			if inclusive {
+				return m, "", true
+			}
+			continue
+		}
+		if strings.HasPrefix(path, m.path+"/") {
+			return m, path[len(m.path):], true
+		}
+	}
+	return mount{}, "", false
+}
+
+// Save writes the changes to the documents that own the changed values.
+// Changes at or below a reference are applied to the referenced document at the
+// referenced location. Removing a reference removes it from the document containing it.
+func (l *Layer) Save(ctx context.Context, changeset document.JSONPatchSet) error {
+	l.mu.Lock()
+	targets := make(map[*file]document.JSONPatchSet)
+	var order []*file
+	for _, patch := range changeset {
+		path, remove := patch.Path, patch.Op == document.PatchOpRemove
+		target := l.root
+		if m, rest, ok := l.mountFor(patch.Path, !remove); ok {
+			target = m.file
+			patch.Path = m.target + rest
+		}
+		// The references replaced or removed by the change no longer apply
+		l.unmountLocked(path, remove)
+
+		if _, ok := targets[target]; !ok {
+			order = append(order, target)
+		}
+		targets[target] = append(targets[target], patch)
+	}
+	l.mu.Unlock()
+
+	for _, f := range order {
+		if err := f.layer.Save(ctx, targets[f]); err != nil {
+			if f.location != "" {
+				return fmt.Errorf("save %s: %w", f.location, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// unmountLocked removes the mounts below path, and the mount at path if
+// inclusive is true. Caller must hold mu.
+func (l *Layer) unmountLocked(path string, inclusive bool) {
+	mounts := l.mounts[:0]
+	for _, m := range l.mounts {
+		if (inclusive && m.path == path) || strings.HasPrefix(m.path, path+"/") {
+			continue
+		}
+		mounts = append(mounts, m)
+	}
+	l.mounts = mounts
+}
+
+// CanSave returns true if the layer's source supports saving.
+func (l *Layer) CanSave() bool {
+	return l.root.layer.CanSave()
+}
+
+// FillDetails populates the Details struct with the metadata of the layer's own document.
+func (l *Layer) FillDetails(d *types.Details) {
+	l.root.layer.FillDetails(d)
+}
+
+// resolver resolves references for a single Load or watch update.
+type resolver struct {
+	ctx   context.Context
+	layer *Layer
+
+	// cached provides the last loaded data of the documents that did not change.
+	// Documents missing from it are loaded from their sources.
+	cached map[string]map[string]any
+
+	files  map[string]*file
+	mounts []mount
+	stack  []string
+}
+
+func newResolver(ctx context.Context, l *Layer, cached map[string]map[string]any) *resolver {
+	return &resolver{
+		ctx:    ctx,
+		layer:  l,
+		cached: cached,
+		files:  make(map[string]*file),
+	}
+}
+
+// resolveFile loads f and returns its data with all references resolved.
+func (r *resolver) resolveFile(f *file) (map[string]any, error) {
+	if err := r.load(f); err != nil {
+		return nil, err
+	}
+	resolved, err := r.resolveNode(f, f.data, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(r.mounts, func(i, j int) bool {
+		return len(r.mounts[i].path) > len(r.mounts[j].path)
+	})
+	data, _ := resolved.(map[string]any)
+	return data, nil
+}
+
+// load reads the data of f, unless it is cached.
+func (r *resolver) load(f *file) error {
+	r.files[f.location] = f
+	if data, ok := r.cached[f.location]; ok {
+		f.data = data
+		return nil
+	}
+	data, err := f.layer.Load(r.ctx)
+	if err != nil {
+		if f.location != "" {
+			return fmt.Errorf("include %s: %w", f.location, err)
+		}
+		return err
+	}
+	f.data = data
+	return nil
+}
+
+// resolveNode returns a copy of node (found in f) with its references resolved.
+// path is the location of node in the layer's data.
+func (r *resolver) resolveNode(f *file, node any, path string) (any, error) {
+	switch v := node.(type) {
+	case map[string]any:
+		if ref, ok := v[RefKey]; ok && len(v) == 1 {
+			return r.resolveRef(f, ref, path, false)
+		}
+		if ref, ok := v[IncludeKey]; ok && len(v) == 1 {
+			return r.resolveRef(f, ref, path, true)
+		}
+		result := make(map[string]any, len(v))
+		for key, child := range v {
+			resolved, err := r.resolveNode(f, child, path+"/"+jsonptr.Escape(key))
+			if err != nil {
+				return nil, err
+			}
+			result[key] = resolved
+		}
+		return result, nil
+	case []any:
+		result := make([]any, len(v))
+		for i, child := range v {
+			resolved, err := r.resolveNode(f, child, path+"/"+strconv.Itoa(i))
+			if err != nil {
+				return nil, err
+			}
+			result[i] = resolved
+		}
+		return result, nil
+	default:
+		return node, nil
+	}
+}
+
+// resolveRef resolves a $ref (include=false) or $include (include=true) found in f at path.
+func (r *resolver) resolveRef(f *file, value any, path string, include bool) (any, error) {
+	key := RefKey
+	if include {
+		key = IncludeKey
+	}
+	ref, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s at %s: expected string, got %T", key, path, value)
+	}
+
+	location, pointer, hasPointer := strings.Cut(ref, "#")
+	if !include && !hasPointer {
+		return nil, fmt.Errorf("%s at %s: %q has no #<JSON Pointer>", key, path, ref)
+	}
+	if include && location == "" {
+		return nil, fmt.Errorf("%s at %s: %q has no document", key, path, ref)
+	}
+
+	target := f
+	if location != "" {
+		var err error
+		if target, err = r.open(f, location); err != nil {
+			return nil, fmt.Errorf("%s at %s: %w", key, path, err)
+		}
+	}
+
+	id := target.location + "#" + pointer
+	for _, visiting := range r.stack {
+		if visiting == id {
+			return nil, fmt.Errorf("%s at %s: reference cycle: %s -> %s", key, path, strings.Join(r.stack, " -> "), id)
+		}
+	}
+
+	node, ok := jsonptr.GetPath(target.data, pointer)
+	if !ok {
+		return nil, fmt.Errorf("%s at %s: %q not found", key, path, ref)
+	}
+
+	r.stack = append(r.stack, id)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+
+	r.mounts = append(r.mounts, mount{path: path, file: target, target: pointer})
+	return r.resolveNode(target, node, path)
+}
+
+// open returns the document at location, relative to f.
+func (r *resolver) open(f *file, location string) (*file, error) {
+	rs, ok := f.src.(source.RelativeSource)
+	if !ok {
+		return nil, fmt.Errorf("source %s cannot open %q: it does not implement source.RelativeSource", f.src.Type(), location)
+	}
+	src, err := rs.Relative(location)
+	if err != nil {
+		return nil, err
+	}
+
+	// Identify the document by its path, so that documents reached through
+	// different relative paths are loaded once.
+	key := location
+	if df, ok := src.(types.DetailsFiller); ok {
+		var d types.Details
+		df.FillDetails(&d)
+		if d.Path != "" {
+			key = d.Path
+		}
+	}
+	if existing, ok := r.files[key]; ok {
+		return existing, nil
+	}
+
+	included := &file{location: key, src: src, layer: layer.New(r.layer.name, src, r.layer.doc)}
+	if err := r.load(included); err != nil {
+		return nil, err
+	}
+	return included, nil
+}
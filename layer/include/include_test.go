@@ -0,0 +1,259 @@
+package include_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yacchi/jubako"
+	jjson "github.com/yacchi/jubako/format/json"
+	"github.com/yacchi/jubako/layer/include"
+	"github.com/yacchi/jubako/source/bytes"
+	"github.com/yacchi/jubako/source/fs"
+)
+
+type dbConfig struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+type testConfig struct {
+	Templates struct {
+		DB dbConfig `json:"db"`
+	} `json:"templates"`
+	Databases map[string]dbConfig `json:"databases"`
+	Name      string              `json:"name"`
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+func readJSON(t *testing.T, path string) map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	return m
+}
+
+func setup(t *testing.T) (dir string, store *jubako.Store[testConfig]) {
+	t.Helper()
+	dir = t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.json"), `{
+  "name": "app",
+  "templates": {"db": {"host": "db.internal", "port": 5432}},
+  "databases": {
+    "primary": {"$ref": "#/templates/db"},
+    "replica": {"$include": "replica.json"}
+  }
+}`)
+	writeFile(t, filepath.Join(dir, "replica.json"), `{"host": "replica.internal", "port": {"$ref": "#/defaults/port"}, "defaults": {"port": 6432}}`)
+
+	store = jubako.New[testConfig]()
+	if err := store.Add(include.New("user", fs.New(filepath.Join(dir, "config.json")), jjson.New())); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return dir, store
+}
+
+func TestLayer_Load(t *testing.T) {
+	_, store := setup(t)
+
+	got := store.Get().Databases
+	if got["primary"] != (dbConfig{Host: "db.internal", Port: 5432}) {
+		t.Errorf("primary = %+v", got["primary"])
+	}
+	if got["replica"] != (dbConfig{Host: "replica.internal", Port: 6432}) {
+		t.Errorf("replica = %+v", got["replica"])
+	}
+}
+
+func TestLayer_Reference(t *testing.T) {
+	dir, store := setup(t)
+	replica := filepath.Join(dir, "replica.json")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/name", ""},
+		{"/templates/db/host", ""},
+		{"/databases/primary/host", "#/templates/db/host"},
+		{"/databases/replica/host", replica + "#/host"},
+		{"/databases/replica/port", replica + "#/defaults/port"},
+		{"/databases/replica", replica + "#"},
+	}
+	for _, tt := range tests {
+		rv := store.GetAt(tt.path)
+		if !rv.Exists {
+			t.Errorf("GetAt(%s) should exist", tt.path)
+			continue
+		}
+		if rv.Reference != tt.want {
+			t.Errorf("GetAt(%s).Reference = %q, want %q", tt.path, rv.Reference, tt.want)
+		}
+		if rv.Layer.Name() != "user" {
+			t.Errorf("GetAt(%s).Layer = %q, want user", tt.path, rv.Layer.Name())
+		}
+	}
+}
+
+func TestLayer_Save(t *testing.T) {
+	dir, store := setup(t)
+	ctx := context.Background()
+
+	for path, value := range map[string]any{
+		"/name":                   "renamed",
+		"/databases/primary/port": 5433,
+		"/databases/replica/host": "replica2.internal",
+		"/databases/replica/port": 6433,
+	} {
+		if err := store.SetTo("user", path, value); err != nil {
+			t.Fatalf("SetTo(%s) error = %v", path, err)
+		}
+	}
+	if err := store.Save(ctx); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	root := readJSON(t, filepath.Join(dir, "config.json"))
+	if root["name"] != "renamed" {
+		t.Errorf("config.json name = %v", root["name"])
+	}
+	wantRoot := map[string]any{
+		"primary": map[string]any{"$ref": "#/templates/db"},
+		"replica": map[string]any{"$include": "replica.json"},
+	}
+	if !reflect.DeepEqual(root["databases"], wantRoot) {
+		t.Errorf("config.json databases = %v, want references kept", root["databases"])
+	}
+	if port := root["templates"].(map[string]any)["db"].(map[string]any)["port"]; port != float64(5433) {
+		t.Errorf("config.json templates.db.port = %v, want 5433", port)
+	}
+
+	replica := readJSON(t, filepath.Join(dir, "replica.json"))
+	if replica["host"] != "replica2.internal" {
+		t.Errorf("replica.json host = %v", replica["host"])
+	}
+	if port := replica["defaults"].(map[string]any)["port"]; port != float64(6433) {
+		t.Errorf("replica.json defaults.port = %v, want 6433", port)
+	}
+
+	// Replacing a referenced subtree writes to the referenced location
+	if err := store.SetTo("user", "/databases/primary", map[string]any{"host": "own", "port": 1}); err != nil {
+		t.Fatalf("SetTo() error = %v", err)
+	}
+	if err := store.Save(ctx); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	root = readJSON(t, filepath.Join(dir, "config.json"))
+	if db := root["templates"].(map[string]any)["db"]; !reflect.DeepEqual(db, map[string]any{"host": "own", "port": float64(1)}) {
+		t.Errorf("config.json templates.db = %v", db)
+	}
+
+	// Removing a reference removes it from the document containing it
+	if err := store.DeleteFrom("user", "/databases/replica/port"); err != nil {
+		t.Fatalf("DeleteFrom() error = %v", err)
+	}
+	if err := store.Save(ctx); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	replica = readJSON(t, filepath.Join(dir, "replica.json"))
+	if _, ok := replica["port"]; ok {
+		t.Errorf("replica.json port = %v, want removed", replica["port"])
+	}
+	if port := replica["defaults"].(map[string]any)["port"]; port != float64(6433) {
+		t.Errorf("replica.json defaults.port = %v, want 6433", port)
+	}
+}
+
+func TestLayer_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{"missing target", `{"a": {"$ref": "#/missing"}}`, `"#/missing" not found`},
+		{"cycle", `{"a": {"$ref": "#/b"}, "b": {"$ref": "#/a"}}`, "reference cycle"},
+		{"self", `{"a": {"b": {"$ref": "#/a"}}}`, "reference cycle"},
+		{"no pointer", `{"a": {"$ref": "other.json"}}`, "has no #<JSON Pointer>"},
+		{"not a string", `{"a": {"$include": 1}}`, "expected string"},
+		{"no relative source", `{"a": {"$include": "other.json"}}`, "does not implement source.RelativeSource"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := include.New("test", bytes.FromString(tt.doc), jjson.New())
+			_, err := l.Load(ctx)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("missing include", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "config.json"), `{"a": {"$include": "missing.json"}}`)
+		l := include.New("test", fs.New(filepath.Join(dir, "config.json")), jjson.New())
+		if _, err := l.Load(ctx); err == nil || !strings.Contains(err.Error(), "missing.json") {
+			t.Errorf("Load() error = %v, want error naming missing.json", err)
+		}
+	})
+}
+
+func TestLayer_Watch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.json"), `{"db": {"$include": "db.json"}}`)
+	writeFile(t, filepath.Join(dir, "db.json"), `{"host": "a"}`)
+
+	l := include.New("test", fs.New(filepath.Join(dir, "config.json")), jjson.New())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := l.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	w, err := l.Watch()
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	if err := w.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer w.Stop(context.Background())
+
+	writeFile(t, filepath.Join(dir, "db.json"), `{"host": "b"}`)
+
+	want := map[string]any{"db": map[string]any{"host": "b"}}
+	for {
+		select {
+		case result := <-w.Results():
+			if result.Error != nil {
+				t.Fatalf("watch error = %v", result.Error)
+			}
+			if reflect.DeepEqual(result.Data, want) {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for the included document change")
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package include
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yacchi/jubako/layer"
+)
+
+// Watch returns a LayerWatcher that watches the layer's document and every
+// document it includes. A change to any of them resolves the references again
+// and reports the resolved data. Documents included after a change are
+// watched from then on.
+func (l *Layer) Watch(opts ...layer.WatchOption) (layer.LayerWatcher, error) {
+	return &includeWatcher{layer: l, opts: opts}, nil
+}
+
+// fileResult is a watch result of a single document.
+type fileResult struct {
+	location string
+	result   layer.LayerWatchResult
+}
+
+// includeWatcher fans in the watchers of the documents of a Layer.
+type includeWatcher struct {
+	layer *Layer
+	opts  []layer.WatchOption
+
+	results  chan layer.LayerWatchResult
+	events   chan fileResult
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+
+	// watchers is only accessed by the run goroutine after Start.
+	watchers map[string]layer.LayerWatcher
+}
+
+// Start starts watching the documents known from the last Load.
+func (w *includeWatcher) Start(ctx context.Context) error {
+	w.results = make(chan layer.LayerWatchResult)
+	w.events = make(chan fileResult)
+	w.stopCh = make(chan struct{})
+	w.done = make(chan struct{})
+	w.watchers = make(map[string]layer.LayerWatcher)
+
+	if err := w.watchFiles(ctx); err != nil {
+		w.stopWatchers(ctx)
+		close(w.results)
+		close(w.done)
+		return err
+	}
+
+	go w.run(ctx)
+	return nil
+}
+
+// watchFiles starts watchers for the documents that are not watched yet.
+func (w *includeWatcher) watchFiles(ctx context.Context) error {
+	w.layer.mu.Lock()
+	files := make([]*file, 0, len(w.layer.files))
+	for _, f := range w.layer.files {
+		files = append(files, f)
+	}
+	w.layer.mu.Unlock()
+
+	for _, f := range files {
+		if _, ok := w.watchers[f.location]; ok {
+			continue
+		}
+		lw, err := f.layer.Watch(w.opts...)
+		if err != nil {
+			return err
+		}
+		if err := lw.Start(ctx); err != nil {
+			return err
+		}
+		w.watchers[f.location] = lw
+		go w.forward(f.location, lw)
+	}
+	return nil
+}
+
+// forward sends the results of a document watcher to the run goroutine.
+func (w *includeWatcher) forward(location string, lw layer.LayerWatcher) {
+	for result := range lw.Results() {
+		select {
+		case w.events <- fileResult{location: location, result: result}:
+		case <-w.stopCh:
+			return
+		}
+	}
+}
+
+// run resolves the references whenever a document changes.
+func (w *includeWatcher) run(ctx context.Context) {
+	defer close(w.done)
+	defer close(w.results)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stopCh:
+			return
+		case ev := <-w.events:
+			result := ev.result
+			if result.Error == nil {
+				result.Data, result.Error = w.layer.reload(ctx, ev.location, result.Data)
+			}
+			if result.Error == nil {
+				result.Error = w.watchFiles(ctx)
+			}
+			select {
+			case w.results <- result:
+			case <-ctx.Done():
+				return
+			case <-w.stopCh:
+				return
+			}
+		}
+	}
+}
+
+// Stop stops watching all documents.
+func (w *includeWatcher) Stop(ctx context.Context) error {
+	if w.stopCh == nil {
+		return nil
+	}
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+	})
+	<-w.done
+	return w.stopWatchers(ctx)
+}
+
+// stopWatchers stops the document watchers and returns the first error.
+func (w *includeWatcher) stopWatchers(ctx context.Context) error {
+	var firstErr error
+	for _, lw := range w.watchers {
+		if err := lw.Stop(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Results returns the channel receiving the resolved data.
+func (w *includeWatcher) Results() <-chan layer.LayerWatchResult {
+	return w.results
+}
+
+// reload resolves the references again after the document at location changed
+// to data. The other documents are taken from the last resolution.
+func (l *Layer) reload(ctx context.Context, location string, data map[string]any) (map[string]any, error) {
+	l.mu.Lock()
+	cached := make(map[string]map[string]any, len(l.files))
+	for key, f := range l.files {
+		cached[key] = f.data
+	}
+	l.mu.Unlock()
+	cached[location] = data
+
+	return l.resolve(newResolver(ctx, l, cached))
+}
@@ -5,6 +5,7 @@ package layer
 
 import (
 	"context"
+	"errors"
 	"sync"
 
 	"github.com/yacchi/jubako/document"
@@ -77,12 +78,50 @@ type DocumentProvider interface {
 	Document() document.Document
 }
 
+// ReferenceTracker is an optional interface for layers that assemble their data
+// from several documents, such as layers resolving $ref and $include references.
+// The Store reports the reference in ResolvedValue.Reference.
+type ReferenceTracker interface {
+	// ReferenceOf returns the location of the value at path (in the layer's data)
+	// as "<document>#<JSON Pointer>", for example "/etc/app/db.yaml#/host".
+	// The document part is empty for references within the layer's own document.
+	// Returns "" if the value is not provided through a reference.
+	ReferenceOf(path string) string
+}
+
+// FileTracker is an optional interface for layers that merge several files,
+// such as a conf.d directory. The Store reports the file that provides a value
+// through ResolvedValue.Layer.Path() and Format().
+type FileTracker interface {
+	// FileOf returns the path and document format of the file that provides
+	// the value at path (in the layer's data). ok is false if no file provides it.
+	FileOf(path string) (file string, format document.DocumentFormat, ok bool)
+}
+
+// RestorableLayer is an optional interface for layers whose saves can be undone.
+// Store.Tx uses it to restore the layers it has already written when saving
+// another layer of the transaction fails.
+type RestorableLayer interface {
+	// SaveRestorable saves changeset like Save and returns the bytes the source
+	// held before the write, as passed to source.UpdateFunc. previous is nil
+	// if the source did not exist.
+	SaveRestorable(ctx context.Context, changeset document.JSONPatchSet) (previous []byte, err error)
+
+	// Restore writes previous, as returned by SaveRestorable, back to the source.
+	// If previous is nil, the source is removed instead where it supports it
+	// (source.RemovableSource).
+	Restore(ctx context.Context, previous []byte) error
+}
+
 // Ensure basicLayer implements Layer interface (which includes types.DetailsFiller).
 var _ Layer = (*basicLayer)(nil)
 
 // Ensure basicLayer implements DocumentProvider interface.
 var _ DocumentProvider = (*basicLayer)(nil)
 
+// Ensure basicLayer implements RestorableLayer interface.
+var _ RestorableLayer = (*basicLayer)(nil)
+
 // New creates a new Layer with the given Source and Document.
 // The Document is stateless and handles format parsing/serialization.
 //
@@ -140,6 +179,44 @@ func (l *basicLayer) Save(ctx context.Context, changeset document.JSONPatchSet)
 	})
 }
 
+// SaveRestorable is like Save, and also returns the bytes the source held
+// before the write, as captured in the source's UpdateFunc, or nil if the
+// source did not exist.
+func (l *basicLayer) SaveRestorable(ctx context.Context, changeset document.JSONPatchSet) ([]byte, error) {
+	l.opMu.Lock()
+	defer l.opMu.Unlock()
+
+	_, err := l.loadRawNoLock(ctx)
+	existed := !errors.Is(err, source.ErrNotExist)
+
+	var previous []byte
+	err = l.source.Save(ctx, func(current []byte) ([]byte, error) {
+		if existed {
+			previous = append([]byte{}, current...)
+		}
+		return l.doc.Apply(current, changeset)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+// Restore writes previous back to the source, undoing a SaveRestorable.
+// A nil previous removes the source if it implements source.RemovableSource.
+// Restore is synchronized with Load and poll operations via opMu.
+func (l *basicLayer) Restore(ctx context.Context, previous []byte) error {
+	l.opMu.Lock()
+	defer l.opMu.Unlock()
+
+	if remover, ok := l.source.(source.RemovableSource); ok && previous == nil {
+		return remover.Remove(ctx)
+	}
+	return l.source.Save(ctx, func([]byte) ([]byte, error) {
+		return previous, nil
+	})
+}
+
 // FillDetails populates the Details struct with metadata from this layer.
 // It sets the source type, document format, watcher type, and delegates to the
 // underlying source if it implements types.DetailsFiller for additional details.
@@ -107,6 +107,40 @@ func TestFileLayer_LoadSaveAndMetadata(t *testing.T) {
 	}
 }
 
+func TestFileLayer_SaveRestorable(t *testing.T) {
+	original := []byte("{\"a\": 1}\n")
+	src := &memSource{data: original, canSave: true}
+	l, ok := New("test", src, json.New()).(RestorableLayer)
+	if !ok {
+		t.Fatal("layer does not implement RestorableLayer")
+	}
+
+	var patches document.JSONPatchSet
+	patches.Add("/b", "x")
+	previous, err := l.SaveRestorable(context.Background(), patches)
+	if err != nil {
+		t.Fatalf("SaveRestorable() error = %v", err)
+	}
+	if string(previous) != string(original) {
+		t.Errorf("SaveRestorable() previous = %q, want %q", previous, original)
+	}
+	if string(src.data) == string(original) {
+		t.Fatal("SaveRestorable() did not write the changeset")
+	}
+
+	if err := l.Restore(context.Background(), previous); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if string(src.data) != string(original) {
+		t.Errorf("after Restore, data = %q, want %q", src.data, original)
+	}
+
+	src.canSave = false
+	if _, err := l.SaveRestorable(context.Background(), patches); !errors.Is(err, source.ErrSaveNotSupported) {
+		t.Errorf("SaveRestorable() error = %v, want ErrSaveNotSupported", err)
+	}
+}
+
 func TestFileLayer_CanSaveFalse(t *testing.T) {
 	doc := json.New()
 	src := &memSource{
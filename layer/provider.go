@@ -35,6 +35,16 @@ type StoreProvider interface {
 	FieldTagName() string
 }
 
+// ValueConverterProvider is an optional interface for StoreProviders that
+// convert values to the field types of the Store's configuration struct.
+// Store implements it; layers check for it with a type assertion.
+type ValueConverterProvider interface {
+	// ConvertValue converts value to targetType using the Store's converters
+	// and ValueConverter. path is the JSON Pointer path of the value, used in
+	// error messages.
+	ConvertValue(path string, value any, targetType reflect.Type) (any, error)
+}
+
 // StoreAwareLayerFunc is a function that creates a Layer with access to StoreProvider.
 // It implements Layer interface so it can be passed to Store.Add directly.
 // Store.Add detects StoreAwareLayerFunc via StoreAwareLayerInitializer interface and calls
@@ -14,6 +14,12 @@ type LayerWatchResult struct {
 
 	// Error is set if the watch encountered an error.
 	Error error
+
+	// Applied, if set, is called by the Store once Data has replaced the
+	// layer's data. It is not called for updates the Store rejects, so layers
+	// use it to commit state derived from Data, such as the files reported by
+	// FileTracker.
+	Applied func()
 }
 
 // LayerWatcher watches a layer for changes and notifies via a channel.
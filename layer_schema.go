@@ -0,0 +1,93 @@
+package jubako
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yacchi/jubako/jsonschema"
+	"github.com/yacchi/jubako/layer"
+)
+
+// WithLayerSchema validates the layer's data against a JSON Schema document
+// whenever the layer is loaded, reloaded or updated by a watcher.
+// This is useful for layers owned by other teams that publish a JSON Schema
+// rather than a Go type.
+//
+// The data is checked as read from the source, before migrations (see
+// WithMigrations). Data that does not match the schema is rejected with a
+// *ValidationError naming the layer, which wraps a *LayerSchemaError listing
+// the path of each violation; use errors.As to inspect either. Load and
+// Reload fail and leave every layer as it was, and watch updates are reported
+// to StoreWatchConfig.OnError while the layer keeps its previous data, so an
+// invalid layer never reaches the merged configuration.
+//
+// The schema is compiled when the option is applied; Add and ReplaceLayer
+// return an error if it is not a valid schema. See the jsonschema package for
+// the supported keywords.
+//
+// Example:
+//
+//	//go:embed schemas/remote.json
+//	var remoteSchema []byte
+//
+//	store.Add(
+//	    layer.New("remote", httpSource, yaml.New()),
+//	    jubako.WithLayerSchema(remoteSchema),
+//	)
+func WithLayerSchema(schema []byte) AddOption {
+	compiled, err := jsonschema.Compile(schema)
+	return func(o *addOptions) {
+		o.schema = compiled
+		o.schemaErr = err
+	}
+}
+
+// LayerSchemaError reports layer data that does not match the JSON Schema
+// given with WithLayerSchema. It is returned wrapped in a *ValidationError.
+type LayerSchemaError struct {
+	// Layer provides metadata about the rejected layer.
+	Layer LayerInfo
+
+	// Violations lists the schema violations, each with the JSON Pointer of
+	// the offending value within the layer's data.
+	Violations []jsonschema.Violation
+}
+
+// Error implements the error interface.
+// The message names the layer, source and violations, for example:
+// "layer remote (https://example.com/config.yaml) does not match its JSON Schema: /server/port: must be <= 65535".
+func (e *LayerSchemaError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "layer %s", e.Layer.Name())
+	if path := e.Layer.Path(); path != "" {
+		fmt.Fprintf(&sb, " (%s)", path)
+	}
+	sb.WriteString(" does not match its JSON Schema: ")
+	for i, v := range e.Violations {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(v.String())
+	}
+	return sb.String()
+}
+
+// validateLayerSchema checks freshly loaded data against the layer's schema.
+// Violations are returned as a *ValidationError naming the layer.
+func validateLayerSchema(entry *layerEntry, data map[string]any) error {
+	if entry.schema == nil {
+		return nil
+	}
+	var value any = data
+	if data == nil {
+		// A missing document is an empty object, as for the merged configuration
+		value = map[string]any{}
+	}
+	if violations := entry.schema.Validate(value); len(violations) > 0 {
+		return &ValidationError{
+			Layers: []layer.Name{entry.layer.Name()},
+			Err:    &LayerSchemaError{Layer: entry, Violations: violations},
+		}
+	}
+	return nil
+}
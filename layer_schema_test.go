@@ -0,0 +1,161 @@
+package jubako
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/format/json"
+	"github.com/yacchi/jubako/jsonschema"
+	"github.com/yacchi/jubako/layer"
+	"github.com/yacchi/jubako/layer/mapdata"
+	"github.com/yacchi/jubako/source/fs"
+)
+
+const layerSchemaTestSchema = `{
+	"type": "object",
+	"properties": {
+		"server": {
+			"type": "object",
+			"properties": {
+				"host": {"type": "string"},
+				"port": {"type": "integer", "minimum": 1, "maximum": 65535}
+			},
+			"required": ["host"]
+		}
+	}
+}`
+
+type layerSchemaTestConfig struct {
+	Server struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	} `json:"server"`
+}
+
+func TestStore_WithLayerSchema(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("valid", func(t *testing.T) {
+		store := New[layerSchemaTestConfig]()
+		data := map[string]any{"server": map[string]any{"host": "example.com", "port": 8080}}
+		if err := store.Add(mapdata.New("remote", data), WithLayerSchema([]byte(layerSchemaTestSchema))); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if cfg := store.Get(); cfg.Server.Port != 8080 {
+			t.Errorf("Get() = %+v", cfg)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "remote.json")
+		if err := os.WriteFile(path, []byte(`{"server": {"port": 70000}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		store := New[layerSchemaTestConfig]()
+		if err := store.Add(mapdata.New("defaults", map[string]any{"server": map[string]any{"host": "localhost"}})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Add(layer.New("remote", fs.New(path), json.New()), WithLayerSchema([]byte(layerSchemaTestSchema))); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+
+		err := store.Load(ctx)
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("Load() error = %v, want *ValidationError", err)
+		}
+		if len(verr.Layers) != 1 || verr.Layers[0] != "remote" {
+			t.Errorf("Layers = %v, want [remote]", verr.Layers)
+		}
+		var schemaErr *LayerSchemaError
+		if !errors.As(err, &schemaErr) {
+			t.Fatalf("Load() error = %v, want *LayerSchemaError", err)
+		}
+		if schemaErr.Layer.Name() != "remote" || schemaErr.Layer.Path() != path {
+			t.Errorf("Layer = %s (%s)", schemaErr.Layer.Name(), schemaErr.Layer.Path())
+		}
+		want := []jsonschema.Violation{
+			{Path: "/server", Keyword: "required", Message: `missing required property "host"`},
+			{Path: "/server/port", Keyword: "maximum", Message: "must be <= 65535"},
+		}
+		if len(schemaErr.Violations) != len(want) {
+			t.Fatalf("Violations = %v, want %v", schemaErr.Violations, want)
+		}
+		for i := range want {
+			if schemaErr.Violations[i] != want[i] {
+				t.Errorf("Violations[%d] = %v, want %v", i, schemaErr.Violations[i], want[i])
+			}
+		}
+		wantMsg := "config validation failed (layers: remote): layer remote (" + path + `) does not match its JSON Schema: /server: missing required property "host"; /server/port: must be <= 65535`
+		if err.Error() != wantMsg {
+			t.Errorf("Error() = %q, want %q", err.Error(), wantMsg)
+		}
+
+		// The rejected layer never reaches the merged configuration
+		if rv := store.GetAt("/server/port"); rv.Exists {
+			t.Errorf("GetAt(/server/port) = %v, want no value", rv.Value)
+		}
+	})
+
+	t.Run("rejected reload", func(t *testing.T) {
+		remote := mapdata.New("remote", map[string]any{"server": map[string]any{"host": "example.com", "port": 8080}})
+		store := New[layerSchemaTestConfig]()
+		if err := store.Add(mapdata.New("defaults", map[string]any{"server": map[string]any{"port": 80}})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Add(remote, WithLayerSchema([]byte(layerSchemaTestSchema))); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if err := store.SetTo("defaults", "/server/port", 81); err != nil {
+			t.Fatalf("SetTo() error = %v", err)
+		}
+
+		if err := remote.Save(ctx, document.JSONPatchSet{document.NewReplacePatch("/server", map[string]any{"port": 0})}); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		err := store.Reload(ctx)
+		var verr *ValidationError
+		if !errors.As(err, &verr) || len(verr.Layers) != 1 || verr.Layers[0] != "remote" {
+			t.Fatalf("Reload() error = %v, want *ValidationError for remote", err)
+		}
+
+		// Every layer keeps its previous data
+		if rv := store.GetAt("/server/port"); rv.Value != 8080 || rv.Layer.Name() != "remote" {
+			t.Errorf("GetAt(/server/port) = %v from %s, want 8080 from remote", rv.Value, rv.Layer.Name())
+		}
+		if !store.GetLayerInfo("defaults").Dirty() {
+			t.Error("defaults should keep its pending change")
+		}
+	})
+
+	t.Run("invalid schema", func(t *testing.T) {
+		store := New[layerSchemaTestConfig]()
+		err := store.Add(mapdata.New("remote", nil), WithLayerSchema([]byte(`{"type": 1}`)))
+		if err == nil || !strings.Contains(err.Error(), `invalid schema for layer "remote"`) {
+			t.Fatalf("Add() error = %v, want invalid schema error", err)
+		}
+		if len(store.ListLayers()) != 0 {
+			t.Error("the layer should not be added")
+		}
+
+		if err := store.Add(mapdata.New("remote", nil)); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		err = store.ReplaceLayer(ctx, "remote", mapdata.New("remote", nil), WithLayerSchema([]byte(`{`)))
+		if err == nil || !strings.Contains(err.Error(), `invalid schema for layer "remote"`) {
+			t.Fatalf("ReplaceLayer() error = %v, want invalid schema error", err)
+		}
+	})
+}
@@ -0,0 +1,451 @@
+// Package lint provides a static analyzer for the jubako struct tags of
+// configuration types.
+//
+// Mistakes in jubako tags otherwise only show up when a Store is created or
+// an environment layer is loaded at runtime, often silently. The Analyzer
+// reports them as compiler-style diagnostics:
+//
+//   - the sensitive directive on a struct, map, slice or array field
+//   - a malformed env: pattern, or one whose {key} and {index} placeholders
+//     do not match the maps and slices the field is nested in
+//   - two fields, or a field and an alias, resolving to the same path
+//   - two fields mapped from the same environment variable
+//   - the union= directive on a field that is not an interface, or a slice
+//     or map of an interface
+//   - a min= or max= bound that is not a number, a pattern= that does not
+//     compile, or an unknown merge: strategy
+//
+// The checks start from the configuration structs of a package, i.e. the
+// struct types using jubako tags that no other such struct contains. Types
+// declared in other packages are checked when their own package is analyzed.
+//
+// The analyzer is run by "jubako lint ./...". To run it from go vet, build a
+// vet tool with unitchecker:
+//
+//	package main
+//
+//	import (
+//		"github.com/yacchi/jubako/lint"
+//		"golang.org/x/tools/go/analysis/unitchecker"
+//	)
+//
+//	func main() { unitchecker.Main(lint.Analyzer) }
+//
+// and pass it to go vet:
+//
+//	go build -o jubako-vet . && go vet -vettool=$(pwd)/jubako-vet ./...
+package lint
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/yacchi/jubako/internal/tag"
+	"github.com/yacchi/jubako/jsonptr"
+	"github.com/yacchi/jubako/layer/env"
+	"golang.org/x/tools/go/analysis"
+)
+
+const doc = `check jubako struct tags of configuration types
+
+Reports sensitive directives on non-leaf fields, malformed env: patterns,
+fields remapped onto the path of another field, environment variables
+mapped to more than one field, union directives on fields that cannot
+hold variants and invalid constraint and merge directives.`
+
+// Analyzer reports mistakes in the jubako struct tags of configuration types.
+var Analyzer = &analysis.Analyzer{
+	Name: "jubako",
+	Doc:  doc,
+	URL:  "https://pkg.go.dev/github.com/yacchi/jubako/lint",
+	Run:  run,
+}
+
+var (
+	// tagName is the struct tag used for field keys (see jubako.WithTagName).
+	tagName string
+	// delimiter separates the directives of jubako tags (see jubako.WithTagDelimiter).
+	delimiter string
+)
+
+func init() {
+	Analyzer.Flags.StringVar(&tagName, "tag", tag.DefaultFieldTagName, "struct tag name used for field keys, as set by jubako.WithTagName")
+	Analyzer.Flags.StringVar(&delimiter, "delimiter", tag.DefaultDelimiter, "delimiter of jubako tag directives, as set by jubako.WithTagDelimiter")
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	reported := make(map[string]bool)
+	for _, root := range configRoots(pass.Pkg) {
+		c := &checker{
+			pass:     pass,
+			reported: reported,
+			paths:    make(map[string]fieldRef),
+			envVars:  make(map[string]fieldRef),
+		}
+		c.walk(root.Type().Underlying().(*types.Struct), root.Name(), "", "", nil)
+	}
+	return nil, nil
+}
+
+// fieldRef identifies a field within a configuration struct.
+type fieldRef struct {
+	pos  token.Pos
+	name string // e.g., Config.Server.Port
+	// via describes how the tag moves the field: "" when it keeps its
+	// structural path, "remapped" or "alias".
+	via string
+}
+
+// checker walks the fields of one configuration struct, following the rules
+// used to build the mapping table and the environment variable schema.
+type checker struct {
+	pass     *analysis.Pass
+	reported map[string]bool
+	// paths maps each resolved path to the field it belongs to.
+	// Slice elements and map values are written as "*".
+	paths map[string]fieldRef
+	// envVars maps each environment variable name or pattern to its field.
+	envVars map[string]fieldRef
+}
+
+// walk checks the fields of st. prefix is the path of st in the mapping
+// table, while envPrefix is its path in the environment variable schema,
+// where slice elements and map values are written as {index} and {key}.
+// stack holds the structs being walked to stop at recursive types.
+func (c *checker) walk(st *types.Struct, typePath, prefix, envPrefix string, stack []*types.Struct) {
+	if containsStruct(stack, st) {
+		return
+	}
+	stack = append(stack, st)
+
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		if !v.Exported() {
+			continue
+		}
+
+		field := reflect.StructField{Name: v.Name(), Tag: reflect.StructTag(st.Tag(i))}
+		key := tag.ParseFieldKey(field, tagName)
+		if key == "-" {
+			continue
+		}
+		var info tag.FieldInfo
+		if jubakoTag, ok := field.Tag.Lookup(tag.JubakoTagName); ok {
+			info = tag.ParseJubakoTag(jubakoTag, delimiter)
+		}
+		if info.Skipped {
+			continue
+		}
+
+		ref := fieldRef{pos: v.Pos(), name: typePath + "." + v.Name()}
+		if info.Err != nil {
+			c.report(ref.pos, "jubako tag of field %s: %v", ref.name, info.Err)
+		}
+		path := prefix + "/" + key
+		if info.Path != "" {
+			path = info.Path
+			if info.IsRelative {
+				path = prefix + info.Path
+			}
+			c.checkPath(path, fieldRef{pos: ref.pos, name: ref.name, via: "remapped"})
+		} else {
+			c.checkPath(path, ref)
+		}
+		for _, alias := range info.Aliases {
+			aliasPath := alias
+			if !strings.HasPrefix(alias, "/") {
+				aliasPath = prefix + "/" + alias
+			}
+			c.checkPath(aliasPath, fieldRef{pos: ref.pos, name: ref.name, via: "alias"})
+		}
+
+		fieldType := v.Type()
+		if ptr, ok := fieldType.Underlying().(*types.Pointer); ok {
+			fieldType = ptr.Elem()
+		}
+		if info.Sensitive == tag.SensitiveExplicit {
+			if kind := containerKind(fieldType); kind != "" {
+				c.report(ref.pos, "sensitive directive on %s field %s: mark its leaf fields as sensitive instead", kind, ref.name)
+			}
+		}
+		if info.Union != "" && !unionType(v.Type()) {
+			c.report(ref.pos, "union directive on field %s: its type must be an interface, or a slice or map of an interface", ref.name)
+		}
+
+		envKey := tag.ParseFieldKey(field, tag.DefaultFieldTagName)
+		envPath := envPrefix + "/" + jsonptr.Escape(envKey)
+		if info.EnvVar != "" && envKey != "-" {
+			target := envPath
+			if info.Path != "" {
+				target = info.Path
+				if info.IsRelative {
+					target = envPrefix + info.Path
+				}
+			}
+			c.checkEnv(info.EnvVar, target, ref)
+		}
+
+		// Descend into structs declared in this package, as elements and
+		// values of slices and maps too.
+		switch t := fieldType.Underlying().(type) {
+		case *types.Struct:
+			if c.local(fieldType) {
+				c.walk(t, ref.name, prefix+"/"+key, envPath, stack)
+			}
+		case *types.Slice, *types.Array, *types.Map:
+			elem, placeholder := t.(interface{ Elem() types.Type }).Elem(), "{index}"
+			if _, ok := t.(*types.Map); ok {
+				placeholder = "{key}"
+			}
+			if ptr, ok := elem.Underlying().(*types.Pointer); ok {
+				elem = ptr.Elem()
+			}
+			if est, ok := elem.Underlying().(*types.Struct); ok && c.local(elem) {
+				c.walk(est, ref.name+"[]", prefix+"/"+key+"/*", envPath+"/"+placeholder, stack)
+			}
+		}
+	}
+}
+
+// checkPath records path for ref and reports a collision with another field.
+func (c *checker) checkPath(path string, ref fieldRef) {
+	other, ok := c.paths[path]
+	if !ok {
+		c.paths[path] = ref
+		return
+	}
+	if other.name == ref.name {
+		return
+	}
+	// Report at the tag that moved a field onto the path
+	if other.via != "" && ref.via == "" {
+		ref, other = other, ref
+	}
+	what := "path"
+	if ref.via != "" {
+		what = ref.via + " path"
+	}
+	c.report(ref.pos, "%s %s of field %s collides with field %s", what, path, ref.name, other.name)
+}
+
+// checkEnv validates the env: directive of ref, which maps to target, and
+// reports an environment variable already mapped to another field.
+func (c *checker) checkEnv(pattern, target string, ref fieldRef) {
+	if err := env.ValidatePattern(pattern); err != nil {
+		c.report(ref.pos, "env directive of field %s: %v", ref.name, err)
+		return
+	}
+	for _, p := range []struct{ placeholder, container string }{
+		{"{key}", "map"},
+		{"{index}", "slice"},
+	} {
+		inPattern := strings.Contains(pattern, p.placeholder[:len(p.placeholder)-1])
+		inPath := strings.Contains(target, p.placeholder)
+		switch {
+		case inPath && !inPattern:
+			c.report(ref.pos, "env directive of field %s: %q needs a %s placeholder as %s is inside a %s", ref.name, pattern, p.placeholder, target, p.container)
+			return
+		case inPattern && !inPath:
+			c.report(ref.pos, "env directive of field %s: %q uses %s but %s is not inside a %s", ref.name, pattern, p.placeholder, target, p.container)
+			return
+		}
+	}
+
+	if other, ok := c.envVars[pattern]; ok && other.name != ref.name {
+		c.report(ref.pos, "environment variable %s of field %s is also mapped to field %s", pattern, ref.name, other.name)
+		return
+	}
+	c.envVars[pattern] = ref
+}
+
+// local reports whether t is declared in the analyzed package or is an
+// anonymous type, whose fields are positioned in its files.
+func (c *checker) local(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return true
+	}
+	return named.Obj().Pkg() == c.pass.Pkg
+}
+
+func (c *checker) report(pos token.Pos, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	key := fmt.Sprintf("%d:%s", pos, msg)
+	if c.reported[key] {
+		return
+	}
+	c.reported[key] = true
+	c.pass.Reportf(pos, "%s", msg)
+}
+
+// convertedTypes are the types converted as a whole by the default
+// jubako.Converters, which are leaves despite their structure.
+var convertedTypes = map[string]bool{
+	"net.IP":           true,
+	"net/netip.Addr":   true,
+	"net/netip.Prefix": true,
+	"net/url.URL":      true,
+	"regexp.Regexp":    true,
+	"time.Location":    true,
+}
+
+// containerKind returns the kind of t as used in the sensitive warning, or
+// "" for leaf types.
+func containerKind(t types.Type) string {
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil {
+		if convertedTypes[named.Obj().Pkg().Path()+"."+named.Obj().Name()] {
+			return ""
+		}
+	}
+	switch t.Underlying().(type) {
+	case *types.Struct:
+		return "struct"
+	case *types.Map:
+		return "map"
+	case *types.Slice:
+		return "slice"
+	case *types.Array:
+		return "array"
+	}
+	return ""
+}
+
+// unionType reports whether t can hold the variants of a union: an
+// interface, or a slice or a map with string keys of an interface.
+func unionType(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Interface:
+		return true
+	case *types.Slice:
+		return types.IsInterface(u.Elem())
+	case *types.Map:
+		key, ok := u.Key().Underlying().(*types.Basic)
+		return ok && key.Kind() == types.String && types.IsInterface(u.Elem())
+	}
+	return false
+}
+
+// configRoots returns the struct types of pkg that use jubako tags and are
+// not contained in another such struct, sorted by name.
+func configRoots(pkg *types.Package) []*types.TypeName {
+	var candidates []*types.TypeName
+	for _, name := range pkg.Scope().Names() {
+		tn, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		named, ok := tn.Type().(*types.Named)
+		if !ok || named.TypeParams().Len() > 0 {
+			continue
+		}
+		if st, ok := named.Underlying().(*types.Struct); ok && usesJubakoTags(pkg, st, nil) {
+			candidates = append(candidates, tn)
+		}
+	}
+
+	contained := make(map[*types.TypeName]bool)
+	for _, tn := range candidates {
+		forEachStruct(pkg, tn.Type().Underlying().(*types.Struct), nil, func(named *types.Named) {
+			if named.Obj() != tn {
+				contained[named.Obj()] = true
+			}
+		})
+	}
+
+	var roots []*types.TypeName
+	for _, tn := range candidates {
+		if !contained[tn] {
+			roots = append(roots, tn)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].Name() < roots[j].Name() })
+	return roots
+}
+
+// usesJubakoTags reports whether st or a struct of pkg within it has a field
+// with a jubako tag.
+func usesJubakoTags(pkg *types.Package, st *types.Struct, stack []*types.Struct) bool {
+	if containsStruct(stack, st) {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if _, ok := reflect.StructTag(st.Tag(i)).Lookup(tag.JubakoTagName); ok {
+			return true
+		}
+	}
+	for _, nested := range nestedStructs(pkg, st) {
+		if usesJubakoTags(pkg, nested, append(stack, st)) {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachStruct calls fn for each named struct type of pkg reachable from the
+// fields of st.
+func forEachStruct(pkg *types.Package, st *types.Struct, stack []*types.Struct, fn func(*types.Named)) {
+	if containsStruct(stack, st) {
+		return
+	}
+	stack = append(stack, st)
+	for i := 0; i < st.NumFields(); i++ {
+		t := fieldStruct(st.Field(i).Type())
+		if named, ok := t.(*types.Named); ok {
+			if named.Obj().Pkg() != pkg {
+				continue
+			}
+			fn(named)
+		}
+		if nested, ok := t.Underlying().(*types.Struct); ok {
+			forEachStruct(pkg, nested, stack, fn)
+		}
+	}
+}
+
+// nestedStructs returns the struct types of pkg, or anonymous struct types,
+// used by the fields of st.
+func nestedStructs(pkg *types.Package, st *types.Struct) []*types.Struct {
+	var nested []*types.Struct
+	for i := 0; i < st.NumFields(); i++ {
+		t := fieldStruct(st.Field(i).Type())
+		if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != pkg {
+			continue
+		}
+		if s, ok := t.Underlying().(*types.Struct); ok {
+			nested = append(nested, s)
+		}
+	}
+	return nested
+}
+
+// fieldStruct unwraps pointers, slices, arrays and maps around the type of a
+// field.
+func fieldStruct(t types.Type) types.Type {
+	for {
+		switch u := t.Underlying().(type) {
+		case *types.Pointer:
+			t = u.Elem()
+		case *types.Slice:
+			t = u.Elem()
+		case *types.Array:
+			t = u.Elem()
+		case *types.Map:
+			t = u.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+func containsStruct(stack []*types.Struct, st *types.Struct) bool {
+	for _, s := range stack {
+		if types.Identical(s, st) {
+			return true
+		}
+	}
+	return false
+}
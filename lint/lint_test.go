@@ -0,0 +1,20 @@
+package lint
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "a")
+}
+
+func TestAnalyzer_TagName(t *testing.T) {
+	if err := Analyzer.Flags.Set("tag", "yaml"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = Analyzer.Flags.Set("tag", "json") })
+
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "b")
+}
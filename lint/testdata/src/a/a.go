@@ -0,0 +1,61 @@
+package a
+
+import (
+	"net/url"
+	"time"
+)
+
+type Config struct {
+	Server   Server            `json:"server"`
+	Database Database          `json:"database" jubako:"sensitive"` // want `sensitive directive on struct field Config.Database: mark its leaf fields as sensitive instead`
+	Users    map[string]User   `json:"users"`
+	Backends []Backend         `json:"backends"`
+	Tokens   []string          `json:"tokens" jubako:"sensitive"` // want `sensitive directive on slice field Config.Tokens`
+	Labels   map[string]string `json:"labels"`
+	Started  time.Time         `json:"started"`
+	Password string            `json:"password" jubako:"sensitive,env:DB_PASSWORD"`
+	DSN      *url.URL          `json:"dsn" jubako:"sensitive"`
+	Storage  Storage           `json:"storage" jubako:"union=type"`
+	Mirrors  []Storage         `json:"mirrors" jubako:"union=type"`
+	Cache    string            `json:"cache" jubako:"union=type"`       // want `union directive on field Config.Cache: its type must be an interface, or a slice or map of an interface`
+	Secret   string            `json:"secret" jubako:"env:DB_PASSWORD"` // want `environment variable DB_PASSWORD of field Config.Secret is also mapped to field Config.Password`
+	Addr     string            `json:"addr" jubako:"/server/host"`      // want `remapped path /server/host of field Config.Addr collides with field Config.Server.Host`
+	Listen   string            `json:"listen" jubako:"/server"`         // want `remapped path /server of field Config.Listen collides with field Config.Server`
+	Retries  int               `json:"retries" jubako:"min=none"`       // want `jubako tag of field Config.Retries: invalid min= directive: "none" is not a number`
+	Plugins  []string          `json:"plugins" jubako:"merge:apend"`    // want `jubako tag of field Config.Plugins: invalid merge: directive: unknown strategy "apend"`
+	internal string            `jubako:"sensitive"`
+	Ignored  map[string]string `json:"-" jubako:"sensitive"`
+	Legacy   string            `json:"legacy" jubako:"-"`
+}
+
+type Storage interface {
+	Open() error
+}
+
+type Server struct {
+	Host string `json:"host" jubako:"env:SERVER_HOST"`
+	Port int    `json:"port" jubako:"env:SERVER_{port}"`     // want `env directive of field Config.Server.Port: malformed placeholder in "SERVER_{port}": use {key} or {index}`
+	Bind string `json:"bind" jubako:"env:SERVER_{key|trim}"` // want `env directive of field Config.Server.Bind: invalid pattern "SERVER_{key|trim}"`
+	Name string `json:"name" jubako:"alias=host"`            // want `alias path /server/host of field Config.Server.Name collides with field Config.Server.Host`
+}
+
+type Database struct {
+	URL string `json:"url"`
+}
+
+type User struct {
+	Name  string `json:"name" jubako:"env:USERS_{key}_NAME"`
+	Role  string `json:"role" jubako:"env:USERS_ROLE"`        // want `env directive of field Config.Users\[\].Role: "USERS_ROLE" needs a \{key\} placeholder as /users/\{key\}/role is inside a map`
+	Email string `json:"email" jubako:"env:USERS_{key}_NAME"` // want `environment variable USERS_\{key\}_NAME of field Config.Users\[\].Email is also mapped to field Config.Users\[\].Name`
+}
+
+type Backend struct {
+	URL  string `json:"url" jubako:"env:BACKEND_{index}_URL"`
+	Name string `json:"name" jubako:"env:BACKEND_{key}_NAME"` // want `"BACKEND_\{key\}_NAME" uses \{key\} but /backends/\{index\}/name is not inside a map`
+}
+
+// Node is recursive and is walked once per level.
+type Node struct {
+	Name     string `json:"name" jubako:"env:NODE_NAME"`
+	Children []Node `json:"children"`
+}
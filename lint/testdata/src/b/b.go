@@ -0,0 +1,8 @@
+// Package b uses the yaml tag for field keys.
+package b
+
+type Config struct {
+	Port    int    `yaml:"port" json:"listen_port"`
+	Listen  int    `yaml:"listen" jubako:"/port"` // want `remapped path /port of field Config.Listen collides with field Config.Port`
+	Address string `yaml:"address" jubako:"/listen_port"`
+}
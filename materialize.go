@@ -17,10 +17,12 @@ import (
 //
 // The merging process:
 // 1. Sort layers by priority (lowest first)
-// 2. Merge each layer's data into a single map, tracking origins
-// 3. Unmarshal the merged map into the configuration type T
-// 4. Update the resolved Cell with the new value
-// 5. Notify all subscribers
+// 2. Merge each layer's data (with aliases resolved) into a single map, tracking origins
+// 3. Expand ${...} references when interpolation is enabled
+// 4. Unmarshal the merged map into the configuration type T
+// 5. Validate the decoded value (a rejected value keeps the previous one)
+// 6. Update the resolved Cell with the new value
+// 7. Notify all subscribers
 func (s *Store[T]) materializeLocked(ctx context.Context) (T, []subscriber[T], error) {
 	if len(s.layers) == 0 {
 		// No layers - use zero value
@@ -35,37 +37,100 @@ func (s *Store[T]) materializeLocked(ctx context.Context) (T, []subscriber[T], e
 		return zero, nil, err
 	}
 
-	merged := s.mergeLayerDataLocked(func(entry *layerEntry) map[string]any {
+	// Report keys that do not map to any field of T. Each layer is checked on
+	// its own, so that the report names the layer and file containing the key.
+	if err := s.checkUnknownKeysLocked(); err != nil {
+		var zero T
+		return zero, nil, &ValidationError{Err: err}
+	}
+
+	// Merge all layers into fresh origins after stabilization settles, recording
+	// which layer contributed each path. The origins are published together with
+	// the value so that GetAt stays consistent with Get if validation fails.
+	origins := newOrigins()
+	origins.sentinel = s.unsetSentinel
+	merger := layerMerger{trie: s.schema.Trie, origins: origins, sentinel: s.unsetSentinel}
+	// Values at alias paths are read as their fields when the layer has no value
+	// at the field's path. origins resolves values from the aliased data.
+	aliased := s.resolveAliasesLocked()
+	origins.aliased = aliased
+	merged := s.mergeEntriesLocked(merger, func(entry *layerEntry) map[string]any {
+		if data, ok := aliased[entry]; ok {
+			return data
+		}
 		return entry.data
 	})
 
-	// Clear existing origins after stabilization settles.
-	s.origins.clear()
-	for _, entry := range s.layers {
-		if entry.data == nil {
-			continue
+	// Expand ${...} references in string values. Layers keep the templates;
+	// origins records the expanded values so that GetAt can report both.
+	if s.interpolation {
+		expanded, changed, err := interpolate(merged, origins)
+		if err != nil {
+			var zero T
+			return zero, nil, &ValidationError{Err: err}
 		}
-		walkMapForOrigins("", entry.data, entry, s.origins)
+		merged = expanded
+		origins.setInterpolated(expanded, changed)
 	}
 
-	// Convert merged map to type T
-	// 1. Apply path remapping based on pre-built mapping table (from jubako struct tags)
-	//    Also applies type conversion using the configured ValueConverter
-	remapped := applyMappings(merged, s.schema.Table, s.valueConverter)
+	// Check the declarative constraints from jubako struct tags, the values
+	// of types with a Converter and the discriminators of union fields before
+	// decoding, so that violations can be reported with the path and layer of
+	// the value.
+	if s.hasConstraints || s.hasConverters || s.hasUnions {
+		if err := checkConstraints(merged, s.schema.Table, origins, s.converters); err != nil {
+			var zero T
+			return zero, nil, &ValidationError{Err: err}
+		}
+	}
 
-	// 2. Decode using the configured decoder
+	// Convert merged map to type T
 	var result T
-	if err := s.decoder(remapped, &result); err != nil {
+	if err := s.decodeInto(merged, &result); err != nil {
 		var zero T
 		return zero, nil, fmt.Errorf("failed to decode merged config: %w", err)
 	}
 
+	// Validate before publishing; the previous value stays in place on failure
+	if err := s.validate(result); err != nil {
+		var zero T
+		return zero, nil, err
+	}
+
 	// Update the resolved value. Subscribers are notified by the caller after locks are released.
+	s.origins = origins
 	s.resolved.Set(result)
 	subscribers := append([]subscriber[T](nil), s.subscribers...)
 	return result, subscribers, nil
 }
 
+// decodeInto converts data, a map with the paths of layer documents, into dst.
+func (s *Store[T]) decodeInto(data map[string]any, dst *T) error {
+	// 1. Apply path remapping based on pre-built mapping table (from jubako struct tags)
+	//    Also applies type conversion using the configured converters and ValueConverter
+	remapped := applyMappings(data, s.schema.Table, s.convertValue)
+
+	// 2. Decode using the configured decoder. Union fields are decoded into
+	//    the variants selected by their discriminators afterwards.
+	decoded := remapped
+	if s.hasUnions {
+		decoded = withoutUnions(remapped, s.schema.Table)
+	}
+	if err := s.decoder(decoded, dst); err != nil {
+		return err
+	}
+	if s.hasConverters {
+		assignConverted(reflect.ValueOf(dst), remapped, s.schema.Table, s.converters)
+	}
+	if s.hasUnions {
+		unions := unionDecoder{decode: s.decoder, converters: s.converters}
+		if err := unions.assign(reflect.ValueOf(dst), remapped, s.schema.Table, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 const maxStabilizationPasses = 8
 
 func (s *Store[T]) stabilizeLayersLocked(ctx context.Context) error {
@@ -84,6 +149,8 @@ func (s *Store[T]) stabilizeLayersLocked(ctx context.Context) error {
 		snapshot := s.mergeLayerDataLocked(func(entry *layerEntry) map[string]any {
 			return entry.data
 		})
+		// Fill layers only supply missing values, so they see the other layers alone.
+		var fillSnapshot map[string]any
 		schemaView := newStoreSchemaView(s.schema)
 
 		changed := false
@@ -96,10 +163,19 @@ func (s *Store[T]) stabilizeLayersLocked(ctx context.Context) error {
 				continue
 			}
 
-			result, err := stabilizer.Stabilize(ctx, storeStabilizeContext{
-				snapshot: snapshot,
-				schema:   schemaView,
-			})
+			stabilizeCtx := storeStabilizeContext{snapshot: snapshot, schema: schemaView}
+			if entry.fill {
+				if fillSnapshot == nil {
+					fillSnapshot = s.mergeLayerDataLocked(func(entry *layerEntry) map[string]any {
+						if entry.fill {
+							return nil
+						}
+						return entry.data
+					})
+				}
+				stabilizeCtx.snapshot = fillSnapshot
+			}
+			result, err := stabilizer.Stabilize(ctx, stabilizeCtx)
 			if err != nil {
 				return fmt.Errorf("failed to stabilize layer %q: %w", entry.layer.Name(), err)
 			}
@@ -166,86 +242,273 @@ func (c storeStabilizeContext) Schema() layer.SchemaView {
 	return c.schema
 }
 
-// mergeValues merges two values and returns the result.
+// deepMerge performs a deep merge of src into dst.
 // For maps, keys are merged recursively.
-// For other types (including slices), src replaces dst.
-// This is used for dynamic container resolution in GetAt.
-func mergeValues(dst, src any) any {
-	dstMap, dstIsMap := dst.(map[string]any)
-	srcMap, srcIsMap := src.(map[string]any)
+// For other types, src values replace dst values.
+// document.Tombstone values in src remove the key from dst.
+// Values are deep copied to avoid modifying the original src data.
+func deepMerge(dst, src map[string]any) {
+	layerMerger{}.mergeMap(dst, src, "", "", nil)
+}
 
-	if dstIsMap && srcIsMap {
-		// Both are maps - merge recursively into a copy
-		result := container.DeepCopyValue(dstMap).(map[string]any)
-		deepMerge(result, srcMap)
-		return result
+// mergeEntriesLocked merges the data selected for each layer into a new map.
+// Regular layers are merged in priority order; fill layers (layer.FillLayer) are
+// merged afterwards, highest priority first, and only supply missing values.
+// Caller must hold the lock.
+func (s *Store[T]) mergeEntriesLocked(merger layerMerger, selectData func(*layerEntry) map[string]any) map[string]any {
+	if merger.deleted == nil {
+		merger.deleted = make(map[string]struct{})
 	}
+	merged := make(map[string]any)
+	for _, entry := range s.layers {
+		if entry.fill {
+			continue
+		}
+		if data := selectData(entry); data != nil {
+			merger.mergeMap(merged, data, "", "", entry)
+		}
+	}
+	for i := len(s.layers) - 1; i >= 0; i-- {
+		entry := s.layers[i]
+		if !entry.fill {
+			continue
+		}
+		if data := selectData(entry); data != nil {
+			merger.fillMap(merged, data, "", entry)
+		}
+	}
+	return merged
+}
 
-	// Not both maps - src replaces dst
-	return container.DeepCopyValue(src)
+// layerMerger merges layer data while honouring the per-path slice merge
+// strategies declared with the merge: directive and tombstones that remove
+// paths. When origins is non-nil, it also records which layer contributed
+// each merged path.
+type layerMerger struct {
+	trie     *MappingTrie
+	origins  *origins
+	sentinel string
+
+	// deleted collects the paths removed by tombstones, so that fill layers
+	// do not bring them back. nil disables tracking.
+	deleted map[string]struct{}
 }
 
-// deepMerge performs a deep merge of src into dst.
-// For maps, keys are merged recursively.
-// For other types, src values replace dst values.
-// Values are deep copied to avoid modifying the original src data.
-func deepMerge(dst, src map[string]any) {
+// mergeMap merges src into dst. path is the merged path of dst and srcPath is
+// the path of src within the contributing layer's data; they differ when a
+// merge strategy moves slice elements to a different index.
+func (m layerMerger) mergeMap(dst, src map[string]any, path, srcPath string, entry *layerEntry) {
 	for key, srcValue := range src {
+		segment := "/" + jsonptr.Escape(key)
+		if isTombstone(srcValue, m.sentinel) {
+			delete(dst, key)
+			m.recordDeleted(path+segment, entry)
+			continue
+		}
 		dstValue, exists := dst[key]
+		dst[key] = m.mergeValue(dstValue, exists, srcValue, path+segment, srcPath+segment, entry)
+	}
+}
 
-		if !exists {
-			// Key doesn't exist in dst - deep copy it
-			dst[key] = container.DeepCopyValue(srcValue)
+// mergeValue merges srcValue into dstValue and returns the merged value.
+// Maps are merged recursively, slices follow the merge strategy for path,
+// and everything else is replaced by a deep copy of srcValue.
+func (m layerMerger) mergeValue(dstValue any, exists bool, srcValue any, path, srcPath string, entry *layerEntry) any {
+	switch src := srcValue.(type) {
+	case map[string]any:
+		m.recordContainer(path, srcPath, entry)
+		dst, ok := dstValue.(map[string]any)
+		if !exists || !ok || dst == nil {
+			dst = make(map[string]any, len(src))
+		}
+		m.mergeMap(dst, src, path, srcPath, entry)
+		return dst
+	case []any:
+		m.recordContainer(path, srcPath, entry)
+		dst, _ := dstValue.([]any)
+		return m.mergeSlice(dst, src, path, srcPath, entry)
+	default:
+		m.recordLeaf(path, srcPath, entry)
+		return container.DeepCopyValue(srcValue)
+	}
+}
+
+// mergeSlice merges src into dst according to the merge strategy for path.
+func (m layerMerger) mergeSlice(dst, src []any, path, srcPath string, entry *layerEntry) []any {
+	strategy, key := m.strategy(path)
+
+	var result []any
+	if strategy == MergeReplace {
+		result = make([]any, 0, len(src))
+	} else {
+		result = make([]any, len(dst), len(dst)+len(src))
+		copy(result, dst)
+	}
+
+	for i, srcValue := range src {
+		// Tombstone elements are dropped
+		if isTombstone(srcValue, m.sentinel) {
 			continue
 		}
 
-		// Both dst and src have this key - need to merge
-		dstMap, dstIsMap := dstValue.(map[string]any)
-		srcMap, srcIsMap := srcValue.(map[string]any)
+		index := -1
+		switch strategy {
+		case MergeUnion:
+			index = indexOfValue(result, srcValue)
+		case MergeByKey:
+			index = indexOfKey(result, key, srcValue)
+		}
 
-		if dstIsMap && srcIsMap {
-			// Both are maps - merge recursively
-			deepMerge(dstMap, srcMap)
-		} else {
-			// One or both are not maps - replace with deep copy
-			dst[key] = container.DeepCopyValue(srcValue)
+		elemSrcPath := fmt.Sprintf("%s/%d", srcPath, i)
+		if index >= 0 {
+			result[index] = m.mergeValue(result[index], true, srcValue, fmt.Sprintf("%s/%d", path, index), elemSrcPath, entry)
+			continue
 		}
+		elemPath := fmt.Sprintf("%s/%d", path, len(result))
+		result = append(result, m.mergeValue(nil, false, srcValue, elemPath, elemSrcPath, entry))
 	}
+	return result
 }
 
-// walkMapForOrigins recursively walks a map and records origins for all paths.
-// It records both leaf values and container paths.
-func walkMapForOrigins(prefix string, data map[string]any, entry *layerEntry, o *origins) {
-	for key, value := range data {
-		path := prefix + "/" + jsonptr.Escape(key)
+// fillMap copies values from src into dst for keys that dst does not have yet.
+// Maps are filled recursively and slices element by element, so a fill layer
+// never overrides a value or brings back a path removed by a tombstone.
+func (m layerMerger) fillMap(dst, src map[string]any, path string, entry *layerEntry) {
+	for key, srcValue := range src {
+		childPath := path + "/" + jsonptr.Escape(key)
+		if _, deleted := m.deleted[childPath]; deleted || isTombstone(srcValue, m.sentinel) {
+			continue
+		}
+		dstValue, exists := dst[key]
+		dst[key] = m.fillValue(dstValue, exists, srcValue, childPath, entry)
+	}
+}
 
-		switch v := value.(type) {
-		case map[string]any:
-			o.setContainer(path, entry)
-			walkMapForOrigins(path, v, entry, o)
-		case []any:
-			o.setContainer(path, entry)
-			walkSliceForOrigins(path, v, entry, o)
-		default:
-			o.setLeaf(path, entry)
+// fillValue fills dstValue from srcValue and returns the result.
+// A missing dstValue is replaced by a copy of srcValue; existing maps and the
+// map elements of existing slices are filled recursively; anything else is kept.
+func (m layerMerger) fillValue(dstValue any, exists bool, srcValue any, path string, entry *layerEntry) any {
+	switch src := srcValue.(type) {
+	case map[string]any:
+		dst, ok := dstValue.(map[string]any)
+		if exists && !ok {
+			return dstValue
+		}
+		if dst == nil {
+			dst = make(map[string]any, len(src))
+		}
+		m.recordFill(path, entry, true)
+		m.fillMap(dst, src, path, entry)
+		return dst
+	case []any:
+		dst, ok := dstValue.([]any)
+		if exists && !ok {
+			return dstValue
+		}
+		m.recordFill(path, entry, true)
+		if !exists {
+			dst = make([]any, 0, len(src))
+			for _, elem := range src {
+				if isTombstone(elem, m.sentinel) {
+					continue
+				}
+				dst = append(dst, m.fillValue(nil, false, elem, fmt.Sprintf("%s/%d", path, len(dst)), entry))
+			}
+			return dst
+		}
+		for i := 0; i < len(dst) && i < len(src); i++ {
+			if _, isMap := dst[i].(map[string]any); isMap {
+				dst[i] = m.fillValue(dst[i], true, src[i], fmt.Sprintf("%s/%d", path, i), entry)
+			}
+		}
+		return dst
+	default:
+		if exists {
+			return dstValue
 		}
+		m.recordFill(path, entry, false)
+		return container.DeepCopyValue(srcValue)
 	}
 }
 
-// walkSliceForOrigins recursively walks a slice and records origins for all paths.
-func walkSliceForOrigins(prefix string, data []any, entry *layerEntry, o *origins) {
-	for i, value := range data {
-		path := fmt.Sprintf("%s/%d", prefix, i)
+// strategy returns the merge strategy declared for the slice at path.
+func (m layerMerger) strategy(path string) (MergeStrategy, string) {
+	if m.trie == nil {
+		return MergeReplace, ""
+	}
+	mapping := m.trie.Lookup(path)
+	if mapping == nil {
+		return MergeReplace, ""
+	}
+	return mapping.Merge, mapping.MergeKey
+}
+
+func (m layerMerger) recordContainer(path, srcPath string, entry *layerEntry) {
+	if m.origins == nil || entry == nil {
+		return
+	}
+	m.origins.setContainer(path, entry)
+	m.origins.setSourcePath(path, entry, srcPath)
+}
+
+func (m layerMerger) recordLeaf(path, srcPath string, entry *layerEntry) {
+	if m.origins == nil || entry == nil {
+		return
+	}
+	m.origins.setLeaf(path, entry)
+	m.origins.setSourcePath(path, entry, srcPath)
+}
+
+func (m layerMerger) recordDeleted(path string, entry *layerEntry) {
+	if m.deleted != nil {
+		m.deleted[path] = struct{}{}
+	}
+	if m.origins == nil || entry == nil {
+		return
+	}
+	m.origins.setDeleted(path, entry)
+}
 
-		switch v := value.(type) {
-		case map[string]any:
-			o.setContainer(path, entry)
-			walkMapForOrigins(path, v, entry, o)
-		case []any:
-			o.setContainer(path, entry)
-			walkSliceForOrigins(path, v, entry, o)
-		default:
-			o.setLeaf(path, entry)
+func (m layerMerger) recordFill(path string, entry *layerEntry, isContainer bool) {
+	if m.origins == nil || entry == nil {
+		return
+	}
+	if isContainer {
+		m.origins.fillContainer(path, entry)
+	} else {
+		m.origins.fillLeaf(path, entry)
+	}
+}
+
+// indexOfValue returns the index of the first element equal to value, or -1.
+func indexOfValue(values []any, value any) int {
+	for i, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexOfKey returns the index of the first map element whose key field equals
+// that of value, or -1 if value has no such key or no element matches.
+func indexOfKey(values []any, key string, value any) int {
+	valueMap, ok := value.(map[string]any)
+	if !ok {
+		return -1
+	}
+	want, ok := valueMap[key]
+	if !ok {
+		return -1
+	}
+	for i, v := range values {
+		elem, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		if got, ok := elem[key]; ok && reflect.DeepEqual(got, want) {
+			return i
 		}
 	}
+	return -1
 }
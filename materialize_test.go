@@ -2,10 +2,13 @@ package jubako
 
 import (
 	"context"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/yacchi/jubako/decoder"
+	"github.com/yacchi/jubako/document"
 	"github.com/yacchi/jubako/layer"
 	"github.com/yacchi/jubako/layer/mapdata"
 )
@@ -521,3 +524,386 @@ func BenchmarkDeepMerge(b *testing.B) {
 		deepMerge(dstCopy, src)
 	}
 }
+
+type testMergeServer struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+type testMergeConfig struct {
+	Plugins   []string          `json:"plugins" jubako:"merge:append"`
+	Allowlist []string          `json:"allowlist" jubako:"merge:union"`
+	Servers   []testMergeServer `json:"servers" jubako:"merge:key=name"`
+	Tags      []string          `json:"tags"`
+}
+
+func newMergeStrategyStore(t *testing.T) *Store[testMergeConfig] {
+	t.Helper()
+
+	store := New[testMergeConfig]()
+	if err := store.Add(mapdata.New("defaults", map[string]any{
+		"plugins":   []any{"auth", "metrics"},
+		"allowlist": []any{"10.0.0.1", "10.0.0.2"},
+		"servers": []any{
+			map[string]any{"name": "primary", "host": "db1", "port": 5432},
+			map[string]any{"name": "replica", "host": "db2", "port": 5432},
+		},
+		"tags": []any{"a", "b"},
+	})); err != nil {
+		t.Fatalf("Add(defaults) error = %v", err)
+	}
+	if err := store.Add(mapdata.New("user", map[string]any{
+		"plugins":   []any{"tracing"},
+		"allowlist": []any{"10.0.0.2", "10.0.0.3"},
+		"servers": []any{
+			map[string]any{"name": "replica", "port": 6432},
+			map[string]any{"name": "analytics", "host": "db3", "port": 5433},
+		},
+		"tags": []any{"c"},
+	})); err != nil {
+		t.Fatalf("Add(user) error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return store
+}
+
+func TestStore_materialize_MergeStrategies(t *testing.T) {
+	store := newMergeStrategyStore(t)
+	cfg := store.Get()
+
+	if want := []string{"auth", "metrics", "tracing"}; !reflect.DeepEqual(cfg.Plugins, want) {
+		t.Errorf("Plugins = %v, want %v", cfg.Plugins, want)
+	}
+	if want := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}; !reflect.DeepEqual(cfg.Allowlist, want) {
+		t.Errorf("Allowlist = %v, want %v", cfg.Allowlist, want)
+	}
+	wantServers := []testMergeServer{
+		{Name: "primary", Host: "db1", Port: 5432},
+		{Name: "replica", Host: "db2", Port: 6432},
+		{Name: "analytics", Host: "db3", Port: 5433},
+	}
+	if !reflect.DeepEqual(cfg.Servers, wantServers) {
+		t.Errorf("Servers = %+v, want %+v", cfg.Servers, wantServers)
+	}
+	// Fields without a merge directive keep replace semantics
+	if want := []string{"c"}; !reflect.DeepEqual(cfg.Tags, want) {
+		t.Errorf("Tags = %v, want %v", cfg.Tags, want)
+	}
+}
+
+func TestStore_materialize_MergeStrategyOrigins(t *testing.T) {
+	store := newMergeStrategyStore(t)
+
+	tests := []struct {
+		path      string
+		wantValue any
+		wantLayer layer.Name
+	}{
+		{"/plugins/0", "auth", "defaults"},
+		{"/plugins/2", "tracing", "user"},
+		{"/allowlist/2", "10.0.0.3", "user"},
+		{"/servers/1/host", "db2", "defaults"},
+		{"/servers/1/port", 6432, "user"},
+		{"/servers/2/name", "analytics", "user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rv := store.GetAt(tt.path)
+			if !rv.Exists {
+				t.Fatalf("GetAt(%q).Exists = false, want true", tt.path)
+			}
+			if rv.Value != tt.wantValue {
+				t.Errorf("GetAt(%q).Value = %v, want %v", tt.path, rv.Value, tt.wantValue)
+			}
+			if rv.Layer.Name() != tt.wantLayer {
+				t.Errorf("GetAt(%q).Layer = %q, want %q", tt.path, rv.Layer.Name(), tt.wantLayer)
+			}
+		})
+	}
+
+	// Shared union element is reported by both layers
+	values := store.GetAllAt("/allowlist/1")
+	if values.Len() != 2 {
+		t.Fatalf("GetAllAt(/allowlist/1).Len() = %d, want 2", values.Len())
+	}
+	for i, want := range []layer.Name{"defaults", "user"} {
+		if values[i].Layer.Name() != want || values[i].Value != "10.0.0.2" {
+			t.Errorf("GetAllAt(/allowlist/1)[%d] = %v from %q, want 10.0.0.2 from %q", i, values[i].Value, values[i].Layer.Name(), want)
+		}
+	}
+
+	// Key-merged element combines both layers
+	rv := store.GetAt("/servers/1")
+	want := map[string]any{"name": "replica", "host": "db2", "port": 6432}
+	if !reflect.DeepEqual(rv.Value, want) {
+		t.Errorf("GetAt(/servers/1).Value = %v, want %v", rv.Value, want)
+	}
+	if values := store.GetAllAt("/servers/1"); values.Len() != 2 {
+		t.Errorf("GetAllAt(/servers/1).Len() = %d, want 2", values.Len())
+	}
+
+	// Container resolution honours the strategy
+	rv = store.GetAt("/plugins")
+	if want := []any{"auth", "metrics", "tracing"}; !reflect.DeepEqual(rv.Value, want) {
+		t.Errorf("GetAt(/plugins).Value = %v, want %v", rv.Value, want)
+	}
+}
+
+func TestStore_materialize_Tombstones(t *testing.T) {
+	ctx := context.Background()
+
+	newStore := func(t *testing.T, userData map[string]any, opts ...StoreOption) *Store[map[string]any] {
+		t.Helper()
+		store := New[map[string]any](opts...)
+		if err := store.Add(mapdata.New("defaults", map[string]any{
+			"feature": map[string]any{
+				"x":     true,
+				"y":     "keep",
+				"group": map[string]any{"a": 1, "b": 2},
+			},
+		})); err != nil {
+			t.Fatalf("Add(defaults) error = %v", err)
+		}
+		if err := store.Add(mapdata.New("user", userData)); err != nil {
+			t.Fatalf("Add(user) error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		return store
+	}
+
+	t.Run("tombstone removes leaf", func(t *testing.T) {
+		store := newStore(t, map[string]any{
+			"feature": map[string]any{"x": document.Tombstone{}},
+		})
+
+		feature := store.Get()["feature"].(map[string]any)
+		if _, ok := feature["x"]; ok {
+			t.Errorf("feature.x exists after tombstone: %v", feature)
+		}
+		if feature["y"] != "keep" {
+			t.Errorf("feature.y = %v, want keep", feature["y"])
+		}
+
+		rv := store.GetAt("/feature/x")
+		if rv.Exists || !rv.Deleted || rv.Layer.Name() != "user" {
+			t.Errorf("GetAt(/feature/x) = %+v, want deleted by user", rv)
+		}
+
+		values := store.GetAllAt("/feature/x")
+		if values.Len() != 2 {
+			t.Fatalf("GetAllAt(/feature/x).Len() = %d, want 2", values.Len())
+		}
+		if values[0].Value != true || values[0].Layer.Name() != "defaults" {
+			t.Errorf("GetAllAt(/feature/x)[0] = %+v, want true from defaults", values[0])
+		}
+		if !values[1].Deleted || values[1].Layer.Name() != "user" {
+			t.Errorf("GetAllAt(/feature/x)[1] = %+v, want deletion from user", values[1])
+		}
+
+		store.Walk(func(ctx WalkContext) bool {
+			if ctx.Path == "/feature/x" {
+				t.Errorf("Walk() visited deleted path %s", ctx.Path)
+			}
+			return true
+		})
+	})
+
+	t.Run("sentinel string is a plain value by default", func(t *testing.T) {
+		store := newStore(t, map[string]any{
+			"feature": map[string]any{"x": document.DefaultUnsetSentinel},
+		})
+
+		feature := store.Get()["feature"].(map[string]any)
+		if feature["x"] != document.DefaultUnsetSentinel {
+			t.Errorf("feature.x = %v, want the sentinel string kept as a plain value", feature["x"])
+		}
+		if rv := store.GetAt("/feature/x"); !rv.Exists || rv.Deleted {
+			t.Errorf("GetAt(/feature/x) = %+v, want an existing value", rv)
+		}
+	})
+
+	t.Run("sentinel string removes container", func(t *testing.T) {
+		store := newStore(t, map[string]any{
+			"feature": map[string]any{"group": document.DefaultUnsetSentinel},
+		}, WithUnsetSentinel(document.DefaultUnsetSentinel))
+
+		feature := store.Get()["feature"].(map[string]any)
+		if _, ok := feature["group"]; ok {
+			t.Errorf("feature.group exists after tombstone: %v", feature)
+		}
+		for _, path := range []string{"/feature/group", "/feature/group/a"} {
+			if rv := store.GetAt(path); rv.Exists || !rv.Deleted {
+				t.Errorf("GetAt(%s) = %+v, want deleted", path, rv)
+			}
+		}
+	})
+
+	t.Run("custom sentinel", func(t *testing.T) {
+		store := newStore(t, map[string]any{
+			"feature": map[string]any{"x": "__unset__", "y": document.DefaultUnsetSentinel},
+		}, WithUnsetSentinel("__unset__"))
+
+		feature := store.Get()["feature"].(map[string]any)
+		if _, ok := feature["x"]; ok {
+			t.Errorf("feature.x exists after custom sentinel: %v", feature)
+		}
+		if feature["y"] != document.DefaultUnsetSentinel {
+			t.Errorf("feature.y = %v, want the default sentinel kept as a plain value", feature["y"])
+		}
+	})
+
+	t.Run("higher layer redefines deleted path", func(t *testing.T) {
+		store := newStore(t, map[string]any{
+			"feature": map[string]any{"group": document.Tombstone{}},
+		})
+		if err := store.Add(mapdata.New("local", map[string]any{
+			"feature": map[string]any{"group": map[string]any{"c": 3}},
+		})); err != nil {
+			t.Fatalf("Add(local) error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		// Decoded through JSON, so numbers become float64
+		if got, want := store.Get()["feature"].(map[string]any)["group"], map[string]any{"c": float64(3)}; !reflect.DeepEqual(got, want) {
+			t.Errorf("feature.group = %v, want %v", got, want)
+		}
+		want := map[string]any{"c": 3}
+		rv := store.GetAt("/feature/group")
+		if !rv.Exists || !reflect.DeepEqual(rv.Value, want) || rv.Layer.Name() != "local" {
+			t.Errorf("GetAt(/feature/group) = %+v, want %v from local", rv, want)
+		}
+		if rv := store.GetAt("/feature/group/a"); rv.Exists || !rv.Deleted {
+			t.Errorf("GetAt(/feature/group/a) = %+v, want deleted", rv)
+		}
+	})
+}
+
+func TestStore_materialize_Constraints(t *testing.T) {
+	type backend struct {
+		Name string `json:"name" jubako:"required"`
+	}
+	type config struct {
+		Port     int       `json:"port" jubako:"required,min=1,max=65535"`
+		Level    string    `json:"level" jubako:"enum=debug|info|warn"`
+		URL      string    `json:"url" jubako:"/endpoint/url,pattern=^https://"`
+		Tags     []string  `json:"tags" jubako:"max=2"`
+		Backends []backend `json:"backends"`
+	}
+
+	load := func(user map[string]any) (*Store[config], error) {
+		store := New[config]()
+		base := map[string]any{
+			"port":     8080,
+			"level":    "info",
+			"endpoint": map[string]any{"url": "https://example.com"},
+		}
+		if err := store.Add(mapdata.New("defaults", base)); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Add(mapdata.New("user", user)); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		return store, store.Load(context.Background())
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		store, err := load(map[string]any{"port": "9000", "tags": []any{"a", "b"}})
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if got := store.Get(); got.Port != 9000 || got.URL != "https://example.com" {
+			t.Errorf("Get() = %+v", got)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		user       map[string]any
+		path       string
+		constraint string
+		layer      layer.Name
+		message    string
+	}{
+		{"min", map[string]any{"port": 0}, "/port", "min=1", "user", "/port=0 violates min=1 (from layer user)"},
+		{"max from string", map[string]any{"port": "70000"}, "/port", "max=65535", "user", "/port=70000 violates max=65535 (from layer user)"},
+		{"required", map[string]any{"port": nil}, "/port", "required", "", "/port is missing (required)"},
+		{"enum", map[string]any{"level": "trace"}, "/level", "enum=debug|info|warn", "user", "/level=trace violates enum=debug|info|warn (from layer user)"},
+		{"pattern on remapped path", map[string]any{"endpoint": map[string]any{"url": "http://x"}}, "/endpoint/url", "pattern=^https://", "user", ""},
+		{"slice length", map[string]any{"tags": []any{"a", "b", "c"}}, "/tags", "max=2", "user", ""},
+		{"slice element", map[string]any{"backends": []any{map[string]any{"name": "a"}, map[string]any{}}}, "/backends/1/name", "required", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := load(tt.user)
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Load() error = %v, want *ValidationError", err)
+			}
+			var cerr *ConstraintError
+			if !errors.As(err, &cerr) {
+				t.Fatalf("Load() error = %v, want *ConstraintError", err)
+			}
+			if cerr.Path != tt.path || cerr.Constraint != tt.constraint {
+				t.Errorf("ConstraintError = {Path:%q, Constraint:%q}, want {Path:%q, Constraint:%q}",
+					cerr.Path, cerr.Constraint, tt.path, tt.constraint)
+			}
+			var gotLayer layer.Name
+			if cerr.Layer != nil {
+				gotLayer = cerr.Layer.Name()
+			}
+			if gotLayer != tt.layer {
+				t.Errorf("Layer = %q, want %q", gotLayer, tt.layer)
+			}
+			if tt.message != "" && cerr.Error() != tt.message {
+				t.Errorf("Error() = %q, want %q", cerr.Error(), tt.message)
+			}
+			if got := store.Get(); !reflect.DeepEqual(got, config{}) {
+				t.Errorf("Get() = %+v, want zero value", got)
+			}
+		})
+	}
+}
+
+func TestNew_InvalidTagDirectives(t *testing.T) {
+	type badBound struct {
+		Port int `json:"port" jubako:"min=one"`
+	}
+	type badPattern struct {
+		URL string `json:"url" jubako:"pattern=^(https"`
+	}
+	type badMerge struct {
+		Plugins []string `json:"plugins" jubako:"merge:apend"`
+	}
+	type nested struct {
+		Inner badBound `json:"inner"`
+	}
+
+	tests := []struct {
+		name string
+		new  func()
+		want string
+	}{
+		{"bound", func() { New[badBound]() }, `jubako: invalid jubako tag on field badBound.Port: invalid min= directive: "one" is not a number`},
+		{"pattern", func() { New[badPattern]() }, "jubako: invalid jubako tag on field badPattern.URL: invalid pattern= directive"},
+		{"merge", func() { New[badMerge]() }, `jubako: invalid jubako tag on field badMerge.Plugins: invalid merge: directive: unknown strategy "apend"`},
+		{"nested", func() { New[nested]() }, "jubako: invalid jubako tag on field nested.Inner.Port"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if r == nil || !strings.HasPrefix(r.(string), tt.want) {
+					t.Errorf("New() panic = %v, want %q", r, tt.want)
+				}
+			}()
+			tt.new()
+		})
+	}
+}
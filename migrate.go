@@ -0,0 +1,248 @@
+package jubako
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/yacchi/jubako/container"
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/jsonptr"
+	"github.com/yacchi/jubako/layer"
+)
+
+// Migration upgrades layer data from one schema version to the next.
+// Migrations are registered with WithMigrations.
+type Migration struct {
+	// From is the version of the data the migration accepts.
+	From int
+
+	// To is the version of the data the migration produces. It must be greater than From.
+	To int
+
+	// Up converts data of version From to version To.
+	// It receives a copy of the layer's data and may modify it in place.
+	// The Store sets the version path to To after Up returns.
+	Up func(data map[string]any) (map[string]any, error)
+}
+
+// migrations holds the migrations registered with WithMigrations.
+type migrations struct {
+	// versionPath is the JSON Pointer path of the version number.
+	versionPath string
+	// steps maps each source version to its migration.
+	steps map[int]Migration
+	// latest is the highest version produced by a migration.
+	latest int
+}
+
+// WithMigrations registers migrations that upgrade each layer's data to the
+// current schema version before it is merged. The version of a layer is read
+// from versionPath, either as a number or as a numeric string. Layers without
+// a version (such as environment variables or defaults) are left untouched.
+//
+// Migrations are chained on Load, Reload and watch updates: data of version 1
+// is passed to the migration from 1, then to the migration from its To
+// version, and so on until no migration matches. Loading fails when a layer's
+// version is older than the latest version and no migration accepts it.
+//
+// The migrated data is kept in memory only. Saving a migrated layer fails
+// until PersistMigrations is called for it, so that the upgraded shape is
+// never written to a document by accident.
+//
+// New panics if a migration does not increase the version, has no Up
+// function, or shares its From version with another migration.
+//
+// Example:
+//
+//	store := jubako.New[Config](jubako.WithMigrations("/version",
+//	    jubako.Migration{From: 1, To: 2, Up: func(data map[string]any) (map[string]any, error) {
+//	        // v2 renamed "listen" to "server"
+//	        data["server"] = data["listen"]
+//	        delete(data, "listen")
+//	        return data, nil
+//	    }},
+//	))
+func WithMigrations(versionPath string, migrations ...Migration) StoreOption {
+	return func(o *storeOptions) {
+		o.versionPath = versionPath
+		o.migrations = append(o.migrations, migrations...)
+	}
+}
+
+// newMigrations validates the registered migrations and indexes them by source version.
+// It returns nil when no migration is registered.
+func newMigrations(versionPath string, list []Migration) *migrations {
+	if len(list) == 0 {
+		return nil
+	}
+	if _, err := jsonptr.Parse(versionPath); err != nil || versionPath == "" {
+		panic(fmt.Sprintf("jubako: WithMigrations: invalid version path %q", versionPath))
+	}
+	m := &migrations{versionPath: versionPath, steps: make(map[int]Migration, len(list))}
+	for _, mig := range list {
+		if mig.To <= mig.From {
+			panic(fmt.Sprintf("jubako: WithMigrations: migration from version %d to %d does not increase the version", mig.From, mig.To))
+		}
+		if mig.Up == nil {
+			panic(fmt.Sprintf("jubako: WithMigrations: migration from version %d has no Up function", mig.From))
+		}
+		if _, ok := m.steps[mig.From]; ok {
+			panic(fmt.Sprintf("jubako: WithMigrations: duplicate migration from version %d", mig.From))
+		}
+		m.steps[mig.From] = mig
+		m.latest = max(m.latest, mig.To)
+	}
+	return m
+}
+
+// versionOf returns the version number stored at the version path of data.
+func (m *migrations) versionOf(data map[string]any) (int, bool) {
+	value, ok := jsonptr.GetPath(data, m.versionPath)
+	if !ok {
+		return 0, false
+	}
+	return parseVersion(value)
+}
+
+// parseVersion converts a decoded version value to an int.
+func parseVersion(value any) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case uint64:
+		return int(v), true
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, false
+		}
+		return int(v), true
+	case json.Number:
+		n, err := v.Int64()
+		return int(n), err == nil
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// migrate runs the chain of migrations starting at the version of data.
+// It returns the migrated data, the detected version, whether a version was
+// detected, and whether any migration ran. data itself is never modified.
+func (m *migrations) migrate(data map[string]any) (map[string]any, int, bool, bool, error) {
+	version, ok := m.versionOf(data)
+	if !ok {
+		return data, 0, false, false, nil
+	}
+
+	result := data
+	current := version
+	for {
+		mig, exists := m.steps[current]
+		if !exists {
+			break
+		}
+		migrated, err := mig.Up(container.DeepCopyMap(result))
+		if err != nil {
+			return nil, version, true, false, fmt.Errorf("from version %d to %d: %w", mig.From, mig.To, err)
+		}
+		if migrated == nil {
+			migrated = make(map[string]any)
+		}
+		jsonptr.SetPath(migrated, m.versionPath, mig.To)
+		result = migrated
+		current = mig.To
+	}
+	if current < m.latest {
+		return nil, version, true, false, fmt.Errorf("no migration from version %d", current)
+	}
+	return result, version, true, current != version, nil
+}
+
+// setLoadedDataLocked stores freshly loaded data in entry, validating it
+// against the layer's schema and upgrading it with the registered migrations first.
+// A pending PersistMigrations opt-in is kept as long as the data still needs
+// migrating; it is cleared only by a successful save or by Load.
+// Caller must hold the write lock, unless the entry is not yet registered.
+// On error, entry is left unchanged.
+func (s *Store[T]) setLoadedDataLocked(entry *layerEntry, data map[string]any) error {
+	if err := validateLayerSchema(entry, data); err != nil {
+		return err
+	}
+	var original map[string]any
+	version, hasVersion := 0, false
+	if s.migrations != nil && data != nil {
+		migrated, detected, ok, changed, err := s.migrations.migrate(data)
+		if err != nil {
+			return fmt.Errorf("failed to migrate layer %q: %w", entry.layer.Name(), err)
+		}
+		version, hasVersion = detected, ok
+		if changed {
+			original = data
+			data = migrated
+		}
+	}
+	entry.original = original
+	entry.persistMigration = entry.persistMigration && original != nil
+	entry.version, entry.hasVersion = version, hasVersion
+	entry.data = data
+	entry.loadedData = container.DeepCopyMap(data)
+	return nil
+}
+
+// migrationPatches returns the patches that rewrite the layer's document from
+// its original shape to the migrated one, if the user opted in with PersistMigrations.
+func migrationPatches(entry *layerEntry) document.JSONPatchSet {
+	if entry.original == nil || !entry.persistMigration {
+		return nil
+	}
+	return document.Diff(entry.original, container.DeepCopyMap(entry.loadedData))
+}
+
+// PersistMigrations opts in to writing the migrated shape of a layer back to
+// its source. The next Save or SaveLayer rewrites the layer's document from
+// its original version to the latest one with JSON patches, preserving
+// comments for formats that support it, followed by any pending changes.
+//
+// PersistMigrations is a no-op if the layer was not migrated. The opt-in
+// lasts until the layer is saved or loaded again with Load; Reload and watch
+// updates keep it.
+//
+// Example:
+//
+//	// Upgrade the user's config file to the current version
+//	if err := store.PersistMigrations("user"); err != nil {
+//	    log.Fatal(err)
+//	}
+//	if err := store.SaveLayer(ctx, "user"); err != nil {
+//	    log.Fatal(err)
+//	}
+func (s *Store[T]) PersistMigrations(layerName layer.Name) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.findLayerLocked(layerName)
+	if entry == nil {
+		return fmt.Errorf("layer %q not found", layerName)
+	}
+	if !entry.Writable() {
+		if entry.readOnly {
+			return fmt.Errorf("layer %q is marked as read-only", layerName)
+		}
+		return fmt.Errorf("layer %q does not support saving (source is not writable)", layerName)
+	}
+	if entry.data == nil {
+		return fmt.Errorf("layer %q has not been loaded", layerName)
+	}
+	if entry.original == nil {
+		return nil
+	}
+	entry.persistMigration = true
+	s.syncLayerDirty(entry)
+	return nil
+}
@@ -0,0 +1,253 @@
+package jubako
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/layer/mapdata"
+)
+
+type migrateTestConfig struct {
+	Version int `json:"version"`
+	Server  struct {
+		Host string `json:"host"`
+		Port string `json:"port"`
+	} `json:"server"`
+	Debug bool `json:"debug"`
+}
+
+// migrateTestMigrations upgrades v1 {"listen": "host:port"} to v2 {"server": {"address": ...}}
+// and then to v3 {"server": {"host", "port"}}.
+func migrateTestMigrations() []Migration {
+	return []Migration{
+		{From: 1, To: 2, Up: func(data map[string]any) (map[string]any, error) {
+			data["server"] = map[string]any{"address": data["listen"]}
+			delete(data, "listen")
+			return data, nil
+		}},
+		{From: 2, To: 3, Up: func(data map[string]any) (map[string]any, error) {
+			server, _ := data["server"].(map[string]any)
+			address, _ := server["address"].(string)
+			host, port, ok := strings.Cut(address, ":")
+			if !ok {
+				return nil, errors.New("invalid address")
+			}
+			delete(server, "address")
+			server["host"] = host
+			server["port"] = port
+			return data, nil
+		}},
+	}
+}
+
+func TestStore_WithMigrations(t *testing.T) {
+	ctx := context.Background()
+
+	store := New[migrateTestConfig](WithMigrations("/version", migrateTestMigrations()...))
+	if err := store.Add(mapdata.New("defaults", map[string]any{"debug": true})); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	user := mapdata.New("user", map[string]any{"version": 1, "listen": "example.com:8080"})
+	if err := store.Add(user); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	current := mapdata.New("current", map[string]any{"version": "3"})
+	if err := store.Add(current); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	cfg := store.Get()
+	if cfg.Server.Host != "example.com" || cfg.Server.Port != "8080" || !cfg.Debug {
+		t.Errorf("Get() = %+v", cfg)
+	}
+
+	// ListLayers reports the detected version of each layer
+	versions := make(map[string]int)
+	for _, info := range store.ListLayers() {
+		if v, ok := info.Version(); ok {
+			versions[string(info.Name())] = v
+		}
+	}
+	if want := map[string]int{"user": 1, "current": 3}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("versions = %v, want %v", versions, want)
+	}
+
+	// Saving a migrated layer requires an explicit opt-in
+	if err := store.SetTo("user", "/debug", false); err != nil {
+		t.Fatalf("SetTo() error = %v", err)
+	}
+	if err := store.SaveLayer(ctx, "user"); err == nil {
+		t.Fatal("SaveLayer() should fail before PersistMigrations")
+	}
+	if got := user.Data(); !reflect.DeepEqual(got, map[string]any{"version": 1, "listen": "example.com:8080"}) {
+		t.Errorf("data = %v, want it untouched", got)
+	}
+
+	if err := store.PersistMigrations("user"); err != nil {
+		t.Fatalf("PersistMigrations() error = %v", err)
+	}
+	if err := store.SaveLayer(ctx, "user"); err != nil {
+		t.Fatalf("SaveLayer() error = %v", err)
+	}
+	want := map[string]any{
+		"version": 3,
+		"server":  map[string]any{"host": "example.com", "port": "8080"},
+		"debug":   false,
+	}
+	if got := user.Data(); !reflect.DeepEqual(got, want) {
+		t.Errorf("saved data = %v, want %v", got, want)
+	}
+	if v, _ := store.GetLayerInfo("user").Version(); v != 3 {
+		t.Errorf("Version() = %d after save, want 3", v)
+	}
+	if store.IsDirty() {
+		t.Error("store should not be dirty after save")
+	}
+
+	// A migrated layer without pending changes is not dirty
+	if err := store.PersistMigrations("current"); err != nil {
+		t.Fatalf("PersistMigrations() error = %v", err)
+	}
+	if store.GetLayerInfo("current").Dirty() {
+		t.Error("PersistMigrations() should be a no-op for a layer at the latest version")
+	}
+	if err := store.PersistMigrations("missing"); err == nil {
+		t.Error("PersistMigrations() should fail for an unknown layer")
+	}
+}
+
+func TestStore_PersistMigrations_Reload(t *testing.T) {
+	ctx := context.Background()
+
+	store := New[migrateTestConfig](WithMigrations("/version", migrateTestMigrations()...))
+	user := mapdata.New("user", map[string]any{"version": 1, "listen": "example.com:8080"})
+	if err := store.Add(user); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// Load discards the opt-in
+	if err := store.PersistMigrations("user"); err != nil {
+		t.Fatalf("PersistMigrations() error = %v", err)
+	}
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if store.GetLayerInfo("user").Dirty() {
+		t.Error("layer should not be dirty after Load")
+	}
+
+	// Reload keeps it until the layer is saved
+	if err := store.PersistMigrations("user"); err != nil {
+		t.Fatalf("PersistMigrations() error = %v", err)
+	}
+	if err := store.Reload(ctx); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if !store.GetLayerInfo("user").Dirty() {
+		t.Error("layer should stay dirty after Reload")
+	}
+	if err := store.SaveLayer(ctx, "user"); err != nil {
+		t.Fatalf("SaveLayer() error = %v", err)
+	}
+	want := map[string]any{"version": 3, "server": map[string]any{"host": "example.com", "port": "8080"}}
+	if got := user.Data(); !reflect.DeepEqual(got, want) {
+		t.Errorf("saved data = %v, want %v", got, want)
+	}
+}
+
+func TestStore_WithMigrations_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("migration error", func(t *testing.T) {
+		store := New[migrateTestConfig](WithMigrations("/version", migrateTestMigrations()...))
+		if err := store.Add(mapdata.New("user", map[string]any{"version": 2, "server": map[string]any{"address": "bad"}})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		err := store.Load(ctx)
+		if err == nil || !strings.Contains(err.Error(), `failed to migrate layer "user": from version 2 to 3: invalid address`) {
+			t.Errorf("Load() error = %v", err)
+		}
+	})
+
+	t.Run("missing migration", func(t *testing.T) {
+		store := New[migrateTestConfig](WithMigrations("/version", migrateTestMigrations()[1]))
+		if err := store.Add(mapdata.New("user", map[string]any{"version": 1})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Load(ctx); err == nil || !strings.Contains(err.Error(), "no migration from version 1") {
+			t.Errorf("Load() error = %v", err)
+		}
+	})
+
+	t.Run("invalid migrations", func(t *testing.T) {
+		up := func(data map[string]any) (map[string]any, error) { return data, nil }
+		tests := map[string][]Migration{
+			"not increasing": {{From: 2, To: 2, Up: up}},
+			"no up":          {{From: 1, To: 2}},
+			"duplicate":      {{From: 1, To: 2, Up: up}, {From: 1, To: 3, Up: up}},
+		}
+		for name, migrations := range tests {
+			t.Run(name, func(t *testing.T) {
+				defer func() {
+					if recover() == nil {
+						t.Error("New() should panic")
+					}
+				}()
+				New[migrateTestConfig](WithMigrations("/version", migrations...))
+			})
+		}
+	})
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		value any
+		want  int
+		ok    bool
+	}{
+		{2, 2, true},
+		{int64(3), 3, true},
+		{uint64(4), 4, true},
+		{float64(5), 5, true},
+		{1.5, 0, false},
+		{" 6 ", 6, true},
+		{"v7", 0, false},
+		{true, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseVersion(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseVersion(%v) = %d, %v, want %d, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMigrationPatches(t *testing.T) {
+	entry := &layerEntry{
+		original:         map[string]any{"version": 1, "listen": "a:1"},
+		loadedData:       map[string]any{"version": 2, "server": map[string]any{"address": "a:1"}},
+		persistMigration: true,
+	}
+	want := document.JSONPatchSet{
+		document.NewRemovePatch("/listen"),
+		document.NewAddPatch("/server", map[string]any{"address": "a:1"}),
+		document.NewReplacePatch("/version", 2),
+	}
+	if got := migrationPatches(entry); !reflect.DeepEqual(got, want) {
+		t.Errorf("migrationPatches() = %v, want %v", got, want)
+	}
+	entry.persistMigration = false
+	if got := migrationPatches(entry); got != nil {
+		t.Errorf("migrationPatches() = %v, want nil without PersistMigrations", got)
+	}
+}
@@ -1,6 +1,13 @@
 package jubako
 
-import "github.com/yacchi/jubako/jsonptr"
+import (
+	"strconv"
+	"strings"
+
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/jsonptr"
+	"github.com/yacchi/jubako/layer"
+)
 
 // ResolvedValue represents a configuration value with its origin information.
 // It provides the value, whether the key exists, and which layer it came from.
@@ -9,6 +16,7 @@ import "github.com/yacchi/jubako/jsonptr"
 //   - Key does not exist: Exists=false, Value=nil, Layer=nil
 //   - Explicit null: Exists=true, Value=nil, Layer!=nil (IsNull() returns true)
 //   - Non-null value: Exists=true, Value=<value>, Layer!=nil (HasValue() returns true)
+//   - Deleted by a tombstone: Exists=false, Deleted=true, Layer!=nil (the deleting layer)
 //
 // If the value is from a sensitive field and masking is enabled, Masked will be true
 // and Value will contain the masked value instead of the original.
@@ -30,6 +38,24 @@ type ResolvedValue struct {
 	// When true, Value contains the masked representation, not the original.
 	// Use Store.GetAtUnmasked to retrieve the original value.
 	Masked bool
+
+	// Deleted indicates that Layer removes this path with a tombstone
+	// (e.g., YAML `!unset`, the unset sentinel string, or Unset).
+	// Exists is false and Value is nil for deleted values.
+	Deleted bool
+
+	// Interpolated indicates that Value contains expanded ${...} references
+	// (see WithInterpolation). Raw then holds the value as written in the layer.
+	Interpolated bool
+
+	// Raw is the value before interpolation, e.g. "https://${/server/host}".
+	// It is set only when Interpolated is true, and masked together with Value.
+	Raw any
+
+	// Reference is the location that provides the value when the layer resolves
+	// $ref or $include references (see layer.ReferenceTracker), for example
+	// "/etc/app/db.yaml#/host". Empty for values read from the layer's own document.
+	Reference string
 }
 
 // IsNull returns true if the key exists but the value is explicitly null.
@@ -53,21 +79,112 @@ func (rv ResolvedValue) HasValue() bool {
 // newResolvedValue creates a ResolvedValue for the given path from a layer entry.
 // Returns an unset ResolvedValue if the entry is nil, data is nil, or path doesn't exist.
 func newResolvedValue(entry *layerEntry, path string) ResolvedValue {
+	return resolveValueAt(entry, path, "")
+}
+
+// resolveValueAt is like newResolvedValue, but also reports values removed by a
+// tombstone at path or at one of its ancestors. sentinel is the unset sentinel string.
+func resolveValueAt(entry *layerEntry, path string, sentinel string) ResolvedValue {
 	if entry == nil {
 		return ResolvedValue{}
 	}
-	if entry.data == nil {
+	return resolveDataAt(entry, entry.data, path, sentinel)
+}
+
+// resolveDataAt is like resolveValueAt, but reads the value from data
+// instead of the entry's own data.
+func resolveDataAt(entry *layerEntry, data map[string]any, path string, sentinel string) ResolvedValue {
+	if data == nil {
 		return ResolvedValue{}
 	}
-	value, ok := jsonptr.GetPath(entry.data, path)
+	value, ok := jsonptr.GetPath(data, path)
 	if !ok {
+		if hasTombstoneAncestor(data, path, sentinel) {
+			return ResolvedValue{Layer: entry, Deleted: true}
+		}
 		return ResolvedValue{}
 	}
-	return ResolvedValue{
+	if isTombstone(value, sentinel) {
+		return ResolvedValue{Layer: layerInfoAt(entry, path), Deleted: true}
+	}
+	rv := ResolvedValue{
 		Value:  value,
 		Exists: true,
-		Layer:  entry,
+		Layer:  layerInfoAt(entry, path),
+	}
+	if tracker, ok := entry.layer.(layer.ReferenceTracker); ok {
+		rv.Reference = tracker.ReferenceOf(path)
 	}
+	return rv
+}
+
+// fileLayerInfo is the LayerInfo of a value provided by one of the files
+// merged by a layer (see layer.FileTracker).
+type fileLayerInfo struct {
+	*layerEntry
+	path   string
+	format document.DocumentFormat
+}
+
+// Path returns the path of the file that provides the value.
+func (f fileLayerInfo) Path() string {
+	return f.path
+}
+
+// Format returns the document format of the file that provides the value.
+func (f fileLayerInfo) Format() document.DocumentFormat {
+	if f.format != "" {
+		return f.format
+	}
+	return f.layerEntry.Format()
+}
+
+// layerInfoAt returns the LayerInfo reported for the value at path in entry's data.
+// For layers merging several files, it names the file that provides the value.
+func layerInfoAt(entry *layerEntry, path string) LayerInfo {
+	if tracker, ok := entry.layer.(layer.FileTracker); ok {
+		if file, format, ok := tracker.FileOf(path); ok {
+			return fileLayerInfo{layerEntry: entry, path: file, format: format}
+		}
+	}
+	return entry
+}
+
+// isTombstone reports whether value marks its path as deleted, either as a
+// document.Tombstone or as a string equal to the unset sentinel.
+func isTombstone(value any, sentinel string) bool {
+	if document.IsTombstone(value) {
+		return true
+	}
+	str, ok := value.(string)
+	return ok && sentinel != "" && str == sentinel
+}
+
+// hasTombstoneAncestor reports whether a tombstone in data removes an ancestor of path.
+func hasTombstoneAncestor(data map[string]any, path string, sentinel string) bool {
+	segments, err := jsonptr.Parse(path)
+	if err != nil {
+		return false
+	}
+	var current any = data
+	for _, segment := range segments {
+		switch v := current.(type) {
+		case map[string]any:
+			current = v[segment]
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(v) {
+				return false
+			}
+			current = v[index]
+		default:
+			return false
+		}
+		if isTombstone(current, sentinel) {
+			return true
+		}
+	}
+	return false
 }
 
 // origin represents the layer entries for a single path,
@@ -76,10 +193,24 @@ type origin []*layerEntry
 
 // add appends a layer entry to the origin list.
 // Entries should be added in priority order (lowest first).
+// Adding the same entry consecutively is a no-op, which happens when a
+// merge strategy folds several elements of one layer into the same path.
 func (o *origin) add(entry *layerEntry) {
+	if n := len(*o); n > 0 && (*o)[n-1] == entry {
+		return
+	}
 	*o = append(*o, entry)
 }
 
+// prepend inserts a layer entry at the front of the origin list.
+// Fill layers use it because they rank below every other layer.
+func (o *origin) prepend(entry *layerEntry) {
+	if len(*o) > 0 && (*o)[0] == entry {
+		return
+	}
+	*o = append(origin{entry}, *o...)
+}
+
 // get returns the highest priority layer entry (last element).
 // Returns nil if empty.
 func (o *origin) get() *layerEntry {
@@ -106,13 +237,34 @@ type origins struct {
 	// containers maps container paths (maps/slices) to their origin layer entries.
 	// This enables fast lookup for container paths in GetAt without full layer traversal.
 	containers map[string]*origin
+
+	// sourcePaths maps a merged path to the path within each contributing layer's
+	// data, for the paths where they differ. This happens when a slice merge
+	// strategy (append, union, key) moves elements to a different index.
+	sourcePaths map[string]map[*layerEntry]string
+
+	// sentinel is the unset sentinel string used to recognize tombstones.
+	sentinel string
+
+	// interpolated maps leaf paths to their values after interpolation,
+	// for the values that differ from the template provided by the layer.
+	interpolated map[string]any
+
+	// expanded is the merged configuration after interpolation.
+	// It is used to resolve containers holding interpolated values.
+	expanded map[string]any
+
+	// aliased holds the data of the layers that provide values through aliases,
+	// with those values moved to the paths of their fields (see resolveAliases).
+	aliased map[*layerEntry]map[string]any
 }
 
 // newOrigins creates a new empty origins.
 func newOrigins() *origins {
 	return &origins{
-		leafs:      make(map[string]*origin),
-		containers: make(map[string]*origin),
+		leafs:       make(map[string]*origin),
+		containers:  make(map[string]*origin),
+		sourcePaths: make(map[string]map[*layerEntry]string),
 	}
 }
 
@@ -134,6 +286,114 @@ func (o *origins) setContainer(path string, entry *layerEntry) {
 	o.containers[path].add(entry)
 }
 
+// fillLeaf records a fill layer entry for a leaf path, ranking it below all other entries.
+func (o *origins) fillLeaf(path string, entry *layerEntry) {
+	if o.leafs[path] == nil {
+		o.leafs[path] = &origin{}
+	}
+	o.leafs[path].prepend(entry)
+}
+
+// fillContainer records a fill layer entry for a container path, ranking it below all other entries.
+func (o *origins) fillContainer(path string, entry *layerEntry) {
+	if o.containers[path] == nil {
+		o.containers[path] = &origin{}
+	}
+	o.containers[path].prepend(entry)
+}
+
+// setSourcePath records the path within entry's data that provides the value
+// for the merged path. Nothing is recorded when both paths are the same.
+func (o *origins) setSourcePath(path string, entry *layerEntry, sourcePath string) {
+	if path == sourcePath {
+		if paths := o.sourcePaths[path]; paths != nil {
+			delete(paths, entry)
+		}
+		return
+	}
+	if o.sourcePaths[path] == nil {
+		o.sourcePaths[path] = make(map[*layerEntry]string)
+	}
+	o.sourcePaths[path][entry] = sourcePath
+}
+
+// sourcePath returns the path within entry's data for the merged path.
+func (o *origins) sourcePath(path string, entry *layerEntry) string {
+	if o == nil {
+		return path
+	}
+	if sourcePath, ok := o.sourcePaths[path][entry]; ok {
+		return sourcePath
+	}
+	return path
+}
+
+// resolve returns the value entry contributes to the merged path.
+func (o *origins) resolve(entry *layerEntry, path string) ResolvedValue {
+	if o == nil {
+		return newResolvedValue(entry, path)
+	}
+	if data, ok := o.aliased[entry]; ok {
+		return resolveDataAt(entry, data, o.sourcePath(path, entry), o.sentinel)
+	}
+	return resolveValueAt(entry, o.sourcePath(path, entry), o.sentinel)
+}
+
+// setDeleted records that entry removes path with a tombstone.
+// The entry is also added to every tracked path below path,
+// so the removal takes effect for descendants recorded by lower layers.
+func (o *origins) setDeleted(path string, entry *layerEntry) {
+	o.setLeaf(path, entry)
+	prefix := path + "/"
+	for p, orig := range o.leafs {
+		if strings.HasPrefix(p, prefix) {
+			orig.add(entry)
+		}
+	}
+	for p, orig := range o.containers {
+		if strings.HasPrefix(p, prefix) {
+			orig.add(entry)
+		}
+	}
+}
+
+// setInterpolated records the result of interpolation.
+// changed maps leaf paths to their expanded values.
+func (o *origins) setInterpolated(expanded map[string]any, changed map[string]any) {
+	if len(changed) == 0 {
+		return
+	}
+	o.interpolated = changed
+	o.expanded = expanded
+}
+
+// interpolate replaces the value of rv with its interpolated value, keeping the
+// template in Raw. rv is returned as is if nothing at or below path was expanded.
+func (o *origins) interpolate(rv ResolvedValue, path string) ResolvedValue {
+	if o == nil || len(o.interpolated) == 0 || !rv.Exists {
+		return rv
+	}
+	if value, ok := o.interpolated[path]; ok {
+		rv.Raw, rv.Value, rv.Interpolated = rv.Value, value, true
+		return rv
+	}
+	switch rv.Value.(type) {
+	case map[string]any, []any:
+	default:
+		return rv
+	}
+	prefix := path + "/"
+	for p := range o.interpolated {
+		if strings.HasPrefix(p, prefix) {
+			if value, ok := jsonptr.GetPath(o.expanded, path); ok {
+				rv.Raw, rv.Value, rv.Interpolated = rv.Value, value, true
+			}
+			return rv
+		}
+	}
+	return rv
+}
+
 // getLeaf returns the highest priority layer entry for a leaf path.
 // Returns nil if not tracked.
 func (o *origins) getLeaf(path string) *layerEntry {
@@ -175,12 +435,6 @@ func (o *origins) isContainer(path string) bool {
 	return o.containers[path] != nil
 }
 
-// clear removes all entries from the origins.
-func (o *origins) clear() {
-	clear(o.leafs)
-	clear(o.containers)
-}
-
 // WalkContext provides access to a configuration path during Walk traversal.
 // It allows lazy retrieval of the resolved value or all values from all layers.
 //
@@ -193,6 +447,9 @@ type WalkContext struct {
 
 	origin *origin
 
+	// origins resolves per-layer source paths for merged slice elements (may be nil)
+	origins *origins
+
 	// maskFunc is the function to apply for sensitive values (may be nil)
 	maskFunc SensitiveMaskFunc
 	// sensitive indicates if this path is sensitive
@@ -204,13 +461,15 @@ type WalkContext struct {
 // Empty values (nil or empty string) are not masked to avoid misleading users.
 // Use ValueUnmasked to get the original value.
 func (c WalkContext) Value() ResolvedValue {
-	entry := c.origin.get()
-	rv := newResolvedValue(entry, c.Path)
+	rv := c.ValueUnmasked()
 
 	// Apply masking if configured and path is sensitive
 	// Don't mask empty values (nil or empty string) to avoid misleading users
 	if c.maskFunc != nil && c.sensitive && rv.Exists && !isEmptyValue(rv.Value) {
 		rv.Value = c.maskFunc(rv.Value)
+		if rv.Interpolated {
+			rv.Raw = c.maskFunc(rv.Raw)
+		}
 		rv.Masked = true
 	}
 
@@ -221,7 +480,7 @@ func (c WalkContext) Value() ResolvedValue {
 // Use this when you need the actual value for processing.
 func (c WalkContext) ValueUnmasked() ResolvedValue {
 	entry := c.origin.get()
-	return newResolvedValue(entry, c.Path)
+	return c.origins.interpolate(c.origins.resolve(entry, c.Path), c.Path)
 }
 
 // IsSensitive returns whether this path is marked as sensitive.
@@ -240,7 +499,7 @@ func (c WalkContext) AllValues() ResolvedValues {
 
 	results := make(ResolvedValues, 0, len(entries))
 	for _, entry := range entries {
-		if rv := newResolvedValue(entry, c.Path); rv.Exists {
+		if rv := c.origins.resolve(entry, c.Path); rv.Exists || rv.Deleted {
 			results = append(results, rv)
 		}
 	}
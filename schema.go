@@ -1,6 +1,7 @@
 package jubako
 
 import (
+	"reflect"
 	"strings"
 
 	"github.com/yacchi/jubako/jsonptr"
@@ -119,13 +120,15 @@ func NewMappingTrie(table *MappingTable) *MappingTrie {
 	}
 
 	// Build trie from mapping table
-	trie.buildFromTable(table, "")
+	trie.buildFromTable(table, "", make(map[*MappingTable]bool))
 
 	return trie
 }
 
 // buildFromTable recursively builds the trie from a MappingTable.
-func (t *MappingTrie) buildFromTable(table *MappingTable, prefix string) {
+// visiting holds the union variant tables on the current path; the fields of
+// recursive variants are mapped down to their first recurrence.
+func (t *MappingTrie) buildFromTable(table *MappingTable, prefix string, visiting map[*MappingTable]bool) {
 	if table == nil {
 		return
 	}
@@ -151,26 +154,60 @@ func (t *MappingTrie) buildFromTable(table *MappingTable, prefix string) {
 		}
 		m.Path = path
 		t.insert(path, m)
+
+		// Aliases resolve to the same mapping, so that their values are recognized
+		// as the field (e.g., for sensitivity checks and unknown key detection).
+		m.AliasPaths = nil
+		for _, alias := range m.Aliases {
+			aliasPath := alias
+			if !strings.HasPrefix(alias, "/") {
+				aliasPath = prefix + "/" + alias
+			}
+			m.AliasPaths = append(m.AliasPaths, aliasPath)
+			t.insert(aliasPath, m)
+		}
 	}
 
 	// Recurse into nested structs
 	for key, nested := range table.Nested {
 		nestedPrefix := prefix + "/" + key
-		t.buildFromTable(nested, nestedPrefix)
+		t.buildFromTable(nested, nestedPrefix, visiting)
 	}
 
 	// Handle slice elements with wildcard
 	for key, elemTable := range table.SliceElement {
 		// Use "*" as wildcard for slice indices
 		elemPrefix := prefix + "/" + key + "/*"
-		t.buildFromTable(elemTable, elemPrefix)
+		t.buildFromTable(elemTable, elemPrefix, visiting)
 	}
 
 	// Handle map values with wildcard
 	for key, valueTable := range table.MapValue {
 		// Use "*" as wildcard for map keys
 		valuePrefix := prefix + "/" + key + "/*"
-		t.buildFromTable(valueTable, valuePrefix)
+		t.buildFromTable(valueTable, valuePrefix, visiting)
+	}
+
+	// Union variants share the union's path; slice and map unions use a wildcard
+	for key, union := range table.Unions {
+		unionPrefix := prefix + "/" + key
+		if union.kind != reflect.Interface {
+			unionPrefix += "/*"
+		}
+		for _, name := range union.names() {
+			variantTable := union.Tables[name]
+			if visiting[variantTable] {
+				continue
+			}
+			visiting[variantTable] = true
+			t.buildFromTable(variantTable, unionPrefix, visiting)
+			delete(visiting, variantTable)
+		}
+		discriminatorPath := unionPrefix + "/" + jsonptr.Escape(union.Discriminator)
+		if t.Lookup(discriminatorPath) == nil {
+			union.discriminator.Path = discriminatorPath
+			t.insert(discriminatorPath, union.discriminator)
+		}
 	}
 }
 
@@ -180,11 +217,13 @@ func collectMappings(table *MappingTable) []*PathMapping {
 	}
 
 	var mappings []*PathMapping
+	seen := make(map[*MappingTable]bool)
 	var walk func(*MappingTable)
 	walk = func(current *MappingTable) {
-		if current == nil {
+		if current == nil || seen[current] {
 			return
 		}
+		seen[current] = true
 		mappings = append(mappings, current.Mappings...)
 		for _, nested := range current.Nested {
 			walk(nested)
@@ -195,6 +234,11 @@ func collectMappings(table *MappingTable) []*PathMapping {
 		for _, nested := range current.MapValue {
 			walk(nested)
 		}
+		for _, union := range current.Unions {
+			for _, name := range union.names() {
+				walk(union.Tables[name])
+			}
+		}
 	}
 	walk(table)
 	return mappings
@@ -224,6 +268,11 @@ func (t *MappingTrie) insert(path string, mapping *PathMapping) {
 			node = child
 		}
 	}
+	// A path shared by several fields, such as the fields of union variants,
+	// stays sensitive if any of them is.
+	if node.mapping != nil && node.mapping.Sensitive == sensitiveExplicit && mapping.Sensitive != sensitiveExplicit {
+		return
+	}
 	node.mapping = mapping
 }
 
@@ -3,6 +3,7 @@ package jubako
 import (
 	"reflect"
 
+	"github.com/yacchi/jubako/document"
 	"github.com/yacchi/jubako/jsonptr"
 )
 
@@ -66,6 +67,19 @@ func Value(path string, value any) SetOption {
 	}
 }
 
+// Unset writes a tombstone at the given path.
+// Unlike DeleteFrom, which only removes the value from the layer, a tombstone
+// also hides values defined at that path by lower priority layers.
+//
+// YAML documents persist the tombstone as `!unset`; formats without tag support
+// persist it as the store's unset sentinel string, and Unset fails on them when
+// no sentinel is set (see WithUnsetSentinel).
+func Unset(path string) SetOption {
+	return func(c *setConfig) {
+		c.patches = append(c.patches, pathValue{path: path, value: document.Tombstone{}})
+	}
+}
+
 // Path groups multiple SetOptions under a common path prefix.
 // Child options use relative paths that are joined with the prefix.
 //
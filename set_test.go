@@ -2,10 +2,17 @@ package jubako
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/format/json"
+	"github.com/yacchi/jubako/layer"
 	"github.com/yacchi/jubako/layer/mapdata"
+	"github.com/yacchi/jubako/source/fs"
 )
 
 func TestStore_Set(t *testing.T) {
@@ -599,3 +606,82 @@ func TestIsZeroValue(t *testing.T) {
 		})
 	}
 }
+
+func TestSetOption_Unset(t *testing.T) {
+	ctx := context.Background()
+
+	store := New[testConfig]()
+	if err := store.Add(mapdata.New("defaults", map[string]any{"host": "localhost", "port": 8080})); err != nil {
+		t.Fatalf("Add(defaults) error = %v", err)
+	}
+	user := mapdata.New("user", map[string]any{"port": 9000})
+	if err := store.Add(user); err != nil {
+		t.Fatalf("Add(user) error = %v", err)
+	}
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if err := store.Set("user", Unset("/host"), Unset("/port")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	cfg := store.Get()
+	if cfg.Host != "" || cfg.Port != 0 {
+		t.Errorf("Get() = %+v, want zero value after Unset", cfg)
+	}
+	if rv := store.GetAt("/host"); !rv.Deleted || rv.Layer.Name() != "user" {
+		t.Errorf("GetAt(/host) = %+v, want deleted by user", rv)
+	}
+
+	if err := store.SaveLayer(ctx, "user"); err != nil {
+		t.Fatalf("SaveLayer() error = %v", err)
+	}
+	want := document.Tombstone{}
+	if got := user.Data()["host"]; got != want {
+		t.Errorf("saved host = %#v, want %#v", got, want)
+	}
+}
+
+func TestSetOption_Unset_Sentinel(t *testing.T) {
+	ctx := context.Background()
+	newStore := func(t *testing.T, opts ...StoreOption) (*Store[testConfig], string) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "user.json")
+		if err := os.WriteFile(path, []byte(`{"host": "example.com"}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		store := New[testConfig](opts...)
+		if err := store.Add(layer.New("user", fs.New(path), json.New())); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		return store, path
+	}
+
+	// JSON cannot hold a tombstone without a sentinel string
+	store, _ := newStore(t)
+	err := store.Set("user", Unset("/host"))
+	if err == nil || !strings.Contains(err.Error(), "need an unset sentinel") {
+		t.Errorf("Set() error = %v, want unset sentinel error", err)
+	}
+
+	store, path := newStore(t, WithUnsetSentinel(document.DefaultUnsetSentinel))
+	if err := store.Set("user", Unset("/host")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Save(ctx); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{\n  \"host\": \"!unset\"\n}\n" {
+		t.Errorf("user.json = %q", data)
+	}
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if rv := store.GetAt("/host"); !rv.Deleted {
+		t.Errorf("GetAt(/host) = %+v, want deleted after reload", rv)
+	}
+}
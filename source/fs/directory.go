@@ -0,0 +1,425 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/yacchi/jubako/container"
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/jsonptr"
+	"github.com/yacchi/jubako/layer"
+	"github.com/yacchi/jubako/source"
+	"github.com/yacchi/jubako/types"
+	"github.com/yacchi/jubako/watcher"
+)
+
+// DirectoryLayer is a layer that merges the configuration files in a directory,
+// such as /etc/app/conf.d, in lexical order of their names: later files override
+// earlier ones. Each file is parsed with the document registered for its
+// extension with WithDocument; other files, subdirectories and hidden files
+// are ignored.
+//
+// The Store reports the file that provides each value in ResolvedValue.Layer.Path()
+// (see layer.FileTracker). The directory is watched with fsnotify, so that adding,
+// changing or removing a file triggers a reload. DirectoryLayer does not support saving.
+type DirectoryLayer struct {
+	name layer.Name
+	dir  string
+	docs map[string]document.Document
+
+	opMu sync.Mutex // serializes directory reads
+
+	// mu protects files.
+	mu sync.Mutex
+	// files maps JSON Pointer paths to the file that provides them, for the
+	// data last returned by Load or applied from a watch result.
+	files map[string]dirFile
+}
+
+// dirFile is a file read by a DirectoryLayer.
+type dirFile struct {
+	path   string
+	format document.DocumentFormat
+}
+
+// DirectoryOption configures a DirectoryLayer.
+type DirectoryOption func(*DirectoryLayer)
+
+// WithDocument registers the document used for files with the extension ext
+// (e.g., ".yaml"). Files whose extension has no document are ignored, so the
+// caller registers one for each format the directory holds.
+func WithDocument(ext string, doc document.Document) DirectoryOption {
+	return func(l *DirectoryLayer) {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		l.docs[strings.ToLower(ext)] = doc
+	}
+}
+
+// Ensure DirectoryLayer implements the layer interfaces.
+var (
+	_ layer.Layer       = (*DirectoryLayer)(nil)
+	_ layer.FileTracker = (*DirectoryLayer)(nil)
+)
+
+// NewDirectory creates a layer that merges the files in dir.
+// The path can be absolute or relative. Tilde (~) expansion is supported.
+// A missing directory is reported as source.ErrNotExist, so the layer can be
+// added with jubako.WithOptional.
+//
+// Example:
+//
+//	store.Add(fs.NewDirectory("conf.d", "/etc/app/conf.d",
+//	    fs.WithDocument(".json", json.New()),
+//	    fs.WithDocument(".yaml", yaml.New()),
+//	    fs.WithDocument(".yml", yaml.New())),
+//	    jubako.WithPriority(jubako.PriorityProject))
+func NewDirectory(name layer.Name, dir string, opts ...DirectoryOption) *DirectoryLayer {
+	l := &DirectoryLayer{
+		name: name,
+		dir:  dir,
+		docs: make(map[string]document.Document),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Name returns the layer name.
+func (l *DirectoryLayer) Name() layer.Name {
+	return l.name
+}
+
+// Load reads and merges the files in the directory.
+func (l *DirectoryLayer) Load(ctx context.Context) (map[string]any, error) {
+	l.opMu.Lock()
+	defer l.opMu.Unlock()
+
+	loaded, err := l.loadNoLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	l.setFiles(loaded.files)
+	return loaded.data, nil
+}
+
+// dirLoad is the result of reading the directory.
+type dirLoad struct {
+	data  map[string]any
+	files map[string]dirFile
+	// fingerprint identifies the file names and contents, to detect changes.
+	fingerprint []byte
+}
+
+// loadNoLock reads and merges the files. The file list is not committed:
+// the caller does it with setFiles once the data is used.
+func (l *DirectoryLayer) loadNoLock(ctx context.Context) (dirLoad, error) {
+	if err := ctx.Err(); err != nil {
+		return dirLoad{}, err
+	}
+	dir, err := expandTilde(l.dir)
+	if err != nil {
+		return dirLoad{}, fmt.Errorf("failed to expand path %q: %w", l.dir, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return dirLoad{}, source.NewNotExistError(dir, err)
+		}
+		return dirLoad{}, fmt.Errorf("failed to read directory %q: %w", dir, err)
+	}
+
+	// os.ReadDir returns the entries sorted by name
+	data := make(map[string]any)
+	files := make(map[string]dirFile)
+	var fingerprint bytes.Buffer
+	for _, entry := range entries {
+		doc, ok := l.documentFor(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		raw, err := osReadFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// Removed since the directory was read
+				continue
+			}
+			return dirLoad{}, fmt.Errorf("failed to read %q: %w", path, err)
+		}
+		fileData, err := doc.Get(raw)
+		if err != nil {
+			return dirLoad{}, fmt.Errorf("failed to parse %q: %w", path, err)
+		}
+		fmt.Fprintf(&fingerprint, "%s\x00%d\x00", path, len(raw))
+		fingerprint.Write(raw)
+
+		mergeFile(data, fileData, "", dirFile{path: path, format: doc.Format()}, files)
+	}
+
+	return dirLoad{data: data, files: files, fingerprint: fingerprint.Bytes()}, nil
+}
+
+// setFiles commits the file list of loaded data, as reported by FileOf.
+func (l *DirectoryLayer) setFiles(files map[string]dirFile) {
+	l.mu.Lock()
+	l.files = files
+	l.mu.Unlock()
+}
+
+// documentFor returns the document registered for the extension of name.
+// Hidden files (e.g., editor swap files) are ignored.
+func (l *DirectoryLayer) documentFor(name string) (document.Document, bool) {
+	if strings.HasPrefix(name, ".") {
+		return nil, false
+	}
+	doc, ok := l.docs[strings.ToLower(filepath.Ext(name))]
+	return doc, ok
+}
+
+// mergeFile merges src into dst, recording the file that provides each path.
+// Maps are merged recursively; other values, including slices, are replaced.
+func mergeFile(dst, src map[string]any, prefix string, file dirFile, files map[string]dirFile) {
+	for key, value := range src {
+		path := prefix + "/" + jsonptr.Escape(key)
+		if srcMap, ok := value.(map[string]any); ok {
+			if dstMap, ok := dst[key].(map[string]any); ok {
+				files[path] = file
+				mergeFile(dstMap, srcMap, path, file, files)
+				continue
+			}
+		}
+		forgetBelow(files, path)
+		dst[key] = container.DeepCopyValue(value)
+		recordFile(value, path, file, files)
+	}
+}
+
+// recordFile records file as the provider of path and of the maps below it.
+func recordFile(value any, path string, file dirFile, files map[string]dirFile) {
+	files[path] = file
+	if m, ok := value.(map[string]any); ok {
+		for key, child := range m {
+			recordFile(child, path+"/"+jsonptr.Escape(key), file, files)
+		}
+	}
+}
+
+// forgetBelow removes the paths below path, whose values are being replaced.
+func forgetBelow(files map[string]dirFile, path string) {
+	prefix := path + "/"
+	for p := range files {
+		if strings.HasPrefix(p, prefix) {
+			delete(files, p)
+		}
+	}
+}
+
+// FileOf returns the file that provides the value at path.
+// Paths inside slices are reported for the file that provides the slice.
+func (l *DirectoryLayer) FileOf(path string) (string, document.DocumentFormat, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for p := path; p != ""; p = p[:strings.LastIndex(p, "/")] {
+		if f, ok := l.files[p]; ok {
+			return f.path, f.format, true
+		}
+	}
+	return "", "", false
+}
+
+// Save returns source.ErrSaveNotSupported: drop-in files are managed individually.
+func (l *DirectoryLayer) Save(ctx context.Context, changeset document.JSONPatchSet) error {
+	return source.ErrSaveNotSupported
+}
+
+// CanSave returns false because drop-in files are managed individually.
+func (l *DirectoryLayer) CanSave() bool {
+	return false
+}
+
+// CanNotExist returns true because the directory can be missing.
+func (l *DirectoryLayer) CanNotExist() bool {
+	return true
+}
+
+// FillDetails populates the Details struct with the directory path.
+// The format of each value is reported per file through FileOf.
+func (l *DirectoryLayer) FillDetails(d *types.Details) {
+	d.Source = source.TypeFS
+	d.Path = l.dir
+	d.Watcher = watcher.TypeSubscription
+}
+
+// Subscribe implements the watcher.SubscriptionHandler interface.
+// It watches the directory with fsnotify and calls notify when a file with a
+// registered extension is created, written, removed or renamed.
+func (l *DirectoryLayer) Subscribe(ctx context.Context, notify watcher.NotifyFunc) (watcher.StopFunc, error) {
+	dir, err := expandTilde(l.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand path %q: %w", l.dir, err)
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fsnotify watcher: %w", err)
+	}
+	if err := w.Add(dir); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("failed to watch directory %q: %w", dir, err)
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if _, ok := l.documentFor(filepath.Base(event.Name)); !ok {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+					notify(nil, nil)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				notify(nil, err)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func(ctx context.Context) error {
+		return w.Close()
+	}, nil
+}
+
+// Watch returns a LayerWatcher that reloads the directory when its files change.
+func (l *DirectoryLayer) Watch(opts ...layer.WatchOption) (layer.LayerWatcher, error) {
+	dw := &directoryWatcher{layer: l}
+	var last []byte
+	fetch := func(ctx context.Context) (bool, []byte, error) {
+		loaded, err := l.loadNoLock(ctx)
+		if err != nil {
+			return false, nil, err
+		}
+		// Editors emit several events per save; report each content once
+		if last != nil && bytes.Equal(last, loaded.fingerprint) {
+			return false, nil, nil
+		}
+		last = loaded.fingerprint
+		dw.push(loaded)
+		return true, loaded.fingerprint, nil
+	}
+
+	init := watcher.NewSubscription(watcher.SubscriptionHandlerFunc(l.Subscribe))
+	w, err := init(watcher.WatcherInitializerParams{
+		Fetch:  fetch,
+		OpMu:   &l.opMu,
+		Config: layer.ResolveWatchConfig(opts...),
+	})
+	if err != nil {
+		return nil, err
+	}
+	dw.watcher = w
+	return dw, nil
+}
+
+// directoryWatcher converts the results of the subscription watcher, which
+// carry a fingerprint of the directory, to the merged data. The file list of
+// the data is committed when the Store applies the result.
+type directoryWatcher struct {
+	layer   *DirectoryLayer
+	watcher watcher.Watcher
+	results chan layer.LayerWatchResult
+	stop    chan struct{}
+
+	stopOnce sync.Once
+
+	// pending holds the data of fetched results that have not been converted
+	// yet, in fetch order. The subscription watcher drops results when it is
+	// stopped, so results are matched by fingerprint.
+	mu      sync.Mutex
+	pending []dirLoad
+}
+
+// push records the result of a fetch.
+func (w *directoryWatcher) push(loaded dirLoad) {
+	w.mu.Lock()
+	w.pending = append(w.pending, loaded)
+	w.mu.Unlock()
+}
+
+// take returns the fetch with fingerprint, discarding earlier fetches whose
+// results were dropped.
+func (w *directoryWatcher) take(fingerprint []byte) (dirLoad, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, fetched := range w.pending {
+		if bytes.Equal(fetched.fingerprint, fingerprint) {
+			w.pending = w.pending[i+1:]
+			return fetched, true
+		}
+	}
+	return dirLoad{}, false
+}
+
+// Start begins watching the directory.
+func (w *directoryWatcher) Start(ctx context.Context) error {
+	w.results = make(chan layer.LayerWatchResult)
+	w.stop = make(chan struct{})
+	if err := w.watcher.Start(ctx); err != nil {
+		close(w.results)
+		return err
+	}
+
+	go func() {
+		defer close(w.results)
+		for result := range w.watcher.Results() {
+			converted := layer.LayerWatchResult{Error: result.Error}
+			if result.Error == nil {
+				loaded, ok := w.take(result.Data)
+				if !ok {
+					continue
+				}
+				converted.Data = loaded.data
+				converted.Applied = func() { w.layer.setFiles(loaded.files) }
+			}
+			select {
+			case w.results <- converted:
+			case <-ctx.Done():
+				return
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops watching the directory.
+func (w *directoryWatcher) Stop(ctx context.Context) error {
+	if w.stop != nil {
+		w.stopOnce.Do(func() { close(w.stop) })
+	}
+	return w.watcher.Stop(ctx)
+}
+
+// Results returns the channel receiving the merged data.
+func (w *directoryWatcher) Results() <-chan layer.LayerWatchResult {
+	return w.results
+}
@@ -0,0 +1,264 @@
+package fs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yacchi/jubako"
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/format/json"
+	"github.com/yacchi/jubako/layer"
+	"github.com/yacchi/jubako/layer/mapdata"
+	"github.com/yacchi/jubako/source"
+	"github.com/yacchi/jubako/source/fs"
+)
+
+func writeDropIn(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+// newJSONDirectory returns a directory layer reading the JSON files of dir.
+func newJSONDirectory(dir string) *fs.DirectoryLayer {
+	return fs.NewDirectory("conf.d", dir, fs.WithDocument(".json", json.New()))
+}
+
+func TestDirectoryLayer_Load(t *testing.T) {
+	dir := t.TempDir()
+	writeDropIn(t, dir, "10-base.json", `{"server": {"host": "a", "port": 80}, "tags": ["x"]}`)
+	writeDropIn(t, dir, "20-override.json", `{"server": {"port": 8080}, "tags": ["y", "z"]}`)
+	writeDropIn(t, dir, "30-ignored.txt", `not a config`)
+	writeDropIn(t, dir, ".hidden.json", `{"server": {"host": "hidden"}}`)
+	if err := os.Mkdir(filepath.Join(dir, "sub.json"), 0o755); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+
+	l := newJSONDirectory(dir)
+	data, err := l.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := map[string]any{
+		"server": map[string]any{"host": "a", "port": float64(8080)},
+		"tags":   []any{"y", "z"},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("Load() = %v, want %v", data, want)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/server/host", "10-base.json"},
+		{"/server/port", "20-override.json"},
+		{"/server", "20-override.json"},
+		{"/tags/1", "20-override.json"},
+	}
+	for _, tt := range tests {
+		file, format, ok := l.FileOf(tt.path)
+		if !ok || file != filepath.Join(dir, tt.want) || format != document.FormatJSON {
+			t.Errorf("FileOf(%s) = %q, %q, %v, want %s", tt.path, file, format, ok, tt.want)
+		}
+	}
+	if _, _, ok := l.FileOf("/missing"); ok {
+		t.Error("FileOf(/missing) should not be found")
+	}
+}
+
+func TestDirectoryLayer_NotExist(t *testing.T) {
+	l := newJSONDirectory(filepath.Join(t.TempDir(), "missing"))
+	_, err := l.Load(context.Background())
+	if !errors.Is(err, source.ErrNotExist) {
+		t.Errorf("Load() error = %v, want source.ErrNotExist", err)
+	}
+}
+
+func TestDirectoryLayer_Store(t *testing.T) {
+	type config struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	dir := t.TempDir()
+	writeDropIn(t, dir, "10-a.json", `{"host": "a", "port": 1}`)
+	writeDropIn(t, dir, "20-b.json", `{"port": 2}`)
+
+	store := jubako.New[config]()
+	if err := store.Add(mapdata.New("defaults", map[string]any{"host": "default"})); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Add(newJSONDirectory(dir)); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := store.Get(); got != (config{Host: "a", Port: 2}) {
+		t.Errorf("Get() = %+v", got)
+	}
+	rv := store.GetAt("/port")
+	if rv.Layer.Name() != "conf.d" || rv.Layer.Path() != filepath.Join(dir, "20-b.json") {
+		t.Errorf("GetAt(/port).Layer = %s (%s), want conf.d (20-b.json)", rv.Layer.Name(), rv.Layer.Path())
+	}
+	if rv.Layer.Format() != document.FormatJSON {
+		t.Errorf("GetAt(/port).Layer.Format() = %q", rv.Layer.Format())
+	}
+	if rv := store.GetAt("/host"); rv.Layer.Path() != filepath.Join(dir, "10-a.json") {
+		t.Errorf("GetAt(/host).Layer.Path() = %q, want 10-a.json", rv.Layer.Path())
+	}
+	if info := store.GetLayerInfo("conf.d"); info.Path() != dir {
+		t.Errorf("GetLayerInfo().Path() = %q, want %q", info.Path(), dir)
+	}
+}
+
+func TestDirectoryLayer_Watch(t *testing.T) {
+	dir := t.TempDir()
+	writeDropIn(t, dir, "10-a.json", `{"a": 1}`)
+
+	l := newJSONDirectory(dir)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := l.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	w, err := l.Watch()
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	if err := w.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer w.Stop(context.Background())
+
+	waitFor := func(want map[string]any) {
+		t.Helper()
+		for {
+			select {
+			case result := <-w.Results():
+				if result.Error != nil {
+					t.Fatalf("watch error = %v", result.Error)
+				}
+				if reflect.DeepEqual(result.Data, want) {
+					return
+				}
+			case <-ctx.Done():
+				t.Fatalf("timed out waiting for %v", want)
+			}
+		}
+	}
+
+	writeDropIn(t, dir, "20-b.json", `{"b": 2}`)
+	waitFor(map[string]any{"a": float64(1), "b": float64(2)})
+
+	if err := os.Remove(filepath.Join(dir, "10-a.json")); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	waitFor(map[string]any{"b": float64(2)})
+}
+
+func TestDirectoryLayer_Watch_StopWithoutReading(t *testing.T) {
+	dir := t.TempDir()
+	writeDropIn(t, dir, "10-a.json", `{"a": 1}`)
+
+	l := newJSONDirectory(dir)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	w, err := l.Watch()
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	if err := w.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// A change nobody reads must not keep the watcher running after Stop
+	writeDropIn(t, dir, "20-b.json", `{"b": 2}`)
+	time.Sleep(100 * time.Millisecond)
+	if err := w.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	select {
+	case result, ok := <-w.Results():
+		if ok {
+			t.Errorf("Results() delivered %v after Stop", result)
+		}
+	case <-ctx.Done():
+		t.Fatal("Results() was not closed after Stop")
+	}
+}
+
+func TestDirectoryLayer_Watch_Rejected(t *testing.T) {
+	type config struct {
+		Port int `json:"port"`
+	}
+	dir := t.TempDir()
+	writeDropIn(t, dir, "10-a.json", `{"port": 1}`)
+
+	store := jubako.New[config](jubako.WithValidator(func(c config) error {
+		if c.Port <= 0 {
+			return errors.New("port must be positive")
+		}
+		return nil
+	}))
+	if err := store.Add(newJSONDirectory(dir)); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	rejected := make(chan error, 1)
+	stop, err := store.Watch(ctx, jubako.StoreWatchConfig{
+		DebounceDelay: 10 * time.Millisecond,
+		OnError: func(_ layer.Name, err error) {
+			select {
+			case rejected <- err:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	defer stop(context.Background())
+
+	// The rejected file must not be reported as the provider of the kept value
+	writeDropIn(t, dir, "20-b.json", `{"port": 0}`)
+	select {
+	case <-rejected:
+	case <-ctx.Done():
+		t.Fatal("the update was not rejected")
+	}
+	rv := store.GetAt("/port")
+	if rv.Value != float64(1) || rv.Layer.Path() != filepath.Join(dir, "10-a.json") {
+		t.Errorf("GetAt(/port) = %v from %q, want 1 from 10-a.json", rv.Value, rv.Layer.Path())
+	}
+
+	// An accepted update commits its file list
+	writeDropIn(t, dir, "20-b.json", `{"port": 2}`)
+	for rv.Value != float64(2) {
+		select {
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for the update")
+		case <-time.After(10 * time.Millisecond):
+		}
+		rv = store.GetAt("/port")
+	}
+	if rv.Layer.Path() != filepath.Join(dir, "20-b.json") {
+		t.Errorf("GetAt(/port).Layer.Path() = %q, want 20-b.json", rv.Layer.Path())
+	}
+}
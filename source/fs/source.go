@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/yacchi/jubako/source"
@@ -86,6 +87,12 @@ var _ source.WatchableSource = (*Source)(nil)
 // Ensure Source implements the source.NotExistCapable interface.
 var _ source.NotExistCapable = (*Source)(nil)
 
+// Ensure Source implements the source.RelativeSource interface.
+var _ source.RelativeSource = (*Source)(nil)
+
+// Ensure Source implements the source.RemovableSource interface.
+var _ source.RemovableSource = (*Source)(nil)
+
 // Option configures a Source.
 type Option func(*Source)
 
@@ -283,6 +290,27 @@ func (s *Source) Save(ctx context.Context, updateFunc source.UpdateFunc) error {
 	return nil
 }
 
+// Remove implements the source.RemovableSource interface.
+// It deletes the file Save writes to; a missing file is not an error.
+func (s *Source) Remove(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	targetPath := s.resolvedPath
+	if targetPath == "" {
+		var err error
+		targetPath, _, err = s.resolvePath()
+		if err != nil {
+			return err
+		}
+	}
+	if err := osRemove(targetPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove file %q: %w", targetPath, err)
+	}
+	return nil
+}
+
 // Type returns the source type identifier.
 func (s *Source) Type() source.SourceType {
 	return source.TypeFS
@@ -336,6 +364,19 @@ func (s *Source) resolvePath() (expanded string, original string, err error) {
 	return expanded, s.path, nil
 }
 
+// Relative returns a file source for path, resolved against the directory of
+// this source's file. Absolute paths and paths starting with ~ are used as is.
+// The returned source uses the same file and directory modes.
+func (s *Source) Relative(path string) (source.Source, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty relative path")
+	}
+	if !filepath.IsAbs(path) && path != "~" && !strings.HasPrefix(path, "~/") {
+		path = filepath.Join(filepath.Dir(s.ResolvedPath()), path)
+	}
+	return New(path, WithFileMode(s.fileMode), WithDirMode(s.dirMode)), nil
+}
+
 // CanSave returns true because file system sources support saving.
 func (s *Source) CanSave() bool {
 	return true
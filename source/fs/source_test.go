@@ -81,6 +81,42 @@ func TestResolvePathAndResolvedPath(t *testing.T) {
 	}
 }
 
+func TestSource_Relative(t *testing.T) {
+	dir := t.TempDir()
+	s := New(filepath.Join(dir, "config.yaml"), WithFileMode(0o600))
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"db.yaml", filepath.Join(dir, "db.yaml")},
+		{"conf/db.yaml", filepath.Join(dir, "conf", "db.yaml")},
+		{"../db.yaml", filepath.Join(filepath.Dir(dir), "db.yaml")},
+		{"/etc/app/db.yaml", "/etc/app/db.yaml"},
+		{"~/db.yaml", "~/db.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			rel, err := s.Relative(tt.in)
+			if err != nil {
+				t.Fatalf("Relative() error = %v", err)
+			}
+			details := &types.Details{}
+			rel.(*Source).FillDetails(details)
+			if details.Path != tt.want {
+				t.Errorf("Relative(%q).Path = %q, want %q", tt.in, details.Path, tt.want)
+			}
+			if mode := rel.(*Source).fileMode; mode != 0o600 {
+				t.Errorf("Relative(%q) file mode = %o, want 600", tt.in, mode)
+			}
+		})
+	}
+
+	if _, err := s.Relative(""); err == nil {
+		t.Error("Relative(\"\") expected error, got nil")
+	}
+}
+
 func TestLoad_NoFilesFound(t *testing.T) {
 	dir := t.TempDir()
 	primary := filepath.Join(dir, "missing.json")
@@ -137,6 +137,14 @@ type Source interface {
 	CanSave() bool
 }
 
+// RemovableSource is an optional interface for sources whose data can be
+// deleted. Layers use it to undo a save that created the data.
+type RemovableSource interface {
+	// Remove deletes the data Save writes. It returns nil if the data does
+	// not exist.
+	Remove(ctx context.Context) error
+}
+
 // WatchableSource is an optional interface that sources can implement
 // to support change detection and hot reload.
 //
@@ -174,3 +182,16 @@ type WatchableSource interface {
 	//	}
 	Watch() (watcher.WatcherInitializer, error)
 }
+
+// RelativeSource is an optional interface for sources that can open other
+// resources relative to their own location, such as a file next to a
+// configuration file. It is used to resolve $include references.
+type RelativeSource interface {
+	// Relative returns a source of the same kind for path.
+	// Relative paths are resolved against the location of this source.
+	//
+	// Example:
+	//
+	//	// fs.New("/etc/app/config.yaml").Relative("db.yaml") reads /etc/app/db.yaml
+	Relative(path string) (Source, error)
+}
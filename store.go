@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"sort"
 	"sync"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/yacchi/jubako/decoder"
 	"github.com/yacchi/jubako/document"
 	"github.com/yacchi/jubako/jsonptr"
+	"github.com/yacchi/jubako/jsonschema"
 	"github.com/yacchi/jubako/layer"
 	"github.com/yacchi/jubako/source"
 	"github.com/yacchi/jubako/types"
@@ -68,6 +70,11 @@ type LayerInfo interface {
 	// Optional returns whether the layer is marked as optional.
 	// Optional layers do not cause an error if their source does not exist.
 	Optional() bool
+
+	// Version returns the schema version detected in the layer's data at the
+	// version path registered with WithMigrations, before any migration ran.
+	// Returns false if no version was detected.
+	Version() (int, bool)
 }
 
 // AddOption is a functional option for configuring layer addition.
@@ -81,6 +88,8 @@ type addOptions struct {
 	noWatch     bool
 	sensitive   bool
 	optional    bool
+	schema      *jsonschema.Schema
+	schemaErr   error
 }
 
 // WithPriority sets a specific priority for the layer.
@@ -165,6 +174,9 @@ type layerEntry struct {
 	// optional indicates whether the layer source is optional (missing source is not an error)
 	optional bool
 
+	// fill indicates that the layer only supplies values missing from other layers (layer.FillLayer)
+	fill bool
+
 	// dirty is the aggregated pending-save state derived from changeset and
 	// projectionDirty via syncLayerDirty.
 	dirty bool
@@ -183,6 +195,19 @@ type layerEntry struct {
 
 	// projectionDirty tracks stable subtrees that need reprojection on next save.
 	projectionDirty []string
+
+	// version is the schema version detected in the loaded data (see WithMigrations).
+	version    int
+	hasVersion bool
+
+	// original holds the loaded data before migrations, or nil if no migration ran.
+	original map[string]any
+
+	// schema validates loaded data, if set with WithLayerSchema.
+	schema *jsonschema.Schema
+
+	// persistMigration indicates that the migrated shape is written on next save.
+	persistMigration bool
 }
 
 // Name returns the unique identifier for this layer.
@@ -247,6 +272,11 @@ func (e *layerEntry) Optional() bool {
 	return e.optional
 }
 
+// Version returns the schema version detected in the layer's data.
+func (e *layerEntry) Version() (int, bool) {
+	return e.version, e.hasVersion
+}
+
 // findLayerLocked finds a layer by name.
 // Returns nil if the layer is not found.
 // Caller must hold the lock (read or write).
@@ -264,12 +294,21 @@ type StoreOption func(*storeOptions)
 
 // storeOptions holds the options for New.
 type storeOptions struct {
-	priorityStep   int
-	decoder        MapDecoder
-	sensitiveMask  SensitiveMaskFunc
-	tagDelimiter   string
-	tagName        string
-	valueConverter ValueConverter
+	priorityStep      int
+	decoder           MapDecoder
+	reflectDecoder    bool
+	sensitiveMask     SensitiveMaskFunc
+	tagDelimiter      string
+	tagName           string
+	valueConverter    ValueConverter
+	converters        []Converter
+	unsetSentinel     string
+	validators        []any
+	interpolation     bool
+	unknownKeys       UnknownKeyMode
+	unknownKeyHandler UnknownKeyHandler
+	versionPath       string
+	migrations        []Migration
 }
 
 // defaultPriorityStep is the default step size for auto-assigned priorities.
@@ -299,6 +338,7 @@ func WithPriorityStep(step int) StoreOption {
 func WithDecoder(decoder MapDecoder) StoreOption {
 	return func(o *storeOptions) {
 		o.decoder = decoder
+		o.reflectDecoder = false
 	}
 }
 
@@ -412,6 +452,27 @@ func WithValueConverter(fn ValueConverter) StoreOption {
 	}
 }
 
+// WithUnsetSentinel enables a string value that marks a path as deleted (a tombstone).
+// A layer value equal to the sentinel removes the path from the merged configuration,
+// so values from lower priority layers no longer show through.
+//
+// By default no string is a sentinel, so a value such as "!unset" in a JSON file
+// is an ordinary string. An empty string keeps sentinels disabled.
+// document.Tombstone values (e.g., YAML `!unset` or values written via Unset) are
+// always honoured.
+//
+// Formats without tag support (JSON, JSONC, TOML) persist tombstones written via
+// Unset as this sentinel string; without a sentinel, Unset fails on such layers.
+//
+// Example:
+//
+//	store := jubako.New[Config](jubako.WithUnsetSentinel(document.DefaultUnsetSentinel))
+func WithUnsetSentinel(sentinel string) StoreOption {
+	return func(o *storeOptions) {
+		o.unsetSentinel = sentinel
+	}
+}
+
 // Store manages multiple configuration layers and provides a materialized view
 // of the merged configuration.
 //
@@ -463,7 +524,50 @@ type Store[T any] struct {
 	// If nil, DefaultValueConverter is used
 	valueConverter ValueConverter
 
-	// mu protects layers, origins, and subscribers
+	// converters convert values of the types they handle, in place of valueConverter
+	converters converterRegistry
+
+	// hasConverters is true if the schema has fields of types handled by converters
+	hasConverters bool
+
+	// hasUnions is true if the schema has fields declared with the union= directive
+	hasUnions bool
+
+	// unsetSentinel is the string value treated as a tombstone during merge
+	// (empty if only document.Tombstone values are tombstones)
+	unsetSentinel string
+
+	// validators are run against every materialized value before it is published
+	validators []func(T) error
+
+	// hasConstraints is true if the schema declares constraints in jubako struct tags
+	hasConstraints bool
+
+	// interpolation enables ${...} references in string values
+	interpolation bool
+
+	// unknownKeys controls the detection of keys that are not in the schema.
+	unknownKeys       UnknownKeyMode
+	unknownKeyHandler UnknownKeyHandler
+	// reportedUnknownKeys holds the unknown keys reported by the last
+	// materialization, so that UnknownKeysWarn reports each key once.
+	reportedUnknownKeys map[string]struct{}
+
+	// aliasMappings are the mappings declaring aliases with the alias= directive.
+	aliasMappings []*PathMapping
+	// reportedAliases holds the deprecated aliases reported by the last
+	// materialization, so that each one is reported once.
+	reportedAliases map[string]struct{}
+
+	// migrations upgrades layer data to the latest schema version, or nil if
+	// no migration is registered.
+	migrations *migrations
+
+	// watchSessions holds the running Watch invocations.
+	// Layer stack changes use it to stop and restart per-layer watchers.
+	watchSessions []*watchSession
+
+	// mu protects layers, origins, subscribers, and watchSessions
 	mu sync.RWMutex
 }
 
@@ -473,8 +577,14 @@ type Store[T any] struct {
 // Options:
 //   - WithPriorityStep(step): Set the step size for auto-assigned priorities (default: 10)
 //   - WithDecoder(decoder): Set a custom map decoder (default: JSON marshal/unmarshal)
+//   - WithReflectDecoder(): Decode with reflection, without the JSON round-trip
 //   - WithTagDelimiter(delimiter): Set a custom delimiter for jubako struct tags (default: ",")
 //   - WithTagName(name): Set the struct tag name for field resolution (default: "json")
+//   - WithConverters(converters...): Convert values to types such as *url.URL or netip.Prefix
+//   - WithValidator(fn): Reject materialized values that fail validation
+//   - WithInterpolation(): Expand ${...} references in string values
+//   - WithUnknownKeys(mode): Report keys that do not map to any field of T
+//   - WithMigrations(path, migrations...): Upgrade versioned layer data before merging
 //
 // Example:
 //
@@ -490,6 +600,8 @@ func New[T any](opts ...StoreOption) *Store[T] {
 		tagName:      DefaultFieldTagName,
 		// Set DefaultValueConverter if not provided
 		valueConverter: DefaultValueConverter,
+		// Log unknown keys to stderr unless WithUnknownKeyHandler is given
+		unknownKeyHandler: defaultUnknownKeyHandler,
 	}
 	for _, opt := range opts {
 		opt(&options)
@@ -497,30 +609,53 @@ func New[T any](opts ...StoreOption) *Store[T] {
 
 	var zero T
 
+	converters := newConverterRegistry(options.converters)
+
 	// Build mapping table from T's struct tags at initialization time
-	table := buildMappingTable(reflect.TypeOf(zero), options.tagDelimiter, options.tagName)
+	table := buildMappingTableWithConverters(reflect.TypeOf(zero), options.tagDelimiter, options.tagName, converters)
 
 	// Build schema containing both table and trie
 	schema := NewSchema(table)
 
-	return &Store[T]{
-		layers:         make([]*layerEntry, 0),
-		resolved:       NewCell(zero),
-		origins:        newOrigins(),
-		subscribers:    make([]subscriber[T], 0),
-		nextSubID:      1,
-		priorityStep:   options.priorityStep,
-		decoder:        options.decoder,
-		schema:         schema,
-		sensitiveMask:  options.sensitiveMask,
-		tagDelimiter:   options.tagDelimiter,
-		tagName:        options.tagName,
-		valueConverter: options.valueConverter,
+	if options.reflectDecoder {
+		options.decoder = newReflectDecoder(reflect.TypeOf(zero), table, options.tagName, options.tagDelimiter, converters).decode
 	}
-}
 
-// Ensure Store implements layer.StoreProvider interface.
-var _ layer.StoreProvider = (*Store[struct{}])(nil)
+	origins := newOrigins()
+	origins.sentinel = options.unsetSentinel
+
+	return &Store[T]{
+		layers:            make([]*layerEntry, 0),
+		resolved:          NewCell(zero),
+		origins:           origins,
+		subscribers:       make([]subscriber[T], 0),
+		nextSubID:         1,
+		priorityStep:      options.priorityStep,
+		decoder:           options.decoder,
+		schema:            schema,
+		sensitiveMask:     options.sensitiveMask,
+		tagDelimiter:      options.tagDelimiter,
+		tagName:           options.tagName,
+		valueConverter:    options.valueConverter,
+		converters:        converters,
+		hasConverters:     hasConverters(schema, converters),
+		hasUnions:         table.hasUnions(),
+		unsetSentinel:     options.unsetSentinel,
+		validators:        buildValidators[T](options.validators),
+		hasConstraints:    hasConstraints(schema),
+		interpolation:     options.interpolation,
+		unknownKeys:       options.unknownKeys,
+		unknownKeyHandler: options.unknownKeyHandler,
+		aliasMappings:     collectAliasMappings(schema),
+		migrations:        newMigrations(options.versionPath, options.migrations),
+	}
+}
+
+// Ensure Store implements layer.StoreProvider and layer.ValueConverterProvider interfaces.
+var (
+	_ layer.StoreProvider          = (*Store[struct{}])(nil)
+	_ layer.ValueConverterProvider = (*Store[struct{}])(nil)
+)
 
 // SchemaType returns the reflect.Type of the Store's configuration struct T.
 // This implements layer.StoreProvider.
@@ -547,6 +682,13 @@ func (s *Store[T]) FieldTagName() string {
 	return s.tagName
 }
 
+// ConvertValue converts value to targetType using the Store's converters
+// (see Converter) and ValueConverter.
+// This implements layer.ValueConverterProvider.
+func (s *Store[T]) ConvertValue(path string, value any, targetType reflect.Type) (any, error) {
+	return s.convertValue(path, value, targetType)
+}
+
 // Add registers a new configuration layer.
 // If WithPriority option is provided, layers are sorted by priority (higher overrides lower).
 // If no priority is specified, layers are processed in the order they were added.
@@ -586,17 +728,35 @@ func (s *Store[T]) Add(l layer.Layer, opts ...AddOption) error {
 		}
 	}
 
+	// Auto-assign priority based on current count, with gaps for future insertions
+	entry, err := newLayerEntry(l, layer.Priority(len(s.layers)*s.priorityStep), opts)
+	if err != nil {
+		return err
+	}
+
+	s.layers = append(s.layers, entry)
+	s.sortLayersLocked()
+
+	return nil
+}
+
+// newLayerEntry creates a layer entry from the given options.
+// defaultPriority is used when no WithPriority option is provided.
+// It returns an error if an option is invalid, such as a malformed WithLayerSchema.
+func newLayerEntry(l layer.Layer, defaultPriority layer.Priority, opts []AddOption) (*layerEntry, error) {
 	// Apply options
 	var options addOptions
 	for _, opt := range opts {
 		opt(&options)
 	}
+	if options.schemaErr != nil {
+		return nil, fmt.Errorf("invalid schema for layer %q: %w", l.Name(), options.schemaErr)
+	}
 
-	// Determine priority: use explicit value or auto-assign based on insertion order
+	// Determine priority: use explicit value or the caller-provided default
 	priority := options.priority
 	if !options.hasPriority {
-		// Auto-assign priority based on current count, with gaps for future insertions
-		priority = layer.Priority(len(s.layers) * s.priorityStep)
+		priority = defaultPriority
 	}
 
 	entry := &layerEntry{
@@ -606,21 +766,274 @@ func (s *Store[T]) Add(l layer.Layer, opts ...AddOption) error {
 		noWatch:   options.noWatch,
 		sensitive: options.sensitive,
 		optional:  options.optional,
+		schema:    options.schema,
+	}
+
+	if f, ok := l.(layer.FillLayer); ok {
+		entry.fill = f.Fill()
 	}
 
 	// Populate layer details (Layer interface includes DetailsFiller)
 	l.FillDetails(&entry.details)
 
-	s.layers = append(s.layers, entry)
+	return entry, nil
+}
 
-	// Keep layers sorted by priority (stable sort preserves insertion order for same priority)
+// sortLayersLocked keeps layers sorted by priority.
+// The stable sort preserves insertion order for layers with the same priority.
+// Caller must hold the write lock.
+func (s *Store[T]) sortLayersLocked() {
 	sort.SliceStable(s.layers, func(i, j int) bool {
 		return s.layers[i].priority < s.layers[j].priority
 	})
+}
+
+// layerIndexLocked returns the index of the named layer, or -1 if not found.
+// Caller must hold the lock (read or write).
+func (s *Store[T]) layerIndexLocked(name layer.Name) int {
+	for i, entry := range s.layers {
+		if entry.layer.Name() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// anyLayerLoadedLocked returns true if at least one layer has been loaded.
+// Layer stack changes only re-materialize a store that has been loaded,
+// so that subscribers are not notified before the initial Load.
+// Caller must hold the lock (read or write).
+func (s *Store[T]) anyLayerLoadedLocked() bool {
+	for _, entry := range s.layers {
+		if entry.Loaded() {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveLayer unregisters the named layer from the store.
+// Any pending (unsaved) changes in the layer are discarded.
+//
+// If the store has been loaded, the configuration is re-materialized without
+// the layer and subscribers are notified once. Watchers started by a running
+// Watch for the layer are stopped; failures to stop them are reported through
+// StoreWatchConfig.OnError. If validation rejects the configuration without the
+// layer, the layer is kept and a *ValidationError is returned.
+//
+// Example:
+//
+//	// Switch away from the current tenant
+//	if err := store.RemoveLayer("tenant"); err != nil {
+//	  log.Fatal(err)
+//	}
+func (s *Store[T]) RemoveLayer(name layer.Name) error {
+	s.mu.Lock()
 
+	idx := s.layerIndexLocked(name)
+	if idx < 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("layer %q not found", name)
+	}
+
+	loaded := s.anyLayerLoadedLocked()
+	entry := s.layers[idx]
+	previous := s.layers
+	s.layers = append(s.layers[:idx:idx], s.layers[idx+1:]...)
+
+	var (
+		current     T
+		subscribers []subscriber[T]
+		err         error
+	)
+	if loaded {
+		current, subscribers, err = s.commitLayerStackLocked(context.Background(), previous, name, nil)
+		if errors.As(err, new(*ValidationError)) {
+			s.mu.Unlock()
+			return err
+		}
+	}
+	detached := s.detachLayerWatchersLocked(entry)
+	s.mu.Unlock()
+
+	stopDetachedWatchers(context.Background(), detached)
+
+	if err != nil {
+		return err
+	}
+	for _, sub := range subscribers {
+		sub.fn(current)
+	}
+	return nil
+}
+
+// ReplaceLayer swaps the named layer for a new layer implementation.
+// The replacement may use a different name, as long as it does not collide
+// with another registered layer.
+//
+// Options are applied as in Add. If WithPriority is not given, the replacement
+// keeps the priority of the layer it replaces. Any pending (unsaved) changes in
+// the replaced layer are discarded.
+//
+// If the store has been loaded, the replacement is loaded immediately, the
+// configuration is re-materialized, and subscribers are notified once. If loading
+// the replacement fails, or validation rejects the resulting configuration, the
+// store is left unchanged. Watchers started by a running Watch for the old layer
+// are stopped, and the replacement is watched in their place unless it is marked
+// with WithNoWatch; failures to stop them are reported through
+// StoreWatchConfig.OnError.
+//
+// Unlike Add, RemoveLayer and SetLayerPriority, ReplaceLayer takes a context:
+// it loads the replacement from its source, as Load does.
+//
+// Example:
+//
+//	// Switch to another profile without recreating the store
+//	err := store.ReplaceLayer(ctx, "profile",
+//	    layer.New("profile", fs.New("~/.config/app/profiles/staging.yaml"), yaml.New()),
+//	)
+func (s *Store[T]) ReplaceLayer(ctx context.Context, name layer.Name, l layer.Layer, opts ...AddOption) error {
+	// If the layer implements StoreAwareLayerInitializer, initialize it with this Store
+	if lazy, ok := l.(layer.StoreAwareLayerInitializer); ok {
+		l = lazy.InitWithStore(s)
+	}
+
+	s.mu.RLock()
+	old := s.findLayerLocked(name)
+	var (
+		priority layer.Priority
+		loaded   bool
+	)
+	if old != nil {
+		priority = old.priority
+		loaded = s.anyLayerLoadedLocked()
+	}
+	s.mu.RUnlock()
+
+	if old == nil {
+		return fmt.Errorf("layer %q not found", name)
+	}
+
+	// Load the replacement outside the store lock; the entry is not shared yet.
+	entry, err := newLayerEntry(l, priority, opts)
+	if err != nil {
+		return err
+	}
+	if loaded {
+		if err := s.loadLayerEntry(ctx, entry); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+
+	idx := s.layerIndexLocked(name)
+	if idx < 0 || s.layers[idx] != old {
+		s.mu.Unlock()
+		return fmt.Errorf("layer %q was modified concurrently", name)
+	}
+	if l.Name() != name && s.findLayerLocked(l.Name()) != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("layer %q already exists", l.Name())
+	}
+
+	previous := slices.Clone(s.layers)
+	s.layers[idx] = entry
+	s.sortLayersLocked()
+
+	var (
+		current     T
+		subscribers []subscriber[T]
+	)
+	if loaded {
+		current, subscribers, err = s.commitLayerStackLocked(ctx, previous, l.Name(), nil)
+		if errors.As(err, new(*ValidationError)) {
+			s.mu.Unlock()
+			return err
+		}
+	}
+	detached := s.detachLayerWatchersLocked(old)
+	s.attachLayerWatchersLocked(entry)
+	s.mu.Unlock()
+
+	stopDetachedWatchers(ctx, detached)
+
+	if err != nil {
+		return err
+	}
+	for _, sub := range subscribers {
+		sub.fn(current)
+	}
+	return nil
+}
+
+// SetLayerPriority changes the merge priority of the named layer.
+// Layers are re-sorted so that higher priorities override lower ones.
+// If the store has been loaded, the configuration is re-materialized and
+// subscribers are notified once. If validation rejects the result, the layer
+// keeps its previous priority and a *ValidationError is returned.
+//
+// Example:
+//
+//	// Let the tenant layer override project settings
+//	err := store.SetLayerPriority("tenant", jubako.PriorityProject+5)
+func (s *Store[T]) SetLayerPriority(name layer.Name, priority layer.Priority) error {
+	s.mu.Lock()
+
+	entry := s.findLayerLocked(name)
+	if entry == nil {
+		s.mu.Unlock()
+		return fmt.Errorf("layer %q not found", name)
+	}
+
+	if entry.priority == priority {
+		s.mu.Unlock()
+		return nil
+	}
+
+	// Move the entry to the end first so that it is ordered after existing
+	// layers sharing the same priority, mirroring Add.
+	idx := s.layerIndexLocked(name)
+	previous, previousPriority := s.layers, entry.priority
+	s.layers = append(append(s.layers[:idx:idx], s.layers[idx+1:]...), entry)
+	entry.priority = priority
+	s.sortLayersLocked()
+
+	if !s.anyLayerLoadedLocked() {
+		s.mu.Unlock()
+		return nil
+	}
+
+	current, subscribers, err := s.commitLayerStackLocked(context.Background(), previous, name, func() {
+		entry.priority = previousPriority
+	})
+	s.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	for _, sub := range subscribers {
+		sub.fn(current)
+	}
 	return nil
 }
 
+// commitLayerStackLocked materializes the configuration after a change to the
+// layer stack. If validation rejects the result, the layer stack is restored to
+// previous, undo (if non-nil) reverts changes made to the entries, and the
+// returned *ValidationError records the named layer as the cause.
+// Caller must hold the write lock.
+func (s *Store[T]) commitLayerStackLocked(ctx context.Context, previous []*layerEntry, name layer.Name, undo func()) (T, []subscriber[T], error) {
+	current, subscribers, err := s.commitLocked(ctx, s.snapshotLayersLocked(), []layer.Name{name})
+	if errors.As(err, new(*ValidationError)) {
+		s.layers = previous
+		if undo != nil {
+			undo()
+		}
+	}
+	return current, subscribers, err
+}
+
 // Get returns the current materialized configuration.
 // The returned value is a snapshot at the time of the call.
 // For reactive updates, use Subscribe() instead.
@@ -699,36 +1112,47 @@ func (s *Store[T]) loadLocked(ctx context.Context) (T, []subscriber[T], error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	snapshot := s.snapshotLayersLocked()
+
 	// Load each layer's data
 	for _, entry := range s.layers {
-		// Load data through the layer interface
-		data, err := entry.layer.Load(ctx)
-		if err != nil {
-			// For optional layers, treat source.ErrNotExist as empty data
-			if entry.optional && errors.Is(err, source.ErrNotExist) {
-				entry.data = make(map[string]any)
-				entry.loadedData = make(map[string]any)
-				entry.changeset = nil
-				entry.dependencies = nil
-				entry.projectionDirty = nil
-				s.syncLayerDirty(entry)
-				continue
+		if err := s.loadLayerEntry(ctx, entry); err != nil {
+			// Data rejected by a layer schema leaves every layer as it was
+			if errors.As(err, new(*ValidationError)) {
+				s.restoreLayersLocked(snapshot)
 			}
 			var zero T
-			return zero, nil, fmt.Errorf("failed to load layer %q: %w", entry.layer.Name(), err)
+			return zero, nil, err
 		}
-		// Store the loaded data in the entry
-		entry.data = data
-		entry.loadedData = container.DeepCopyMap(data)
-		// Clear changeset as we have fresh data
-		entry.changeset = nil
-		entry.dependencies = nil
-		entry.projectionDirty = nil
-		s.syncLayerDirty(entry)
 	}
 
 	// Materialize the merged configuration
-	return s.materializeLocked(ctx)
+	return s.commitLocked(ctx, snapshot, s.layerNamesLocked())
+}
+
+// loadLayerEntry loads a single layer and resets its in-memory state.
+// For optional layers, source.ErrNotExist is treated as empty data.
+// Caller must hold the write lock, unless the entry is not yet registered.
+func (s *Store[T]) loadLayerEntry(ctx context.Context, entry *layerEntry) error {
+	// Load data through the layer interface
+	data, err := entry.layer.Load(ctx)
+	if err != nil {
+		if !entry.optional || !errors.Is(err, source.ErrNotExist) {
+			return fmt.Errorf("failed to load layer %q: %w", entry.layer.Name(), err)
+		}
+		data = make(map[string]any)
+	}
+	// Store the loaded data in the entry, migrated to the latest version
+	if err := s.setLoadedDataLocked(entry, data); err != nil {
+		return err
+	}
+	// Clear changeset as we have fresh data
+	entry.changeset = nil
+	entry.dependencies = nil
+	entry.projectionDirty = nil
+	entry.persistMigration = false
+	s.syncLayerDirty(entry)
+	return nil
 }
 
 // Reload reloads all layers and re-materializes the configuration.
@@ -759,6 +1183,8 @@ func (s *Store[T]) reloadLocked(ctx context.Context) (T, []subscriber[T], error)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	snapshot := s.snapshotLayersLocked()
+
 	// Save existing changesets before reloading
 	savedChangesets := make(map[layer.Name][]document.JSONPatch)
 	for _, entry := range s.layers {
@@ -772,17 +1198,21 @@ func (s *Store[T]) reloadLocked(ctx context.Context) (T, []subscriber[T], error)
 		data, err := entry.layer.Load(ctx)
 		if err != nil {
 			// For optional layers, treat source.ErrNotExist as empty data
-			if entry.optional && errors.Is(err, source.ErrNotExist) {
-				entry.data = make(map[string]any)
-				entry.loadedData = make(map[string]any)
-				continue
+			if !entry.optional || !errors.Is(err, source.ErrNotExist) {
+				var zero T
+				return zero, nil, fmt.Errorf("failed to load layer %q: %w", entry.layer.Name(), err)
+			}
+			data = make(map[string]any)
+		}
+		// Store the loaded data in the entry, migrated to the latest version
+		if err := s.setLoadedDataLocked(entry, data); err != nil {
+			// Data rejected by a layer schema leaves every layer as it was
+			if errors.As(err, new(*ValidationError)) {
+				s.restoreLayersLocked(snapshot)
 			}
 			var zero T
-			return zero, nil, fmt.Errorf("failed to load layer %q: %w", entry.layer.Name(), err)
+			return zero, nil, err
 		}
-		// Store the loaded data in the entry
-		entry.data = data
-		entry.loadedData = container.DeepCopyMap(data)
 	}
 
 	// Reapply saved changesets
@@ -812,7 +1242,7 @@ func (s *Store[T]) reloadLocked(ctx context.Context) (T, []subscriber[T], error)
 	}
 
 	// Materialize the merged configuration
-	return s.materializeLocked(ctx)
+	return s.commitLocked(ctx, snapshot, s.layerNamesLocked())
 }
 
 // SetTo sets a value in a specific layer at the given JSONPointer path.
@@ -845,6 +1275,7 @@ func (s *Store[T]) SetTo(layerName layer.Name, path string, value any) error {
 //   - Struct(path, v): Expand a struct into multiple path-value pairs
 //   - Map(path, m): Expand a map into multiple path-value pairs
 //   - Path(prefix, opts...): Group options under a common path prefix
+//   - Unset(path): Write a tombstone that hides values from lower layers
 //
 // Behavior options:
 //   - SkipZeroValues(): Skip entries with zero values
@@ -902,27 +1333,38 @@ func (s *Store[T]) setLocked(layerName layer.Name, opts []SetOption) (T, []subsc
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	entry := s.findLayerLocked(layerName)
-	if entry == nil {
+	entry, err := s.writableLayerLocked(layerName)
+	if err != nil {
 		var zero T
-		return zero, nil, fmt.Errorf("layer %q not found", layerName)
+		return zero, nil, err
 	}
 
-	if !entry.Writable() {
-		var zero T
-		if entry.readOnly {
-			return zero, nil, fmt.Errorf("layer %q is marked as read-only", layerName)
-		}
-		return zero, nil, fmt.Errorf("layer %q does not support saving (source is not writable)", layerName)
-	}
+	snapshot := s.snapshotLayersLocked()
 
-	if entry.data == nil {
+	if err := s.applySetLocked(entry, layerName, cfg); err != nil {
 		var zero T
-		return zero, nil, fmt.Errorf("layer %q has not been loaded", layerName)
+		return zero, nil, err
 	}
 
-	// Apply patches
+	s.syncLayerDirty(entry)
+
+	// Re-materialize to update the resolved config
+	return s.commitLocked(context.Background(), snapshot, []layer.Name{layerName})
+}
+
+// applySetLocked applies the patches of cfg to the layer's data and records them
+// in its changeset. The configuration is not materialized.
+// Caller must hold the write lock.
+func (s *Store[T]) applySetLocked(entry *layerEntry, layerName layer.Name, cfg *setConfig) error {
 	for _, pv := range cfg.patches {
+		// Handle tombstones written by Unset
+		if document.IsTombstone(pv.value) {
+			if err := s.setTombstoneLocked(entry, pv.path); err != nil {
+				return err
+			}
+			continue
+		}
+
 		// Handle SkipZeroValues
 		if cfg.skipZeroValues && isZeroValue(pv.value) {
 			continue
@@ -942,19 +1384,30 @@ func (s *Store[T]) setLocked(layerName layer.Name, opts []SetOption) (T, []subsc
 
 		// Validate sensitivity
 		if err := validateSensitivity(s.schema.Trie, pv.path, entry.sensitive); err != nil {
-			var zero T
-			return zero, nil, fmt.Errorf("%w: path %s, layer %s", err, pv.path, layerName)
+			return fmt.Errorf("%w: path %s, layer %s", err, pv.path, layerName)
 		}
 
 		// Apply type conversion if needed
 		value := pv.value
-		if m := s.schema.Trie.Lookup(pv.path); m != nil && m.FieldType != nil && value != nil {
+		if m := s.schema.Trie.Lookup(pv.path); m != nil && s.converters.handles(m.FieldType) && value != nil {
+			// Values of types with a Converter are checked, and written to the
+			// layer as text; materialize converts them again.
+			if _, err := s.converters.convert(pv.path, value, m.FieldType); err != nil {
+				var convErr *ConversionError
+				if errors.As(err, &convErr) {
+					convErr.Layer = layerInfoAt(entry, pv.path)
+					convErr.sensitive = m.Sensitive == sensitiveExplicit
+				}
+				return err
+			}
+			value = converterDocumentValue(value)
+		} else if m != nil && m.FieldType != nil && m.Union == "" && value != nil {
+			// Union values keep their generic form until a variant is decoded
 			valueType := reflect.TypeOf(value)
 			if valueType != m.FieldType {
 				converted, err := s.valueConverter(pv.path, value, m.FieldType)
 				if err != nil {
-					var zero T
-					return zero, nil, fmt.Errorf("failed to convert value at path %q: %w", pv.path, err)
+					return fmt.Errorf("failed to convert value at path %q: %w", pv.path, err)
 				}
 				value = converted
 			}
@@ -963,8 +1416,7 @@ func (s *Store[T]) setLocked(layerName layer.Name, opts []SetOption) (T, []subsc
 		// Set the value
 		result := jsonptr.SetPath(entry.data, pv.path, value)
 		if !result.Success {
-			var zero T
-			return zero, nil, fmt.Errorf("failed to set value at path %q", pv.path)
+			return fmt.Errorf("failed to set value at path %q", pv.path)
 		}
 
 		// Determine the patch operation
@@ -982,11 +1434,59 @@ func (s *Store[T]) setLocked(layerName layer.Name, opts []SetOption) (T, []subsc
 			Value: value,
 		})
 	}
+	return nil
+}
 
-	s.syncLayerDirty(entry)
+// writableLayerLocked returns the loaded, writable layer with the given name.
+// Caller must hold the lock.
+func (s *Store[T]) writableLayerLocked(layerName layer.Name) (*layerEntry, error) {
+	entry := s.findLayerLocked(layerName)
+	if entry == nil {
+		return nil, fmt.Errorf("layer %q not found", layerName)
+	}
 
-	// Re-materialize to update the resolved config
-	return s.materializeLocked(context.Background())
+	if !entry.Writable() {
+		if entry.readOnly {
+			return nil, fmt.Errorf("layer %q is marked as read-only", layerName)
+		}
+		return nil, fmt.Errorf("layer %q does not support saving (source is not writable)", layerName)
+	}
+
+	if entry.data == nil {
+		return nil, fmt.Errorf("layer %q has not been loaded", layerName)
+	}
+	return entry, nil
+}
+
+// setTombstoneLocked writes a tombstone at path in the layer and records the change.
+// The tombstone carries the store's unset sentinel so that formats without tag
+// support persist a value the store recognizes on the next load; without a
+// sentinel, layers with such formats are rejected.
+// Caller must hold the write lock.
+func (s *Store[T]) setTombstoneLocked(entry *layerEntry, path string) error {
+	if s.unsetSentinel == "" {
+		if provider, ok := entry.layer.(layer.DocumentProvider); ok {
+			if format := provider.Document().Format(); format != document.FormatYAML {
+				return fmt.Errorf("cannot unset %s in layer %q: %s documents need an unset sentinel (see WithUnsetSentinel)", path, entry.layer.Name(), format)
+			}
+		}
+	}
+	value := document.Tombstone{Sentinel: s.unsetSentinel}
+	result := jsonptr.SetPath(entry.data, path, value)
+	if !result.Success {
+		return fmt.Errorf("failed to set value at path %q", path)
+	}
+
+	op := document.PatchOpReplace
+	if result.Created {
+		op = document.PatchOpAdd
+	}
+	entry.changeset = append(entry.changeset, document.JSONPatch{
+		Op:    op,
+		Path:  path,
+		Value: value,
+	})
+	return nil
 }
 
 // DeleteFrom removes values at the specified JSON Pointer paths from a specific layer.
@@ -1030,26 +1530,33 @@ func (s *Store[T]) deleteFromLocked(layerName layer.Name, paths []string) (T, []
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	entry := s.findLayerLocked(layerName)
-	if entry == nil {
+	entry, err := s.writableLayerLocked(layerName)
+	if err != nil {
 		var zero T
-		return zero, nil, fmt.Errorf("layer %q not found", layerName)
+		return zero, nil, err
 	}
 
-	if !entry.Writable() {
-		var zero T
-		if entry.readOnly {
-			return zero, nil, fmt.Errorf("layer %q is marked as read-only", layerName)
-		}
-		return zero, nil, fmt.Errorf("layer %q does not support saving (source is not writable)", layerName)
-	}
+	snapshot := s.snapshotLayersLocked()
 
-	if entry.data == nil {
-		var zero T
-		return zero, nil, fmt.Errorf("layer %q has not been loaded", layerName)
+	// Only mark dirty and re-materialize if something was actually deleted
+	if !applyDeleteLocked(entry, paths) {
+		// Return current state without re-materializing
+		current := s.resolved.Get()
+		subscribers := append([]subscriber[T](nil), s.subscribers...)
+		return current, subscribers, nil
 	}
 
-	// Delete each path
+	// Mark the layer as dirty
+	s.syncLayerDirty(entry)
+
+	// Re-materialize to update the resolved config
+	return s.commitLocked(context.Background(), snapshot, []layer.Name{layerName})
+}
+
+// applyDeleteLocked removes paths from the layer's data and records the
+// removals in its changeset. It reports whether anything was deleted.
+// Caller must hold the write lock.
+func applyDeleteLocked(entry *layerEntry, paths []string) bool {
 	anyDeleted := false
 	for _, path := range paths {
 		if path == "" {
@@ -1067,25 +1574,14 @@ func (s *Store[T]) deleteFromLocked(layerName layer.Name, paths []string) (T, []
 			anyDeleted = true
 		}
 	}
-
-	// Only mark dirty and re-materialize if something was actually deleted
-	if !anyDeleted {
-		// Return current state without re-materializing
-		current := s.resolved.Get()
-		subscribers := append([]subscriber[T](nil), s.subscribers...)
-		return current, subscribers, nil
-	}
-
-	// Mark the layer as dirty
-	s.syncLayerDirty(entry)
-
-	// Re-materialize to update the resolved config
-	return s.materializeLocked(context.Background())
+	return anyDeleted
 }
 
 // Save persists all modified (dirty) layers to their sources.
 // Only layers that have been modified via SetTo() or DeleteFrom() and support saving will be persisted.
 // After successful save, the dirty flag is cleared for each saved layer.
+// Layers are saved independently; a failure does not undo the layers already
+// saved. Use Tx to change several layers as a unit.
 //
 // Example:
 //
@@ -1146,6 +1642,13 @@ func (s *Store[T]) SaveLayer(ctx context.Context, layerName layer.Name) error {
 // saveLayerLocked saves a single layer entry.
 // Caller must hold the lock.
 func (s *Store[T]) saveLayerLocked(ctx context.Context, entry *layerEntry) error {
+	return s.saveLayerWithLocked(ctx, entry, entry.layer.Save)
+}
+
+// saveLayerWithLocked saves a single layer entry, writing the changeset of
+// ordinary layers with save.
+// Caller must hold the lock.
+func (s *Store[T]) saveLayerWithLocked(ctx context.Context, entry *layerEntry, save func(context.Context, document.JSONPatchSet) error) error {
 	if entry.data == nil {
 		return fmt.Errorf("layer %q has not been loaded", entry.layer.Name())
 	}
@@ -1162,7 +1665,14 @@ func (s *Store[T]) saveLayerLocked(ctx context.Context, entry *layerEntry) error
 		return fmt.Errorf("layer %q does not support saving", entry.layer.Name())
 	}
 
+	if entry.original != nil && !entry.persistMigration {
+		return fmt.Errorf("layer %q was migrated from version %d; call PersistMigrations to save it", entry.layer.Name(), entry.version)
+	}
+
 	changeset := normalizeSavePatches(entry.changeset, entry.projectionDirty, entry.data)
+	if patches := migrationPatches(entry); len(patches) > 0 {
+		changeset = append(patches, changeset...)
+	}
 
 	if saver, ok := entry.layer.(layer.ContextualSaveLayer); ok {
 		saveCtx := s.newLayerSaveContext(entry)
@@ -1176,13 +1686,18 @@ func (s *Store[T]) saveLayerLocked(ctx context.Context, entry *layerEntry) error
 		if changeset.IsEmpty() {
 			return nil
 		}
-		if err := entry.layer.Save(ctx, changeset); err != nil {
+		if err := save(ctx, changeset); err != nil {
 			return fmt.Errorf("failed to save layer %q: %w", entry.layer.Name(), err)
 		}
 	}
 
 	// Clear dirty flag and changeset on successful save
 	entry.loadedData = container.DeepCopyMap(entry.data)
+	if entry.original != nil && s.migrations != nil {
+		entry.version, entry.hasVersion = s.migrations.versionOf(entry.data)
+	}
+	entry.original = nil
+	entry.persistMigration = false
 	entry.changeset = nil
 	entry.dependencies = nil
 	entry.projectionDirty = nil
@@ -1270,6 +1785,9 @@ func (s *Store[T]) applyMaskLocked(rv ResolvedValue, path string) ResolvedValue
 
 	// Apply masking
 	rv.Value = s.sensitiveMask(rv.Value)
+	if rv.Interpolated {
+		rv.Raw = s.sensitiveMask(rv.Raw)
+	}
 	rv.Masked = true
 	return rv
 }
@@ -1287,23 +1805,45 @@ func isEmptyValue(v any) bool {
 	return false
 }
 
-// getAtLocked returns the resolved value at path. Caller must hold the lock.
+// getAtLocked returns the resolved value at path, after interpolation.
+// Caller must hold the lock.
 func (s *Store[T]) getAtLocked(path string) ResolvedValue {
-	// First, check if it's a known leaf path
-	if entry := s.origins.getLeaf(path); entry != nil {
-		return newResolvedValue(entry, path)
+	return s.origins.interpolate(s.resolveAtLocked(path), path)
+}
+
+// resolveAtLocked returns the value at path as provided by the layers.
+// Caller must hold the lock.
+func (s *Store[T]) resolveAtLocked(path string) ResolvedValue {
+	leaf := s.origins.getLeaf(path)
+	containerEntry := s.origins.getContainer(path)
+
+	// A path can be a leaf in one layer and a container in another;
+	// the higher priority layer decides which one takes effect.
+	if leaf != nil && (containerEntry == nil || s.entryIndexLocked(leaf) > s.entryIndexLocked(containerEntry)) {
+		return s.origins.resolve(leaf, path)
 	}
 
 	// Check if it's a known container path
-	if entry := s.origins.getContainer(path); entry != nil {
+	if containerEntry != nil {
 		// Container path - need to compute merged value
-		return s.resolveContainerLocked(path, entry)
+		return s.resolveContainerLocked(path, containerEntry)
 	}
 
 	// Path not found in either leafs or containers
 	return ResolvedValue{}
 }
 
+// entryIndexLocked returns the position of entry in the priority-sorted layer list,
+// or -1 if the entry is not registered. Caller must hold the lock.
+func (s *Store[T]) entryIndexLocked(entry *layerEntry) int {
+	for i, e := range s.layers {
+		if e == entry {
+			return i
+		}
+	}
+	return -1
+}
+
 // resolveContainerLocked computes the merged value for a container path.
 // The origin is the highest priority layer that has a value at that path.
 // Caller must hold the lock.
@@ -1314,35 +1854,69 @@ func (s *Store[T]) resolveContainerLocked(path string, topEntry *layerEntry) Res
 		return ResolvedValue{}
 	}
 
-	// Merge values from all layers (lowest priority first)
+	// Containers from layers below a leaf value (or tombstone) at this path
+	// were replaced by it and do not contribute.
+	floor := -1
+	if leaf := s.origins.getLeaf(path); leaf != nil {
+		floor = s.entryIndexLocked(leaf)
+	}
+
+	// Merge values from all layers (lowest priority first),
+	// honouring slice merge strategies and tombstones below this path.
+	merger := layerMerger{trie: s.schema.Trie, sentinel: s.unsetSentinel, deleted: make(map[string]struct{})}
 	var merged any
+	var deletedBy *layerEntry
+	var fills []*layerEntry
 	for _, entry := range entries {
-		if entry.data == nil {
+		if entry.fill {
+			fills = append(fills, entry)
+			continue
+		}
+		if floor >= 0 && s.entryIndexLocked(entry) < floor {
 			continue
 		}
 
-		value, ok := jsonptr.GetPath(entry.data, path)
-		if !ok {
+		rv := s.origins.resolve(entry, path)
+		if rv.Deleted {
+			// A tombstone at or above this path discards everything below it
+			merged = nil
+			deletedBy = entry
+			continue
+		}
+		if !rv.Exists {
 			continue
 		}
 
-		if merged == nil {
-			merged = container.DeepCopyValue(value)
-		} else {
-			// Merge this layer's value into merged
-			merged = mergeValues(merged, value)
+		merged = merger.mergeValue(merged, merged != nil, rv.Value, path, path, nil)
+		deletedBy = nil
+	}
+
+	// Fill layers supply missing values last, highest priority first.
+	if deletedBy == nil {
+		for i := len(fills) - 1; i >= 0; i-- {
+			if rv := s.origins.resolve(fills[i], path); rv.Exists {
+				merged = merger.fillValue(merged, merged != nil, rv.Value, path, nil)
+			}
 		}
 	}
 
 	if merged == nil {
+		if deletedBy != nil {
+			return ResolvedValue{Layer: layerInfoAt(deletedBy, s.origins.sourcePath(path, deletedBy)), Deleted: true}
+		}
 		return ResolvedValue{}
 	}
 
-	return ResolvedValue{
+	sourcePath := s.origins.sourcePath(path, topEntry)
+	rv := ResolvedValue{
 		Value:  merged,
 		Exists: true,
-		Layer:  topEntry,
+		Layer:  layerInfoAt(topEntry, sourcePath),
+	}
+	if tracker, ok := topEntry.layer.(layer.ReferenceTracker); ok {
+		rv.Reference = tracker.ReferenceOf(sourcePath)
 	}
+	return rv
 }
 
 // GetAllAt returns all values at the given JSON Pointer path from all layers
@@ -1352,6 +1926,12 @@ func (s *Store[T]) resolveContainerLocked(path string, topEntry *layerEntry) Res
 // For container paths (maps/slices), each layer's raw value is returned (not merged).
 // This allows callers to see what each layer contributes.
 //
+// Layers that remove the path with a tombstone are included with Deleted set.
+//
+// For elements of slices with a merge strategy (merge:append, merge:union,
+// merge:key=NAME), path refers to the merged index and each layer's value is
+// read from the element it contributed at that index.
+//
 // Example:
 //
 //	values := store.GetAllAt("/server/port")
@@ -1365,29 +1945,28 @@ func (s *Store[T]) GetAllAt(path string) ResolvedValues {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Check leaf paths first
-	if entries := s.origins.getAllLeaf(path); len(entries) > 0 {
-		results := make(ResolvedValues, 0, len(entries))
-		for _, entry := range entries {
-			if rv := newResolvedValue(entry, path); rv.Exists {
-				results = append(results, rv)
-			}
-		}
-		return results
+	leafs := s.origins.getAllLeaf(path)
+	containers := s.origins.getAllContainer(path)
+	if len(leafs) == 0 && len(containers) == 0 {
+		return nil
 	}
 
-	// Check container paths
-	if entries := s.origins.getAllContainer(path); len(entries) > 0 {
-		results := make(ResolvedValues, 0, len(entries))
-		for _, entry := range entries {
-			if rv := newResolvedValue(entry, path); rv.Exists {
-				results = append(results, rv)
-			}
-		}
-		return results
+	// A path may be a leaf in some layers and a container (or tombstone) in others;
+	// report every layer in priority order.
+	entries := append(append(make([]*layerEntry, 0, len(leafs)+len(containers)), leafs...), containers...)
+	if len(leafs) > 0 && len(containers) > 0 {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return s.entryIndexLocked(entries[i]) < s.entryIndexLocked(entries[j])
+		})
 	}
 
-	return nil
+	results := make(ResolvedValues, 0, len(entries))
+	for _, entry := range entries {
+		if rv := s.origins.resolve(entry, path); rv.Exists || rv.Deleted {
+			results = append(results, rv)
+		}
+	}
+	return results
 }
 
 // Walk traverses all paths in the resolved configuration, calling fn for each path.
@@ -1433,9 +2012,14 @@ func (s *Store[T]) Walk(fn func(ctx WalkContext) bool) {
 
 	for _, path := range paths {
 		orig := s.origins.leafs[path]
+		// Skip paths removed by a tombstone
+		if s.origins.resolve(orig.get(), path).Deleted {
+			continue
+		}
 		ctx := WalkContext{
 			Path:      path,
 			origin:    orig,
+			origins:   s.origins,
 			maskFunc:  s.sensitiveMask,
 			sensitive: s.schema.Trie.IsSensitive(path),
 		}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -712,6 +713,227 @@ func TestStore_Load(t *testing.T) {
 	})
 }
 
+func TestStore_RemoveLayer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("remove rematerializes and notifies once", func(t *testing.T) {
+		store := New[testConfig]()
+		if err := store.Add(mapdata.New("defaults", map[string]any{"host": "localhost", "port": 8080})); err != nil {
+			t.Fatalf("Add(defaults) error = %v", err)
+		}
+		if err := store.Add(mapdata.New("tenant", map[string]any{"port": 9000})); err != nil {
+			t.Fatalf("Add(tenant) error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		var calls atomic.Int32
+		store.Subscribe(func(testConfig) { calls.Add(1) })
+
+		if err := store.RemoveLayer("tenant"); err != nil {
+			t.Fatalf("RemoveLayer() error = %v", err)
+		}
+
+		if got := store.Get().Port; got != 8080 {
+			t.Errorf("Port = %d, want 8080", got)
+		}
+		if got := calls.Load(); got != 1 {
+			t.Errorf("subscriber calls = %d, want 1", got)
+		}
+		if rv := store.GetAt("/port"); rv.Layer == nil || rv.Layer.Name() != "defaults" {
+			t.Errorf("GetAt(/port).Layer = %v, want defaults", rv.Layer)
+		}
+		if info := store.GetLayerInfo("tenant"); info != nil {
+			t.Errorf("GetLayerInfo(tenant) = %v, want nil", info)
+		}
+	})
+
+	t.Run("remove before load does not notify", func(t *testing.T) {
+		store := New[testConfig]()
+		if err := store.Add(mapdata.New("tenant", map[string]any{"port": 9000})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+
+		var calls atomic.Int32
+		store.Subscribe(func(testConfig) { calls.Add(1) })
+
+		if err := store.RemoveLayer("tenant"); err != nil {
+			t.Fatalf("RemoveLayer() error = %v", err)
+		}
+		if got := calls.Load(); got != 0 {
+			t.Errorf("subscriber calls = %d, want 0", got)
+		}
+		if got := len(store.ListLayers()); got != 0 {
+			t.Errorf("len(ListLayers()) = %d, want 0", got)
+		}
+	})
+
+	t.Run("unknown layer", func(t *testing.T) {
+		store := New[testConfig]()
+		if err := store.RemoveLayer("missing"); err == nil {
+			t.Error("RemoveLayer() expected error for unknown layer")
+		}
+	})
+}
+
+func TestStore_ReplaceLayer(t *testing.T) {
+	ctx := context.Background()
+
+	newStore := func(t *testing.T) *Store[testConfig] {
+		t.Helper()
+		store := New[testConfig]()
+		if err := store.Add(mapdata.New("defaults", map[string]any{"host": "localhost", "port": 8080})); err != nil {
+			t.Fatalf("Add(defaults) error = %v", err)
+		}
+		if err := store.Add(mapdata.New("profile", map[string]any{"host": "dev.example.com"})); err != nil {
+			t.Fatalf("Add(profile) error = %v", err)
+		}
+		if err := store.Add(mapdata.New("flags", map[string]any{})); err != nil {
+			t.Fatalf("Add(flags) error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		return store
+	}
+
+	t.Run("replace keeps priority and loads replacement", func(t *testing.T) {
+		store := newStore(t)
+
+		var calls atomic.Int32
+		store.Subscribe(func(testConfig) { calls.Add(1) })
+
+		err := store.ReplaceLayer(ctx, "profile", mapdata.New("profile", map[string]any{"host": "stg.example.com", "port": 9000}))
+		if err != nil {
+			t.Fatalf("ReplaceLayer() error = %v", err)
+		}
+
+		cfg := store.Get()
+		if cfg.Host != "stg.example.com" || cfg.Port != 9000 {
+			t.Errorf("Get() = %+v, want stg.example.com:9000", cfg)
+		}
+		if got := calls.Load(); got != 1 {
+			t.Errorf("subscriber calls = %d, want 1", got)
+		}
+		if info := store.GetLayerInfo("profile"); info == nil || info.Priority() != 10 {
+			t.Errorf("GetLayerInfo(profile) = %v, want priority 10", info)
+		}
+		if rv := store.GetAt("/port"); rv.Layer == nil || rv.Layer.Name() != "profile" {
+			t.Errorf("GetAt(/port).Layer = %v, want profile", rv.Layer)
+		}
+	})
+
+	t.Run("replace with new name and priority", func(t *testing.T) {
+		store := newStore(t)
+
+		err := store.ReplaceLayer(ctx, "profile", mapdata.New("staging", map[string]any{"host": "stg.example.com"}), WithPriority(100))
+		if err != nil {
+			t.Fatalf("ReplaceLayer() error = %v", err)
+		}
+
+		layers := store.ListLayers()
+		want := []layer.Name{"defaults", "flags", "staging"}
+		if len(layers) != len(want) {
+			t.Fatalf("len(ListLayers()) = %d, want %d", len(layers), len(want))
+		}
+		for i, info := range layers {
+			if info.Name() != want[i] {
+				t.Errorf("layers[%d] = %q, want %q", i, info.Name(), want[i])
+			}
+		}
+	})
+
+	t.Run("replace with conflicting name", func(t *testing.T) {
+		store := newStore(t)
+
+		err := store.ReplaceLayer(ctx, "profile", mapdata.New("defaults", map[string]any{}))
+		if err == nil {
+			t.Fatal("ReplaceLayer() expected error for duplicate name")
+		}
+		if got := store.Get().Host; got != "dev.example.com" {
+			t.Errorf("Host = %q, want dev.example.com (unchanged)", got)
+		}
+	})
+
+	t.Run("load failure leaves store unchanged", func(t *testing.T) {
+		store := newStore(t)
+
+		err := store.ReplaceLayer(ctx, "profile", &notExistLayer{name: "profile"})
+		if err == nil {
+			t.Fatal("ReplaceLayer() expected load error")
+		}
+		if got := store.Get().Host; got != "dev.example.com" {
+			t.Errorf("Host = %q, want dev.example.com (unchanged)", got)
+		}
+	})
+
+	t.Run("optional replacement may be missing", func(t *testing.T) {
+		store := newStore(t)
+
+		err := store.ReplaceLayer(ctx, "profile", &notExistLayer{name: "profile"}, WithOptional())
+		if err != nil {
+			t.Fatalf("ReplaceLayer() error = %v", err)
+		}
+		if got := store.Get().Host; got != "localhost" {
+			t.Errorf("Host = %q, want localhost", got)
+		}
+	})
+
+	t.Run("unknown layer", func(t *testing.T) {
+		store := newStore(t)
+		if err := store.ReplaceLayer(ctx, "missing", mapdata.New("missing", nil)); err == nil {
+			t.Error("ReplaceLayer() expected error for unknown layer")
+		}
+	})
+}
+
+func TestStore_SetLayerPriority(t *testing.T) {
+	ctx := context.Background()
+
+	store := New[testConfig]()
+	if err := store.Add(mapdata.New("tenant", map[string]any{"port": 9000})); err != nil {
+		t.Fatalf("Add(tenant) error = %v", err)
+	}
+	if err := store.Add(mapdata.New("user", map[string]any{"port": 7000})); err != nil {
+		t.Fatalf("Add(user) error = %v", err)
+	}
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := store.Get().Port; got != 7000 {
+		t.Fatalf("Port = %d, want 7000", got)
+	}
+
+	var calls atomic.Int32
+	store.Subscribe(func(testConfig) { calls.Add(1) })
+
+	if err := store.SetLayerPriority("tenant", 50); err != nil {
+		t.Fatalf("SetLayerPriority() error = %v", err)
+	}
+	if got := store.Get().Port; got != 9000 {
+		t.Errorf("Port = %d, want 9000", got)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("subscriber calls = %d, want 1", got)
+	}
+	if values := store.GetAllAt("/port"); values.Effective().Layer.Name() != "tenant" {
+		t.Errorf("GetAllAt(/port).Effective() = %v, want tenant", values.Effective().Layer.Name())
+	}
+
+	// Same priority is a no-op
+	if err := store.SetLayerPriority("tenant", 50); err != nil {
+		t.Fatalf("SetLayerPriority() error = %v", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("subscriber calls = %d, want 1 after no-op", got)
+	}
+
+	if err := store.SetLayerPriority("missing", 1); err == nil {
+		t.Error("SetLayerPriority() expected error for unknown layer")
+	}
+}
+
 func TestStore_SetTo(t *testing.T) {
 	ctx := context.Background()
 
@@ -1133,8 +1355,8 @@ func TestStore_ListLayers(t *testing.T) {
 
 type noSaveLayer struct{ name layer.Name }
 
-func (l *noSaveLayer) Name() layer.Name                    { return l.name }
-func (l *noSaveLayer) FillDetails(d *types.Details)        {}
+func (l *noSaveLayer) Name() layer.Name             { return l.name }
+func (l *noSaveLayer) FillDetails(d *types.Details) {}
 func (l *noSaveLayer) Load(ctx context.Context) (map[string]any, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
@@ -1316,6 +1538,46 @@ func TestStore_Load_DecodeError(t *testing.T) {
 	}
 }
 
+func TestStore_WithReflectDecoder(t *testing.T) {
+	ctx := context.Background()
+	type reflectConfig struct {
+		Server struct {
+			Host string `json:"host"`
+			Port int    `json:"port"`
+		} `json:"server"`
+		ID      int64             `json:"id"`
+		Timeout time.Duration     `json:"timeout" jubako:"/settings/timeout"`
+		Labels  map[string]string `json:"labels"`
+	}
+
+	store := New[reflectConfig](WithReflectDecoder())
+	if err := store.Add(mapdata.New("user", map[string]any{
+		"server":   map[string]any{"host": "h", "port": 8080},
+		"id":       int64(1<<53 + 1),
+		"settings": map[string]any{"timeout": int64(time.Second)},
+		"labels":   map[string]any{"env": "prod"},
+	})); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	got := store.Get()
+	if got.Server.Host != "h" || got.Server.Port != 8080 {
+		t.Errorf("Server = %+v", got.Server)
+	}
+	if got.ID != 1<<53+1 {
+		t.Errorf("ID = %d, want %d", got.ID, int64(1<<53+1))
+	}
+	if got.Timeout != time.Second {
+		t.Errorf("Timeout = %v, want 1s", got.Timeout)
+	}
+	if got.Labels["env"] != "prod" {
+		t.Errorf("Labels = %v", got.Labels)
+	}
+}
+
 func TestStore_SaveLayer_ErrorPaths(t *testing.T) {
 	ctx := context.Background()
 
@@ -1927,6 +2189,121 @@ func TestParseJubakoTag_CustomDelimiter(t *testing.T) {
 	}
 }
 
+func TestParseJubakoTag_MergeDirective(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantPath  string
+		wantMerge tag.MergeStrategy
+		wantKey   string
+	}{
+		{"merge:append", "", tag.MergeAppend, ""},
+		{"merge:union", "", tag.MergeUnion, ""},
+		{"merge:replace", "", tag.MergeReplace, ""},
+		{"merge:key=name", "", tag.MergeByKey, "name"},
+		{"/servers,merge:key=id", "/servers", tag.MergeByKey, "id"},
+		{"merge:append,sensitive", "", tag.MergeAppend, ""},
+	}
+	for _, tt := range tests {
+		info := tag.ParseJubakoTag(tt.in, DefaultTagDelimiter)
+		if info.Path != tt.wantPath || info.Merge != tt.wantMerge || info.MergeKey != tt.wantKey || info.Err != nil {
+			t.Fatalf("tag.ParseJubakoTag(%q) = {Path:%q, Merge:%v, MergeKey:%q, Err:%v}, want {Path:%q, Merge:%v, MergeKey:%q}",
+				tt.in, info.Path, info.Merge, info.MergeKey, info.Err, tt.wantPath, tt.wantMerge, tt.wantKey)
+		}
+	}
+
+	// Invalid strategies are reported and leave replace
+	invalid := map[string]string{
+		"merge:key=":   "invalid merge: directive: key= needs a key name",
+		"merge:apend":  `invalid merge: directive: unknown strategy "apend"`,
+		"merge:UNION":  `invalid merge: directive: unknown strategy "UNION"`,
+		"/a,merge:key": `invalid merge: directive: unknown strategy "key"`,
+	}
+	for in, want := range invalid {
+		info := tag.ParseJubakoTag(in, DefaultTagDelimiter)
+		if info.Merge != tag.MergeReplace || info.Err == nil || info.Err.Error() != want {
+			t.Errorf("tag.ParseJubakoTag(%q) = {Merge:%v, Err:%v}, want replace and %q", in, info.Merge, info.Err, want)
+		}
+	}
+}
+
+func TestParseJubakoTag_Constraints(t *testing.T) {
+	info := tag.ParseJubakoTag("required,min=1,max=65535", DefaultTagDelimiter)
+	if info.Path != "" || !info.Constraints.Required {
+		t.Fatalf("Path = %q, Required = %v, want empty path and required", info.Path, info.Constraints.Required)
+	}
+	if info.Constraints.Min == nil || *info.Constraints.Min != 1 {
+		t.Errorf("Min = %v, want 1", info.Constraints.Min)
+	}
+	if info.Constraints.Max == nil || *info.Constraints.Max != 65535 {
+		t.Errorf("Max = %v, want 65535", info.Constraints.Max)
+	}
+
+	info = tag.ParseJubakoTag("/log/level,enum=debug|info|warn", DefaultTagDelimiter)
+	if info.Path != "/log/level" || !reflect.DeepEqual(info.Constraints.Enum, []string{"debug", "info", "warn"}) {
+		t.Errorf("Path = %q, Enum = %v", info.Path, info.Constraints.Enum)
+	}
+
+	info = tag.ParseJubakoTag("pattern=^https://", DefaultTagDelimiter)
+	if info.Constraints.Pattern != "^https://" {
+		t.Errorf("Pattern = %q, want ^https://", info.Constraints.Pattern)
+	}
+
+	// Invalid bounds and patterns are reported
+	info = tag.ParseJubakoTag("min=abc", DefaultTagDelimiter)
+	if info.Constraints.Min != nil || !info.Constraints.IsEmpty() {
+		t.Errorf("Constraints = %+v, want empty", info.Constraints)
+	}
+	if info.Err == nil || info.Err.Error() != `invalid min= directive: "abc" is not a number` {
+		t.Errorf("Err = %v, want invalid min= directive", info.Err)
+	}
+	info = tag.ParseJubakoTag("pattern=[a-", DefaultTagDelimiter)
+	if info.Err == nil || !strings.HasPrefix(info.Err.Error(), "invalid pattern= directive: ") {
+		t.Errorf("Err = %v, want invalid pattern= directive", info.Err)
+	}
+}
+
+func TestParseJubakoTag_Default(t *testing.T) {
+	info := tag.ParseJubakoTag("default=8080,min=1", DefaultTagDelimiter)
+	if !info.HasDefault || info.Default != "8080" {
+		t.Errorf("Default = %q (HasDefault=%v), want 8080", info.Default, info.HasDefault)
+	}
+	if info.Constraints.Min == nil {
+		t.Error("Min should be parsed after default=")
+	}
+
+	// JSON values containing the delimiter are kept intact
+	info = tag.ParseJubakoTag(`default=["a","b"],sensitive`, DefaultTagDelimiter)
+	if info.Default != `["a","b"]` || info.Sensitive != tag.SensitiveExplicit {
+		t.Errorf("Default = %q, Sensitive = %v, want [\"a\",\"b\"] and sensitive", info.Default, info.Sensitive)
+	}
+
+	info = tag.ParseJubakoTag(`/a/b,default={"x":1,"y":2}`, DefaultTagDelimiter)
+	if info.Path != "/a/b" || info.Default != `{"x":1,"y":2}` {
+		t.Errorf("Path = %q, Default = %q", info.Path, info.Default)
+	}
+
+	info = tag.ParseJubakoTag("default=", DefaultTagDelimiter)
+	if !info.HasDefault || info.Default != "" {
+		t.Errorf("Default = %q (HasDefault=%v), want empty default", info.Default, info.HasDefault)
+	}
+}
+
+func TestParseJubakoTag_Alias(t *testing.T) {
+	info := tag.ParseJubakoTag("alias=/server/listen_port|./old_port|legacy/port,deprecated", DefaultTagDelimiter)
+	want := []string{"/server/listen_port", "old_port", "legacy/port"}
+	if !reflect.DeepEqual(info.Aliases, want) || !info.Deprecated {
+		t.Errorf("Aliases = %v, Deprecated = %v, want %v and deprecated", info.Aliases, info.Deprecated, want)
+	}
+	if info.Path != "" {
+		t.Errorf("Path = %q, want empty", info.Path)
+	}
+
+	info = tag.ParseJubakoTag("/server/port,alias=/port", DefaultTagDelimiter)
+	if info.Path != "/server/port" || !reflect.DeepEqual(info.Aliases, []string{"/port"}) || info.Deprecated {
+		t.Errorf("Path = %q, Aliases = %v, Deprecated = %v", info.Path, info.Aliases, info.Deprecated)
+	}
+}
+
 func TestWithTagDelimiter(t *testing.T) {
 	ctx := context.Background()
 
@@ -2271,7 +2648,7 @@ type notExistLayer struct {
 	name layer.Name
 }
 
-func (l *notExistLayer) Name() layer.Name              { return l.name }
+func (l *notExistLayer) Name() layer.Name { return l.name }
 func (l *notExistLayer) Load(_ context.Context) (map[string]any, error) {
 	return nil, source.NewNotExistError("test-path", errors.New("file not found"))
 }
@@ -2370,3 +2747,421 @@ func TestStore_WithOptional_MissingSource(t *testing.T) {
 		}
 	})
 }
+
+type validatedConfig struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+func (c validatedConfig) Validate() error {
+	if c.Port <= 0 {
+		return errors.New("port must be positive")
+	}
+	return nil
+}
+
+func TestStore_WithValidator(t *testing.T) {
+	ctx := context.Background()
+	errHost := errors.New("host is required")
+	requireHost := func(c testConfig) error {
+		if c.Host == "" {
+			return errHost
+		}
+		return nil
+	}
+
+	t.Run("load rejects invalid config", func(t *testing.T) {
+		store := New[testConfig](WithValidator(requireHost))
+		if err := store.Add(mapdata.New("base", map[string]any{"port": 8080})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+
+		err := store.Load(ctx)
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("Load() error = %v, want *ValidationError", err)
+		}
+		if !errors.Is(err, errHost) {
+			t.Errorf("Load() error should wrap validator error, got %v", err)
+		}
+		if !reflect.DeepEqual(verr.Layers, []layer.Name{"base"}) {
+			t.Errorf("Layers = %v, want [base]", verr.Layers)
+		}
+		if got := store.Get(); got != (testConfig{}) {
+			t.Errorf("Get() = %+v, want zero value", got)
+		}
+		if store.GetLayerInfo("base").Loaded() {
+			t.Error("layer should be rolled back to unloaded state")
+		}
+	})
+
+	t.Run("set keeps previous value and rolls back layer", func(t *testing.T) {
+		store := New[testConfig](WithValidator(requireHost))
+		if err := store.Add(mapdata.New("base", map[string]any{"host": "localhost", "port": 8080})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		var notified atomic.Int32
+		store.Subscribe(func(testConfig) { notified.Add(1) })
+
+		err := store.Set("base", String("/host", ""), Int("/port", 9000))
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("Set() error = %v, want *ValidationError", err)
+		}
+		if !reflect.DeepEqual(verr.Layers, []layer.Name{"base"}) {
+			t.Errorf("Layers = %v, want [base]", verr.Layers)
+		}
+		if got := store.Get(); got.Host != "localhost" || got.Port != 8080 {
+			t.Errorf("Get() = %+v, want previous value", got)
+		}
+		if notified.Load() != 0 {
+			t.Error("subscribers should not be notified for rejected updates")
+		}
+		if rv := store.GetAt("/port"); rv.Value != 8080 {
+			t.Errorf("GetAt(/port) = %v, want 8080", rv.Value)
+		}
+		if store.IsDirty() {
+			t.Error("rejected changes should not leave the layer dirty")
+		}
+
+		// A valid change after the rejected one applies cleanly.
+		if err := store.SetTo("base", "/port", 9000); err != nil {
+			t.Fatalf("SetTo() error = %v", err)
+		}
+		if got := store.Get(); got.Host != "localhost" || got.Port != 9000 {
+			t.Errorf("Get() = %+v, want host=localhost port=9000", got)
+		}
+	})
+
+	t.Run("delete from rejected", func(t *testing.T) {
+		store := New[testConfig](WithValidator(requireHost))
+		if err := store.Add(mapdata.New("base", map[string]any{"host": "localhost"})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		err := store.DeleteFrom("base", "/host")
+		if !errors.Is(err, errHost) {
+			t.Fatalf("DeleteFrom() error = %v, want validator error", err)
+		}
+		if rv := store.GetAt("/host"); rv.Value != "localhost" {
+			t.Errorf("GetAt(/host) = %v, want localhost", rv.Value)
+		}
+	})
+
+	t.Run("reload rejected keeps previous value", func(t *testing.T) {
+		base := mapdata.New("base", map[string]any{"host": "localhost"})
+		store := New[testConfig](WithValidator(requireHost))
+		if err := store.Add(base); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		// Change the underlying data behind the store's back.
+		if err := base.Save(ctx, document.JSONPatchSet{document.NewRemovePatch("/host")}); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		err := store.Reload(ctx)
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("Reload() error = %v, want *ValidationError", err)
+		}
+		if got := store.Get(); got.Host != "localhost" {
+			t.Errorf("Get() = %+v, want previous value", got)
+		}
+	})
+
+	t.Run("layer stack changes rejected", func(t *testing.T) {
+		store := New[testConfig](WithValidator(requireHost))
+		if err := store.Add(mapdata.New("defaults", map[string]any{"port": 8080})); err != nil {
+			t.Fatalf("Add(defaults) error = %v", err)
+		}
+		if err := store.Add(mapdata.New("tenant", map[string]any{"host": "tenant.example.com"})); err != nil {
+			t.Fatalf("Add(tenant) error = %v", err)
+		}
+		if err := store.Add(mapdata.New("blank", map[string]any{"host": ""}), WithPriority(-10)); err != nil {
+			t.Fatalf("Add(blank) error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		wantLayers := []layer.Name{"blank", "defaults", "tenant"}
+
+		check := func(t *testing.T, op string, err error) {
+			t.Helper()
+			if !errors.Is(err, errHost) {
+				t.Fatalf("%s error = %v, want validator error", op, err)
+			}
+			if got := store.GetAt("/host"); got.Value != "tenant.example.com" || got.Layer.Name() != "tenant" {
+				t.Errorf("GetAt(/host) = %v from %v, want tenant.example.com from tenant", got.Value, got.Layer.Name())
+			}
+			var names []layer.Name
+			for _, info := range store.ListLayers() {
+				names = append(names, info.Name())
+			}
+			if !reflect.DeepEqual(names, wantLayers) {
+				t.Errorf("ListLayers() = %v, want %v", names, wantLayers)
+			}
+		}
+
+		check(t, "RemoveLayer()", store.RemoveLayer("tenant"))
+		check(t, "ReplaceLayer()", store.ReplaceLayer(ctx, "tenant", mapdata.New("tenant", map[string]any{})))
+		check(t, "SetLayerPriority()", store.SetLayerPriority("blank", 100))
+		if got := store.GetLayerInfo("blank").Priority(); got != -10 {
+			t.Errorf("blank priority = %d, want -10", got)
+		}
+
+		// The store still accepts valid changes afterwards.
+		if err := store.SetLayerPriority("defaults", 50); err != nil {
+			t.Fatalf("SetLayerPriority() error = %v", err)
+		}
+	})
+
+	t.Run("validate method on type", func(t *testing.T) {
+		store := New[validatedConfig]()
+		if err := store.Add(mapdata.New("base", map[string]any{"port": 8080})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		err := store.SetTo("base", "/port", -1)
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("SetTo() error = %v, want *ValidationError", err)
+		}
+		if got := store.Get().Port; got != 8080 {
+			t.Errorf("Port = %d, want 8080", got)
+		}
+	})
+
+	t.Run("mismatched validator type panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("New() should panic for a validator of another type")
+			}
+		}()
+		New[testConfig](WithValidator(func(validatedConfig) error { return nil }))
+	})
+}
+
+func TestValidationError_Error(t *testing.T) {
+	err := &ValidationError{Err: errors.New("bad")}
+	if got := err.Error(); got != "config validation failed: bad" {
+		t.Errorf("Error() = %q", got)
+	}
+	err.Layers = []layer.Name{"defaults", "user"}
+	if got := err.Error(); got != "config validation failed (layers: defaults, user): bad" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+type interpolatedConfig struct {
+	Server struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	} `json:"server"`
+	URL      string `json:"url"`
+	Port     int    `json:"port"`
+	CacheDir string `json:"cache_dir"`
+	Literal  string `json:"literal"`
+	Token    string `json:"token" jubako:"sensitive"`
+}
+
+func TestStore_WithInterpolation(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("JUBAKO_TEST_TOKEN", "s3cret")
+	t.Setenv("HOME", "/home/tester")
+
+	newStore := func(t *testing.T, data map[string]any) (*Store[interpolatedConfig], *mapdata.Layer) {
+		t.Helper()
+		store := New[interpolatedConfig](WithInterpolation(), WithSensitiveMaskString("***"))
+		base := mapdata.New("base", map[string]any{
+			"server": map[string]any{"host": "example.com", "port": 8443},
+		})
+		user := mapdata.New("user", data)
+		if err := store.Add(base); err != nil {
+			t.Fatalf("Add(base) error = %v", err)
+		}
+		if err := store.Add(user); err != nil {
+			t.Fatalf("Add(user) error = %v", err)
+		}
+		return store, user
+	}
+
+	t.Run("expands references", func(t *testing.T) {
+		store, _ := newStore(t, map[string]any{
+			"url":       "https://${/server/host}:${/server/port}/api",
+			"port":      "${/server/port}",
+			"cache_dir": "${home}/.cache",
+			"literal":   "$${/server/host}",
+			"token":     "${env:JUBAKO_TEST_TOKEN}",
+		})
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		got := store.Get()
+		if got.URL != "https://example.com:8443/api" {
+			t.Errorf("URL = %q", got.URL)
+		}
+		if got.Port != 8443 {
+			t.Errorf("Port = %d, want 8443", got.Port)
+		}
+		if got.CacheDir != "/home/tester/.cache" {
+			t.Errorf("CacheDir = %q", got.CacheDir)
+		}
+		if got.Literal != "${/server/host}" {
+			t.Errorf("Literal = %q, want escaped reference", got.Literal)
+		}
+		if got.Token != "s3cret" {
+			t.Errorf("Token = %q", got.Token)
+		}
+	})
+
+	t.Run("GetAt reports raw and interpolated values", func(t *testing.T) {
+		store, _ := newStore(t, map[string]any{
+			"url":   "https://${/server/host}",
+			"token": "${env:JUBAKO_TEST_TOKEN}",
+		})
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		rv := store.GetAt("/url")
+		if !rv.Interpolated || rv.Value != "https://example.com" || rv.Raw != "https://${/server/host}" {
+			t.Errorf("GetAt(/url) = %+v", rv)
+		}
+		if rv.Layer == nil || rv.Layer.Name() != "user" {
+			t.Errorf("GetAt(/url).Layer = %v, want user", rv.Layer)
+		}
+
+		if rv := store.GetAt("/server/host"); rv.Interpolated || rv.Raw != nil {
+			t.Errorf("GetAt(/server/host) should not be interpolated, got %+v", rv)
+		}
+
+		rv = store.GetAt("/token")
+		if !rv.Masked || rv.Value != "***" || rv.Raw != "***" {
+			t.Errorf("GetAt(/token) should mask value and raw, got %+v", rv)
+		}
+		if rv := store.GetAtUnmasked("/token"); rv.Value != "s3cret" {
+			t.Errorf("GetAtUnmasked(/token).Value = %v", rv.Value)
+		}
+
+		var walked ResolvedValue
+		store.Walk(func(ctx WalkContext) bool {
+			if ctx.Path == "/url" {
+				walked = ctx.Value()
+			}
+			return true
+		})
+		if walked.Value != "https://example.com" {
+			t.Errorf("Walk /url value = %v", walked.Value)
+		}
+	})
+
+	t.Run("save keeps templates", func(t *testing.T) {
+		store, user := newStore(t, map[string]any{"url": "https://${/server/host}"})
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if err := store.SetTo("user", "/literal", "x"); err != nil {
+			t.Fatalf("SetTo() error = %v", err)
+		}
+		if err := store.Save(ctx); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		if got := user.Data()["url"]; got != "https://${/server/host}" {
+			t.Errorf("saved url = %v, want template", got)
+		}
+	})
+
+	t.Run("reports missing references with origin", func(t *testing.T) {
+		store, _ := newStore(t, map[string]any{"url": "https://${/server/hostname}"})
+		err := store.Load(ctx)
+		var ierr *InterpolationError
+		if !errors.As(err, &ierr) {
+			t.Fatalf("Load() error = %v, want *InterpolationError", err)
+		}
+		if ierr.Path != "/url" || ierr.Reference != "${/server/hostname}" {
+			t.Errorf("Path = %q, Reference = %q", ierr.Path, ierr.Reference)
+		}
+		if ierr.Layer == nil || ierr.Layer.Name() != "user" {
+			t.Errorf("Layer = %v, want user", ierr.Layer)
+		}
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("Load() error = %v, want *ValidationError", err)
+		}
+		if got := ierr.Error(); got != "interpolate /url: ${/server/hostname}: path not found (from layer user)" {
+			t.Errorf("Error() = %q", got)
+		}
+	})
+
+	t.Run("detects cycles", func(t *testing.T) {
+		store, _ := newStore(t, map[string]any{
+			"url":     "${/literal}",
+			"literal": "x${/url}",
+		})
+		err := store.Load(ctx)
+		var ierr *InterpolationError
+		if !errors.As(err, &ierr) {
+			t.Fatalf("Load() error = %v, want *InterpolationError", err)
+		}
+		if ierr.Err.Error() != "reference cycle: /literal -> /url -> /literal" {
+			t.Errorf("Err = %v", ierr.Err)
+		}
+	})
+
+	t.Run("rejected write is rolled back", func(t *testing.T) {
+		store, _ := newStore(t, map[string]any{"url": "${/literal}", "literal": "x"})
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		err := store.SetTo("user", "/literal", "${/url}")
+		var ierr *InterpolationError
+		var verr *ValidationError
+		if !errors.As(err, &ierr) || !errors.As(err, &verr) {
+			t.Fatalf("SetTo() error = %v, want *InterpolationError in a *ValidationError", err)
+		}
+		if store.IsDirty() {
+			t.Error("store should not be dirty after a rejected write")
+		}
+		if err := store.SetTo("user", "/literal", "y"); err != nil {
+			t.Fatalf("SetTo() error = %v", err)
+		}
+		if got := store.Get().URL; got != "y" {
+			t.Errorf("URL = %q, want y", got)
+		}
+	})
+
+	t.Run("missing environment variable", func(t *testing.T) {
+		store, _ := newStore(t, map[string]any{"token": "${env:JUBAKO_TEST_UNDEFINED}"})
+		var ierr *InterpolationError
+		if err := store.Load(ctx); !errors.As(err, &ierr) {
+			t.Fatalf("Load() error = %v, want *InterpolationError", err)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		store := New[interpolatedConfig]()
+		if err := store.Add(mapdata.New("user", map[string]any{"url": "${/server/host}"})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if got := store.Get().URL; got != "${/server/host}" {
+			t.Errorf("URL = %q, want template kept", got)
+		}
+	})
+}
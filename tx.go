@@ -0,0 +1,294 @@
+package jubako
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/layer"
+)
+
+// Tx stages changes to several layers within Store.Tx.
+// Its methods apply changes to the layers in memory; the configuration is
+// materialized once, and the changed layers are saved, when the transaction
+// function returns.
+type Tx struct {
+	set    func(layerName layer.Name, cfg *setConfig) error
+	delete func(layerName layer.Name, paths []string) error
+
+	// layers lists the layers changed by the transaction, in order of change.
+	layers []layer.Name
+}
+
+// SetTo stages a value at the given JSONPointer path in a layer.
+// See Store.SetTo.
+func (tx *Tx) SetTo(layerName layer.Name, path string, value any) error {
+	return tx.Set(layerName, Value(path, value))
+}
+
+// Set stages values in a layer using functional options.
+// See Store.Set.
+func (tx *Tx) Set(layerName layer.Name, opts ...SetOption) error {
+	cfg := &setConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if len(cfg.patches) == 0 {
+		return nil
+	}
+	return tx.set(layerName, cfg)
+}
+
+// DeleteFrom stages the removal of values at the given JSONPointer paths from
+// a layer. See Store.DeleteFrom.
+func (tx *Tx) DeleteFrom(layerName layer.Name, paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	return tx.delete(layerName, paths)
+}
+
+// touch records a layer as changed by the transaction.
+func (tx *Tx) touch(layerName layer.Name) {
+	if !slices.Contains(tx.layers, layerName) {
+		tx.layers = append(tx.layers, layerName)
+	}
+}
+
+// TxError is returned by Store.Tx when saving a layer of the transaction fails.
+// The layers saved before the failure are restored to the contents they had
+// before the transaction where possible, and the Store returns to its state
+// before the transaction.
+type TxError struct {
+	// Layer is the layer whose save failed.
+	Layer layer.Name
+
+	// Err is the error returned by the save.
+	Err error
+
+	// Restored lists the layers that had been saved and were restored.
+	Restored []layer.Name
+
+	// Unrestored lists the layers that had been saved but could not be
+	// restored; their sources keep the contents written by the transaction.
+	Unrestored []layer.Name
+
+	// RestoreErr holds the errors of the layers in Unrestored.
+	RestoreErr error
+}
+
+// Error implements the error interface.
+func (e *TxError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "transaction failed: %v", e.Err)
+	if len(e.Restored) > 0 {
+		fmt.Fprintf(&sb, " (restored layers: %s)", joinLayerNames(e.Restored))
+	}
+	if len(e.Unrestored) > 0 {
+		fmt.Fprintf(&sb, " (layers not restored: %s: %v)", joinLayerNames(e.Unrestored), e.RestoreErr)
+	}
+	return sb.String()
+}
+
+// Unwrap returns the error of the failed save.
+func (e *TxError) Unwrap() error {
+	return e.Err
+}
+
+func joinLayerNames(names []layer.Name) string {
+	strs := make([]string, len(names))
+	for i, name := range names {
+		strs[i] = string(name)
+	}
+	return strings.Join(strs, ", ")
+}
+
+// Tx changes several layers as a unit.
+// fn stages changes through tx; when it returns nil, the configuration is
+// materialized once and every layer changed by the transaction is saved.
+// If fn returns an error, or the materialized configuration is rejected by
+// validation, the staged changes are discarded and nothing is saved.
+//
+// If saving a layer fails, the layers already saved are restored to their
+// previous contents, using the bytes the sources held before the write, and a
+// *TxError is returned; files created by the transaction are removed. Only
+// layers implementing layer.RestorableLayer can be restored; they are saved
+// before the others. Saving writes all pending changes of the layers,
+// including changes made before the transaction.
+//
+// fn is called while the store is locked, so it must not call any method of
+// the store, including reads such as Get, GetAt and Explain: they would
+// deadlock. Use the methods of tx to stage changes, and read the values fn
+// needs before calling Tx.
+//
+// Example:
+//
+//	err := store.Tx(ctx, func(tx *jubako.Tx) error {
+//	  if err := tx.SetTo("secrets", "/credential/token", token); err != nil {
+//	    return err
+//	  }
+//	  return tx.SetTo("user", "/credential/updated_at", now)
+//	})
+//	var txErr *jubako.TxError
+//	if errors.As(err, &txErr) {
+//	  log.Printf("saving %s failed; restored %v", txErr.Layer, txErr.Restored)
+//	}
+func (s *Store[T]) Tx(ctx context.Context, fn func(tx *Tx) error) error {
+	current, subscribers, err := s.txLocked(ctx, fn)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subscribers {
+		sub.fn(current)
+	}
+	return nil
+}
+
+// txLocked runs a transaction under lock.
+// Returns the current configuration and subscribers snapshot for notification outside the lock.
+func (s *Store[T]) txLocked(ctx context.Context, fn func(tx *Tx) error) (T, []subscriber[T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var zero T
+	snapshot := s.captureLayersLocked()
+
+	tx := &Tx{}
+	tx.set = func(layerName layer.Name, cfg *setConfig) error {
+		entry, err := s.writableLayerLocked(layerName)
+		if err != nil {
+			return err
+		}
+		tx.touch(layerName)
+		return s.applySetLocked(entry, layerName, cfg)
+	}
+	tx.delete = func(layerName layer.Name, paths []string) error {
+		entry, err := s.writableLayerLocked(layerName)
+		if err != nil {
+			return err
+		}
+		if applyDeleteLocked(entry, paths) {
+			tx.touch(layerName)
+		}
+		return nil
+	}
+
+	if err := fn(tx); err != nil {
+		s.restoreLayersLocked(snapshot)
+		return zero, nil, err
+	}
+	if len(tx.layers) == 0 {
+		return zero, nil, nil
+	}
+	for _, name := range tx.layers {
+		s.syncLayerDirty(s.findLayerLocked(name))
+	}
+
+	// Materialize once for all staged changes
+	current, subscribers, err := s.commitLocked(ctx, snapshot, tx.layers)
+	if err != nil {
+		s.restoreLayersLocked(snapshot)
+		return zero, nil, err
+	}
+
+	if err := s.saveTxLocked(ctx, tx.layers); err != nil {
+		// The resolved value goes back to the one before the transaction
+		s.restoreLayersLocked(snapshot)
+		if _, _, merr := s.materializeLocked(ctx); merr != nil {
+			err = errors.Join(err, merr)
+		}
+		return zero, nil, err
+	}
+	return current, subscribers, nil
+}
+
+// savedLayer records a layer saved by a transaction.
+type savedLayer struct {
+	entry    *layerEntry
+	restorer layer.RestorableLayer
+	previous []byte
+}
+
+// saveTxLocked saves the layers of a transaction, in priority order with the
+// layers that can be restored first. If a save fails, the layers already
+// saved are restored and a *TxError is returned.
+// Caller must hold the write lock.
+func (s *Store[T]) saveTxLocked(ctx context.Context, names []layer.Name) error {
+	var restorable, others []*layerEntry
+	for _, entry := range s.layers {
+		if !slices.Contains(names, entry.layer.Name()) {
+			continue
+		}
+		if _, ok := restorableLayer(entry); ok {
+			restorable = append(restorable, entry)
+		} else {
+			others = append(others, entry)
+		}
+	}
+
+	var saved []savedLayer
+	for _, entry := range append(restorable, others...) {
+		record := savedLayer{entry: entry}
+		// Contextual saves do not go through save, and are written whenever
+		// they succeed
+		_, written := entry.layer.(layer.ContextualSaveLayer)
+		save := func(ctx context.Context, changeset document.JSONPatchSet) error {
+			err := entry.layer.Save(ctx, changeset)
+			written = err == nil
+			return err
+		}
+		if restorer, ok := restorableLayer(entry); ok {
+			record.restorer = restorer
+			save = func(ctx context.Context, changeset document.JSONPatchSet) error {
+				previous, err := restorer.SaveRestorable(ctx, changeset)
+				record.previous, written = previous, err == nil
+				return err
+			}
+		}
+
+		if err := s.saveLayerWithLocked(ctx, entry, save); err != nil {
+			return s.restoreTxLocked(ctx, entry.layer.Name(), err, saved)
+		}
+		if written {
+			saved = append(saved, record)
+		}
+	}
+	return nil
+}
+
+// restoreTxLocked restores the layers saved by a transaction, in reverse
+// order, after saving failedLayer failed with err.
+// Caller must hold the write lock.
+func (s *Store[T]) restoreTxLocked(ctx context.Context, failedLayer layer.Name, err error, saved []savedLayer) error {
+	txErr := &TxError{Layer: failedLayer, Err: err}
+	var restoreErrs []error
+	for i := len(saved) - 1; i >= 0; i-- {
+		name := saved[i].entry.layer.Name()
+		if saved[i].restorer == nil {
+			txErr.Unrestored = append(txErr.Unrestored, name)
+			restoreErrs = append(restoreErrs, fmt.Errorf("layer %q does not support restoring", name))
+			continue
+		}
+		if err := saved[i].restorer.Restore(ctx, saved[i].previous); err != nil {
+			txErr.Unrestored = append(txErr.Unrestored, name)
+			restoreErrs = append(restoreErrs, fmt.Errorf("failed to restore layer %q: %w", name, err))
+			continue
+		}
+		txErr.Restored = append(txErr.Restored, name)
+	}
+	txErr.RestoreErr = errors.Join(restoreErrs...)
+	return txErr
+}
+
+// restorableLayer returns the layer of entry as a layer.RestorableLayer.
+// Layers saved with context (layer.ContextualSaveLayer) are not restorable.
+func restorableLayer(entry *layerEntry) (layer.RestorableLayer, bool) {
+	if _, ok := entry.layer.(layer.ContextualSaveLayer); ok {
+		return nil, false
+	}
+	restorer, ok := entry.layer.(layer.RestorableLayer)
+	return restorer, ok
+}
@@ -0,0 +1,253 @@
+package jubako
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yacchi/jubako/format/json"
+	"github.com/yacchi/jubako/layer"
+	"github.com/yacchi/jubako/layer/mapdata"
+	"github.com/yacchi/jubako/source/fs"
+)
+
+type txConfig struct {
+	Name  string `json:"name"`
+	Port  int    `json:"port"`
+	Token string `json:"token" jubako:"sensitive"`
+}
+
+// newTxStore returns a store with a JSON file layer "user" and the given
+// sensitive layer "secrets".
+func newTxStore(t *testing.T, secrets layer.Layer, opts ...StoreOption) (*Store[txConfig], string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "user.json")
+	if err := os.WriteFile(path, []byte(`{"name": "app", "port": 8080}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := New[txConfig](opts...)
+	if err := store.Add(layer.New("user", fs.New(path), json.New())); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Add(secrets, WithSensitive()); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return store, path
+}
+
+func TestStore_Tx(t *testing.T) {
+	ctx := context.Background()
+	secrets := mapdata.New("secrets", map[string]any{})
+	store, path := newTxStore(t, secrets)
+
+	var notified []txConfig
+	store.Subscribe(func(cfg txConfig) {
+		notified = append(notified, cfg)
+	})
+
+	err := store.Tx(ctx, func(tx *Tx) error {
+		if err := tx.SetTo("secrets", "/token", "secret"); err != nil {
+			return err
+		}
+		if err := tx.Set("user", String("/name", "renamed")); err != nil {
+			return err
+		}
+		return tx.DeleteFrom("user", "/port")
+	})
+	if err != nil {
+		t.Fatalf("Tx() error = %v", err)
+	}
+
+	want := txConfig{Name: "renamed", Token: "secret"}
+	if got := store.Get(); got != want {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+	if len(notified) != 1 || notified[0] != want {
+		t.Errorf("notified = %+v, want one notification with %+v", notified, want)
+	}
+	if store.IsDirty() {
+		t.Error("store should not be dirty after Tx")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "{\n  \"name\": \"renamed\"\n}\n" {
+		t.Errorf("user.json = %q", data)
+	}
+	if got := secrets.Data(); !reflect.DeepEqual(got, map[string]any{"token": "secret"}) {
+		t.Errorf("secrets = %v", got)
+	}
+}
+
+func TestStore_Tx_Discarded(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("callback error", func(t *testing.T) {
+		store, _ := newTxStore(t, mapdata.New("secrets", map[string]any{}))
+		errCallback := errors.New("callback failed")
+		err := store.Tx(ctx, func(tx *Tx) error {
+			if err := tx.SetTo("user", "/name", "renamed"); err != nil {
+				return err
+			}
+			return errCallback
+		})
+		if !errors.Is(err, errCallback) {
+			t.Errorf("Tx() error = %v, want %v", err, errCallback)
+		}
+		if got := store.Get().Name; got != "app" || store.IsDirty() {
+			t.Errorf("Name = %q, dirty = %v, want unchanged", got, store.IsDirty())
+		}
+	})
+
+	t.Run("sensitive field in normal layer", func(t *testing.T) {
+		store, _ := newTxStore(t, mapdata.New("secrets", map[string]any{}))
+		err := store.Tx(ctx, func(tx *Tx) error {
+			if err := tx.SetTo("user", "/name", "renamed"); err != nil {
+				return err
+			}
+			return tx.SetTo("user", "/token", "secret")
+		})
+		if !errors.Is(err, ErrSensitiveFieldToNormalLayer) {
+			t.Errorf("Tx() error = %v, want ErrSensitiveFieldToNormalLayer", err)
+		}
+		if store.IsDirty() {
+			t.Error("store should not be dirty after a discarded Tx")
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		store, path := newTxStore(t, mapdata.New("secrets", map[string]any{}), WithValidator(func(cfg txConfig) error {
+			if cfg.Name == "" {
+				return errors.New("name is required")
+			}
+			return nil
+		}))
+		err := store.Tx(ctx, func(tx *Tx) error {
+			if err := tx.SetTo("secrets", "/token", "secret"); err != nil {
+				return err
+			}
+			return tx.DeleteFrom("user", "/name")
+		})
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || !reflect.DeepEqual(validationErr.Layers, []layer.Name{"secrets", "user"}) {
+			t.Fatalf("Tx() error = %v, want *ValidationError for secrets and user", err)
+		}
+		if got := store.Get(); got.Name != "app" || got.Token != "" || store.IsDirty() {
+			t.Errorf("Get() = %+v, dirty = %v, want unchanged", got, store.IsDirty())
+		}
+		if data, _ := os.ReadFile(path); string(data) != `{"name": "app", "port": 8080}` {
+			t.Errorf("user.json = %q, want unchanged", data)
+		}
+	})
+}
+
+func TestStore_Tx_SaveFailure(t *testing.T) {
+	ctx := context.Background()
+	errSave := errors.New("secret storage unavailable")
+	secrets := &failingMetadataLayer{Layer: mapdata.New("secrets", map[string]any{}), saveErr: errSave}
+	store, path := newTxStore(t, secrets)
+
+	// The file layer is saved first since it can be restored
+	err := store.Tx(ctx, func(tx *Tx) error {
+		if err := tx.SetTo("secrets", "/token", "secret"); err != nil {
+			return err
+		}
+		return tx.SetTo("user", "/name", "renamed")
+	})
+	var txErr *TxError
+	if !errors.As(err, &txErr) {
+		t.Fatalf("Tx() error = %v, want *TxError", err)
+	}
+	if !errors.Is(err, errSave) {
+		t.Errorf("Tx() error = %v, want to wrap %v", err, errSave)
+	}
+	if txErr.Layer != "secrets" || !reflect.DeepEqual(txErr.Restored, []layer.Name{"user"}) || txErr.Unrestored != nil {
+		t.Errorf("TxError = %+v", txErr)
+	}
+	want := `transaction failed: failed to save layer "secrets": secret storage unavailable (restored layers: user)`
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err, want)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != `{"name": "app", "port": 8080}` {
+		t.Errorf("user.json = %q, want restored", data)
+	}
+	if got := store.Get(); got.Name != "app" || got.Token != "" || store.IsDirty() {
+		t.Errorf("Get() = %+v, dirty = %v, want the state before Tx", got, store.IsDirty())
+	}
+}
+
+func TestStore_Tx_Unrestored(t *testing.T) {
+	ctx := context.Background()
+	store := New[txConfig]()
+	meta := mapdata.New("meta", map[string]any{})
+	if err := store.Add(meta); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	failing := &failingMetadataLayer{Layer: mapdata.New("other", map[string]any{}), saveErr: errors.New("boom")}
+	if err := store.Add(failing); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	err := store.Tx(ctx, func(tx *Tx) error {
+		if err := tx.SetTo("meta", "/name", "app"); err != nil {
+			return err
+		}
+		return tx.SetTo("other", "/port", 8080)
+	})
+	var txErr *TxError
+	if !errors.As(err, &txErr) {
+		t.Fatalf("Tx() error = %v, want *TxError", err)
+	}
+	if txErr.Layer != "other" || txErr.Restored != nil || !reflect.DeepEqual(txErr.Unrestored, []layer.Name{"meta"}) {
+		t.Errorf("TxError = %+v", txErr)
+	}
+	if txErr.RestoreErr == nil || !strings.Contains(txErr.RestoreErr.Error(), `layer "meta" does not support restoring`) {
+		t.Errorf("RestoreErr = %v", txErr.RestoreErr)
+	}
+	if got := meta.Data(); !reflect.DeepEqual(got, map[string]any{"name": "app"}) {
+		t.Errorf("meta = %v, want the value written by Tx", got)
+	}
+}
+
+func TestStore_Tx_RemovesCreatedFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "user.json")
+	store := New[txConfig]()
+	if err := store.Add(layer.New("user", fs.New(path), json.New()), WithOptional()); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	failing := &failingMetadataLayer{Layer: mapdata.New("secrets", map[string]any{}), saveErr: errors.New("boom")}
+	if err := store.Add(failing, WithSensitive()); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	err := store.Tx(ctx, func(tx *Tx) error {
+		if err := tx.SetTo("user", "/name", "app"); err != nil {
+			return err
+		}
+		return tx.SetTo("secrets", "/token", "secret")
+	})
+	var txErr *TxError
+	if !errors.As(err, &txErr) || !reflect.DeepEqual(txErr.Restored, []layer.Name{"user"}) {
+		t.Fatalf("Tx() error = %v, want *TxError with user restored", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Stat(user.json) error = %v, want the file created by Tx removed", err)
+	}
+}
@@ -0,0 +1,479 @@
+package jubako
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/yacchi/jubako/internal/variant"
+	"github.com/yacchi/jubako/jsonptr"
+)
+
+// RegisterVariant registers the type of v as the variant of the interface
+// type I selected by name. Fields of type I declared with the union=KEY
+// directive, as well as slices and maps of I, are decoded into the variant
+// whose name is the value of KEY in the field's object.
+//
+// v must be a struct or a pointer to a struct; fields are set to a value of
+// the same kind. Variants are used by Stores created afterwards, so
+// RegisterVariant is typically called from an init function. It panics if
+// name is empty or already registered for I.
+//
+// Example:
+//
+//	type Storage interface{ Open() (Bucket, error) }
+//
+//	type Config struct {
+//	    Storage Storage `yaml:"storage" jubako:"union=type"`
+//	}
+//
+//	func init() {
+//	    jubako.RegisterVariant[Storage]("s3", S3Storage{})
+//	    jubako.RegisterVariant[Storage]("local", &LocalStorage{})
+//	}
+//
+// With the YAML document
+//
+//	storage:
+//	  type: s3
+//	  bucket: my-bucket
+//
+// Config.Storage is an S3Storage with its Bucket field set to "my-bucket".
+func RegisterVariant[I any](name string, v I) {
+	variant.Register(reflect.TypeFor[I](), name, reflect.TypeOf(v))
+}
+
+// UnionTable holds the variants of a field declared with the union= directive.
+//
+// The paths of all variants are part of the Schema's MappingTrie, so that
+// they are known to unknown key detection and to sensitivity checks; a path
+// shared by several variants is sensitive if any of them marks it sensitive.
+// Decoding, constraints and converters follow the variant selected by the
+// discriminator of each merged value.
+type UnionTable struct {
+	// Discriminator is the key whose value names the variant.
+	Discriminator string
+	// Variants maps variant names to the types registered with RegisterVariant.
+	Variants map[string]reflect.Type
+	// Tables maps variant names to the mapping tables of the variant types.
+	Tables map[string]*MappingTable
+
+	// kind is reflect.Interface for interface fields, or reflect.Slice and
+	// reflect.Map for fields holding several variant values.
+	kind reflect.Kind
+	// discriminator is the mapping of the discriminator key, used for the
+	// paths of variants that do not declare a field for it.
+	discriminator *PathMapping
+}
+
+// buildUnionTable builds the variant tables of a union field of type t.
+// It returns nil if t is not an interface, or a slice or map of an interface.
+//
+// A union inside a variant of its own interface, such as a composite storage
+// holding other storages, shares the variant tables of the enclosing union,
+// found in variantTables. The table graph is then cyclic; walks along the
+// data terminate, and walks of the tables alone skip variant tables they are
+// already visiting.
+func buildUnionTable(t reflect.Type, discriminator string, delimiter string, fieldTagName string, typePath string, converters converterRegistry, variantTables map[reflect.Type]map[string]*MappingTable) *UnionTable {
+	iface := variant.Interface(t)
+	if iface == nil {
+		return nil
+	}
+	union := &UnionTable{
+		Discriminator: discriminator,
+		Variants:      variant.Lookup(iface),
+		Tables:        make(map[string]*MappingTable),
+		kind:          t.Kind(),
+		discriminator: &PathMapping{FieldKey: discriminator, FieldType: reflect.TypeOf("")},
+	}
+	if tables, ok := variantTables[iface]; ok {
+		union.Tables = tables
+		return union
+	}
+
+	if variantTables == nil {
+		variantTables = make(map[reflect.Type]map[string]*MappingTable)
+	}
+	variantTables[iface] = union.Tables
+	defer delete(variantTables, iface)
+	for name, typ := range union.Variants {
+		union.Tables[name] = buildMappingTableRecursive(typ, delimiter, fieldTagName, typePath+"{"+name+"}", converters, variantTables)
+	}
+	return union
+}
+
+// variantName returns the variant named by the discriminator of value.
+func (u *UnionTable) variantName(value map[string]any) (string, bool) {
+	name, ok := value[u.Discriminator].(string)
+	if !ok {
+		return "", false
+	}
+	_, ok = u.Variants[name]
+	return name, ok
+}
+
+// names returns the registered variant names in sorted order.
+func (u *UnionTable) names() []string {
+	return sortedKeys(u.Variants)
+}
+
+// rebuild returns value with each variant object replaced by the result of
+// fn. value is the field's value, or the slice or map of values for slice
+// and map fields; elements that are not objects are kept as they are.
+func (u *UnionTable) rebuild(value any, path string, fn func(elem map[string]any, path string) any) any {
+	switch u.kind {
+	case reflect.Slice:
+		elems, ok := value.([]any)
+		if !ok {
+			return value
+		}
+		result := make([]any, len(elems))
+		for i, elem := range elems {
+			result[i] = elem
+			if elemMap, ok := elem.(map[string]any); ok {
+				result[i] = fn(elemMap, path+"/"+strconv.Itoa(i))
+			}
+		}
+		return result
+	case reflect.Map:
+		values, ok := value.(map[string]any)
+		if !ok {
+			return value
+		}
+		result := make(map[string]any, len(values))
+		for _, k := range sortedKeys(values) {
+			result[k] = values[k]
+			if valueMap, ok := values[k].(map[string]any); ok {
+				result[k] = fn(valueMap, path+"/"+jsonptr.Escape(k))
+			}
+		}
+		return result
+	default:
+		if valueMap, ok := value.(map[string]any); ok {
+			return fn(valueMap, path)
+		}
+		return value
+	}
+}
+
+// hasUnions reports whether table or any table below it has union fields.
+func (t *MappingTable) hasUnions() bool {
+	if t == nil {
+		return false
+	}
+	if len(t.Unions) > 0 {
+		return true
+	}
+	for _, nested := range t.Nested {
+		if nested.hasUnions() {
+			return true
+		}
+	}
+	for _, nested := range t.SliceElement {
+		if nested.hasUnions() {
+			return true
+		}
+	}
+	for _, nested := range t.MapValue {
+		if nested.hasUnions() {
+			return true
+		}
+	}
+	return false
+}
+
+// VariantError reports a union value whose discriminator does not name a
+// variant registered with RegisterVariant.
+//
+// When returned by Load or Reload, the error is wrapped in a *ValidationError
+// together with the other invalid values. Use errors.As to inspect it.
+type VariantError struct {
+	// Path is the JSON Pointer path of the discriminator in the merged configuration.
+	Path string
+
+	// Value is the value of the discriminator, or nil if it is missing.
+	Value any
+
+	// Variants lists the registered variant names in sorted order.
+	Variants []string
+
+	// Layer provides metadata about the layer that provided the value, or
+	// the union object if the discriminator is missing.
+	Layer LayerInfo
+}
+
+// Error implements the error interface.
+// The message names the path, value, variants and layer, for example:
+// `/storage/type=ftp is not one of gcs|local|s3 (from layer user (~/.config/app.yaml))`.
+func (e *VariantError) Error() string {
+	var sb strings.Builder
+	variants := strings.Join(e.Variants, "|")
+	if variants == "" {
+		variants = "(no variants registered)"
+	}
+	if e.Value == nil {
+		fmt.Fprintf(&sb, "%s is missing (one of %s)", e.Path, variants)
+	} else {
+		fmt.Fprintf(&sb, "%s=%v is not one of %s", e.Path, e.Value, variants)
+	}
+	if e.Layer != nil {
+		fmt.Fprintf(&sb, " (from layer %s", e.Layer.Name())
+		if path := e.Layer.Path(); path != "" {
+			fmt.Fprintf(&sb, " (%s)", path)
+		}
+		sb.WriteString(")")
+	}
+	return sb.String()
+}
+
+// checkUnion checks that the discriminator of each value of a union field
+// names a variant, and checks the values against the variant's table.
+func (c *constraintChecker) checkUnion(union *UnionTable, fv fieldValue) {
+	if !fv.exists || fv.value == nil {
+		return
+	}
+	union.rebuild(fv.value, fv.path, func(elem map[string]any, path string) any {
+		if name, ok := union.variantName(elem); ok {
+			c.checkTable(elem, union.Tables[name], path)
+			return elem
+		}
+		err := &VariantError{
+			Path:     path + "/" + jsonptr.Escape(union.Discriminator),
+			Value:    elem[union.Discriminator],
+			Variants: union.names(),
+		}
+		if c.origins != nil {
+			entry := c.origins.getLeaf(err.Path)
+			originPath := err.Path
+			if entry == nil {
+				entry, originPath = c.origins.getContainer(path), path
+			}
+			if entry != nil {
+				err.Layer = layerInfoAt(entry, c.origins.sourcePath(originPath, entry))
+			}
+		}
+		c.errs = append(c.errs, err)
+		return elem
+	})
+}
+
+// withoutUnions returns a copy of src in which the values of union fields are
+// replaced by nil, so that decoders leave the interface fields unset for
+// assignUnions. Maps are copied only along the paths to union fields.
+func withoutUnions(src map[string]any, table *MappingTable) map[string]any {
+	if src == nil || !table.hasUnions() {
+		return src
+	}
+	dst := maps.Clone(src)
+	for key, union := range table.Unions {
+		if value, ok := dst[key]; ok {
+			dst[key] = union.rebuild(value, "", func(map[string]any, string) any { return nil })
+		}
+	}
+	for key, nested := range table.Nested {
+		if sub, ok := dst[key].(map[string]any); ok {
+			dst[key] = withoutUnions(sub, nested)
+		}
+	}
+	for key, elemTable := range table.SliceElement {
+		if elems, ok := dst[key].([]any); ok && elemTable.hasUnions() {
+			stripped := make([]any, len(elems))
+			for i, elem := range elems {
+				stripped[i] = elem
+				if elemMap, ok := elem.(map[string]any); ok {
+					stripped[i] = withoutUnions(elemMap, elemTable)
+				}
+			}
+			dst[key] = stripped
+		}
+	}
+	for key, valueTable := range table.MapValue {
+		if values, ok := dst[key].(map[string]any); ok && valueTable.hasUnions() {
+			stripped := make(map[string]any, len(values))
+			for k, v := range values {
+				stripped[k] = v
+				if valueMap, ok := v.(map[string]any); ok {
+					stripped[k] = withoutUnions(valueMap, valueTable)
+				}
+			}
+			dst[key] = stripped
+		}
+	}
+	return dst
+}
+
+// unionDecoder decodes the values of union fields into their variants.
+type unionDecoder struct {
+	decode     MapDecoder
+	converters converterRegistry
+}
+
+// assign sets the union fields of dst, decoded from src without their values
+// (see withoutUnions), to the variants selected by their discriminators.
+func (d unionDecoder) assign(dst reflect.Value, src map[string]any, table *MappingTable, path string) error {
+	if !table.hasUnions() {
+		return nil
+	}
+	return walkDecoded(dst, src, table, path, func(table *MappingTable, m *PathMapping, field reflect.Value, value any, path string) (bool, error) {
+		union, ok := table.Unions[m.FieldKey]
+		if !ok {
+			return true, nil
+		}
+		field = reflect.Indirect(field)
+		switch union.kind {
+		case reflect.Slice:
+			elems, _ := value.([]any)
+			if field.Kind() != reflect.Slice {
+				return false, nil
+			}
+			for i := 0; i < len(elems) && i < field.Len(); i++ {
+				if err := d.assignVariant(field.Index(i), union, elems[i], path+"/"+strconv.Itoa(i)); err != nil {
+					return false, err
+				}
+			}
+		case reflect.Map:
+			values, _ := value.(map[string]any)
+			if field.Kind() != reflect.Map || field.IsNil() {
+				return false, nil
+			}
+			for _, k := range sortedKeys(values) {
+				if _, ok := values[k].(map[string]any); !ok {
+					continue
+				}
+				elem := reflect.New(field.Type().Elem()).Elem()
+				if err := d.assignVariant(elem, union, values[k], path+"/"+jsonptr.Escape(k)); err != nil {
+					return false, err
+				}
+				field.SetMapIndex(reflect.ValueOf(k).Convert(field.Type().Key()), elem)
+			}
+		default:
+			return false, d.assignVariant(field, union, value, path)
+		}
+		return false, nil
+	})
+}
+
+// assignVariant decodes value into the variant named by its discriminator
+// and sets dst, an interface value, to it. Values that are not objects are
+// left to the decoder.
+func (d unionDecoder) assignVariant(dst reflect.Value, union *UnionTable, value any, path string) error {
+	elem, ok := value.(map[string]any)
+	if !ok || !dst.CanSet() {
+		return nil
+	}
+	name, ok := union.variantName(elem)
+	if !ok {
+		return &VariantError{
+			Path:     path + "/" + jsonptr.Escape(union.Discriminator),
+			Value:    elem[union.Discriminator],
+			Variants: union.names(),
+		}
+	}
+	typ, table := union.Variants[name], union.Tables[name]
+
+	ptr := reflect.New(variant.Struct(typ))
+	if err := d.decode(withoutUnions(elem, table), ptr.Interface()); err != nil {
+		return fmt.Errorf("variant %q at %s: %w", name, path, err)
+	}
+	if len(d.converters) > 0 {
+		assignConverted(ptr, elem, table, d.converters)
+	}
+	if err := d.assign(ptr, elem, table, path); err != nil {
+		return err
+	}
+	if typ.Kind() == reflect.Ptr {
+		dst.Set(ptr)
+	} else {
+		dst.Set(ptr.Elem())
+	}
+	return nil
+}
+
+// walkDecoded calls visit for each field of dst, a struct decoded from src
+// with table. When visit returns true, walkDecoded descends into the nested
+// struct, slice elements or map values of the field. path is the path of src,
+// following the field keys.
+func walkDecoded(dst reflect.Value, src map[string]any, table *MappingTable, path string, visit func(table *MappingTable, m *PathMapping, field reflect.Value, value any, path string) (bool, error)) error {
+	for dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			return nil
+		}
+		dst = dst.Elem()
+	}
+	if table == nil || src == nil || dst.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for _, m := range table.Mappings {
+		if m.Skipped {
+			continue
+		}
+		value, ok := src[m.FieldKey]
+		if !ok || value == nil {
+			continue
+		}
+		field := dst.FieldByIndex(m.StructField.Index)
+		fieldPath := path + "/" + jsonptr.Escape(m.FieldKey)
+		descend, err := visit(table, m, field, value, fieldPath)
+		if err != nil {
+			return err
+		}
+		if !descend {
+			continue
+		}
+
+		if nested, ok := table.Nested[m.FieldKey]; ok {
+			sub, _ := value.(map[string]any)
+			if err := walkDecoded(field, sub, nested, fieldPath, visit); err != nil {
+				return err
+			}
+		}
+		if elemTable, ok := table.SliceElement[m.FieldKey]; ok {
+			elems, _ := value.([]any)
+			field = reflect.Indirect(field)
+			if field.Kind() == reflect.Slice || field.Kind() == reflect.Array {
+				for i := 0; i < len(elems) && i < field.Len(); i++ {
+					sub, _ := elems[i].(map[string]any)
+					if err := walkDecoded(field.Index(i), sub, elemTable, fieldPath+"/"+strconv.Itoa(i), visit); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if valueTable, ok := table.MapValue[m.FieldKey]; ok {
+			values, _ := value.(map[string]any)
+			field = reflect.Indirect(field)
+			if field.Kind() != reflect.Map || field.IsNil() || field.Type().Key().Kind() != reflect.String {
+				continue
+			}
+			for k, v := range values {
+				sub, ok := v.(map[string]any)
+				if !ok {
+					continue
+				}
+				key := reflect.ValueOf(k).Convert(field.Type().Key())
+				elem := field.MapIndex(key)
+				if !elem.IsValid() {
+					continue
+				}
+				elemPath := fieldPath + "/" + jsonptr.Escape(k)
+				if elem.Kind() == reflect.Ptr {
+					if err := walkDecoded(elem, sub, valueTable, elemPath, visit); err != nil {
+						return err
+					}
+					continue
+				}
+				// Map values are not addressable; update a copy
+				updated := reflect.New(elem.Type()).Elem()
+				updated.Set(elem)
+				if err := walkDecoded(updated, sub, valueTable, elemPath, visit); err != nil {
+					return err
+				}
+				field.SetMapIndex(key, updated)
+			}
+		}
+	}
+	return nil
+}
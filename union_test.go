@@ -0,0 +1,266 @@
+package jubako
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yacchi/jubako/decoder"
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/layer/mapdata"
+)
+
+type unionStorage interface {
+	Kind() string
+}
+
+type unionS3 struct {
+	Bucket   string   `json:"bucket" jubako:"required"`
+	Endpoint *url.URL `json:"endpoint"`
+	Token    string   `json:"token" jubako:"sensitive"`
+}
+
+func (unionS3) Kind() string { return "s3" }
+
+type unionLocal struct {
+	Type  string `json:"type"`
+	Path  string `json:"path" jubako:"local_path"`
+	Token string `json:"token"`
+}
+
+func (*unionLocal) Kind() string { return "local" }
+
+type unionNested struct {
+	Inner unionStorage `json:"inner" jubako:"union=type"`
+}
+
+func (unionNested) Kind() string { return "nested" }
+
+func init() {
+	RegisterVariant[unionStorage]("s3", unionS3{})
+	RegisterVariant[unionStorage]("local", &unionLocal{})
+	RegisterVariant[unionStorage]("nested", unionNested{})
+}
+
+type unionConfig struct {
+	Name    string                  `json:"name"`
+	Storage unionStorage            `json:"storage" jubako:"union=type"`
+	Backups []unionStorage          `json:"backups" jubako:"union=type"`
+	Named   map[string]unionStorage `json:"named" jubako:"union=kind"`
+}
+
+func TestStore_Union(t *testing.T) {
+	for name, opt := range map[string]StoreOption{"json": WithDecoder(decoder.JSON), "reflect": WithReflectDecoder()} {
+		t.Run(name, func(t *testing.T) {
+			store := New[unionConfig](opt)
+			if err := store.Add(mapdata.New("defaults", map[string]any{
+				"storage": map[string]any{"type": "local", "local_path": "/var/lib/app"},
+			})); err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+			if err := store.Add(mapdata.New("user", map[string]any{
+				"name": "app",
+				"storage": map[string]any{
+					"type":     "s3",
+					"bucket":   "my-bucket",
+					"endpoint": "https://s3.example.com",
+				},
+				"backups": []any{
+					map[string]any{"type": "local", "local_path": "/backup"},
+					map[string]any{"type": "nested", "inner": map[string]any{"type": "s3", "bucket": "inner"}},
+				},
+				"named": map[string]any{
+					"a": map[string]any{"kind": "s3", "bucket": "a"},
+				},
+			})); err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+			if err := store.Load(context.Background()); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			got := store.Get()
+			if got.Name != "app" {
+				t.Errorf("Name = %q, want app", got.Name)
+			}
+			s3, ok := got.Storage.(unionS3)
+			if !ok {
+				t.Fatalf("Storage = %#v, want unionS3", got.Storage)
+			}
+			if s3.Bucket != "my-bucket" || s3.Endpoint == nil || s3.Endpoint.Host != "s3.example.com" {
+				t.Errorf("Storage = %+v", s3)
+			}
+
+			if len(got.Backups) != 2 {
+				t.Fatalf("Backups = %#v, want 2 elements", got.Backups)
+			}
+			local, ok := got.Backups[0].(*unionLocal)
+			if !ok || local.Type != "local" || local.Path != "/backup" {
+				t.Errorf("Backups[0] = %#v, want *unionLocal with path /backup", got.Backups[0])
+			}
+			nested, ok := got.Backups[1].(unionNested)
+			if !ok {
+				t.Fatalf("Backups[1] = %#v, want unionNested", got.Backups[1])
+			}
+			if inner, ok := nested.Inner.(unionS3); !ok || inner.Bucket != "inner" {
+				t.Errorf("Backups[1].Inner = %#v, want unionS3 with bucket inner", nested.Inner)
+			}
+
+			if a, ok := got.Named["a"].(unionS3); !ok || a.Bucket != "a" {
+				t.Errorf("Named = %#v, want a: unionS3 with bucket a", got.Named)
+			}
+		})
+	}
+}
+
+func TestStore_Union_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]any
+		want string
+	}{
+		{
+			name: "unknown variant",
+			data: map[string]any{"storage": map[string]any{"type": "ftp"}},
+			want: "/storage/type=ftp is not one of local|nested|s3 (from layer user)",
+		},
+		{
+			name: "missing discriminator",
+			data: map[string]any{"backups": []any{map[string]any{"bucket": "b"}}},
+			want: "/backups/0/type is missing (one of local|nested|s3) (from layer user)",
+		},
+		{
+			name: "variant constraint",
+			data: map[string]any{"storage": map[string]any{"type": "s3"}},
+			want: "/storage/bucket is missing (required)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := New[unionConfig]()
+			if err := store.Add(mapdata.New("user", tt.data)); err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+			err := store.Load(context.Background())
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Load() error = %v, want *ValidationError", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// Constraints of other variants do not apply
+	store := New[unionConfig]()
+	if err := store.Add(mapdata.New("user", map[string]any{
+		"storage": map[string]any{"type": "local"},
+	})); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Errorf("Load() error = %v", err)
+	}
+
+	var variantErr *VariantError
+	store = New[unionConfig]()
+	if err := store.Add(mapdata.New("user", map[string]any{
+		"storage": map[string]any{"type": "ftp"},
+	})); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(context.Background()); !errors.As(err, &variantErr) {
+		t.Fatalf("Load() error = %v, want *VariantError", err)
+	}
+	if variantErr.Value != "ftp" || variantErr.Layer == nil || variantErr.Layer.Name() != "user" {
+		t.Errorf("VariantError = %+v", variantErr)
+	}
+}
+
+func TestStore_Union_Schema(t *testing.T) {
+	store := New[unionConfig]()
+	trie := store.schema.Trie
+
+	// Paths shared by variants are sensitive if any variant marks them sensitive
+	for _, path := range []string{"/storage/token", "/backups/0/token", "/named/a/token"} {
+		if !trie.IsSensitive(path) {
+			t.Errorf("IsSensitive(%q) = false, want true", path)
+		}
+	}
+	for _, path := range []string{"/storage/type", "/storage/local_path", "/backups/1/inner/bucket", "/named/a/kind"} {
+		if trie.Lookup(path) == nil {
+			t.Errorf("Lookup(%q) = nil, want a mapping", path)
+		}
+	}
+	if m := trie.Lookup("/storage/bucket"); m == nil || m.FieldType != reflect.TypeOf("") {
+		t.Errorf("Lookup(/storage/bucket) = %+v", m)
+	}
+
+	store = New[unionConfig](WithUnknownKeys(UnknownKeysError))
+	if err := store.Add(mapdata.New("user", map[string]any{
+		"storage": map[string]any{"type": "s3", "bucket": "b", "buckt": "typo"},
+	})); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	err := store.Load(context.Background())
+	var unknownErr *UnknownKeyError
+	if !errors.As(err, &unknownErr) || unknownErr.Path != "/storage/buckt" {
+		t.Errorf("Load() error = %v, want unknown key /storage/buckt", err)
+	}
+}
+
+func TestStore_Union_SensitiveLayer(t *testing.T) {
+	store := New[unionConfig]()
+	if err := store.Add(mapdata.New("user", map[string]any{
+		"storage": map[string]any{"type": "s3", "bucket": "b"},
+	})); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	err := store.SetTo("user", "/storage/token", "secret")
+	if !errors.Is(err, ErrSensitiveFieldToNormalLayer) {
+		t.Errorf("SetTo(/storage/token) error = %v, want ErrSensitiveFieldToNormalLayer", err)
+	}
+}
+
+func TestRegisterVariant_Panics(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func()
+		want     string
+	}{
+		{"duplicate", func() { RegisterVariant[unionStorage]("s3", unionS3{}) }, "registered twice"},
+		{"empty name", func() { RegisterVariant[unionStorage]("", unionS3{}) }, "empty variant name"},
+		{"not an interface", func() { RegisterVariant[unionS3]("s3", unionS3{}) }, "is not an interface type"},
+		{"not a struct", func() { RegisterVariant[any]("n", 1) }, "must be a struct"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if r == nil || !strings.Contains(r.(string), tt.want) {
+					t.Errorf("RegisterVariant() panic = %v, want %q", r, tt.want)
+				}
+			}()
+			tt.register()
+		})
+	}
+}
+
+func TestStore_Union_RenderExample(t *testing.T) {
+	store := New[unionConfig]()
+	data, err := store.RenderExample(document.FormatYAML)
+	if err != nil {
+		t.Fatalf("RenderExample() error = %v", err)
+	}
+	want := "name: \"\"\nstorage: null\nbackups: []\nnamed: {}\n"
+	if string(data) != want {
+		t.Errorf("RenderExample() = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,271 @@
+package jubako
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yacchi/jubako/jsonptr"
+)
+
+// UnknownKeyMode controls how the Store reacts to keys in layer data that do
+// not map to any field of the configuration type.
+type UnknownKeyMode int
+
+const (
+	// UnknownKeysIgnore silently ignores unknown keys (default).
+	UnknownKeysIgnore UnknownKeyMode = iota
+	// UnknownKeysWarn reports unknown keys to the UnknownKeyHandler and keeps going.
+	UnknownKeysWarn
+	// UnknownKeysError rejects the configuration with a *ValidationError.
+	UnknownKeysError
+)
+
+// UnknownKeyError reports a key in a layer's data that does not map to any
+// field of the configuration type, such as a misspelled "sever.port".
+//
+// With UnknownKeysError, unknown keys are returned joined into the Err of a
+// *ValidationError. Use errors.As to inspect individual keys.
+type UnknownKeyError struct {
+	// Path is the JSON Pointer path of the key in the layer's data.
+	// For unknown maps, each leaf below the map is reported.
+	Path string
+
+	// Layer provides metadata about the layer containing the key.
+	Layer LayerInfo
+
+	// Suggestions lists known paths close to Path, closest first.
+	Suggestions []string
+}
+
+// Error implements the error interface.
+// The message names the path, layer, source and suggestions, for example:
+// "unknown key /sever/port (from layer user (~/.config/app.yaml)); did you mean /server/port?".
+func (e *UnknownKeyError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "unknown key %s (from layer %s", e.Path, e.Layer.Name())
+	if path := e.Layer.Path(); path != "" {
+		fmt.Fprintf(&sb, " (%s)", path)
+	}
+	sb.WriteString(")")
+	if len(e.Suggestions) > 0 {
+		fmt.Fprintf(&sb, "; did you mean %s?", strings.Join(e.Suggestions, " or "))
+	}
+	return sb.String()
+}
+
+// UnknownKeyHandler is called for each unknown key found with UnknownKeysWarn.
+type UnknownKeyHandler func(err *UnknownKeyError)
+
+// defaultUnknownKeyHandler logs unknown keys to stderr using the standard log package.
+var defaultUnknownKeyHandler UnknownKeyHandler = func(err *UnknownKeyError) {
+	log.Println("jubako: WARNING: " + err.Error())
+}
+
+// WithUnknownKeys enables the detection of keys that do not map to any field
+// of the configuration type. Every layer's data is checked whenever the
+// configuration is materialized, and each unknown key is reported with the
+// layer, file and "did you mean" suggestions based on edit distance.
+// Keys below map-typed fields match any name.
+//
+// With UnknownKeysWarn, each unknown key is reported once while it stays in
+// the data (to stderr by default, see WithUnknownKeyHandler). With
+// UnknownKeysError, unknown keys reject the configuration like a validator.
+//
+// Example:
+//
+//	store := jubako.New[Config](jubako.WithUnknownKeys(jubako.UnknownKeysError))
+func WithUnknownKeys(mode UnknownKeyMode) StoreOption {
+	return func(o *storeOptions) {
+		o.unknownKeys = mode
+	}
+}
+
+// WithUnknownKeyHandler sets the handler receiving unknown keys reported with
+// UnknownKeysWarn. Pass nil to disable reporting. The handler is called while
+// the Store is locked, so it must not call methods of the Store.
+func WithUnknownKeyHandler(handler UnknownKeyHandler) StoreOption {
+	return func(o *storeOptions) {
+		o.unknownKeyHandler = handler
+	}
+}
+
+// checkUnknownKeysLocked reports the keys in the layers' data that are not in
+// the schema, according to the configured mode.
+// Caller must hold the write lock.
+func (s *Store[T]) checkUnknownKeysLocked() error {
+	if s.unknownKeys == UnknownKeysIgnore || s.schema.Trie == nil {
+		return nil
+	}
+
+	var errs []error
+	reported := make(map[string]struct{})
+	for _, entry := range s.layers {
+		for _, path := range s.schema.Trie.unknownPaths(entry.data) {
+			err := &UnknownKeyError{
+				Path:        path,
+				Layer:       layerInfoAt(entry, path),
+				Suggestions: suggestPaths(s.schema.Mappings, path),
+			}
+			if s.unknownKeys == UnknownKeysError {
+				errs = append(errs, err)
+				continue
+			}
+			key := string(entry.layer.Name()) + "\x00" + path
+			reported[key] = struct{}{}
+			if _, ok := s.reportedUnknownKeys[key]; !ok && s.unknownKeyHandler != nil {
+				s.unknownKeyHandler(err)
+			}
+		}
+	}
+	s.reportedUnknownKeys = reported
+	return errors.Join(errs...)
+}
+
+// unknownPaths returns the paths in data that do not match the trie, in sorted order.
+// A key matching a leaf field (including map, slice and interface fields
+// without nested mappings) accepts everything below it.
+func (t *MappingTrie) unknownPaths(data map[string]any) []string {
+	if t == nil || t.root == nil {
+		return nil
+	}
+	var paths []string
+	t.collectUnknown(t.root, data, "", &paths)
+	return paths
+}
+
+func (t *MappingTrie) collectUnknown(node *mappingTrieNode, value any, path string, paths *[]string) {
+	if node.mapping != nil && len(node.children) == 0 && node.wildcard == nil {
+		return
+	}
+
+	visit := func(key string, child any, childPath string) {
+		next, ok := node.children[key]
+		if !ok {
+			next = node.wildcard
+		}
+		if next == nil {
+			collectLeaves(child, childPath, paths)
+			return
+		}
+		t.collectUnknown(next, child, childPath, paths)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, key := range sortedKeys(v) {
+			visit(key, v[key], path+"/"+jsonptr.Escape(key))
+		}
+	case []any:
+		for i, elem := range v {
+			key := strconv.Itoa(i)
+			visit(key, elem, path+"/"+key)
+		}
+	}
+}
+
+// collectLeaves appends the paths of the leaves of an unknown value.
+// Empty maps and slices are reported as leaves.
+func collectLeaves(value any, path string, paths *[]string) {
+	if m, ok := value.(map[string]any); ok && len(m) > 0 {
+		for _, key := range sortedKeys(m) {
+			collectLeaves(m[key], path+"/"+jsonptr.Escape(key), paths)
+		}
+		return
+	}
+	*paths = append(*paths, path)
+}
+
+// maxSuggestions is the maximum number of suggestions for an unknown key.
+const maxSuggestions = 3
+
+// suggestPaths returns the schema paths closest to path by edit distance.
+// Only the paths sharing the smallest distance are returned, and only if the
+// distance is small relative to the length of path.
+// Wildcard segments in schema paths take the corresponding segment of path,
+// so that "/servers/a/prot" suggests "/servers/a/port" for a map field.
+func suggestPaths(mappings []*PathMapping, path string) []string {
+	segments, err := jsonptr.Parse(path)
+	if err != nil {
+		return nil
+	}
+	maxDistance := max(1, len([]rune(path))/3)
+
+	type candidate struct {
+		path     string
+		distance int
+	}
+	var candidates []candidate
+	seen := make(map[string]struct{})
+	for _, m := range mappings {
+		if m.Path == "" || m.Skipped {
+			continue
+		}
+		known, err := jsonptr.Parse(m.Path)
+		if err != nil {
+			continue
+		}
+		var sb strings.Builder
+		for i, seg := range known {
+			if seg == "*" && i < len(segments) {
+				seg = segments[i]
+			}
+			sb.WriteString("/" + jsonptr.Escape(seg))
+		}
+		p := sb.String()
+		if _, ok := seen[p]; ok || p == path {
+			continue
+		}
+		seen[p] = struct{}{}
+		if d := editDistance(path, p); d <= maxDistance {
+			candidates = append(candidates, candidate{path: p, distance: d})
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.path, b.path)
+	})
+	var suggestions []string
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		if candidates[i].distance > candidates[0].distance {
+			break
+		}
+		suggestions = append(suggestions, candidates[i].path)
+	}
+	return suggestions
+}
+
+// editDistance returns the optimal string alignment distance between a and b:
+// the number of insertions, deletions, substitutions and transpositions of
+// adjacent characters needed to turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// d[i][j] is the distance between ra[:i] and rb[:j]
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
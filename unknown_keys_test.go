@@ -0,0 +1,189 @@
+package jubako
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/yacchi/jubako/layer/mapdata"
+)
+
+type unknownKeysServer struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+type unknownKeysConfig struct {
+	Server   unknownKeysServer            `json:"server"`
+	Backends map[string]unknownKeysServer `json:"backends"`
+	Labels   map[string]string            `json:"labels"`
+	Extra    any                          `json:"extra"`
+	Tags     []string                     `json:"tags"`
+	Legacy   string                       `json:"legacy" jubako:"/old/name"`
+}
+
+func TestMappingTrie_UnknownPaths(t *testing.T) {
+	store := New[unknownKeysConfig]()
+	data := map[string]any{
+		"server":   map[string]any{"host": "a", "prot": 1},
+		"sever":    map[string]any{"port": 1, "tls": map[string]any{"cert": "c"}},
+		"backends": map[string]any{"a": map[string]any{"host": "b", "hots": "x"}},
+		"labels":   map[string]any{"any": "value"},
+		"extra":    map[string]any{"free": map[string]any{"form": true}},
+		"tags":     []any{"x", "y"},
+		"old":      map[string]any{"name": "n"},
+		"empty":    map[string]any{},
+	}
+
+	got := store.schema.Trie.unknownPaths(data)
+	want := []string{
+		"/backends/a/hots",
+		"/empty",
+		"/server/prot",
+		"/sever/port",
+		"/sever/tls/cert",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unknownPaths() = %v, want %v", got, want)
+	}
+}
+
+func TestSuggestPaths(t *testing.T) {
+	store := New[unknownKeysConfig]()
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/sever/port", []string{"/server/port"}},
+		{"/server/prot", []string{"/server/port"}},
+		{"/backends/a/hots", []string{"/backends/a/host"}},
+		{"/Server/Host", []string{"/server/host"}},
+		{"/completely/different", nil},
+	}
+	for _, tt := range tests {
+		if got := suggestPaths(store.schema.Mappings, tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("suggestPaths(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"port", "port", 0},
+		{"port", "prot", 1},
+		{"sever", "server", 1},
+		{"host", "", 4},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestStore_WithUnknownKeys(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ignore by default", func(t *testing.T) {
+		store := New[unknownKeysConfig]()
+		if err := store.Add(mapdata.New("user", map[string]any{"sever": map[string]any{"port": 1}})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		store := New[unknownKeysConfig](WithUnknownKeys(UnknownKeysError))
+		if err := store.Add(mapdata.New("base", map[string]any{"server": map[string]any{"port": 80}})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Add(mapdata.New("user", map[string]any{"sever": map[string]any{"port": 8080}})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+
+		err := store.Load(ctx)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Load() error = %v, want *ValidationError", err)
+		}
+		var unknownErr *UnknownKeyError
+		if !errors.As(err, &unknownErr) {
+			t.Fatalf("Load() error = %v, want *UnknownKeyError", err)
+		}
+		if unknownErr.Path != "/sever/port" || unknownErr.Layer.Name() != "user" {
+			t.Errorf("UnknownKeyError = %s in %s", unknownErr.Path, unknownErr.Layer.Name())
+		}
+		if !reflect.DeepEqual(unknownErr.Suggestions, []string{"/server/port"}) {
+			t.Errorf("Suggestions = %v", unknownErr.Suggestions)
+		}
+		want := "unknown key /sever/port (from layer user); did you mean /server/port?"
+		if unknownErr.Error() != want {
+			t.Errorf("Error() = %q, want %q", unknownErr.Error(), want)
+		}
+	})
+
+	t.Run("rejected write is rolled back", func(t *testing.T) {
+		store := New[unknownKeysConfig](WithUnknownKeys(UnknownKeysError))
+		if err := store.Add(mapdata.New("user", map[string]any{"server": map[string]any{"port": 80}})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		var validationErr *ValidationError
+		if err := store.SetTo("user", "/sever/port", 8080); !errors.As(err, &validationErr) {
+			t.Fatalf("SetTo() error = %v, want *ValidationError", err)
+		}
+		if store.IsDirty() {
+			t.Error("store should not be dirty after a rejected write")
+		}
+
+		if err := store.SetTo("user", "/server/port", 8080); err != nil {
+			t.Fatalf("SetTo() error = %v", err)
+		}
+		if got := store.Get().Server.Port; got != 8080 {
+			t.Errorf("Server.Port = %d, want 8080", got)
+		}
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		var reported []string
+		store := New[unknownKeysConfig](
+			WithUnknownKeys(UnknownKeysWarn),
+			WithUnknownKeyHandler(func(err *UnknownKeyError) {
+				reported = append(reported, string(err.Layer.Name())+":"+err.Path)
+			}),
+		)
+		if err := store.Add(mapdata.New("user", map[string]any{
+			"server": map[string]any{"port": 8080},
+			"sever":  map[string]any{"port": 8080},
+		})); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if got := store.Get().Server.Port; got != 8080 {
+			t.Errorf("Server.Port = %d, want 8080", got)
+		}
+
+		// A key is reported once while it stays in the data
+		if err := store.SetTo("user", "/server/hots", "x"); err != nil {
+			t.Fatalf("SetTo() error = %v", err)
+		}
+		want := []string{"user:/sever/port", "user:/server/hots"}
+		if !reflect.DeepEqual(reported, want) {
+			t.Errorf("reported = %v, want %v", reported, want)
+		}
+	})
+}
@@ -0,0 +1,451 @@
+package jubako
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/yacchi/jubako/container"
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/jsonptr"
+	"github.com/yacchi/jubako/layer"
+)
+
+// ErrUpdateConflict is returned by Update when the layer keeps changing while
+// the update function runs.
+var ErrUpdateConflict = errors.New("layer changed during update")
+
+// maxUpdateAttempts bounds the number of times Update calls its function when
+// the layer changes concurrently.
+const maxUpdateAttempts = 3
+
+// Update modifies a layer through the configuration struct.
+// fn receives the layer's own values decoded into T; values of other layers
+// are not included. When fn returns, the fields it changed are compared with
+// the original values, following the field keys and path remappings of the
+// schema, and written to the layer as the minimal add, replace and remove
+// operations. Only the changed paths are recorded, so formatting and comments
+// of the rest of the document are preserved when the layer is saved.
+//
+// fn is called without holding the store's lock. If the layer is changed in
+// the meantime, the result of fn is discarded and fn is called again with the
+// new values; after a few attempts Update fails with ErrUpdateConflict. fn may
+// therefore be called more than once. If fn returns an error, the layer is left
+// unchanged and the error is returned.
+//
+// As with SetTo, writing a sensitive field to a non-sensitive layer fails with
+// ErrSensitiveFieldToNormalLayer; no change is applied in that case.
+//
+// Example:
+//
+//	err := store.Update("user", func(cfg *AppConfig) error {
+//	  cfg.Server.Port = 9000
+//	  delete(cfg.Features, "beta")
+//	  return nil
+//	})
+//	if err != nil {
+//	  log.Fatal(err)
+//	}
+//
+//	// Save the change to disk
+//	err = store.SaveLayer(ctx, "user")
+func (s *Store[T]) Update(layerName layer.Name, fn func(cfg *T) error) error {
+	for range maxUpdateAttempts {
+		view, err := s.layerView(layerName)
+		if err != nil {
+			return err
+		}
+		cfg := view.cfg
+		if err := fn(&cfg); err != nil {
+			return err
+		}
+
+		current, subscribers, applied, err := s.applyUpdate(layerName, view, cfg)
+		if errors.Is(err, ErrUpdateConflict) {
+			continue
+		}
+		if err != nil || !applied {
+			return err
+		}
+		for _, sub := range subscribers {
+			sub.fn(current)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: layer %q", ErrUpdateConflict, layerName)
+}
+
+// updateView holds the values of a layer read by Update.
+type updateView[T any] struct {
+	// entry and data identify the state of the layer the view was read from.
+	entry *layerEntry
+	data  map[string]any
+
+	// cfg holds the layer's values decoded into T, and before the same values
+	// encoded back into a document, the original the result is compared with.
+	cfg    T
+	before map[string]any
+}
+
+// layerView decodes the values of a layer into T.
+// It acquires the read lock.
+func (s *Store[T]) layerView(layerName layer.Name) (updateView[T], error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var view updateView[T]
+	entry, err := s.writableLayerLocked(layerName)
+	if err != nil {
+		return view, err
+	}
+	view.entry = entry
+	view.data = container.DeepCopyMap(entry.data)
+
+	// Values at alias paths are read as their fields, and tombstones are
+	// dropped; the view is a copy of the layer's data.
+	data, _ := resolveAliases(view.data, s.aliasMappings)
+	values := make(map[string]any)
+	layerMerger{sentinel: s.unsetSentinel}.mergeMap(values, data, "", "", nil)
+
+	if err := s.decodeInto(values, &view.cfg); err != nil {
+		return view, fmt.Errorf("failed to decode layer %q: %w", layerName, err)
+	}
+	encoder := documentEncoder{converters: s.converters}
+	view.before = encoder.encode(reflect.ValueOf(view.cfg), s.schema.Table)
+	return view, nil
+}
+
+// applyUpdate writes the changes between view and cfg to a layer and records
+// them in its changeset. It fails with ErrUpdateConflict if the layer no longer
+// holds the data the view was read from. Sensitivity is validated for every
+// patch before any is applied. applied is false if cfg did not change the layer.
+// It acquires the write lock, and returns the current configuration and
+// subscribers snapshot for notification outside the lock.
+func (s *Store[T]) applyUpdate(layerName layer.Name, view updateView[T], cfg T) (current T, subscribers []subscriber[T], applied bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, err := s.writableLayerLocked(layerName)
+	if err != nil {
+		return current, nil, false, err
+	}
+	if entry != view.entry || !reflect.DeepEqual(entry.data, view.data) {
+		return current, nil, false, ErrUpdateConflict
+	}
+
+	encoder := documentEncoder{converters: s.converters}
+	patches := document.Diff(view.before, encoder.encode(reflect.ValueOf(cfg), s.schema.Table))
+	if patches.IsEmpty() {
+		return current, nil, false, nil
+	}
+
+	for _, patch := range patches {
+		if patch.Op == document.PatchOpRemove {
+			continue
+		}
+		if err := validateSensitivityTree(s.schema.Trie, patch.Path, patch.Value, entry.sensitive); err != nil {
+			return current, nil, false, fmt.Errorf("%w: path %s, layer %s", err, patch.Path, layerName)
+		}
+	}
+
+	snapshot := s.snapshotLayersLocked()
+
+	for _, patch := range patches {
+		if patch.Op == document.PatchOpRemove {
+			if jsonptr.DeletePath(entry.data, patch.Path) {
+				entry.changeset = append(entry.changeset, patch)
+				applied = true
+			}
+			continue
+		}
+
+		result := jsonptr.SetPath(entry.data, patch.Path, patch.Value)
+		if !result.Success {
+			s.restoreLayersLocked(snapshot)
+			return current, nil, false, fmt.Errorf("failed to set value at path %q", patch.Path)
+		}
+		// The layer may lack paths the decoded view filled with zero values
+		op := document.PatchOpReplace
+		if result.Created {
+			op = document.PatchOpAdd
+		}
+		entry.changeset = append(entry.changeset, document.JSONPatch{
+			Op:    op,
+			Path:  patch.Path,
+			Value: patch.Value,
+		})
+		applied = true
+	}
+
+	if !applied {
+		return current, nil, false, nil
+	}
+
+	s.syncLayerDirty(entry)
+
+	// Re-materialize to update the resolved config
+	current, subscribers, err = s.commitLocked(context.Background(), snapshot, []layer.Name{layerName})
+	return current, subscribers, true, err
+}
+
+// validateSensitivityTree validates the sensitivity of path and of every path
+// within value, which may be a map or slice written as a whole.
+func validateSensitivityTree(trie *MappingTrie, path string, value any, layerSensitive bool) error {
+	if err := validateSensitivity(trie, path, layerSensitive); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		for _, key := range sortedKeys(v) {
+			if err := validateSensitivityTree(trie, path+"/"+jsonptr.Escape(key), v[key], layerSensitive); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, elem := range v {
+			if err := validateSensitivityTree(trie, path+"/"+strconv.Itoa(i), elem, layerSensitive); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// documentEncoder encodes configuration structs into layer documents: maps
+// following the source paths of the mapping table, with values in the form
+// written by SetTo. Nil pointers, slices, maps and interfaces are omitted.
+type documentEncoder struct {
+	converters converterRegistry
+
+	// absolute holds the values of fields remapped to absolute paths. They are
+	// set once the whole struct has been encoded.
+	absolute []pathValue
+}
+
+// encode encodes v, a struct or a pointer to a struct, with table.
+func (e *documentEncoder) encode(v reflect.Value, table *MappingTable) map[string]any {
+	root := make(map[string]any)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return root
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || table == nil {
+		return root
+	}
+
+	e.absolute = nil
+	e.encodeStruct(root, v, table)
+	for _, pv := range e.absolute {
+		jsonptr.SetPath(root, pv.path, pv.value)
+	}
+	return root
+}
+
+// encodeStruct encodes the fields of v into dst.
+func (e *documentEncoder) encodeStruct(dst map[string]any, v reflect.Value, table *MappingTable) {
+	// Relative remappings are set after the fields at their own keys, so
+	// that a field encoding a whole map does not overwrite them.
+	var relative []pathValue
+	var embedded []*PathMapping
+	for _, m := range table.Mappings {
+		if m.Skipped {
+			continue
+		}
+		if m.Embedded {
+			embedded = append(embedded, m)
+			continue
+		}
+		value, ok := e.encodeField(v.FieldByIndex(m.StructField.Index), m, table)
+		if !ok {
+			continue
+		}
+		switch {
+		case m.SourcePath == "":
+			dst[m.FieldKey] = value
+		case m.IsRelative:
+			relative = append(relative, pathValue{path: m.SourcePath, value: value})
+		default:
+			e.absolute = append(e.absolute, pathValue{path: m.SourcePath, value: value})
+		}
+	}
+	for _, pv := range relative {
+		jsonptr.SetPath(dst, pv.path, pv.value)
+	}
+	for _, m := range embedded {
+		e.encodeEmbedded(dst, v.FieldByIndex(m.StructField.Index), m, table)
+	}
+}
+
+// encodeEmbedded encodes the fields of an embedded struct into dst, the
+// document of the embedding struct. As in encoding/json, the fields of the
+// embedding struct take precedence over promoted fields with the same key.
+func (e *documentEncoder) encodeEmbedded(dst map[string]any, field reflect.Value, m *PathMapping, table *MappingTable) {
+	value, ok := e.encodeValue(field, table.Nested[m.FieldKey])
+	if !ok {
+		return
+	}
+	promoted, ok := value.(map[string]any)
+	if !ok {
+		return
+	}
+	for key, elem := range promoted {
+		if !table.hasFieldKey(key) {
+			dst[key] = elem
+		}
+	}
+}
+
+// encodeField encodes the value of a field. It returns false if the value is
+// omitted.
+func (e *documentEncoder) encodeField(field reflect.Value, m *PathMapping, table *MappingTable) (any, bool) {
+	if e.converters.handles(m.FieldType) {
+		value := converterDocumentValue(field.Interface())
+		return value, value != nil
+	}
+	if union, ok := table.Unions[m.FieldKey]; ok {
+		return e.encodeUnion(field, union)
+	}
+
+	fieldType := m.FieldType
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	switch fieldType.Kind() {
+	case reflect.Slice, reflect.Array:
+		return e.encodeValue(field, table.SliceElement[m.FieldKey])
+	case reflect.Map:
+		return e.encodeValue(field, table.MapValue[m.FieldKey])
+	}
+	return e.encodeValue(field, table.Nested[m.FieldKey])
+}
+
+// encodeValue encodes v. table is the mapping table of v if it is a struct,
+// or of its elements if it is a slice or a map.
+func (e *documentEncoder) encodeValue(v reflect.Value, table *MappingTable) (any, bool) {
+	for {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return nil, false
+		}
+		if v.CanInterface() {
+			switch v.Interface().(type) {
+			case json.Marshaler, encoding.TextMarshaler:
+				return encodeJSONValue(v), true
+			}
+		}
+		if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface {
+			break
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if table == nil {
+			return encodeJSONValue(v), true
+		}
+		values := make(map[string]any)
+		e.encodeStruct(values, v, table)
+		return values, true
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, false
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return encodeJSONValue(v), true
+		}
+		elems := make([]any, v.Len())
+		for i := range elems {
+			elems[i], _ = e.encodeValue(v.Index(i), table)
+		}
+		return elems, true
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, false
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return encodeJSONValue(v), true
+		}
+		values := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()], _ = e.encodeValue(iter.Value(), table)
+		}
+		return values, true
+	case reflect.Bool:
+		return v.Bool(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return v.String(), true
+	}
+	return encodeJSONValue(v), true
+}
+
+// encodeUnion encodes the value of a union field, adding the discriminator
+// of each variant.
+func (e *documentEncoder) encodeUnion(v reflect.Value, union *UnionTable) (any, bool) {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, false
+		}
+		elems := make([]any, v.Len())
+		for i := range elems {
+			elems[i], _ = e.encodeVariant(v.Index(i), union)
+		}
+		return elems, true
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, false
+		}
+		values := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()], _ = e.encodeVariant(iter.Value(), union)
+		}
+		return values, true
+	}
+	return e.encodeVariant(v, union)
+}
+
+// encodeVariant encodes v, an interface holding a variant of union.
+func (e *documentEncoder) encodeVariant(v reflect.Value, union *UnionTable) (any, bool) {
+	if v.Kind() != reflect.Interface || v.IsNil() {
+		return e.encodeValue(v, nil)
+	}
+	for _, name := range union.names() {
+		if union.Variants[name] != v.Elem().Type() {
+			continue
+		}
+		value, ok := e.encodeValue(v.Elem(), union.Tables[name])
+		if values, isMap := value.(map[string]any); isMap {
+			values[union.Discriminator] = name
+		}
+		return value, ok
+	}
+	return e.encodeValue(v.Elem(), nil)
+}
+
+// encodeJSONValue encodes v, a value without a mapping table, through its
+// JSON encoding.
+func encodeJSONValue(v reflect.Value) any {
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return v.Interface()
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return v.Interface()
+	}
+	return value
+}
@@ -0,0 +1,324 @@
+package jubako
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/format/json"
+	"github.com/yacchi/jubako/layer"
+	"github.com/yacchi/jubako/layer/mapdata"
+	"github.com/yacchi/jubako/source/fs"
+)
+
+type updateServer struct {
+	Host  string `json:"host"`
+	Label string `json:"label" jubako:"meta/label"`
+}
+
+type updateDatabase struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+type updateConfig struct {
+	Name     string          `json:"name"`
+	Server   updateServer    `json:"server"`
+	Port     int             `json:"port" jubako:"/server/listen/port"`
+	Timeout  time.Duration   `json:"timeout"`
+	Features map[string]bool `json:"features"`
+	Tags     []string        `json:"tags"`
+	Database *updateDatabase `json:"database"`
+	Token    string          `json:"token" jubako:"sensitive"`
+}
+
+func newUpdateStore(t *testing.T) *Store[updateConfig] {
+	t.Helper()
+	store := New[updateConfig]()
+	if err := store.Add(mapdata.New("defaults", map[string]any{
+		"server": map[string]any{"listen": map[string]any{"port": 8080}},
+	})); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Add(mapdata.New("user", map[string]any{
+		"name":     "app",
+		"server":   map[string]any{"host": "localhost", "meta": map[string]any{"label": "old"}},
+		"timeout":  "5s",
+		"features": map[string]any{"a": true, "b": false},
+		"tags":     []any{"x"},
+	})); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return store
+}
+
+func TestStore_Update(t *testing.T) {
+	store := newUpdateStore(t)
+
+	err := store.Update("user", func(cfg *updateConfig) error {
+		// The layer's own values only
+		if cfg.Port != 0 || cfg.Server.Label != "old" || cfg.Timeout != 5*time.Second {
+			t.Errorf("cfg = %+v, want the values of the user layer", cfg)
+		}
+		cfg.Port = 9000
+		cfg.Server.Label = "new"
+		cfg.Timeout = 10 * time.Second
+		delete(cfg.Features, "b")
+		cfg.Tags = append(cfg.Tags, "y")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	entry := store.findLayerLocked("user")
+	want := document.JSONPatchSet{
+		document.NewRemovePatch("/features/b"),
+		document.NewAddPatch("/server/listen/port", int64(9000)),
+		document.NewReplacePatch("/server/meta/label", "new"),
+		document.NewReplacePatch("/tags", []any{"x", "y"}),
+		document.NewReplacePatch("/timeout", "10s"),
+	}
+	if !reflect.DeepEqual(entry.changeset, want) {
+		t.Errorf("changeset = %v, want %v", entry.changeset, want)
+	}
+	if !store.GetLayerInfo("user").Dirty() {
+		t.Error("layer should be dirty after Update")
+	}
+
+	got := store.Get()
+	if got.Port != 9000 || got.Server.Label != "new" || got.Timeout != 10*time.Second ||
+		!reflect.DeepEqual(got.Features, map[string]bool{"a": true}) || !reflect.DeepEqual(got.Tags, []string{"x", "y"}) {
+		t.Errorf("Get() = %+v", got)
+	}
+
+	// Only the fields set on a new struct are written
+	if err := store.Update("user", func(cfg *updateConfig) error {
+		cfg.Database = &updateDatabase{Host: "db"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	last := entry.changeset[len(entry.changeset)-1]
+	if !reflect.DeepEqual(last, document.NewAddPatch("/database/host", "db")) {
+		t.Errorf("last patch = %v", last)
+	}
+	if db := store.Get().Database; db == nil || db.Host != "db" {
+		t.Errorf("Database = %+v", db)
+	}
+}
+
+func TestStore_Update_NoChange(t *testing.T) {
+	store := newUpdateStore(t)
+	if err := store.Update("user", func(cfg *updateConfig) error {
+		cfg.Name = "app"
+		return nil
+	}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if store.GetLayerInfo("user").Dirty() {
+		t.Error("layer should not be dirty without changes")
+	}
+}
+
+func TestStore_Update_Errors(t *testing.T) {
+	store := newUpdateStore(t)
+
+	errCallback := errors.New("callback failed")
+	err := store.Update("user", func(cfg *updateConfig) error {
+		cfg.Name = "changed"
+		return errCallback
+	})
+	if !errors.Is(err, errCallback) {
+		t.Errorf("Update() error = %v, want %v", err, errCallback)
+	}
+
+	err = store.Update("user", func(cfg *updateConfig) error {
+		cfg.Name = "changed"
+		cfg.Token = "secret"
+		return nil
+	})
+	if !errors.Is(err, ErrSensitiveFieldToNormalLayer) {
+		t.Errorf("Update() error = %v, want ErrSensitiveFieldToNormalLayer", err)
+	}
+
+	if got := store.Get().Name; got != "app" {
+		t.Errorf("Name = %q, want unchanged app", got)
+	}
+	if store.GetLayerInfo("user").Dirty() {
+		t.Error("layer should not be dirty after failed updates")
+	}
+
+	err = store.Update("missing", func(*updateConfig) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), `layer "missing" not found`) {
+		t.Errorf("Update() error = %v, want layer not found", err)
+	}
+}
+
+func TestStore_Update_Conflict(t *testing.T) {
+	store := newUpdateStore(t)
+
+	// A change made while fn runs is not overwritten; fn runs again with it
+	calls := 0
+	err := store.Update("user", func(cfg *updateConfig) error {
+		calls++
+		if calls == 1 {
+			if err := store.SetTo("user", "/name", "concurrent"); err != nil {
+				t.Fatalf("SetTo() error = %v", err)
+			}
+		} else if cfg.Name != "concurrent" {
+			t.Errorf("Name = %q on retry, want concurrent", cfg.Name)
+		}
+		cfg.Server.Host = "example.com"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+	if got := store.Get(); got.Name != "concurrent" || got.Server.Host != "example.com" {
+		t.Errorf("Get() = %+v, want both changes", got)
+	}
+
+	// A layer that keeps changing fails the update
+	err = store.Update("user", func(cfg *updateConfig) error {
+		calls++
+		cfg.Name = "lost"
+		return store.SetTo("user", "/tags", []any{"x", strconv.Itoa(calls)})
+	})
+	if !errors.Is(err, ErrUpdateConflict) {
+		t.Errorf("Update() error = %v, want ErrUpdateConflict", err)
+	}
+	if got := store.Get().Name; got != "concurrent" {
+		t.Errorf("Name = %q, want concurrent", got)
+	}
+}
+
+type UpdateBase struct {
+	Name string `json:"name"`
+	Port int    `json:"port"`
+}
+
+type updateEmbeddedConfig struct {
+	UpdateBase
+	*UpdateExtra
+	Port  int    `json:"port"`
+	Label string `json:"label"`
+}
+
+type UpdateExtra struct {
+	Region string `json:"region"`
+}
+
+func TestStore_Update_Embedded(t *testing.T) {
+	store := New[updateEmbeddedConfig]()
+	if err := store.Add(mapdata.New("user", map[string]any{"name": "a", "port": 8080})); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if err := store.Update("user", func(cfg *updateEmbeddedConfig) error {
+		if cfg.Port != 8080 {
+			t.Errorf("Port = %d, want 8080", cfg.Port)
+		}
+		cfg.Name = "b"
+		cfg.Port = 9000
+		cfg.UpdateExtra = &UpdateExtra{Region: "eu"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	// Promoted fields are written at the keys of the embedding struct; the
+	// outer Port takes precedence over UpdateBase.Port
+	want := document.JSONPatchSet{
+		document.NewReplacePatch("/name", "b"),
+		document.NewReplacePatch("/port", int64(9000)),
+		document.NewAddPatch("/region", "eu"),
+	}
+	if changeset := store.findLayerLocked("user").changeset; !reflect.DeepEqual(changeset, want) {
+		t.Errorf("changeset = %v, want %v", changeset, want)
+	}
+	if got := store.Get(); got.Name != "b" || got.Port != 9000 || got.UpdateExtra == nil || got.Region != "eu" {
+		t.Errorf("Get() = %+v", got)
+	}
+}
+
+func TestStore_Update_Union(t *testing.T) {
+	store := New[unionConfig]()
+	if err := store.Add(mapdata.New("user", map[string]any{
+		"storage": map[string]any{"type": "s3", "bucket": "b"},
+	})); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if err := store.Update("user", func(cfg *unionConfig) error {
+		cfg.Storage = &unionLocal{Path: "/data"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	want := document.JSONPatchSet{
+		document.NewRemovePatch("/storage/bucket"),
+		document.NewAddPatch("/storage/local_path", "/data"),
+		document.NewReplacePatch("/storage/type", "local"),
+	}
+	if changeset := store.findLayerLocked("user").changeset; !reflect.DeepEqual(changeset, want) {
+		t.Errorf("changeset = %v, want %v", changeset, want)
+	}
+	if local, ok := store.Get().Storage.(*unionLocal); !ok || local.Path != "/data" {
+		t.Errorf("Storage = %#v, want *unionLocal with path /data", store.Get().Storage)
+	}
+}
+
+func TestStore_Update_Save(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"name": "app", "extra": {"kept": true}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := New[updateConfig]()
+	if err := store.Add(layer.New("user", fs.New(path), json.New())); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if err := store.Update("user", func(cfg *updateConfig) error {
+		cfg.Server.Host = "example.com"
+		return nil
+	}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := store.Save(ctx); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n  \"extra\": {\n    \"kept\": true\n  },\n  \"name\": \"app\",\n  \"server\": {\n    \"host\": \"example.com\"\n  }\n}\n"
+	if string(data) != want {
+		t.Errorf("saved = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,229 @@
+package jubako
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yacchi/jubako/container"
+	"github.com/yacchi/jubako/document"
+	"github.com/yacchi/jubako/layer"
+)
+
+// ValidationError is returned when a materialized configuration is rejected by
+// a validator registered with WithValidator, by the Validate method of T, or by
+// constraints declared in jubako struct tags (see ConstraintError).
+//
+// When validation fails, the Store keeps the previous configuration value and
+// rolls back the layer changes that produced the rejected value.
+type ValidationError struct {
+	// Layers lists the layers whose changes triggered the validation.
+	// For Load and Reload, this is every registered layer.
+	Layers []layer.Name
+
+	// Err is the error returned by the validator.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	if len(e.Layers) == 0 {
+		return fmt.Sprintf("config validation failed: %v", e.Err)
+	}
+	names := make([]string, len(e.Layers))
+	for i, name := range e.Layers {
+		names[i] = string(name)
+	}
+	return fmt.Sprintf("config validation failed (layers: %s): %v", strings.Join(names, ", "), e.Err)
+}
+
+// Unwrap returns the underlying validator error.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// validatable is implemented by configuration types that validate themselves.
+type validatable interface {
+	Validate() error
+}
+
+// WithValidator registers a function that validates every materialized configuration
+// before it becomes visible through Get and subscribers.
+// Multiple validators may be registered; they run in registration order after
+// the Validate method of T (if any), and the first error aborts the update.
+//
+// A rejected update keeps the previous value, rolls back the layer changes made by
+// Load, Reload, Set, SetTo, DeleteFrom, RemoveLayer, ReplaceLayer, SetLayerPriority
+// or a watch update, and returns a *ValidationError.
+// During Watch, the error is reported through StoreWatchConfig.OnError.
+//
+// The type parameter must match the Store's configuration type; New panics otherwise.
+//
+// Example:
+//
+//	store := jubako.New[Config](jubako.WithValidator(func(c Config) error {
+//	    if c.Server.Port == 0 {
+//	        return errors.New("server.port is required")
+//	    }
+//	    return nil
+//	}))
+func WithValidator[T any](fn func(T) error) StoreOption {
+	return func(o *storeOptions) {
+		o.validators = append(o.validators, fn)
+	}
+}
+
+// buildValidators converts the validators collected by WithValidator to the Store's type.
+func buildValidators[T any](fns []any) []func(T) error {
+	if len(fns) == 0 {
+		return nil
+	}
+	validators := make([]func(T) error, 0, len(fns))
+	for _, fn := range fns {
+		v, ok := fn.(func(T) error)
+		if !ok {
+			var zero T
+			panic(fmt.Sprintf("jubako: WithValidator: %T does not match Store type %T", fn, zero))
+		}
+		validators = append(validators, v)
+	}
+	return validators
+}
+
+// validationEnabled reports whether materialized values can be rejected with
+// a *ValidationError: by validators, by constraint tags, by the checks of
+// converted and union fields, by unknown keys in UnknownKeysError mode, by
+// failed interpolation, or by the schema of a layer (see WithLayerSchema).
+func (s *Store[T]) validationEnabled() bool {
+	if len(s.validators) > 0 || s.hasConstraints || s.hasConverters || s.hasUnions ||
+		s.unknownKeys == UnknownKeysError || s.interpolation {
+		return true
+	}
+	for _, entry := range s.layers {
+		if entry.schema != nil {
+			return true
+		}
+	}
+	var zero T
+	if _, ok := any(zero).(validatable); ok {
+		return true
+	}
+	_, ok := any(&zero).(validatable)
+	return ok
+}
+
+// validate runs the Validate method of T and the registered validators.
+func (s *Store[T]) validate(value T) error {
+	if v, ok := any(value).(validatable); ok {
+		if err := v.Validate(); err != nil {
+			return &ValidationError{Err: err}
+		}
+	} else if v, ok := any(&value).(validatable); ok {
+		if err := v.Validate(); err != nil {
+			return &ValidationError{Err: err}
+		}
+	}
+	for _, fn := range s.validators {
+		if err := fn(value); err != nil {
+			return &ValidationError{Err: err}
+		}
+	}
+	return nil
+}
+
+// layerSnapshot captures the in-memory state of a layer so that it can be
+// restored when validation rejects a change.
+type layerSnapshot struct {
+	entry           *layerEntry
+	data            map[string]any
+	loadedData      map[string]any
+	changeset       document.JSONPatchSet
+	dependencies    []string
+	projectionDirty []string
+
+	version          int
+	hasVersion       bool
+	original         map[string]any
+	persistMigration bool
+}
+
+// snapshotLayersLocked captures the state of all layers.
+// It returns nil when no value can be rejected, since nothing is rolled back then.
+// Caller must hold the write lock.
+func (s *Store[T]) snapshotLayersLocked() []layerSnapshot {
+	if !s.validationEnabled() {
+		return nil
+	}
+	return s.captureLayersLocked()
+}
+
+// captureLayersLocked captures the state of all layers, whether or not
+// validation is enabled.
+// Caller must hold the write lock.
+func (s *Store[T]) captureLayersLocked() []layerSnapshot {
+	snapshots := make([]layerSnapshot, 0, len(s.layers))
+	for _, entry := range s.layers {
+		snap := layerSnapshot{
+			entry:           entry,
+			loadedData:      entry.loadedData,
+			changeset:       entry.changeset,
+			dependencies:    entry.dependencies,
+			projectionDirty: entry.projectionDirty,
+
+			version:          entry.version,
+			hasVersion:       entry.hasVersion,
+			original:         entry.original,
+			persistMigration: entry.persistMigration,
+		}
+		// data is modified in place by Set and DeleteFrom, so it must be copied.
+		if entry.data != nil {
+			snap.data = container.DeepCopyMap(entry.data)
+		}
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots
+}
+
+// restoreLayersLocked restores the layer state captured by snapshotLayersLocked.
+// Caller must hold the write lock.
+func (s *Store[T]) restoreLayersLocked(snapshots []layerSnapshot) {
+	for _, snap := range snapshots {
+		snap.entry.data = snap.data
+		snap.entry.loadedData = snap.loadedData
+		snap.entry.changeset = snap.changeset
+		snap.entry.dependencies = snap.dependencies
+		snap.entry.projectionDirty = snap.projectionDirty
+		snap.entry.version = snap.version
+		snap.entry.hasVersion = snap.hasVersion
+		snap.entry.original = snap.original
+		snap.entry.persistMigration = snap.persistMigration
+		s.syncLayerDirty(snap.entry)
+	}
+}
+
+// commitLocked materializes the configuration after layer changes.
+// If validation rejects the result, the layers are restored from snapshot and the
+// returned *ValidationError records the given layers as the cause.
+// Caller must hold the write lock.
+func (s *Store[T]) commitLocked(ctx context.Context, snapshot []layerSnapshot, layers []layer.Name) (T, []subscriber[T], error) {
+	current, subscribers, err := s.materializeLocked(ctx)
+	if err != nil {
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			verr.Layers = layers
+			s.restoreLayersLocked(snapshot)
+		}
+	}
+	return current, subscribers, err
+}
+
+// layerNamesLocked returns the names of all layers in priority order.
+// Caller must hold the lock.
+func (s *Store[T]) layerNamesLocked() []layer.Name {
+	names := make([]layer.Name, len(s.layers))
+	for i, entry := range s.layers {
+		names[i] = entry.layer.Name()
+	}
+	return names
+}
@@ -4,9 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
-	"github.com/yacchi/jubako/container"
 	"github.com/yacchi/jubako/layer"
 	"github.com/yacchi/jubako/watcher"
 )
@@ -20,6 +21,8 @@ type StoreWatchConfig struct {
 
 	// OnError is called when a watch error occurs.
 	// layerName may be empty for store-level errors (e.g., materialization failures).
+	// When the updated configuration is rejected by validation, err is a *ValidationError
+	// listing the updated layers, and layerName is set if exactly one layer changed.
 	// If nil, errors are silently ignored.
 	OnError func(layerName layer.Name, err error)
 
@@ -56,11 +59,174 @@ type layerUpdate struct {
 	result layer.LayerWatchResult
 }
 
+// watchSession tracks a running Watch invocation so that per-layer watchers
+// can be stopped and restarted when the layer stack changes.
+type watchSession struct {
+	ctx      context.Context
+	cfg      StoreWatchConfig
+	watchCfg watcher.WatchConfig
+	merged   chan layerUpdate
+
+	// forwarders tracks the goroutines sending to merged, including those of
+	// watchers detached by RemoveLayer or ReplaceLayer, so that merged is
+	// closed only after they have returned.
+	forwarders sync.WaitGroup
+
+	// mu protects watchers and closed.
+	// Lock order: Store.mu before watchSession.mu.
+	mu       sync.Mutex
+	watchers []layerWatchState
+	closed   bool
+}
+
+// newLayerWatchState creates (but does not start) a watcher for the layer entry.
+func (ws *watchSession) newLayerWatchState(entry *layerEntry) (layerWatchState, error) {
+	// The watchCfg is passed via WithBaseConfig option
+	lw, err := entry.layer.Watch(layer.WithBaseConfig(ws.watchCfg))
+	if err != nil {
+		return layerWatchState{}, fmt.Errorf("failed to create watcher for layer %q: %w", entry.layer.Name(), err)
+	}
+	return layerWatchState{
+		name:    entry.layer.Name(),
+		watcher: lw,
+		entry:   entry,
+	}, nil
+}
+
+// start starts a layer watcher and forwards its results to the merged channel.
+func (ws *watchSession) start(state layerWatchState) error {
+	// Configuration was already provided at Watch() time via WatcherInitializerParams
+	if err := state.watcher.Start(ws.ctx); err != nil {
+		return fmt.Errorf("failed to start watcher for layer %q: %w", state.name, err)
+	}
+
+	// Forward results to merged channel until the watcher or the session is stopped
+	ws.forwarders.Add(1)
+	go func() {
+		defer ws.forwarders.Done()
+		results := state.watcher.Results()
+		for {
+			select {
+			case result, ok := <-results:
+				if !ok {
+					return
+				}
+				select {
+				case ws.merged <- layerUpdate{name: state.name, entry: state.entry, result: result}:
+				case <-ws.ctx.Done():
+					return
+				}
+			case <-ws.ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// registerWatchSessionLocked adds a session to the store.
+// Caller must hold the write lock.
+func (s *Store[T]) registerWatchSessionLocked(ws *watchSession) {
+	s.watchSessions = append(s.watchSessions, ws)
+}
+
+// unregisterWatchSession removes a session from the store.
+func (s *Store[T]) unregisterWatchSession(ws *watchSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, current := range s.watchSessions {
+		if current == ws {
+			s.watchSessions = append(s.watchSessions[:i], s.watchSessions[i+1:]...)
+			return
+		}
+	}
+}
+
+// detachedWatcher is a layer watcher removed from a running watch session.
+type detachedWatcher struct {
+	session *watchSession
+	state   layerWatchState
+}
+
+// detachLayerWatchersLocked removes the watchers for entry from all running
+// watch sessions and returns them. The caller stops them with
+// stopDetachedWatchers after releasing the lock.
+// Caller must hold the write lock.
+func (s *Store[T]) detachLayerWatchersLocked(entry *layerEntry) []detachedWatcher {
+	var detached []detachedWatcher
+	for _, ws := range s.watchSessions {
+		ws.mu.Lock()
+		kept := ws.watchers[:0]
+		for _, state := range ws.watchers {
+			if state.entry == entry {
+				detached = append(detached, detachedWatcher{session: ws, state: state})
+				continue
+			}
+			kept = append(kept, state)
+		}
+		ws.watchers = kept
+		ws.mu.Unlock()
+	}
+	return detached
+}
+
+// stopDetachedWatchers stops watchers returned by detachLayerWatchersLocked.
+// Failures are reported through the OnError callback of the session each
+// watcher belonged to, as the layer change itself has succeeded.
+func stopDetachedWatchers(ctx context.Context, detached []detachedWatcher) {
+	for _, d := range detached {
+		if err := stopLayerWatchers(ctx, []layerWatchState{d.state}); err != nil && d.session.cfg.OnError != nil {
+			d.session.cfg.OnError(d.state.name, err)
+		}
+	}
+}
+
+// attachLayerWatchersLocked starts watchers for entry in all running watch sessions.
+// Failures are reported through each session's OnError callback.
+// Caller must hold the write lock.
+func (s *Store[T]) attachLayerWatchersLocked(entry *layerEntry) {
+	if entry.noWatch {
+		return
+	}
+	for _, ws := range s.watchSessions {
+		ws.mu.Lock()
+		if ws.closed {
+			ws.mu.Unlock()
+			continue
+		}
+		state, err := ws.newLayerWatchState(entry)
+		if err == nil {
+			err = ws.start(state)
+		}
+		if err == nil {
+			ws.watchers = append(ws.watchers, state)
+		}
+		ws.mu.Unlock()
+
+		if err != nil && ws.cfg.OnError != nil {
+			ws.cfg.OnError(entry.layer.Name(), err)
+		}
+	}
+}
+
+// stopLayerWatchers stops the given layer watchers and joins their errors.
+func stopLayerWatchers(ctx context.Context, states []layerWatchState) error {
+	var errs []error
+	for _, state := range states {
+		if err := state.watcher.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop watcher for layer %q: %w", state.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Watch starts watching all watchable layers for changes.
 // When changes are detected, the store automatically reloads and notifies subscribers.
 // Call Load before Watch to materialize an initial configuration snapshot.
 //
 // Layers marked with WithNoWatch are skipped.
+// Layers replaced or removed while watching (see ReplaceLayer and RemoveLayer)
+// have their watchers stopped, and replacement layers are watched automatically.
 // Returns a stop function to stop watching all layers.
 //
 // Example:
@@ -71,10 +237,15 @@ type layerUpdate struct {
 //	}
 //	defer stop(context.Background())
 func (s *Store[T]) Watch(ctx context.Context, cfg StoreWatchConfig) (stop func(context.Context) error, err error) {
-	s.mu.Lock()
+	watchCtx, watchCancel := context.WithCancel(ctx)
+	session := &watchSession{
+		ctx: watchCtx,
+		cfg: cfg,
+		// Build WatchConfig from options
+		watchCfg: watcher.NewWatchConfig(cfg.WatcherOpts...),
+	}
 
-	// Build WatchConfig from options
-	watchCfg := watcher.NewWatchConfig(cfg.WatcherOpts...)
+	s.mu.Lock()
 
 	// Collect watchable layers
 	var watchers []layerWatchState
@@ -85,75 +256,57 @@ func (s *Store[T]) Watch(ctx context.Context, cfg StoreWatchConfig) (stop func(c
 		}
 
 		// Create the watcher with config (doesn't start yet)
-		// The watchCfg is passed via WithBaseConfig option
-		lw, err := entry.layer.Watch(layer.WithBaseConfig(watchCfg))
+		state, err := session.newLayerWatchState(entry)
 		if err != nil {
 			s.mu.Unlock()
+			watchCancel()
 			// Clean up any watchers we already created
-			for _, ws := range watchers {
-				ws.watcher.Stop(ctx)
-			}
-			return nil, fmt.Errorf("failed to create watcher for layer %q: %w", entry.layer.Name(), err)
+			stopLayerWatchers(ctx, watchers)
+			return nil, err
 		}
-
-		watchers = append(watchers, layerWatchState{
-			name:    entry.layer.Name(),
-			watcher: lw,
-			entry:   entry,
-		})
+		watchers = append(watchers, state)
 	}
-	s.mu.Unlock()
 
-	if len(watchers) == 0 {
-		// No watchable layers - return a no-op stop function
-		return func(ctx context.Context) error { return nil }, nil
-	}
+	// Register the session before releasing the store lock so that layer stack
+	// changes made while the watchers start are applied to this session.
+	session.merged = make(chan layerUpdate, max(len(watchers), 1)*10)
+	session.mu.Lock()
+	s.registerWatchSessionLocked(session)
+	s.mu.Unlock()
 
 	// Start all watchers and merge their results
-	merged := make(chan layerUpdate, len(watchers)*10)
-	watchCtx, watchCancel := context.WithCancel(ctx)
-
-	for _, ws := range watchers {
-		// Configuration was already provided at Watch() time via WatcherInitializerParams
-		if err := ws.watcher.Start(watchCtx); err != nil {
+	for _, state := range watchers {
+		if err := session.start(state); err != nil {
+			session.closed = true
+			session.mu.Unlock()
+			s.unregisterWatchSession(session)
 			watchCancel()
 			// Clean up watchers
-			for _, w := range watchers {
-				w.watcher.Stop(ctx)
-			}
-			return nil, fmt.Errorf("failed to start watcher for layer %q: %w", ws.name, err)
+			stopLayerWatchers(ctx, watchers)
+			return nil, err
 		}
-
-		// Forward results to merged channel
-		// Note: Since Go 1.22, loop variables are scoped per-iteration,
-		// so capturing 'ws' directly in the closure is safe.
-		go func() {
-			for result := range ws.watcher.Results() {
-				select {
-				case merged <- layerUpdate{name: ws.name, entry: ws.entry, result: result}:
-				case <-watchCtx.Done():
-					return
-				}
-			}
-		}()
 	}
+	session.watchers = watchers
+	session.mu.Unlock()
 
 	// Process merged updates with debouncing
-	go s.watchLoop(watchCtx, merged, cfg)
+	go s.watchLoop(watchCtx, session.merged, cfg)
 
 	// Return stop function
 	stop = func(stopCtx context.Context) error {
+		s.unregisterWatchSession(session)
 		watchCancel()
 
-		var errs []error
-		for _, ws := range watchers {
-			if err := ws.watcher.Stop(stopCtx); err != nil {
-				errs = append(errs, fmt.Errorf("failed to stop watcher for layer %q: %w", ws.name, err))
-			}
-		}
+		session.mu.Lock()
+		session.closed = true
+		running := session.watchers
+		session.watchers = nil
+		session.mu.Unlock()
 
-		close(merged)
-		return errors.Join(errs...)
+		err := stopLayerWatchers(stopCtx, running)
+		session.forwarders.Wait()
+		close(session.merged)
+		return err
 	}
 
 	return stop, nil
@@ -223,10 +376,22 @@ func (s *Store[T]) watchLoop(ctx context.Context, updates <-chan layerUpdate, cf
 func (s *Store[T]) applyUpdates(ctx context.Context, updates map[layer.Name]layerUpdate, cfg StoreWatchConfig) {
 	s.mu.Lock()
 
+	snapshot := s.snapshotLayersLocked()
+
 	// Update layer data from watchers
+	var updated []layer.Name
+	loadErrs := make(map[layer.Name]error)
 	for _, update := range updates {
-		update.entry.data = update.result.Data
-		update.entry.loadedData = container.DeepCopyMap(update.result.Data)
+		// Skip updates for layers that were removed or replaced in the meantime
+		if s.findLayerLocked(update.name) != update.entry {
+			continue
+		}
+		// Validate and migrate the new data before it replaces the layer's data
+		if err := s.setLoadedDataLocked(update.entry, update.result.Data); err != nil {
+			loadErrs[update.name] = err
+			continue
+		}
+		updated = append(updated, update.name)
 		// Clear changeset as we have fresh data
 		update.entry.changeset = nil
 		update.entry.dependencies = nil
@@ -235,10 +400,37 @@ func (s *Store[T]) applyUpdates(ctx context.Context, updates map[layer.Name]laye
 	}
 
 	// Re-materialize the configuration
-	current, subscribers, err := s.materializeLocked(ctx)
+	sort.Slice(updated, func(i, j int) bool { return updated[i] < updated[j] })
+	current, subscribers, err := s.commitLocked(ctx, snapshot, updated)
+	if err == nil {
+		for _, name := range updated {
+			if applied := updates[name].result.Applied; applied != nil {
+				applied()
+			}
+		}
+	}
 	s.mu.Unlock()
 
+	// Layers that failed validation or migration keep their previous data
+	if cfg.OnError != nil {
+		for name, loadErr := range loadErrs {
+			cfg.OnError(name, loadErr)
+		}
+	}
+
 	if err != nil {
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			// The previous configuration is kept; report which layers were rejected.
+			if cfg.OnError != nil {
+				var name layer.Name
+				if len(updated) == 1 {
+					name = updated[0]
+				}
+				cfg.OnError(name, err)
+			}
+			return
+		}
 		if cfg.OnError != nil {
 			cfg.OnError("", fmt.Errorf("failed to materialize after watch update: %w", err))
 		}
@@ -2,6 +2,7 @@ package jubako_test
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -395,3 +396,374 @@ func TestStore_Watch_NoWatchableLayers(t *testing.T) {
 
 // Ensure testSource implements the document interface check
 var _ document.Document = json.New()
+
+func TestStore_Watch_ReplaceLayer(t *testing.T) {
+	oldSrc := newTestSource([]byte(`{"value": "old"}`))
+	newSrc := newTestSource([]byte(`{"value": "new"}`))
+
+	store := jubako.New[TestConfig]()
+	if err := store.Add(layer.New("profile", oldSrc, json.New())); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	stop, err := store.Watch(ctx, jubako.StoreWatchConfig{DebounceDelay: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Watch() error: %v", err)
+	}
+	defer stop(context.Background())
+
+	if err := store.ReplaceLayer(ctx, "profile", layer.New("profile", newSrc, json.New())); err != nil {
+		t.Fatalf("ReplaceLayer() error: %v", err)
+	}
+	if got := store.Get().Value; got != "new" {
+		t.Fatalf("expected value=new after replace, got %s", got)
+	}
+
+	// The old source is no longer watched
+	oldSrc.mu.RLock()
+	oldNotify := oldSrc.notifyFn
+	oldSrc.mu.RUnlock()
+	if oldNotify != nil {
+		t.Error("expected old layer watcher to be stopped")
+	}
+
+	// The replacement is watched
+	newSrc.Update([]byte(`{"value": "updated"}`))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if store.Get().Value == "updated" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := store.Get().Value; got != "updated" {
+		t.Errorf("expected value=updated, got %s", got)
+	}
+}
+
+func TestStore_Watch_RemoveLayer(t *testing.T) {
+	baseSrc := newTestSource([]byte(`{"value": "base"}`))
+	tenantSrc := newTestSource([]byte(`{"value": "tenant"}`))
+
+	store := jubako.New[TestConfig]()
+	if err := store.Add(layer.New("base", baseSrc, json.New())); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	if err := store.Add(layer.New("tenant", tenantSrc, json.New())); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	stop, err := store.Watch(ctx, jubako.StoreWatchConfig{DebounceDelay: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Watch() error: %v", err)
+	}
+	defer stop(context.Background())
+
+	if err := store.RemoveLayer("tenant"); err != nil {
+		t.Fatalf("RemoveLayer() error: %v", err)
+	}
+	if got := store.Get().Value; got != "base" {
+		t.Fatalf("expected value=base after remove, got %s", got)
+	}
+
+	tenantSrc.mu.RLock()
+	tenantNotify := tenantSrc.notifyFn
+	tenantSrc.mu.RUnlock()
+	if tenantNotify != nil {
+		t.Error("expected removed layer watcher to be stopped")
+	}
+
+	// Remaining layers are still watched
+	baseSrc.Update([]byte(`{"value": "base-updated"}`))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if store.Get().Value == "base-updated" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := store.Get().Value; got != "base-updated" {
+		t.Errorf("expected value=base-updated, got %s", got)
+	}
+}
+
+// stopFailingLayer wraps a layer so that stopping its watcher fails.
+type stopFailingLayer struct {
+	layer.Layer
+	stopErr error
+}
+
+func (l *stopFailingLayer) Watch(opts ...layer.WatchOption) (layer.LayerWatcher, error) {
+	w, err := l.Layer.Watch(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &stopFailingWatcher{LayerWatcher: w, stopErr: l.stopErr}, nil
+}
+
+type stopFailingWatcher struct {
+	layer.LayerWatcher
+	stopErr error
+}
+
+func (w *stopFailingWatcher) Stop(ctx context.Context) error {
+	if err := w.LayerWatcher.Stop(ctx); err != nil {
+		return err
+	}
+	return w.stopErr
+}
+
+func TestStore_Watch_RemoveLayer_StopError(t *testing.T) {
+	errStop := errors.New("stop failed")
+	store := jubako.New[TestConfig]()
+	if err := store.Add(layer.New("base", newTestSource([]byte(`{"value": "base"}`)), json.New())); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	tenant := &stopFailingLayer{Layer: layer.New("tenant", newTestSource([]byte(`{"value": "tenant"}`)), json.New()), stopErr: errStop}
+	if err := store.Add(tenant); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	var (
+		mu       sync.Mutex
+		reported []error
+	)
+	stop, err := store.Watch(ctx, jubako.StoreWatchConfig{
+		DebounceDelay: 10 * time.Millisecond,
+		OnError: func(layerName layer.Name, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if layerName == "tenant" {
+				reported = append(reported, err)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Watch() error: %v", err)
+	}
+	defer stop(context.Background())
+
+	// The layer is removed; the watcher error goes to OnError
+	if err := store.RemoveLayer("tenant"); err != nil {
+		t.Fatalf("RemoveLayer() error: %v", err)
+	}
+	if got := store.Get().Value; got != "base" {
+		t.Errorf("expected value=base after remove, got %s", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reported) != 1 || !errors.Is(reported[0], errStop) {
+		t.Errorf("OnError reported %v, want %v", reported, errStop)
+	}
+}
+
+// floodingLayer wraps a layer so that its watcher keeps delivering results
+// after Stop, like a watcher with a result in flight.
+type floodingLayer struct {
+	layer.Layer
+	done <-chan struct{}
+}
+
+func (l *floodingLayer) Watch(opts ...layer.WatchOption) (layer.LayerWatcher, error) {
+	return &floodingWatcher{results: make(chan layer.LayerWatchResult), done: l.done}, nil
+}
+
+type floodingWatcher struct {
+	results chan layer.LayerWatchResult
+	done    <-chan struct{}
+}
+
+func (w *floodingWatcher) Start(ctx context.Context) error {
+	go func() {
+		for {
+			select {
+			case w.results <- layer.LayerWatchResult{Data: map[string]any{"value": "late"}}:
+			case <-w.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (w *floodingWatcher) Stop(ctx context.Context) error { return nil }
+
+func (w *floodingWatcher) Results() <-chan layer.LayerWatchResult { return w.results }
+
+func TestStore_Watch_RemoveLayerThenStop(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	ctx := context.Background()
+	for range 20 {
+		store := jubako.New[TestConfig]()
+		if err := store.Add(layer.New("base", newTestSource([]byte(`{"value": "base"}`)), json.New())); err != nil {
+			t.Fatalf("Add() error: %v", err)
+		}
+		tenant := &floodingLayer{Layer: layer.New("tenant", newTestSource([]byte(`{"value": "tenant"}`)), json.New()), done: done}
+		if err := store.Add(tenant); err != nil {
+			t.Fatalf("Add() error: %v", err)
+		}
+		if err := store.Load(ctx); err != nil {
+			t.Fatalf("Load() error: %v", err)
+		}
+
+		stop, err := store.Watch(ctx, jubako.StoreWatchConfig{DebounceDelay: time.Millisecond})
+		if err != nil {
+			t.Fatalf("Watch() error: %v", err)
+		}
+		if err := store.RemoveLayer("tenant"); err != nil {
+			t.Fatalf("RemoveLayer() error: %v", err)
+		}
+		// Stopping must not close the merged channel while the removed
+		// layer's results are still being forwarded.
+		if err := stop(ctx); err != nil {
+			t.Fatalf("stop() error: %v", err)
+		}
+	}
+}
+
+func TestStore_Watch_ValidationRejected(t *testing.T) {
+	src := newTestSource([]byte(`{"value": "initial", "count": 1}`))
+
+	store := jubako.New[TestConfig](jubako.WithValidator(func(c TestConfig) error {
+		if c.Count <= 0 {
+			return errors.New("count must be positive")
+		}
+		return nil
+	}))
+	if err := store.Add(layer.New("test", src, json.New())); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	var subscriberCalled atomic.Int32
+	store.Subscribe(func(TestConfig) { subscriberCalled.Add(1) })
+
+	errCh := make(chan error, 1)
+	var errLayer atomic.Value
+	watchCfg := jubako.StoreWatchConfig{
+		DebounceDelay: 10 * time.Millisecond,
+		OnError: func(name layer.Name, err error) {
+			errLayer.Store(name)
+			select {
+			case errCh <- err:
+			default:
+			}
+		},
+	}
+	stop, err := store.Watch(ctx, watchCfg)
+	if err != nil {
+		t.Fatalf("Watch() error: %v", err)
+	}
+	defer stop(context.Background())
+
+	src.Update([]byte(`{"value": "rejected", "count": 0}`))
+
+	select {
+	case err := <-errCh:
+		var verr *jubako.ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("OnError err = %v, want *ValidationError", err)
+		}
+		if len(verr.Layers) != 1 || verr.Layers[0] != "test" {
+			t.Errorf("Layers = %v, want [test]", verr.Layers)
+		}
+		if name := errLayer.Load().(layer.Name); name != "test" {
+			t.Errorf("OnError layer = %q, want test", name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnError was not called for rejected update")
+	}
+
+	cfg := store.Get()
+	if cfg.Value != "initial" || cfg.Count != 1 {
+		t.Errorf("Get() = %+v, want previous value", cfg)
+	}
+	if rv := store.GetAt("/value"); rv.Value != "initial" {
+		t.Errorf("GetAt(/value) = %v, want initial", rv.Value)
+	}
+	if subscriberCalled.Load() != 0 {
+		t.Error("subscribers should not be notified for rejected updates")
+	}
+}
+
+func TestStore_Watch_LayerSchemaRejected(t *testing.T) {
+	src := newTestSource([]byte(`{"value": "initial", "count": 1}`))
+
+	store := jubako.New[TestConfig]()
+	schema := []byte(`{"properties": {"count": {"type": "integer", "minimum": 1}}}`)
+	if err := store.Add(layer.New("test", src, json.New()), jubako.WithLayerSchema(schema)); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := store.Load(ctx); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	errCh := make(chan error, 1)
+	stop, err := store.Watch(ctx, jubako.StoreWatchConfig{
+		DebounceDelay: 10 * time.Millisecond,
+		OnError: func(name layer.Name, err error) {
+			select {
+			case errCh <- err:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Watch() error: %v", err)
+	}
+	defer stop(context.Background())
+
+	src.Update([]byte(`{"value": "rejected", "count": 0}`))
+
+	select {
+	case err := <-errCh:
+		var schemaErr *jubako.LayerSchemaError
+		if !errors.As(err, &schemaErr) {
+			t.Fatalf("OnError err = %v, want *LayerSchemaError", err)
+		}
+		if len(schemaErr.Violations) != 1 || schemaErr.Violations[0].Path != "/count" {
+			t.Errorf("Violations = %v", schemaErr.Violations)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnError was not called for rejected update")
+	}
+
+	if cfg := store.Get(); cfg.Value != "initial" || cfg.Count != 1 {
+		t.Errorf("Get() = %+v, want previous value", cfg)
+	}
+}